@@ -0,0 +1,148 @@
+package main
+
+import (
+	"aurora/pkg/aurora"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/dustin/go-humanize"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze",
+	Short: "Analyze the mods folder",
+	Args:  cobra.NoArgs,
+}
+
+var analyzeDuplicatesCmd = &cobra.Command{
+	Use:   "duplicates",
+	Short: "Find identical files across mods and the space they waste",
+	Long: `Find identical files across the mods of the mods folder, and report the
+space they waste by file type and by pair of mods. Only files sharing their
+size with another one are hashed; checksums are cached in hashes.json, so
+later runs only hash what changed. CSV output lists every duplicate file.`,
+	Args: cobra.NoArgs,
+	Run:  runAnalyzeDuplicatesCmd,
+}
+
+func init() {
+	analyzeDuplicatesCmd.Flags().Int("limit", 10, "rows per table section (0 for all)")
+	analyzeCmd.AddCommand(analyzeDuplicatesCmd)
+}
+
+func runAnalyzeDuplicatesCmd(cmd *cobra.Command, args []string) {
+	limit, err := cmd.Flags().GetInt("limit")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading limit flag: %v\n", err)
+		os.Exit(exitError)
+	}
+	app := loadApp()
+
+	var progress func(hashed, total int)
+	printer := newProgressPrinter(os.Stderr)
+	if !isMachineOutput(cmd) {
+		progress = printer.count("Hashing files")
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	result, err := app.FindDuplicates(ctx, progress)
+	printer.finish()
+	switch {
+	case err == nil:
+	case errors.Is(err, context.Canceled):
+		fmt.Fprintf(os.Stderr, "Analysis cancelled\n")
+		os.Exit(exitCancelled)
+	case errors.Is(err, aurora.ErrInvalidConfig):
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitInvalidConfig)
+	default:
+		fmt.Fprintf(os.Stderr, "Failed to find duplicates: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	render(cmd, report{
+		data:  result,
+		rows:  duplicatesRows(result),
+		table: func(w io.Writer) { duplicatesTable(w, result, limit) },
+	})
+}
+
+// duplicatesRows lists one csv record per duplicate file
+func duplicatesRows(result aurora.DuplicatesResult) [][]string {
+	rows := [][]string{{"sha256", "size", "mod", "path"}}
+	for _, set := range result.Sets {
+		for _, file := range set.Files {
+			rows = append(rows, []string{set.SHA256, strconv.FormatUint(set.Size, 10), file.Mod, file.Path})
+		}
+	}
+	return rows
+}
+
+func duplicatesTable(w io.Writer, result aurora.DuplicatesResult, limit int) {
+	fmt.Fprintf(w, "Scanned %d files (%s), %d duplicate sets wasting %s\n",
+		result.ScannedFiles, humanize.Bytes(result.ScannedSize), len(result.Sets), result.WastedBytesHuman)
+	if result.SkippedFiles > 0 {
+		fmt.Fprintf(w, "Skipped %d unreadable files, see the log\n", result.SkippedFiles)
+	}
+	if len(result.Sets) == 0 {
+		return
+	}
+
+	types := [][]string{}
+	for _, t := range truncate(result.Types, limit) {
+		ext := t.Extension
+		if ext == "" {
+			ext = "(none)"
+		}
+		types = append(types, []string{ext, strconv.Itoa(t.Sets), strconv.Itoa(t.Files), humanize.Bytes(t.WastedBytes)})
+	}
+	fmt.Fprintln(w, "\nBy file type:")
+	renderTable(w, []string{"Type", "Sets", "Files", "Wasted"}, types)
+
+	pairs := [][]string{}
+	for _, p := range truncate(result.Pairs, limit) {
+		modB := p.ModB
+		if modB == p.ModA {
+			modB = "(itself)"
+		}
+		pairs = append(pairs, []string{abbreviatePath(p.ModA, 40), abbreviatePath(modB, 40), strconv.Itoa(p.Files), humanize.Bytes(p.WastedBytes)})
+	}
+	fmt.Fprintln(w, "\nBy mod pair:")
+	renderTable(w, []string{"Mod", "Shares with", "Files", "Wasted"}, pairs)
+
+	sets := [][]string{}
+	for _, set := range truncate(result.Sets, limit) {
+		first := set.Files[0]
+		sets = append(sets, []string{
+			abbreviatePath(first.Mod+"/"+first.Path, 60),
+			strconv.Itoa(len(set.Files)),
+			humanize.Bytes(set.Size),
+			humanize.Bytes(set.WastedBytes),
+		})
+	}
+	fmt.Fprintln(w, "\nLargest sets:")
+	renderTable(w, []string{"File", "Copies", "Size", "Wasted"}, sets)
+}
+
+// truncate keeps the first limit items, all of them when limit is 0
+func truncate[T any](items []T, limit int) []T {
+	if limit > 0 && len(items) > limit {
+		return items[:limit]
+	}
+	return items
+}
+
+func renderTable(w io.Writer, header []string, rows [][]string) {
+	table := tablewriter.NewTable(w)
+	table.Header(header)
+	table.Bulk(rows)
+	table.Render()
+}
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"aurora/internal/config"
+	"aurora/internal/logger"
+	"aurora/internal/paths"
 	"fmt"
 	"os"
 
@@ -17,17 +20,71 @@ var rootCmd = &cobra.Command{
 	Use:     "aurora",
 	Short:   "Utils for penumbra and mods operations",
 	Version: fmt.Sprintf("%s (commit %s, built %s)", version, commit, date),
+	// main prints the error once; cobra would print it a second time
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if path, _ := cmd.Flags().GetString("config"); path != "" {
+			config.SetConfigFile(path)
+		}
+		if name, _ := cmd.Flags().GetString("profile"); name != "" {
+			config.SetProfile(name)
+		}
+		if err := setupLogging(cmd); err != nil {
+			return err
+		}
+		logger.Logger().Info("Aurora CLI starting", "version", version, "command", cmd.CommandPath())
+		return validateOutputFormat(outputFormat(cmd))
+	},
+}
+
+// setupLogging starts the log of a CLI run from the --log-* flags. The
+// log file follows --config like the other data files, so it is only
+// resolved once the config file is known.
+func setupLogging(cmd *cobra.Command) error {
+	name, _ := cmd.Flags().GetString("log-level")
+	level, err := logger.ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	file, _ := cmd.Flags().GetString("log-file")
+	if file == "" {
+		file = paths.LogFile()
+	}
+	format, _ := cmd.Flags().GetString("log-format")
+	return logger.Configure(logger.Options{File: file, Level: level, Format: format})
 }
 
 func init() {
+	rootCmd.PersistentFlags().String("config", "", "config file path (default: $AURORA_CONFIG, $AURORA_HOME/config.json, or next to the executable)")
+	rootCmd.PersistentFlags().String("profile", "", "use the config of this profile, created from config.json on first use (default: $AURORA_PROFILE)")
+	rootCmd.PersistentFlags().StringP("output", "o", outputTable, "output format: table, json, yaml or csv")
+	rootCmd.PersistentFlags().String("log-level", "info", "log level: debug, info, warn or error")
+	rootCmd.PersistentFlags().String("log-file", "", `log file, "-" for stderr (default: aurora.log next to config.json)`)
+	rootCmd.PersistentFlags().String("log-format", logger.FormatText, "log format: text or json")
 	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(penumbraCmd)
 	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(verifyCmd)
+	rootCmd.AddCommand(decryptCmd)
+	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(watchCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(filtersCmd)
+	rootCmd.AddCommand(inclusionsCmd)
+	rootCmd.AddCommand(mirrorsCmd)
+	rootCmd.AddCommand(profilesCmd)
+	rootCmd.AddCommand(analyzeCmd)
+	rootCmd.AddCommand(cleanupCmd)
+	rootCmd.AddCommand(usageCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(reportCmd)
 }
 
 func main() {
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	logger.Close()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 }
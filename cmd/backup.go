@@ -2,11 +2,17 @@ package main
 
 import (
 	"aurora/pkg/aurora"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
 
-	"github.com/creativeyann17/go-delta/pkg/compress"
+	"github.com/dustin/go-humanize"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 )
@@ -19,43 +25,173 @@ var backupCmd = &cobra.Command{
 
 func init() {
 	backupCmd.Flags().BoolP("validate", "v", false, "display list of mods to backup only")
-	backupCmd.Flags().IntP("threads", "t", 1, "compress folders concurrently")
+	backupCmd.Flags().IntP("threads", "t", 0, "compress folders concurrently (default: config concurrency)")
+	backupCmd.Flags().Bool("resume", false, "continue an interrupted backup, reusing its finished archives")
+	backupCmd.Flags().String("key-file", "", "file holding the encryption passphrase (default: config keyFile, $AURORA_PASSPHRASE or a prompt)")
 }
 
 func runBackupCmd(cmd *cobra.Command, args []string) {
-	app, err := aurora.New()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
-		os.Exit(1)
-	}
-	if !app.IsConfigValid() {
-		cfg := app.GetConfig()
-		fmt.Fprintf(os.Stderr, "Configuration is not valid:\n")
-		fmt.Fprintf(os.Stderr, "  Penumbra: %s\n", cfg.Status.PenumbraStatus)
-		fmt.Fprintf(os.Stderr, "  Mods: %s\n", cfg.Status.ModsStatus)
-		fmt.Fprintf(os.Stderr, "\nRun 'aurora config --reset' to fix\n")
-		return
-	}
+	app := loadApp()
+	requireValidConfig(app)
 
 	validate, err := cmd.Flags().GetBool("validate")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading validate flag: %v\n", err)
-		return
+		os.Exit(exitError)
 	}
 
 	thread, err := cmd.Flags().GetInt("threads")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading thread flag: %v\n", err)
-		return
+		os.Exit(exitError)
+	}
+
+	resume, err := cmd.Flags().GetBool("resume")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading resume flag: %v\n", err)
+		os.Exit(exitError)
+	}
+	if resume && validate {
+		fmt.Fprintf(os.Stderr, "Error: --resume and --validate can't be combined\n")
+		os.Exit(exitError)
 	}
 
-	validation, err := app.ValidateBackup()
+	keyFile, err := cmd.Flags().GetString("key-file")
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to validate backup: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "Error reading key-file flag: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	if validate {
+		validation, err := app.ValidateBackup()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to validate backup: %v\n", err)
+			os.Exit(exitError)
+		}
+		renderValidation(cmd, validation)
+		return
+	}
+
+	// A resumed backup keeps the encryption it started with
+	encrypt := app.GetConfig().Encrypt
+	if resume {
+		pending, _ := app.PendingBackup()
+		encrypt = pending != nil && pending.Encrypted
+	}
+	passphrase := ""
+	if encrypt {
+		passphrase = resolvePassphrase(app, keyFile, !resume)
+	}
+
+	observer := newCLIObserver(cmd)
+	// Ctrl+C stops the backup; finished batches stay in the staging dir
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	backupResult, err := app.RunBackup(ctx, aurora.RunBackupOptions{Threads: thread, Resume: resume, Passphrase: passphrase}, observer)
+	observer.finish()
+
+	switch {
+	case err == nil:
+	case errors.Is(err, aurora.ErrBackupCancelled):
+		fmt.Fprintf(os.Stderr, "Backup cancelled, run 'aurora backup --resume' to continue it\n")
+		os.Exit(exitCancelled)
+	case errors.Is(err, aurora.ErrInvalidConfig):
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitInvalidConfig)
+	case errors.Is(err, aurora.ErrNoSpace):
+		fmt.Fprintf(os.Stderr, "Error: Not enough disk space for backup\n")
+		os.Exit(exitNoSpace)
+	case errors.Is(err, aurora.ErrUploadFailed):
+		fmt.Fprintf(os.Stderr, "Error: %v\nThe backup is kept in the output directory\n", err)
+		os.Exit(exitError)
+	case errors.Is(err, aurora.ErrWrongPassphrase):
+		fmt.Fprintf(os.Stderr, "Error: wrong passphrase, the interrupted backup was encrypted with another one\n")
+		os.Exit(exitError)
+	case errors.Is(err, aurora.ErrNothingToBackup), errors.Is(err, aurora.ErrNoPendingBackup), errors.Is(err, aurora.ErrPassphraseRequired),
+		errors.Is(err, aurora.ErrHookFailed), errors.Is(err, aurora.ErrFinalizingBackup), errors.Is(err, aurora.ErrBackupRunning):
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	default:
+		fmt.Fprintf(os.Stderr, "Failed to backup: %v\n", err)
+		if pending, _ := app.PendingBackup(); pending != nil {
+			fmt.Fprintf(os.Stderr, "Finished mods are kept, run 'aurora backup --resume' to retry the rest\n")
+		}
+		os.Exit(exitError)
+	}
+
+	render(cmd, report{
+		data: backupResult,
+		rows: [][]string{
+			{"outputPath", "originalSize", "compressedSize", "ratio"},
+			{backupResult.OutputPath, strconv.FormatUint(backupResult.OriginalSize, 10), strconv.FormatUint(backupResult.CompressedSize, 10), backupResult.Ratio},
+		},
+		table: func(w io.Writer) { backupResultTable(w, backupResult) },
+	})
+}
+
+// cliObserver renders a backup run: the preview on stdout, notices and
+// progress on stderr. Machine output gets the backup result only.
+type cliObserver struct {
+	cmd     *cobra.Command
+	machine bool
+	printer *progressPrinter
+}
+
+func newCLIObserver(cmd *cobra.Command) *cliObserver {
+	observer := &cliObserver{cmd: cmd, machine: isMachineOutput(cmd)}
+	if !observer.machine {
+		observer.printer = newProgressPrinter(os.Stderr)
+	}
+	return observer
+}
+
+func (o *cliObserver) Validated(validation aurora.BackupValidation) {
+	if !o.machine {
+		renderValidation(o.cmd, validation)
+	}
+}
+
+func (o *cliObserver) Notice(message string) {
+	fmt.Fprintln(os.Stderr, message)
+}
+
+func (o *cliObserver) Progress(progress aurora.BackupProgress) {
+	if o.printer != nil {
+		o.printer.update(progress)
 	}
+}
+
+// finish ends the progress block once the run returns
+func (o *cliObserver) finish() {
+	if o.printer != nil {
+		o.printer.finish()
+	}
+}
+
+func renderValidation(cmd *cobra.Command, validation aurora.BackupValidation) {
+	render(cmd, report{
+		data:  validation,
+		rows:  validationRows(validation),
+		table: func(w io.Writer) { validationTable(w, validation) },
+	})
+}
+
+// validationRows lists one csv record per backup candidate
+func validationRows(validation aurora.BackupValidation) [][]string {
+	rows := [][]string{{"mod", "collections", "size", "excludedBy", "includedBy"}}
+	for _, item := range validation.Items {
+		rows = append(rows, []string{
+			item.Mod.Name,
+			strings.Join(item.Mod.Collections, ";"),
+			strconv.FormatUint(item.Mod.Size, 10),
+			item.FilteredBy,
+			item.IncludedBy,
+		})
+	}
+	return rows
+}
 
-	// Display table
+func validationTable(w io.Writer, validation aurora.BackupValidation) {
 	data := [][]string{
 		{"Mod", "Collections", "Size"},
 	}
@@ -73,52 +209,34 @@ func runBackupCmd(cmd *cobra.Command, args []string) {
 		data = append(data, row)
 	}
 
-	table := tablewriter.NewTable(os.Stdout)
+	table := tablewriter.NewTable(w)
 	table.Header(data[0])
 	table.Bulk(data[1:])
 	table.Render()
 
-	fmt.Printf("Total initial size: %s\n", validation.TotalSizeHuman)
-	fmt.Printf("Backup size: %s (estimated)\n", validation.EstimatedSizeHuman)
-	fmt.Printf("Available disk space: %s\n", validation.AvailableSpaceHuman)
-
-	if validate {
-		return
-	}
-
-	if !validation.HasEnoughSpace {
-		fmt.Fprintf(os.Stderr, "Error: Not enough disk space for backup\n")
-		return
-	}
-
-	// Run backup
-	if thread <= 0 {
-		thread = 1
-	}
-
-	folders, err := app.GetBackupFolders()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to collect mods: %v\n", err)
-		os.Exit(1)
-	}
-	if len(folders) == 0 {
-		fmt.Fprintf(os.Stderr, "No mods to backup\n")
-		return
-	}
-
-	opts := aurora.NewBackupOptions(folders, thread, app.GetCompression(), app.GetConfig().OutputPath, false)
-
-	progressCb, progress := compress.ProgressBarCallback()
-	result, err := compress.Compress(opts, progressCb)
+	fmt.Fprintf(w, "Total initial size: %s\n", validation.TotalSizeHuman)
+	fmt.Fprintf(w, "Backup size: %s (estimated, likely %s)\n", validation.EstimatedSizeHuman, validation.EstimatedRangeHuman)
+	fmt.Fprintf(w, "Available disk space: %s\n", validation.AvailableSpaceHuman)
+}
 
-	if progress != nil {
-		progress.Wait()
-	}
+func backupResultTable(w io.Writer, result aurora.BackupResult) {
+	fmt.Fprintf(w, "Backup written to: %s\n", result.OutputPath)
+	fmt.Fprintf(w, "Original size: %s\n", humanize.Bytes(result.OriginalSize))
+	fmt.Fprintf(w, "Compressed size: %s (%s)\n", humanize.Bytes(result.CompressedSize), result.Ratio)
+	destinationsTable(w, result.Destinations)
+}
 
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to backup: %v\n", err)
-		return
+// destinationsTable prints a line per copy of a backup
+func destinationsTable(w io.Writer, destinations []aurora.DestinationResult) {
+	for _, dest := range destinations {
+		kind := "Uploaded to"
+		if dest.Mirror {
+			kind = "Mirrored to"
+		}
+		if dest.Error != "" {
+			fmt.Fprintf(w, "%s: %s FAILED: %s\n", kind, dest.Target, dest.Error)
+		} else {
+			fmt.Fprintf(w, "%s: %s (%d files, %s, checksums verified)\n", kind, dest.Target, dest.Files, humanize.Bytes(dest.Bytes))
+		}
 	}
-
-	fmt.Print(compress.FormatSummary(result, opts))
 }
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"aurora/pkg/aurora"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+)
+
+// cleanupTimeLayout prints modification and quarantine times
+const cleanupTimeLayout = "2006-01-02 15:04"
+
+var cleanupCmd = &cobra.Command{
+	Use:   "cleanup",
+	Short: "List mods no collection uses, and quarantine them",
+	Long: `List the mods of the mods folder no collection uses, with their size and
+last change. 'aurora cleanup quarantine' moves some of them out of the mods
+folder into a quarantine next to config.json, 'aurora cleanup undo' puts
+them back. Delete a quarantine's folder to get rid of its mods for good.`,
+	Args: cobra.NoArgs,
+	Run:  runCleanupCmd,
+}
+
+var cleanupQuarantineCmd = &cobra.Command{
+	Use:   "quarantine <mod>...",
+	Short: "Move unused mods out of the mods folder",
+	Long: `Move unused mods out of the mods folder into a new quarantine. With
+--backup they are first archived to quarantine/<id> in the output folder,
+in the configured format, encrypted when encryption is on. Mods a
+collection uses are refused. Close Penumbra first, or reload its mods after.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if all, _ := cmd.Flags().GetBool("all"); all != (len(args) == 0) {
+			return fmt.Errorf("give mod names or --all")
+		}
+		return nil
+	},
+	Run: runCleanupQuarantineCmd,
+}
+
+func init() {
+	cleanupQuarantineCmd.Flags().Bool("all", false, "quarantine every unused mod not kept by an inclusion filter")
+	cleanupQuarantineCmd.Flags().Bool("backup", false, "archive the mods to the output folder first")
+	cleanupQuarantineCmd.Flags().String("key-file", "", "file holding the encryption passphrase (default: config keyFile, $AURORA_PASSPHRASE or a prompt)")
+	cleanupCmd.AddCommand(cleanupQuarantineCmd)
+
+	cleanupCmd.AddCommand(&cobra.Command{
+		Use:   "quarantined",
+		Short: "List the quarantines, newest first",
+		Args:  cobra.NoArgs,
+		Run:   runCleanupQuarantinedCmd,
+	})
+
+	cleanupCmd.AddCommand(&cobra.Command{
+		Use:   "undo [id]",
+		Short: "Move the mods of a quarantine back (default: the newest)",
+		Args:  cobra.MaximumNArgs(1),
+		Run:   runCleanupUndoCmd,
+	})
+}
+
+func runCleanupCmd(cmd *cobra.Command, args []string) {
+	app := loadApp()
+	requireValidConfig(app)
+
+	result, err := app.UnusedMods()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to list unused mods: %v\n", err)
+		os.Exit(exitError)
+	}
+	rows := [][]string{{"mod", "size", "modified", "includedBy"}}
+	for _, mod := range result.Mods {
+		rows = append(rows, []string{mod.Name, strconv.FormatUint(mod.Size, 10), mod.ModTime.Format(time.RFC3339), mod.IncludedBy})
+	}
+	render(cmd, report{
+		data:  result,
+		rows:  rows,
+		table: func(w io.Writer) { unusedModsTable(w, result) },
+	})
+}
+
+func unusedModsTable(w io.Writer, result aurora.UnusedModsResult) {
+	if len(result.Mods) == 0 {
+		fmt.Fprintln(w, "Every mod is used by a collection")
+		return
+	}
+	data := [][]string{}
+	for _, mod := range result.Mods {
+		name := abbreviatePath(mod.Name, 60)
+		if mod.IncludedBy != "" {
+			name += " (inclusion: " + mod.IncludedBy + ")"
+		}
+		data = append(data, []string{name, mod.SizeHuman, mod.ModTime.Local().Format(cleanupTimeLayout)})
+	}
+	renderTable(w, []string{"Mod", "Size", "Modified"}, data)
+	fmt.Fprintf(w, "%d unused mods, %s\n", len(result.Mods), result.SizeHuman)
+}
+
+func runCleanupQuarantineCmd(cmd *cobra.Command, args []string) {
+	all, err := cmd.Flags().GetBool("all")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading all flag: %v\n", err)
+		os.Exit(exitError)
+	}
+	backup, err := cmd.Flags().GetBool("backup")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading backup flag: %v\n", err)
+		os.Exit(exitError)
+	}
+	keyFile, err := cmd.Flags().GetString("key-file")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading key-file flag: %v\n", err)
+		os.Exit(exitError)
+	}
+	app := loadApp()
+	requireValidConfig(app)
+
+	mods := args
+	if all {
+		unused, err := app.UnusedMods()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to list unused mods: %v\n", err)
+			os.Exit(exitError)
+		}
+		for _, mod := range unused.Mods {
+			if mod.IncludedBy == "" {
+				mods = append(mods, mod.Name)
+			}
+		}
+		if len(mods) == 0 {
+			fmt.Fprintf(os.Stderr, "No unused mods to quarantine\n")
+			return
+		}
+	}
+	passphrase := ""
+	if backup && app.GetConfig().Encrypt {
+		passphrase = resolvePassphrase(app, keyFile, true)
+	}
+	if backup && !isMachineOutput(cmd) {
+		fmt.Fprintf(os.Stderr, "Archiving %d mods...\n", len(mods))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	q, err := app.QuarantineMods(ctx, aurora.QuarantineOptions{Mods: mods, Backup: backup, Passphrase: passphrase})
+	switch {
+	case err == nil:
+	case errors.Is(err, aurora.ErrBackupCancelled):
+		fmt.Fprintf(os.Stderr, "Quarantine cancelled, no mod was moved\n")
+		os.Exit(exitCancelled)
+	case errors.Is(err, aurora.ErrModNotFound), errors.Is(err, aurora.ErrModInUse), errors.Is(err, aurora.ErrPassphraseRequired):
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	case len(q.Mods) > 0:
+		fmt.Fprintf(os.Stderr, "Failed to quarantine every mod: %v\n%d mods were moved, run 'aurora cleanup undo %s' to put them back\n", err, len(q.Mods), q.ID)
+		os.Exit(exitError)
+	default:
+		fmt.Fprintf(os.Stderr, "Failed to quarantine mods: %v\n", err)
+		os.Exit(exitError)
+	}
+	renderQuarantine(cmd, q, "Quarantined")
+}
+
+func runCleanupQuarantinedCmd(cmd *cobra.Command, args []string) {
+	quarantines, err := loadApp().Quarantines()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to list quarantines: %v\n", err)
+		os.Exit(exitError)
+	}
+	rows := [][]string{{"id", "createdAt", "mods", "size", "dir", "backupDir"}}
+	for _, q := range quarantines {
+		rows = append(rows, []string{q.ID, q.CreatedAt.Format(time.RFC3339), strconv.Itoa(len(q.Mods)), strconv.FormatUint(q.Size, 10), q.Dir, q.BackupDir})
+	}
+	render(cmd, report{
+		data: quarantines,
+		rows: rows,
+		table: func(w io.Writer) {
+			if len(quarantines) == 0 {
+				fmt.Fprintln(w, "No quarantined mods")
+				return
+			}
+			data := [][]string{}
+			for _, q := range quarantines {
+				names := make([]string, len(q.Mods))
+				for i, mod := range q.Mods {
+					names[i] = mod.Name
+				}
+				backup := "no"
+				if q.BackupDir != "" {
+					backup = "yes"
+				}
+				data = append(data, []string{q.ID, q.CreatedAt.Local().Format(cleanupTimeLayout), abbreviatePath(strings.Join(names, ", "), 60), q.SizeHuman, backup})
+			}
+			renderTable(w, []string{"ID", "Created", "Mods", "Size", "Archived"}, data)
+		},
+	})
+}
+
+func runCleanupUndoCmd(cmd *cobra.Command, args []string) {
+	app := loadApp()
+	requireValidConfig(app)
+
+	id := ""
+	if len(args) == 1 {
+		id = args[0]
+	} else {
+		quarantines, err := app.Quarantines()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to list quarantines: %v\n", err)
+			os.Exit(exitError)
+		}
+		if len(quarantines) == 0 {
+			fmt.Fprintf(os.Stderr, "Error: no quarantine to undo\n")
+			os.Exit(exitError)
+		}
+		id = quarantines[0].ID
+	}
+
+	q, err := app.RestoreQuarantine(id)
+	switch {
+	case err == nil:
+	case errors.Is(err, aurora.ErrQuarantineNotFound), errors.Is(err, aurora.ErrRestoreConflict):
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	default:
+		fmt.Fprintf(os.Stderr, "Failed to undo quarantine: %v\n", err)
+		os.Exit(exitError)
+	}
+	renderQuarantine(cmd, q, "Restored")
+}
+
+// renderQuarantine prints a quarantine, the mods it moved as csv records
+func renderQuarantine(cmd *cobra.Command, q aurora.Quarantine, verb string) {
+	rows := [][]string{{"id", "mod", "size"}}
+	for _, mod := range q.Mods {
+		rows = append(rows, []string{q.ID, mod.Name, strconv.FormatUint(mod.Size, 10)})
+	}
+	render(cmd, report{
+		data: q,
+		rows: rows,
+		table: func(w io.Writer) {
+			fmt.Fprintf(w, "%s %d mods (%s), quarantine %s\n", verb, len(q.Mods), humanize.Bytes(q.Size), q.ID)
+			if q.BackupDir != "" {
+				fmt.Fprintf(w, "Archive kept in %s\n", q.BackupDir)
+			}
+		},
+	})
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
-	"aurora/internal/config"
 	"aurora/pkg/aurora"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"strings"
 
+	"github.com/dustin/go-humanize"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 )
@@ -13,24 +16,332 @@ import (
 var configCmd = &cobra.Command{
 	Use:   "config",
 	Short: "Configure the tool settings (config.json)",
+	Args:  cobra.NoArgs,
 	Run:   runConfigCmd,
 }
 
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print a setting (" + strings.Join(configKeyNames(), ", ") + ")",
+	Args:  cobra.ExactArgs(1),
+	Run:   runConfigGetCmd,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change a setting (" + strings.Join(configKeyNames(), ", ") + ")",
+	Args:  cobra.ExactArgs(2),
+	Run:   runConfigSetCmd,
+}
+
 func init() {
 	configCmd.Flags().BoolP("reset", "r", false, "reset the config file with default values")
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+}
+
+// configKey is a setting handled by `aurora config get|set`
+type configKey struct {
+	name     string
+	get      func(cfg aurora.ConfigResult) string
+	validate func(value string) error
+	set      func(app *aurora.Aurora, value string) error
+	// local keys choose what is read, run or written where, whether
+	// backups are encrypted or which are deleted: the API of `aurora serve`
+	// won't set them
+	local bool
+}
+
+var configKeys = []configKey{
+	{
+		name:     "penumbra",
+		get:      func(cfg aurora.ConfigResult) string { return cfg.PenumbraPath },
+		validate: requireDir,
+		set:      (*aurora.Aurora).SetPenumbraPath,
+		local:    true,
+	},
+	{
+		name:     "mods",
+		get:      func(cfg aurora.ConfigResult) string { return cfg.ModsPath },
+		validate: requireDir,
+		set:      (*aurora.Aurora).SetModsPath,
+		local:    true,
+	},
+	{
+		name: "output",
+		get:  func(cfg aurora.ConfigResult) string { return cfg.OutputPath },
+		validate: func(value string) error {
+			if value == "" {
+				return nil // current working directory
+			}
+			return requireDir(value)
+		},
+		set:   (*aurora.Aurora).SetOutputPath,
+		local: true,
+	},
+	{
+		name: "concurrency",
+		get:  func(cfg aurora.ConfigResult) string { return strconv.Itoa(cfg.Concurrency) },
+		validate: func(value string) error {
+			if n, err := strconv.Atoi(value); err != nil || n < 0 {
+				return fmt.Errorf("concurrency must be a number >= 0 (0 = all CPU cores), got %q", value)
+			}
+			return nil
+		},
+		set: func(app *aurora.Aurora, value string) error {
+			n, _ := strconv.Atoi(value)
+			return app.SetConcurrency(n)
+		},
+	},
+	{
+		name: "compression",
+		get:  func(cfg aurora.ConfigResult) string { return cfg.Compression },
+		validate: func(value string) error {
+			if value != aurora.CompressionNormal && value != aurora.CompressionMax {
+				return fmt.Errorf("compression must be %q or %q, got %q", aurora.CompressionNormal, aurora.CompressionMax, value)
+			}
+			return nil
+		},
+		set: (*aurora.Aurora).SetCompression,
+	},
+	{
+		name: "format",
+		get:  func(cfg aurora.ConfigResult) string { return cfg.Format },
+		validate: func(value string) error {
+			if value != aurora.FormatZip && value != aurora.FormatTarZst && value != aurora.FormatDelta {
+				return fmt.Errorf("format must be %q, %q or %q, got %q", aurora.FormatZip, aurora.FormatTarZst, aurora.FormatDelta, value)
+			}
+			return nil
+		},
+		set: (*aurora.Aurora).SetFormat,
+	},
+	{
+		name: "layout",
+		get:  func(cfg aurora.ConfigResult) string { return cfg.Layout },
+		validate: func(value string) error {
+			if value != aurora.LayoutSingle && value != aurora.LayoutCollection && value != aurora.LayoutGroup {
+				return fmt.Errorf("layout must be %q, %q or %q, got %q", aurora.LayoutSingle, aurora.LayoutCollection, aurora.LayoutGroup, value)
+			}
+			return nil
+		},
+		set: (*aurora.Aurora).SetLayout,
+	},
+	{
+		name: "maxPartSize",
+		get:  func(cfg aurora.ConfigResult) string { return formatPartSize(cfg.MaxPartSize) },
+		validate: func(value string) error {
+			_, err := parsePartSize(value)
+			return err
+		},
+		set: func(app *aurora.Aurora, value string) error {
+			size, _ := parsePartSize(value)
+			return app.SetMaxPartSize(size)
+		},
+	},
+	{
+		name: "encrypt",
+		get:  func(cfg aurora.ConfigResult) string { return strconv.FormatBool(cfg.Encrypt) },
+		validate: func(value string) error {
+			if _, err := strconv.ParseBool(value); err != nil {
+				return fmt.Errorf("encrypt must be true or false, got %q", value)
+			}
+			return nil
+		},
+		set: func(app *aurora.Aurora, value string) error {
+			encrypt, _ := strconv.ParseBool(value)
+			return app.SetEncrypt(encrypt)
+		},
+		local: true,
+	},
+	{
+		name: "keyFile",
+		get:  func(cfg aurora.ConfigResult) string { return cfg.KeyFile },
+		validate: func(value string) error {
+			if value == "" {
+				return nil // AURORA_PASSPHRASE or a prompt
+			}
+			info, err := os.Stat(value)
+			if err != nil || info.IsDir() {
+				return fmt.Errorf("keyFile must be an existing file, got %q", value)
+			}
+			return nil
+		},
+		set:   (*aurora.Aurora).SetKeyFile,
+		local: true,
+	},
+	{
+		name:     "preBackupHook",
+		get:      func(cfg aurora.ConfigResult) string { return cfg.PreBackupHook },
+		validate: aurora.ValidateHook,
+		set:      (*aurora.Aurora).SetPreBackupHook,
+		local:    true,
+	},
+	{
+		name:     "postBackupHook",
+		get:      func(cfg aurora.ConfigResult) string { return cfg.PostBackupHook },
+		validate: aurora.ValidateHook,
+		set:      (*aurora.Aurora).SetPostBackupHook,
+		local:    true,
+	},
+	{
+		name: "hookTimeout",
+		get:  func(cfg aurora.ConfigResult) string { return strconv.Itoa(cfg.HookTimeout) },
+		validate: func(value string) error {
+			if n, err := strconv.Atoi(value); err != nil || n < 0 {
+				return fmt.Errorf("hookTimeout must be a number of seconds >= 0 (0 = 10 minutes), got %q", value)
+			}
+			return nil
+		},
+		set: func(app *aurora.Aurora, value string) error {
+			n, _ := strconv.Atoi(value)
+			return app.SetHookTimeout(n)
+		},
+	},
+	{
+		name: "target",
+		get:  func(cfg aurora.ConfigResult) string { return cfg.Target },
+		validate: func(value string) error {
+			_, err := aurora.ParseTarget(value)
+			return err
+		},
+		set: func(app *aurora.Aurora, value string) error {
+			target, _ := aurora.ParseTarget(value)
+			return app.SetTarget(target)
+		},
+		local: true,
+	},
+	{
+		name: "keepIncrementals",
+		get:  func(cfg aurora.ConfigResult) string { return strconv.Itoa(cfg.Retention.Incrementals) },
+		validate: func(value string) error {
+			if n, err := strconv.Atoi(value); err != nil || n < 0 {
+				return fmt.Errorf("keepIncrementals must be a number of incremental backups >= 0 (0 = all), got %q", value)
+			}
+			return nil
+		},
+		set: func(app *aurora.Aurora, value string) error {
+			retention := app.GetRetention()
+			retention.Incrementals, _ = strconv.Atoi(value)
+			return app.SetRetention(retention)
+		},
+		local: true,
+	},
+	{
+		name: "keepDays",
+		get:  func(cfg aurora.ConfigResult) string { return strconv.Itoa(cfg.Retention.Days) },
+		validate: func(value string) error {
+			if n, err := strconv.Atoi(value); err != nil || n < 0 {
+				return fmt.Errorf("keepDays must be a number of days >= 0 (0 = forever), got %q", value)
+			}
+			return nil
+		},
+		set: func(app *aurora.Aurora, value string) error {
+			retention := app.GetRetention()
+			retention.Days, _ = strconv.Atoi(value)
+			return app.SetRetention(retention)
+		},
+		local: true,
+	},
+}
+
+// minPartSize keeps a typo like "4" (bytes) from splitting a backup into
+// millions of archives
+const minPartSize = 1 << 20
+
+// parsePartSize reads a max part size like "4GB", "3.9GiB" or "0" (no limit)
+func parsePartSize(value string) (uint64, error) {
+	if value == "0" {
+		return 0, nil
+	}
+	size, err := humanize.ParseBytes(value)
+	if err != nil || size < minPartSize {
+		return 0, fmt.Errorf("maxPartSize must be a size of at least 1MB like \"4GB\" or \"700MiB\", or 0 for no limit, got %q", value)
+	}
+	return size, nil
+}
+
+func formatPartSize(size uint64) string {
+	if size == 0 {
+		return "0"
+	}
+	return humanize.Bytes(size)
+}
+
+func configKeyNames() []string {
+	names := make([]string, len(configKeys))
+	for i, key := range configKeys {
+		names[i] = key.name
+	}
+	return names
+}
+
+func findConfigKey(name string) (configKey, error) {
+	for _, key := range configKeys {
+		if strings.EqualFold(key.name, name) {
+			return key, nil
+		}
+	}
+	return configKey{}, fmt.Errorf("unknown key %q (valid keys: %s)", name, strings.Join(configKeyNames(), ", "))
+}
+
+// requireDir rejects paths that are not existing directories
+func requireDir(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("invalid path %q: %w", path, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("invalid path %q: not a directory", path)
+	}
+	return nil
+}
+
+func runConfigGetCmd(cmd *cobra.Command, args []string) {
+	key, err := findConfigKey(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	value := key.get(loadApp().GetConfig())
+	render(cmd, report{
+		data:  map[string]string{key.name: value},
+		rows:  [][]string{{key.name}, {value}},
+		table: func(w io.Writer) { fmt.Fprintln(w, value) },
+	})
+}
+
+func runConfigSetCmd(cmd *cobra.Command, args []string) {
+	key, err := findConfigKey(args[0])
+	if err == nil {
+		err = key.validate(args[1])
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	if err := key.set(loadApp(), args[1]); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to save configuration: %v\n", err)
+		os.Exit(exitError)
+	}
 }
 
 func runConfigCmd(cmd *cobra.Command, args []string) {
 	reset, err := cmd.Flags().GetBool("reset")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading flag: %v\n", err)
-		return
+		os.Exit(exitError)
+	}
+	if reset && isMachineOutput(cmd) {
+		fmt.Fprintf(os.Stderr, "Error: --reset prompts for paths, use 'aurora config set' with --output %s\n", outputFormat(cmd))
+		os.Exit(exitError)
 	}
 
 	app, err := aurora.NewWithReset(reset)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 	cfg := app.GetConfig()
 
@@ -40,11 +351,51 @@ func runConfigCmd(cmd *cobra.Command, args []string) {
 		outputPath := prompt(fmt.Sprintf("Enter the backup output folder, empty = current directory (current: %s)", cfg.OutputPath))
 		if err := app.UpdateConfig(penumbraPath, modsPath, outputPath); err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to save configuration: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitError)
 		}
 		cfg = app.GetConfig()
 	}
 
+	render(cmd, report{
+		data:  cfg,
+		rows:  configRows(cfg),
+		table: func(w io.Writer) { configTable(w, cfg) },
+	})
+	if !cfg.Status.Valid {
+		os.Exit(exitInvalidConfig)
+	}
+}
+
+// configRows lists one csv record per setting
+func configRows(cfg aurora.ConfigResult) [][]string {
+	return [][]string{
+		{"field", "value", "status"},
+		{"penumbra", cfg.PenumbraPath, cfg.Status.PenumbraStatus},
+		{"mods", cfg.ModsPath, cfg.Status.ModsStatus},
+		{"output", cfg.OutputPath, cfg.Status.OutputStatus},
+		{"concurrency", strconv.Itoa(cfg.Concurrency), ""},
+		{"compression", cfg.Compression, ""},
+		{"format", cfg.Format, ""},
+		{"layout", cfg.Layout, ""},
+		{"maxPartSize", strconv.FormatUint(cfg.MaxPartSize, 10), ""},
+		{"encrypt", strconv.FormatBool(cfg.Encrypt), ""},
+		{"keyFile", cfg.KeyFile, ""},
+		{"preBackupHook", cfg.PreBackupHook, ""},
+		{"postBackupHook", cfg.PostBackupHook, ""},
+		{"hookTimeout", strconv.Itoa(cfg.HookTimeout), ""},
+		{"filters", strings.Join(cfg.Filters, ";"), ""},
+		{"inclusions", strings.Join(cfg.Inclusions, ";"), ""},
+		{"target", cfg.Target, ""},
+		{"mirrors", strings.Join(cfg.Mirrors, ";"), ""},
+		{"keepIncrementals", strconv.Itoa(cfg.Retention.Incrementals), ""},
+		{"keepDays", strconv.Itoa(cfg.Retention.Days), ""},
+		{"profile", cfg.Profile, ""},
+		{"configFile", cfg.ConfigFile, ""},
+		{"valid", strconv.FormatBool(cfg.Status.Valid), ""},
+	}
+}
+
+func configTable(w io.Writer, cfg aurora.ConfigResult) {
 	data := [][]string{
 		{"FIELD", "VALUE", "STATUS"},
 		{"Penumbra path", abbreviatePath(cfg.PenumbraPath, 100), cfg.Status.PenumbraStatus},
@@ -52,14 +403,18 @@ func runConfigCmd(cmd *cobra.Command, args []string) {
 		{"Output path", abbreviatePath(cfg.OutputPath, 100), cfg.Status.OutputStatus},
 	}
 
-	table := tablewriter.NewWriter(os.Stdout)
+	table := tablewriter.NewWriter(w)
 	table.Header(data[0])
 	table.Bulk(data[1:])
 	table.Render()
 
+	fmt.Fprintf(w, "Config file: %s\n", cfg.ConfigFile)
+	if cfg.Profile != "" {
+		fmt.Fprintf(w, "Profile: %s\n", cfg.Profile)
+	}
 	if !cfg.Status.Valid {
-		fmt.Printf("Current configuration is not valid\nPlease either:\n- run: aurora config --reset\n- edit the config file: %s\n", config.ConfigFile)
+		fmt.Fprintf(w, "Current configuration is not valid\nPlease either:\n- run: aurora config --reset\n- edit the config file above\n")
 	} else {
-		fmt.Printf("Current configuration is valid\n")
+		fmt.Fprintf(w, "Current configuration is valid\n")
 	}
 }
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"aurora/pkg/aurora"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/dustin/go-humanize"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var decryptCmd = &cobra.Command{
+	Use:   "decrypt <dest> [dir]",
+	Short: "Write plain copies of an encrypted backup set",
+	Long: `Write plain copies of the encrypted archives, manifest and report of the
+backup set in dir (default: the output directory) to the dest directory.
+The copies open with any archiver (zip), tar and zstd (tar.zst) or go-delta
+(gdelta), to restore mods by hand. dest can't be the backup's directory.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run:  runDecryptCmd,
+}
+
+func init() {
+	decryptCmd.Flags().String("key-file", "", "file holding the encryption passphrase (default: config keyFile, $AURORA_PASSPHRASE or a prompt)")
+}
+
+func runDecryptCmd(cmd *cobra.Command, args []string) {
+	keyFile, err := cmd.Flags().GetString("key-file")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading key-file flag: %v\n", err)
+		os.Exit(exitError)
+	}
+	dest, dir := args[0], ""
+	if len(args) == 2 {
+		dir = args[1]
+	}
+	app := loadApp()
+
+	// Only prompt when the set turns out to be encrypted
+	passphrase, err := app.ResolvePassphrase(keyFile, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	result, err := app.DecryptBackup(dir, dest, passphrase)
+	if errors.Is(err, aurora.ErrPassphraseRequired) && passphrase == "" {
+		passphrase = resolvePassphrase(app, keyFile, false)
+		result, err = app.DecryptBackup(dir, dest, passphrase)
+	}
+	switch {
+	case err == nil:
+	case errors.Is(err, aurora.ErrNoBackup), errors.Is(err, aurora.ErrWrongPassphrase), errors.Is(err, aurora.ErrPassphraseRequired):
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	default:
+		fmt.Fprintf(os.Stderr, "Failed to decrypt backup: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	render(cmd, report{
+		data:  result,
+		rows:  decryptRows(result),
+		table: func(w io.Writer) { decryptTable(w, result) },
+	})
+}
+
+// decryptRows lists one csv record per plain copy
+func decryptRows(result aurora.DecryptResult) [][]string {
+	rows := [][]string{{"file", "size"}}
+	for _, file := range result.Files {
+		rows = append(rows, []string{file.Name, strconv.FormatInt(file.Size, 10)})
+	}
+	return rows
+}
+
+func decryptTable(w io.Writer, result aurora.DecryptResult) {
+	data := [][]string{{"File", "Size"}}
+	for _, file := range result.Files {
+		data = append(data, []string{file.Name, humanize.Bytes(uint64(file.Size))})
+	}
+	table := tablewriter.NewTable(w)
+	table.Header(data[0])
+	table.Bulk(data[1:])
+	table.Render()
+	fmt.Fprintf(w, "Decrypted %d files to %s\n", len(result.Files), result.Dir)
+}
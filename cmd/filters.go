@@ -0,0 +1,134 @@
+package main
+
+import (
+	"aurora/pkg/aurora"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var filtersCmd = newPatternCmd(patternCmdSpec{
+	name:   "filters",
+	short:  "Manage exclusion filters (matching mods are skipped)",
+	list:   func(cfg aurora.ConfigResult) []string { return cfg.Filters },
+	add:    (*aurora.Aurora).AddFilter,
+	remove: (*aurora.Aurora).RemoveFilter,
+})
+
+var inclusionsCmd = newPatternCmd(patternCmdSpec{
+	name:   "inclusions",
+	short:  "Manage inclusion filters (matching mods are always backed up)",
+	list:   func(cfg aurora.ConfigResult) []string { return cfg.Inclusions },
+	add:    (*aurora.Aurora).AddInclusion,
+	remove: (*aurora.Aurora).RemoveInclusion,
+})
+
+// patternCmdSpec describes one of the filter pattern lists in config.json
+type patternCmdSpec struct {
+	name   string
+	short  string
+	list   func(cfg aurora.ConfigResult) []string
+	add    func(app *aurora.Aurora, pattern string) error
+	remove func(app *aurora.Aurora, pattern string) error
+}
+
+// newPatternCmd builds `aurora <name> add|remove|list` for a pattern list
+func newPatternCmd(spec patternCmdSpec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   spec.name,
+		Short: spec.short,
+		Args:  cobra.NoArgs,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "Print the patterns, one per line",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			patterns := spec.list(loadApp().GetConfig())
+			if patterns == nil {
+				patterns = []string{} // [] rather than null in json
+			}
+			rows := [][]string{{"pattern"}}
+			for _, pattern := range patterns {
+				rows = append(rows, []string{pattern})
+			}
+			render(cmd, report{
+				data: patterns,
+				rows: rows,
+				table: func(w io.Writer) {
+					for _, pattern := range patterns {
+						fmt.Fprintln(w, pattern)
+					}
+				},
+			})
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "add <pattern>...",
+		Short: "Add patterns (prefix match, case-insensitive)",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			patterns, err := cleanPatterns(args)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitError)
+			}
+			app := loadApp()
+			for _, pattern := range patterns {
+				if err := spec.add(app, pattern); err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to save configuration: %v\n", err)
+					os.Exit(exitError)
+				}
+			}
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "remove <pattern>...",
+		Short: "Remove patterns",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			patterns, err := cleanPatterns(args)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitError)
+			}
+			app := loadApp()
+			// Check everything first: a typo must not leave a half-applied removal
+			current := spec.list(app.GetConfig())
+			for _, pattern := range patterns {
+				if !slices.Contains(current, pattern) {
+					fmt.Fprintf(os.Stderr, "Error: %s pattern %q not found\n", spec.name, pattern)
+					os.Exit(exitError)
+				}
+			}
+			for _, pattern := range patterns {
+				if err := spec.remove(app, pattern); err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to save configuration: %v\n", err)
+					os.Exit(exitError)
+				}
+			}
+		},
+	})
+
+	return cmd
+}
+
+// cleanPatterns trims patterns and rejects empty ones: an empty prefix
+// matches every mod
+func cleanPatterns(args []string) ([]string, error) {
+	patterns := make([]string, len(args))
+	for i, arg := range args {
+		patterns[i] = strings.TrimSpace(arg)
+		if patterns[i] == "" {
+			return nil, fmt.Errorf("empty pattern would match every mod")
+		}
+	}
+	return patterns, nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"aurora/pkg/aurora"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show how the mod library changed between snapshots",
+	Long: `Show how the mod library changed over time: the mods added, removed and
+updated and the collections changed between snapshots, newest first. Every
+successful backup takes a snapshot; 'aurora history snapshot' takes one
+now. Snapshots record mod names, sizes and collections, and a fingerprint
+of file sizes and times rather than file contents.`,
+	Args: cobra.NoArgs,
+	Run:  runHistoryCmd,
+}
+
+func init() {
+	historyCmd.Flags().Int("limit", 10, "most recent snapshots to compare (0 for all)")
+
+	historyCmd.AddCommand(&cobra.Command{
+		Use:   "snapshot",
+		Short: "Take a snapshot of the mod library now",
+		Args:  cobra.NoArgs,
+		Run:   runHistorySnapshotCmd,
+	})
+}
+
+func runHistoryCmd(cmd *cobra.Command, args []string) {
+	limit, err := cmd.Flags().GetInt("limit")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading limit flag: %v\n", err)
+		os.Exit(exitError)
+	}
+	history, err := loadApp().History(limit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read the history: %v\n", err)
+		os.Exit(exitError)
+	}
+	render(cmd, report{
+		data:  history,
+		rows:  historyRows(history),
+		table: func(w io.Writer) { historyTable(w, history) },
+	})
+}
+
+func runHistorySnapshotCmd(cmd *cobra.Command, args []string) {
+	app := loadApp()
+	requireValidConfig(app)
+
+	snapshot, err := app.TakeSnapshot(aurora.SnapshotManual)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to take a snapshot: %v\n", err)
+		os.Exit(exitError)
+	}
+	if isMachineOutput(cmd) {
+		render(cmd, report{
+			data: snapshot,
+			rows: [][]string{{"id", "takenAt", "mods", "collections"},
+				{snapshot.ID, snapshot.TakenAt.Format(time.RFC3339), strconv.Itoa(len(snapshot.Mods)), strconv.Itoa(len(snapshot.Collections))}},
+		})
+		return
+	}
+	fmt.Printf("Took snapshot %s of %d mods in %d collections\n", snapshot.ID, len(snapshot.Mods), len(snapshot.Collections))
+}
+
+// historyRows lists one csv record per change: a mod added, removed or
+// updated, or a collection created, deleted or gaining or losing a mod
+func historyRows(history aurora.HistoryResult) [][]string {
+	rows := [][]string{{"takenAt", "snapshot", "change", "collection", "mod", "oldSize", "size"}}
+	for _, entry := range history.Entries {
+		takenAt := entry.TakenAt.Format(time.RFC3339)
+		for _, section := range []struct {
+			change string
+			mods   []aurora.ModChange
+		}{{"added", entry.Added}, {"removed", entry.Removed}, {"updated", entry.Updated}} {
+			for _, mod := range section.mods {
+				rows = append(rows, []string{takenAt, entry.To, section.change, "", mod.Name,
+					strconv.FormatUint(mod.OldSize, 10), strconv.FormatUint(mod.Size, 10)})
+			}
+		}
+		for _, col := range entry.Collections {
+			switch {
+			case col.Created:
+				rows = append(rows, []string{takenAt, entry.To, "collection created", col.Name, "", "", ""})
+			case col.Deleted:
+				rows = append(rows, []string{takenAt, entry.To, "collection deleted", col.Name, "", "", ""})
+			}
+			for _, mod := range col.Added {
+				rows = append(rows, []string{takenAt, entry.To, "collection added", col.Name, mod, "", ""})
+			}
+			for _, mod := range col.Removed {
+				rows = append(rows, []string{takenAt, entry.To, "collection removed", col.Name, mod, "", ""})
+			}
+		}
+	}
+	return rows
+}
+
+func historyTable(w io.Writer, history aurora.HistoryResult) {
+	switch len(history.Snapshots) {
+	case 0:
+		fmt.Fprintln(w, "No snapshots yet: run a backup or 'aurora history snapshot'")
+		return
+	case 1:
+		first := history.Snapshots[0]
+		fmt.Fprintf(w, "One snapshot (%s, %d mods, %s): changes show from the next one\n",
+			first.TakenAt.Local().Format(cleanupTimeLayout), first.Mods, first.SizeHuman)
+		return
+	}
+	latest := history.Snapshots[0]
+	fmt.Fprintf(w, "%d snapshots, the latest %s: %d mods, %s\n",
+		len(history.Snapshots), latest.TakenAt.Local().Format(cleanupTimeLayout), latest.Mods, latest.SizeHuman)
+	if len(history.Entries) == 0 {
+		fmt.Fprintln(w, "\nNo changes")
+		return
+	}
+
+	for _, entry := range history.Entries {
+		fmt.Fprintf(w, "\n%s (%s): %d added, %d removed, %d updated, %s\n",
+			entry.TakenAt.Local().Format(cleanupTimeLayout), entry.Reason,
+			len(entry.Added), len(entry.Removed), len(entry.Updated), entry.SizeDeltaHuman)
+		rows := [][]string{}
+		for _, mod := range entry.Added {
+			rows = append(rows, []string{"+", abbreviatePath(mod.Name, 50), mod.SizeHuman})
+		}
+		for _, mod := range entry.Removed {
+			rows = append(rows, []string{"-", abbreviatePath(mod.Name, 50), mod.OldSizeHuman})
+		}
+		for _, mod := range entry.Updated {
+			rows = append(rows, []string{"~", abbreviatePath(mod.Name, 50), mod.OldSizeHuman + " -> " + mod.SizeHuman})
+		}
+		if len(rows) > 0 {
+			renderTable(w, []string{"", "Mod", "Size"}, rows)
+		}
+		for _, col := range entry.Collections {
+			fmt.Fprintf(w, "  Collection %s\n", collectionChangeSummary(col))
+		}
+	}
+}
+
+// collectionChangeSummary describes a collection change in one line
+func collectionChangeSummary(col aurora.CollectionChange) string {
+	if col.Deleted {
+		return col.Name + " deleted"
+	}
+	var parts []string
+	if col.Created {
+		parts = append(parts, "created")
+	}
+	if len(col.Added) > 0 {
+		parts = append(parts, "+"+abbreviatePath(strings.Join(col.Added, ", "), 60))
+	}
+	if len(col.Removed) > 0 {
+		parts = append(parts, "-"+abbreviatePath(strings.Join(col.Removed, ", "), 60))
+	}
+	return col.Name + ": " + strings.Join(parts, "; ")
+}
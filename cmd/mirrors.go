@@ -0,0 +1,96 @@
+package main
+
+import (
+	"aurora/pkg/aurora"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/spf13/cobra"
+)
+
+var mirrorsCmd = &cobra.Command{
+	Use:   "mirrors",
+	Short: "Manage mirrors (further copies of each backup, verified after transfer)",
+	Long: `Manage mirrors: every finished backup is copied to each of them after the
+target, and each copy is read back to check its checksum. A mirror is an
+absolute directory (a second disk, a mounted NAS) or a target URL, like
+sftp://user@host/path or s3://bucket/prefix. A failed mirror doesn't fail
+the backup.`,
+	Args: cobra.NoArgs,
+}
+
+func init() {
+	mirrorsCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "Print the mirrors, one per line",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			mirrors := loadApp().GetConfig().Mirrors
+			rows := [][]string{{"mirror"}}
+			for _, mirror := range mirrors {
+				rows = append(rows, []string{mirror})
+			}
+			render(cmd, report{
+				data: mirrors,
+				rows: rows,
+				table: func(w io.Writer) {
+					for _, mirror := range mirrors {
+						fmt.Fprintln(w, mirror)
+					}
+				},
+			})
+		},
+	})
+
+	mirrorsCmd.AddCommand(&cobra.Command{
+		Use:   "add <dir|url>...",
+		Short: "Add mirrors",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			// Check everything first: a typo must not leave a half-applied change
+			mirrors := make([]aurora.Target, len(args))
+			for i, arg := range args {
+				mirror, err := aurora.ParseTarget(arg)
+				if err == nil && !mirror.IsRemote() {
+					err = fmt.Errorf("the output directory already holds the backup")
+				}
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: invalid mirror %q: %v\n", arg, err)
+					os.Exit(exitError)
+				}
+				mirrors[i] = mirror
+			}
+			app := loadApp()
+			for _, mirror := range mirrors {
+				if err := app.AddMirror(mirror); err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to save configuration: %v\n", err)
+					os.Exit(exitError)
+				}
+			}
+		},
+	})
+
+	mirrorsCmd.AddCommand(&cobra.Command{
+		Use:   "remove <dir|url>...",
+		Short: "Remove mirrors, as listed",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			app := loadApp()
+			current := app.GetConfig().Mirrors
+			for _, arg := range args {
+				if !slices.Contains(current, arg) {
+					fmt.Fprintf(os.Stderr, "Error: mirror %q not found\n", arg)
+					os.Exit(exitError)
+				}
+			}
+			for _, arg := range args {
+				if err := app.RemoveMirror(arg); err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to save configuration: %v\n", err)
+					os.Exit(exitError)
+				}
+			}
+		},
+	})
+}
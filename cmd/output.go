@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats for the global --output flag
+const (
+	outputTable = "table" // human-readable tables (default)
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+	outputCSV   = "csv"
+)
+
+var outputFormats = []string{outputTable, outputJSON, outputYAML, outputCSV}
+
+// Exit codes, documented in the README for automation
+const (
+	exitError         = 1   // any failure not listed below
+	exitInvalidConfig = 2   // config.json paths are not valid
+	exitNoSpace       = 3   // not enough disk space for the backup
+	exitCancelled     = 130 // backup, analysis or quarantine interrupted (Ctrl+C), shell convention for SIGINT
+)
+
+// report is a command result in every output format: data is serialized
+// for json and yaml (field names come from the json tags), rows is the csv
+// header plus records, table renders the human view.
+type report struct {
+	data  any
+	rows  [][]string
+	table func(w io.Writer)
+}
+
+// outputFormat returns the --output value of the running command
+func outputFormat(cmd *cobra.Command) string {
+	format, _ := cmd.Flags().GetString("output")
+	if format == "" {
+		return outputTable
+	}
+	return format
+}
+
+// isMachineOutput reports whether the command must keep stdout free of
+// anything but the serialized result (no prompts, tables or progress bars)
+func isMachineOutput(cmd *cobra.Command) bool {
+	return outputFormat(cmd) != outputTable
+}
+
+func validateOutputFormat(format string) error {
+	if !slices.Contains(outputFormats, format) {
+		return fmt.Errorf("invalid --output %q (valid: table, json, yaml, csv)", format)
+	}
+	return nil
+}
+
+// render writes r to stdout in the command's output format
+func render(cmd *cobra.Command, r report) {
+	if err := writeReport(os.Stdout, outputFormat(cmd), r); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write output: %v\n", err)
+		os.Exit(exitError)
+	}
+}
+
+func writeReport(w io.Writer, format string, r report) error {
+	switch format {
+	case outputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r.data)
+	case outputYAML:
+		return writeYAML(w, r.data)
+	case outputCSV:
+		writer := csv.NewWriter(w)
+		writer.WriteAll(r.rows)
+		return writer.Error()
+	default:
+		r.table(w)
+		return nil
+	}
+}
+
+// writeYAML encodes v as YAML with the same field names and order as the
+// JSON output. yaml.v3 ignores json tags, so v goes through JSON first; JSON
+// is valid YAML, and decoding it as a node tree keeps the key order.
+func writeYAML(w io.Writer, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(raw, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// blockStyle drops the flow style ({...}, [...], "...") nodes decoded from
+// JSON carry, so the output reads like hand-written YAML
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
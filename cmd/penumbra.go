@@ -3,7 +3,9 @@ package main
 import (
 	"aurora/pkg/aurora"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/olekukonko/tablewriter"
@@ -19,26 +21,40 @@ var penumbraCmd = &cobra.Command{
 }
 
 func runPenumbraCmd(cmd *cobra.Command, args []string) {
-	app, err := aurora.New()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
-		os.Exit(1)
-	}
-	if !app.IsConfigValid() {
-		cfg := app.GetConfig()
-		fmt.Fprintf(os.Stderr, "Configuration is not valid:\n")
-		fmt.Fprintf(os.Stderr, "  Penumbra: %s\n", cfg.Status.PenumbraStatus)
-		fmt.Fprintf(os.Stderr, "  Mods: %s\n", cfg.Status.ModsStatus)
-		fmt.Fprintf(os.Stderr, "\nRun 'aurora config --reset' to fix\n")
-		return
-	}
+	app := loadApp()
+	requireValidConfig(app)
 
 	result, err := app.GetCollections()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to read penumbra data: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 
+	render(cmd, report{
+		data:  result,
+		rows:  collectionsRows(result),
+		table: func(w io.Writer) { collectionsTable(w, result) },
+	})
+}
+
+// collectionsRows flattens collections to one csv record per (collection,
+// mod) pair; mods without collection get an empty collection
+func collectionsRows(result aurora.CollectionsResult) [][]string {
+	rows := [][]string{{"collection", "mod", "size"}}
+	for _, col := range result.Collections {
+		for _, mod := range col.Mods {
+			rows = append(rows, []string{col.Name, mod.Name, strconv.FormatUint(mod.Size, 10)})
+		}
+	}
+	for _, mod := range result.Mods {
+		if len(mod.Collections) == 0 {
+			rows = append(rows, []string{"", mod.Name, strconv.FormatUint(mod.Size, 10)})
+		}
+	}
+	return rows
+}
+
+func collectionsTable(w io.Writer, result aurora.CollectionsResult) {
 	data := [][]string{
 		{"Collection", "Mods"},
 	}
@@ -70,7 +86,7 @@ func runPenumbraCmd(cmd *cobra.Command, args []string) {
 		result.Stats.UsedDiskSizeHuman,
 		result.Stats.TotalDiskSizeHuman)
 
-	table := tablewriter.NewTable(os.Stdout,
+	table := tablewriter.NewTable(w,
 		tablewriter.WithRenderer(renderer.NewBlueprint(tw.Rendition{
 			Settings: tw.Settings{
 				Separators: tw.Separators{
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"aurora/internal/paths"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+var profilesCmd = &cobra.Command{
+	Use:   "profiles",
+	Short: "Manage profiles (config files choosing their own target, mirrors and retention)",
+	Long: `Manage profiles: each is a config file of its own in the profiles folder
+next to config.json, selected with --profile <name> or $AURORA_PROFILE. A
+profile is created from config.json the first time it is used, so only what
+differs has to be set, like its target:
+
+  aurora --profile offsite config set target s3://bucket/ff14
+  aurora --profile offsite backup
+
+Profiles share the log, caches and backup history.`,
+	Args: cobra.NoArgs,
+}
+
+func init() {
+	profilesCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "Print the profiles, one per line",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			profiles := paths.Profiles()
+			rows := [][]string{{"profile", "configFile"}}
+			for _, profile := range profiles {
+				rows = append(rows, []string{profile, paths.ProfileFile(profile)})
+			}
+			render(cmd, report{
+				data: profiles,
+				rows: rows,
+				table: func(w io.Writer) {
+					for _, profile := range profiles {
+						fmt.Fprintln(w, profile)
+					}
+				},
+			})
+		},
+	})
+}
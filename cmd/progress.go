@@ -0,0 +1,129 @@
+package main
+
+import (
+	"aurora/pkg/aurora"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// logProgressInterval spaces progress lines when stderr is not a terminal
+// (redirected to a file, CI logs)
+const logProgressInterval = 10 * time.Second
+
+// progressPrinter draws backup progress on stderr: a block redrawn in place
+// with one line per worker on a terminal, a plain line now and then otherwise
+type progressPrinter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	tty     bool
+	lines   int // lines of the block drawn last
+	printed time.Time
+}
+
+func newProgressPrinter(file *os.File) *progressPrinter {
+	tty := false
+	if info, err := file.Stat(); err == nil {
+		tty = info.Mode()&os.ModeCharDevice != 0
+	}
+	return &progressPrinter{w: file, tty: tty}
+}
+
+// update draws a snapshot; called from compression goroutines
+func (p *progressPrinter) update(progress aurora.BackupProgress) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.tty {
+		if time.Since(p.printed) < logProgressInterval {
+			return
+		}
+		p.printed = time.Now()
+		fmt.Fprintln(p.w, progressLine(progress))
+		return
+	}
+
+	lines := []string{progressLine(progress)}
+	for _, worker := range progress.Workers {
+		lines = append(lines, workerLine(worker))
+	}
+	// Move back to the top of the previous block and redraw it, clearing
+	// lines of workers that are gone
+	if p.lines > 1 {
+		fmt.Fprintf(p.w, "\x1b[%dA", p.lines-1)
+	}
+	fmt.Fprint(p.w, "\r")
+	for i := range max(len(lines), p.lines) {
+		if i > 0 {
+			fmt.Fprint(p.w, "\n")
+		}
+		fmt.Fprint(p.w, "\x1b[2K")
+		if i < len(lines) {
+			fmt.Fprint(p.w, lines[i])
+		}
+	}
+	p.lines = max(len(lines), p.lines)
+}
+
+// count returns a progress callback drawing "label: done/total" as a one
+// line block
+func (p *progressPrinter) count(label string) func(done, total int) {
+	return func(done, total int) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		line := fmt.Sprintf("%s: %d/%d", label, done, total)
+		if !p.tty {
+			if time.Since(p.printed) < logProgressInterval && done < total {
+				return
+			}
+			p.printed = time.Now()
+			fmt.Fprintln(p.w, line)
+			return
+		}
+		fmt.Fprint(p.w, "\r\x1b[2K"+line)
+		p.lines = 1
+	}
+}
+
+// finish ends the block so later output starts on a fresh line
+func (p *progressPrinter) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.tty && p.lines > 0 {
+		fmt.Fprintln(p.w)
+	}
+}
+
+// progressLine summarizes a snapshot on one line
+func progressLine(progress aurora.BackupProgress) string {
+	parts := []string{
+		fmt.Sprintf("%5.1f%%", progress.Percent),
+		fmt.Sprintf("%s / %s", humanize.Bytes(progress.BytesDone), humanize.Bytes(progress.BytesTotal)),
+		fmt.Sprintf("mods %d/%d", progress.ModsDone, progress.ModsTotal),
+		fmt.Sprintf("compressed %s", humanize.Bytes(progress.CompressedBytes)),
+		fmt.Sprintf("elapsed %s", formatSeconds(progress.ElapsedSeconds)),
+	}
+	if progress.BytesPerSec > 0 {
+		parts = append(parts,
+			fmt.Sprintf("%s/s", humanize.Bytes(uint64(progress.BytesPerSec))),
+			fmt.Sprintf("ETA %s", formatSeconds(progress.ETASeconds)))
+	}
+	return strings.Join(parts, "  ")
+}
+
+func workerLine(worker aurora.WorkerStatus) string {
+	percent := 0.0
+	if worker.BytesTotal > 0 {
+		percent = float64(worker.BytesDone) / float64(worker.BytesTotal) * 100
+	}
+	return fmt.Sprintf("  %3.0f%%  %s", percent, abbreviatePath(worker.Mod+" › "+worker.File, 100))
+}
+
+func formatSeconds(seconds int64) string {
+	return (time.Duration(seconds) * time.Second).String()
+}
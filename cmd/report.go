@@ -0,0 +1,118 @@
+package main
+
+import (
+	"aurora/pkg/aurora"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// Rendered forms of aurora report
+const (
+	reportMarkdown = "markdown"
+	reportHTML     = "html"
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report [dir]",
+	Short: "Render the report of a backup set",
+	Long: `Render the report of the backup set in dir (default: the output directory),
+such as an incremental backup or a mirror: the mods included and why, the
+mods filters excluded, sizes, compression ratios, time taken and warnings.
+Every backup writes its report next to its archives, as HTML and Markdown
+unless the set is encrypted. Sets made before reports existed are rebuilt
+from their manifest, with less detail.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runReportCmd,
+}
+
+func init() {
+	reportCmd.Flags().String("format", reportMarkdown, "rendered form: markdown or html")
+	reportCmd.Flags().String("key-file", "", "file holding the encryption passphrase (default: config keyFile, $AURORA_PASSPHRASE or a prompt)")
+}
+
+func runReportCmd(cmd *cobra.Command, args []string) {
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading format flag: %v\n", err)
+		os.Exit(exitError)
+	}
+	if format != reportMarkdown && format != reportHTML {
+		fmt.Fprintf(os.Stderr, "Error: unknown report format %q (markdown or html)\n", format)
+		os.Exit(exitError)
+	}
+	keyFile, err := cmd.Flags().GetString("key-file")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading key-file flag: %v\n", err)
+		os.Exit(exitError)
+	}
+	dir := ""
+	if len(args) == 1 {
+		dir = args[0]
+	}
+	app := loadApp()
+
+	// Only prompt when the set turns out to be encrypted
+	passphrase, err := app.ResolvePassphrase(keyFile, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	result, err := app.BackupReport(dir, passphrase)
+	if errors.Is(err, aurora.ErrPassphraseRequired) && passphrase == "" {
+		passphrase = resolvePassphrase(app, keyFile, false)
+		result, err = app.BackupReport(dir, passphrase)
+	}
+	switch {
+	case err == nil:
+	case errors.Is(err, aurora.ErrNoBackup), errors.Is(err, aurora.ErrWrongPassphrase), errors.Is(err, aurora.ErrPassphraseRequired):
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	default:
+		fmt.Fprintf(os.Stderr, "Failed to read backup report: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	render(cmd, report{
+		data: result,
+		rows: reportRows(result),
+		table: func(w io.Writer) {
+			if format == reportMarkdown {
+				io.WriteString(w, aurora.RenderReportMarkdown(result))
+				return
+			}
+			html, err := aurora.RenderReportHTML(result)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to render report: %v\n", err)
+				os.Exit(exitError)
+			}
+			io.WriteString(w, html)
+		},
+	})
+}
+
+// reportRows lists one csv record per mod, included or excluded, then one
+// per warning
+func reportRows(result aurora.BackupReport) [][]string {
+	rows := [][]string{{"status", "mod", "size", "ratio", "reason"}}
+	for _, section := range []struct {
+		status string
+		mods   []aurora.ReportMod
+	}{{"included", result.Included}, {"excluded", result.Excluded}} {
+		for _, mod := range section.mods {
+			rows = append(rows, []string{section.status, mod.Name, strconv.FormatUint(mod.Size, 10), mod.Ratio, mod.Reason()})
+		}
+	}
+	for _, warning := range result.Warnings {
+		reason := warning.Message
+		if warning.Path != "" {
+			reason = warning.Path + ": " + reason
+		}
+		rows = append(rows, []string{"warning", warning.Mod, "", "", reason})
+	}
+	return rows
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"aurora/pkg/aurora"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore [mod...]",
+	Short: "Restore mods from a backup set",
+	Long: `Extract the mods of the backup set in the output directory (or --from) to
+the mods folder (or --to). Encrypted sets are decrypted on the way. Only
+the mods named are restored, every mod of the set when none is. A mod
+already in the folder is skipped, never overwritten: move it away first to
+restore it. With --target the set on the configured remote target is
+restored instead, downloading one archive at a time. Aurora can't read
+go-delta native archives.`,
+	Run: runRestoreCmd,
+}
+
+func init() {
+	restoreCmd.Flags().String("from", "", "backup set folder (default: the output directory)")
+	restoreCmd.Flags().String("to", "", "folder to restore the mods to (default: the mods folder)")
+	restoreCmd.Flags().String("key-file", "", "file holding the encryption passphrase (default: config keyFile, $AURORA_PASSPHRASE or a prompt)")
+	restoreCmd.Flags().Bool("target", false, "restore the copy on the configured remote target")
+}
+
+func runRestoreCmd(cmd *cobra.Command, args []string) {
+	from, err := cmd.Flags().GetString("from")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading from flag: %v\n", err)
+		os.Exit(exitError)
+	}
+	to, err := cmd.Flags().GetString("to")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading to flag: %v\n", err)
+		os.Exit(exitError)
+	}
+	keyFile, err := cmd.Flags().GetString("key-file")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading key-file flag: %v\n", err)
+		os.Exit(exitError)
+	}
+	remote, err := cmd.Flags().GetBool("target")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading target flag: %v\n", err)
+		os.Exit(exitError)
+	}
+	if remote && from != "" {
+		fmt.Fprintf(os.Stderr, "Error: --from and --target both choose the backup to restore, use one\n")
+		os.Exit(exitError)
+	}
+	opts := aurora.RestoreOptions{Dir: from, Dest: to, Mods: args}
+	app := loadApp()
+	if remote && !app.GetTarget().IsRemote() {
+		fmt.Fprintf(os.Stderr, "Error: no remote target is configured (see aurora config set target)\n")
+		os.Exit(exitError)
+	}
+	restore := func(opts aurora.RestoreOptions) (aurora.RestoreResult, error) {
+		if remote {
+			return app.RestoreTarget(context.Background(), opts)
+		}
+		return app.RestoreBackup(opts)
+	}
+
+	// Only prompt when the set turns out to be encrypted
+	passphrase, err := app.ResolvePassphrase(keyFile, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	opts.Passphrase = passphrase
+	result, err := restore(opts)
+	if errors.Is(err, aurora.ErrPassphraseRequired) && passphrase == "" {
+		opts.Passphrase = resolvePassphrase(app, keyFile, false)
+		result, err = restore(opts)
+	}
+	switch {
+	case err == nil:
+	case errors.Is(err, aurora.ErrNoBackup), errors.Is(err, aurora.ErrWrongPassphrase), errors.Is(err, aurora.ErrPassphraseRequired),
+		errors.Is(err, aurora.ErrCannotRestore), errors.Is(err, aurora.ErrModNotFound), errors.Is(err, aurora.ErrInvalidConfig):
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	default:
+		fmt.Fprintf(os.Stderr, "Failed to restore backup: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	render(cmd, report{
+		data:  result,
+		rows:  restoreRows(result),
+		table: func(w io.Writer) { restoreTable(w, result) },
+	})
+}
+
+// restoreRows lists one csv record per mod of the set considered
+func restoreRows(result aurora.RestoreResult) [][]string {
+	rows := [][]string{{"mod", "status"}}
+	for _, mod := range result.Restored {
+		rows = append(rows, []string{mod, "restored"})
+	}
+	for _, mod := range result.Skipped {
+		rows = append(rows, []string{mod, "skipped"})
+	}
+	return rows
+}
+
+func restoreTable(w io.Writer, result aurora.RestoreResult) {
+	data := [][]string{{"Mod", "Status"}}
+	for _, mod := range result.Restored {
+		data = append(data, []string{mod, "Restored"})
+	}
+	for _, mod := range result.Skipped {
+		data = append(data, []string{mod, "Skipped: already in the folder"})
+	}
+	table := tablewriter.NewTable(w)
+	table.Header(data[0])
+	table.Bulk(data[1:])
+	table.Render()
+	fmt.Fprintf(w, "Restored %d mods (%d files) to %s\n", len(result.Restored), result.Files, result.Dest)
+}
@@ -0,0 +1,583 @@
+package main
+
+import (
+	"aurora/internal/paths"
+	"aurora/pkg/aurora"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultListen keeps the API on this machine
+const defaultListen = "127.0.0.1:7420"
+
+// sseKeepAlive is how often an idle event stream gets a comment, so
+// proxies and clients don't drop it
+const sseKeepAlive = 15 * time.Second
+
+// sseBuffer is how many events a slow stream client may lag behind; older
+// progress is dropped, the final "done" event never is
+const sseBuffer = 64
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the Aurora service as a local HTTP/JSON API",
+	Long: `Serve collections, backup previews, filter matches, config edits and backup
+runs as a REST API for local tooling. Every request needs the header
+"Authorization: Bearer <token>"; the token is kept in the api_token file next
+to config.json, created on first start. Stop with Ctrl+C.
+
+Settings that choose the folders read and written, run commands, send
+backups elsewhere or turn encryption off (penumbra, mods, output, encrypt,
+keyFile, preBackupHook, postBackupHook, target and the mirrors) can't be
+changed through the API, so a leaked token can't run commands or copy
+backups off this machine: change them with aurora config set and aurora
+mirrors. The config is read again before each request, so such changes
+apply without a restart.`,
+	Run: runServeCmd,
+}
+
+func init() {
+	serveCmd.Flags().String("listen", defaultListen, "address to listen on")
+	serveCmd.Flags().Bool("rotate-token", false, "replace the API token, locking out clients of the old one")
+}
+
+func runServeCmd(cmd *cobra.Command, args []string) {
+	app := loadApp()
+
+	listen, err := cmd.Flags().GetString("listen")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading listen flag: %v\n", err)
+		os.Exit(exitError)
+	}
+	rotate, err := cmd.Flags().GetBool("rotate-token")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading rotate-token flag: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	token, created, err := loadAPIToken(rotate)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	if created {
+		fmt.Fprintf(os.Stderr, "New API token written to %s\n", paths.APITokenFile())
+	}
+
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	if host, _, _ := net.SplitHostPort(listen); !isLoopback(host) {
+		fmt.Fprintf(os.Stderr, "Warning: %s is reachable from other machines; anyone with the token can run backups\n", listen)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	server := newAPIServer(ctx, app, token)
+	httpServer := &http.Server{Handler: server.handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		// A running backup stops and can be resumed; streams end with it
+		server.shutdown()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(os.Stderr, "Serving the Aurora API on http://%s/api (token in %s)\n", listener.Addr(), paths.APITokenFile())
+	if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "Failed to serve: %v\n", err)
+		os.Exit(exitError)
+	}
+	fmt.Fprintf(os.Stderr, "Server stopped\n")
+}
+
+// loadAPIToken reads the API token, creating it (readable by the user only)
+// when missing or rotated. created tells a new token was written.
+func loadAPIToken(rotate bool) (token string, created bool, err error) {
+	path := paths.APITokenFile()
+	if !rotate {
+		content, err := os.ReadFile(path)
+		if err == nil && strings.TrimSpace(string(content)) != "" {
+			return strings.TrimSpace(string(content)), false, nil
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", false, fmt.Errorf("read API token: %w", err)
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", false, fmt.Errorf("generate API token: %w", err)
+	}
+	token = hex.EncodeToString(secret)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", false, fmt.Errorf("write API token: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", false, fmt.Errorf("write API token: %w", err)
+	}
+	return token, true, nil
+}
+
+// isLoopback reports whether host only accepts local connections
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// apiServer exposes an Aurora over HTTP. One backup runs at a time; its
+// progress is streamed to every /api/backup/events client.
+type apiServer struct {
+	ctx   context.Context // the server's lifetime, backups stop with it
+	app   *aurora.Aurora
+	token string
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc // set while a backup runs
+	done    chan struct{}      // closed when the running backup returns
+	state   backupState
+	streams map[chan serverEvent]struct{}
+}
+
+// backupState is the last backup run through the API
+type backupState struct {
+	Running    bool                   `json:"running"`
+	StartedAt  *time.Time             `json:"startedAt,omitempty"`
+	FinishedAt *time.Time             `json:"finishedAt,omitempty"`
+	Progress   *aurora.BackupProgress `json:"progress,omitempty"`
+	Result     *aurora.BackupResult   `json:"result,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+// serverEvent is one Server-Sent Event: "state", "notice", "progress" or
+// "done"
+type serverEvent struct {
+	name string
+	data any
+}
+
+// backupRequest is the body of POST /api/backup; every field is optional
+type backupRequest struct {
+	Threads    int    `json:"threads"`
+	Resume     bool   `json:"resume"`
+	Passphrase string `json:"passphrase"` // default: key file or AURORA_PASSPHRASE
+}
+
+// valueRequest is the body of config and filter edits
+type valueRequest struct {
+	Value string `json:"value"`
+}
+
+func newAPIServer(ctx context.Context, app *aurora.Aurora, token string) *apiServer {
+	return &apiServer{ctx: ctx, app: app, token: token, streams: make(map[chan serverEvent]struct{})}
+}
+
+// handler routes the API behind the token check
+func (s *apiServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/config", s.handleGetConfig)
+	mux.HandleFunc("PUT /api/config/{key}", s.handleSetConfig)
+	mux.HandleFunc("GET /api/collections", s.handleCollections)
+	mux.HandleFunc("GET /api/filters/matches", s.handleFilterMatches)
+	mux.HandleFunc("GET /api/history", s.handleHistory)
+	mux.HandleFunc("POST /api/filters", s.patternHandler((*aurora.Aurora).AddFilter))
+	mux.HandleFunc("DELETE /api/filters", s.patternHandler((*aurora.Aurora).RemoveFilter))
+	mux.HandleFunc("POST /api/inclusions", s.patternHandler((*aurora.Aurora).AddInclusion))
+	mux.HandleFunc("DELETE /api/inclusions", s.patternHandler((*aurora.Aurora).RemoveInclusion))
+	mux.HandleFunc("GET /api/backup/validate", s.handleValidate)
+	mux.HandleFunc("GET /api/backup/pending", s.handlePending)
+	mux.HandleFunc("GET /api/backup", s.handleBackupState)
+	mux.HandleFunc("POST /api/backup", s.handleStartBackup)
+	mux.HandleFunc("DELETE /api/backup", s.handleCancelBackup)
+	mux.HandleFunc("GET /api/backup/events", s.handleBackupEvents)
+	return s.authorize(s.reload(mux))
+}
+
+// reload reads the config file again before each request, picking up
+// changes of aurora config set or the desktop app. A running backup keeps
+// the config it started with.
+func (s *apiServer) reload(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		var err error
+		if s.cancel == nil {
+			err = s.app.ReloadConfig()
+		}
+		s.mu.Unlock()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Errorf("reload config: %w", err))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorize rejects requests without the bearer token
+func (s *apiServer) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="aurora"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or wrong API token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *apiServer) handleGetConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.app.GetConfig())
+}
+
+// handleSetConfig sets one key like `aurora config set`, taking the same
+// string values. Local keys are refused.
+func (s *apiServer) handleSetConfig(w http.ResponseWriter, r *http.Request) {
+	key, err := findConfigKey(r.PathValue("key"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if key.local {
+		writeError(w, http.StatusForbidden, fmt.Errorf("%s can only be changed with aurora config set", key.name))
+		return
+	}
+	var body valueRequest
+	if !readJSON(w, r, &body) {
+		return
+	}
+	if err := key.validate(body.Value); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.editConfig(w, func() error { return key.set(s.app, body.Value) })
+}
+
+// patternHandler adds or removes the filter or inclusion in the body
+func (s *apiServer) patternHandler(edit func(*aurora.Aurora, string) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body valueRequest
+		if !readJSON(w, r, &body) {
+			return
+		}
+		patterns, err := cleanPatterns([]string{body.Value})
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		s.editConfig(w, func() error { return edit(s.app, patterns[0]) })
+	}
+}
+
+// editConfig applies a config change and answers with the new config.
+// Changes wait until the running backup is done.
+func (s *apiServer) editConfig(w http.ResponseWriter, edit func() error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		writeError(w, http.StatusConflict, errors.New("a backup is running, change the configuration once it is done"))
+		return
+	}
+	if err := edit(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.app.GetConfig())
+}
+
+func (s *apiServer) handleCollections(w http.ResponseWriter, r *http.Request) {
+	collections, err := s.app.GetCollections()
+	writeResult(w, collections, err)
+}
+
+func (s *apiServer) handleFilterMatches(w http.ResponseWriter, r *http.Request) {
+	matches, err := s.app.GetFilterMatches()
+	writeResult(w, matches, err)
+}
+
+// handleHistory answers the library timeline; ?limit= caps the snapshots
+// compared, like aurora history --limit
+func (s *apiServer) handleHistory(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", value))
+			return
+		}
+	}
+	history, err := s.app.History(limit)
+	writeResult(w, history, err)
+}
+
+func (s *apiServer) handleValidate(w http.ResponseWriter, r *http.Request) {
+	validation, err := s.app.ValidateBackup()
+	writeResult(w, validation, err)
+}
+
+// handlePending answers the interrupted backup, null when there is none
+func (s *apiServer) handlePending(w http.ResponseWriter, r *http.Request) {
+	pending, err := s.app.PendingBackup()
+	writeResult(w, pending, err)
+}
+
+func (s *apiServer) handleBackupState(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	state := s.state
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, state)
+}
+
+// handleStartBackup starts a backup and answers 202 right away: follow it
+// with GET /api/backup or the event stream
+func (s *apiServer) handleStartBackup(w http.ResponseWriter, r *http.Request) {
+	var body backupRequest
+	if r.ContentLength != 0 && !readJSON(w, r, &body) {
+		return
+	}
+	if !s.app.IsConfigValid() {
+		writeError(w, http.StatusConflict, aurora.ErrInvalidConfig)
+		return
+	}
+	passphrase := body.Passphrase
+	if passphrase == "" {
+		var err error
+		if passphrase, err = s.app.ResolvePassphrase("", nil); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	opts := aurora.RunBackupOptions{Threads: body.Threads, Resume: body.Resume, Passphrase: passphrase}
+	if err := s.startBackup(opts); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	s.handleBackupState(w, r)
+}
+
+// handleCancelBackup stops the running backup; it can be resumed
+func (s *apiServer) handleCancelBackup(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+	if cancel == nil {
+		writeError(w, http.StatusConflict, errors.New("no backup is running"))
+		return
+	}
+	cancel()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handleBackupEvents streams backups as Server-Sent Events: the current
+// state first, then notices, progress and a "done" event with the final
+// state of every backup until the client leaves
+func (s *apiServer) handleBackupEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	events, state := s.subscribe()
+	defer s.unsubscribe(events)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	writeEvent(w, serverEvent{name: "state", data: state})
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.ctx.Done():
+			return
+		case event := <-events:
+			writeEvent(w, event)
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// startBackup runs a backup in the background, failing when one already
+// runs
+func (s *apiServer) startBackup(opts aurora.RunBackupOptions) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		return errors.New("a backup is already running")
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	done := make(chan struct{})
+	now := time.Now()
+	s.cancel, s.done = cancel, done
+	s.state = backupState{Running: true, StartedAt: &now}
+
+	go func() {
+		defer close(done)
+		defer cancel()
+		result, err := s.app.RunBackup(ctx, opts, serverObserver{s})
+		s.finishBackup(result, err)
+	}()
+	return nil
+}
+
+// finishBackup records the outcome of the running backup and tells the
+// streams
+func (s *apiServer) finishBackup(result aurora.BackupResult, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.state.Running = false
+	s.state.FinishedAt = &now
+	if err != nil {
+		s.state.Error = err.Error()
+	} else {
+		s.state.Result = &result
+	}
+	s.cancel, s.done = nil, nil
+	s.publish(serverEvent{name: "done", data: s.state})
+}
+
+// shutdown cancels the running backup and waits a moment for it to stop
+func (s *apiServer) shutdown() {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		fmt.Fprintf(os.Stderr, "Backup did not stop in time\n")
+	}
+}
+
+func (s *apiServer) subscribe() (chan serverEvent, backupState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	events := make(chan serverEvent, sseBuffer)
+	s.streams[events] = struct{}{}
+	return events, s.state
+}
+
+func (s *apiServer) unsubscribe(events chan serverEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.streams, events)
+}
+
+// publish hands an event to every stream without waiting for slow ones;
+// called with s.mu held
+func (s *apiServer) publish(event serverEvent) {
+	for events := range s.streams {
+		select {
+		case events <- event:
+		default:
+			if event.name != "done" {
+				continue // the client catches up with the next progress
+			}
+			// Make room: the outcome matters more than old progress
+			select {
+			case <-events:
+			default:
+			}
+			select {
+			case events <- event:
+			default:
+			}
+		}
+	}
+}
+
+// serverObserver forwards a backup run to the event streams; progress also
+// updates the state GET /api/backup answers
+type serverObserver struct{ server *apiServer }
+
+func (o serverObserver) Validated(aurora.BackupValidation) {}
+
+func (o serverObserver) Notice(message string) {
+	o.server.mu.Lock()
+	defer o.server.mu.Unlock()
+	o.server.publish(serverEvent{name: "notice", data: map[string]string{"message": message}})
+}
+
+func (o serverObserver) Progress(progress aurora.BackupProgress) {
+	o.server.mu.Lock()
+	defer o.server.mu.Unlock()
+	o.server.state.Progress = &progress
+	o.server.publish(serverEvent{name: "progress", data: progress})
+}
+
+// writeEvent writes one Server-Sent Event with a JSON payload
+func writeEvent(w io.Writer, event serverEvent) {
+	data, err := json.Marshal(event.data)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.name, data)
+}
+
+// readJSON decodes the request body, answering 400 when it can't
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return false
+	}
+	return true
+}
+
+// writeResult answers v, or err as 409 for an invalid config and 500
+// otherwise
+func writeResult(w http.ResponseWriter, v any, err error) {
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, v)
+	case errors.Is(err, aurora.ErrInvalidConfig):
+		writeError(w, http.StatusConflict, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError answers {"error": "..."}
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
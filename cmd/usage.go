@@ -0,0 +1,113 @@
+package main
+
+import (
+	"aurora/pkg/aurora"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Show where the disk space of the mods folder goes",
+	Long: `Show where the disk space of the mods folder goes: by collection, by
+folder of the mod selector, by file type, by author and by mod. A
+collection's exclusive size counts the mods only it uses, its shared size
+the mods other collections use too. CSV output lists every group.`,
+	Args: cobra.NoArgs,
+	Run:  runUsageCmd,
+}
+
+func init() {
+	usageCmd.Flags().Int("limit", 10, "rows per table section (0 for all)")
+}
+
+func runUsageCmd(cmd *cobra.Command, args []string) {
+	limit, err := cmd.Flags().GetInt("limit")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading limit flag: %v\n", err)
+		os.Exit(exitError)
+	}
+	app := loadApp()
+	requireValidConfig(app)
+
+	result, err := app.DiskUsage()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to compute disk usage: %v\n", err)
+		os.Exit(exitError)
+	}
+	render(cmd, report{
+		data:  result,
+		rows:  usageRows(result),
+		table: func(w io.Writer) { usageTable(w, result, limit) },
+	})
+}
+
+// usageRows lists one csv record per group of every breakdown; exclusive
+// and shared sizes are only set for collections
+func usageRows(result aurora.DiskUsageResult) [][]string {
+	rows := [][]string{{"group", "name", "mods", "files", "size", "exclusiveSize", "sharedSize"}}
+	for _, col := range result.Collections {
+		rows = append(rows, []string{"collection", col.Name, strconv.Itoa(col.Mods), "",
+			strconv.FormatUint(col.Size, 10), strconv.FormatUint(col.ExclusiveSize, 10), strconv.FormatUint(col.SharedSize, 10)})
+	}
+	for _, section := range []struct {
+		group  string
+		groups []aurora.UsageGroup
+	}{{"folder", result.Folders}, {"type", result.Types}, {"author", result.Authors}} {
+		for _, g := range section.groups {
+			rows = append(rows, []string{section.group, g.Name, strconv.Itoa(g.Mods), strconv.Itoa(g.Files), strconv.FormatUint(g.Size, 10), "", ""})
+		}
+	}
+	for _, mod := range result.LargestMods {
+		rows = append(rows, []string{"mod", mod.Name, "1", strconv.Itoa(mod.Files), strconv.FormatUint(mod.Size, 10), "", ""})
+	}
+	return rows
+}
+
+func usageTable(w io.Writer, result aurora.DiskUsageResult, limit int) {
+	fmt.Fprintf(w, "%d mods use %s, %s of them in no collection\n", result.Mods, result.TotalSizeHuman, result.UnusedSizeHuman)
+
+	cols := [][]string{}
+	for _, col := range truncate(result.Collections, limit) {
+		cols = append(cols, []string{
+			abbreviatePath(col.Name, 40),
+			strconv.Itoa(col.Mods),
+			col.SizeHuman,
+			fmt.Sprintf("%s (%d)", col.ExclusiveSizeHuman, col.ExclusiveMods),
+			fmt.Sprintf("%s (%d)", col.SharedSizeHuman, col.SharedMods),
+		})
+	}
+	fmt.Fprintln(w, "\nBy collection:")
+	renderTable(w, []string{"Collection", "Mods", "Size", "Exclusive", "Shared"}, cols)
+
+	for _, section := range []struct {
+		title, header, empty string
+		groups               []aurora.UsageGroup
+	}{
+		{"By folder", "Folder", "(root)", result.Folders},
+		{"By file type", "Type", "(none)", result.Types},
+		{"By author", "Author", "(unknown)", result.Authors},
+	} {
+		rows := [][]string{}
+		for _, g := range truncate(section.groups, limit) {
+			name := g.Name
+			if name == "" {
+				name = section.empty
+			}
+			rows = append(rows, []string{abbreviatePath(name, 40), strconv.Itoa(g.Mods), strconv.Itoa(g.Files), g.SizeHuman})
+		}
+		fmt.Fprintf(w, "\n%s:\n", section.title)
+		renderTable(w, []string{section.header, "Mods", "Files", "Size"}, rows)
+	}
+
+	mods := [][]string{}
+	for _, mod := range truncate(result.LargestMods, limit) {
+		mods = append(mods, []string{abbreviatePath(mod.Name, 40), strconv.Itoa(mod.Collections), strconv.Itoa(mod.Files), mod.SizeHuman})
+	}
+	fmt.Fprintln(w, "\nLargest mods:")
+	renderTable(w, []string{"Mod", "Collections", "Files", "Size"}, mods)
+}
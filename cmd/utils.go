@@ -1,10 +1,14 @@
 package main
 
 import (
+	"aurora/pkg/aurora"
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
+
+	"golang.org/x/term"
 )
 
 func abbreviatePath(path string, maxLength int) string {
@@ -20,7 +24,77 @@ func prompt(prompt string) string {
 	input, err := reader.ReadString('\n')
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "\nFailed to read input: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 	return strings.TrimSpace(input)
 }
+
+// promptPassphrase reads the backup passphrase from the terminal without
+// echoing it, twice when confirm is set. Without a terminal it fails: set
+// AURORA_PASSPHRASE or a key file for unattended runs.
+func promptPassphrase(confirm bool) func() (string, error) {
+	return func() (string, error) {
+		fd := int(os.Stdin.Fd())
+		if !term.IsTerminal(fd) {
+			return "", fmt.Errorf("%w: no terminal to prompt, set %s or a key file", aurora.ErrPassphraseRequired, aurora.EnvPassphrase)
+		}
+		read := func(label string) (string, error) {
+			fmt.Fprintf(os.Stderr, "%s: ", label)
+			input, err := term.ReadPassword(fd)
+			fmt.Fprintln(os.Stderr)
+			return string(input), err
+		}
+		passphrase, err := read("Backup passphrase")
+		if err != nil {
+			return "", err
+		}
+		if passphrase == "" {
+			return "", aurora.ErrPassphraseRequired
+		}
+		if confirm {
+			again, err := read("Confirm passphrase")
+			if err != nil {
+				return "", err
+			}
+			if again != passphrase {
+				return "", errors.New("passphrases don't match")
+			}
+		}
+		return passphrase, nil
+	}
+}
+
+// resolvePassphrase finds the backup passphrase or exits
+func resolvePassphrase(app *aurora.Aurora, keyFile string, confirm bool) string {
+	passphrase, err := app.ResolvePassphrase(keyFile, promptPassphrase(confirm))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	return passphrase
+}
+
+// loadApp loads the aurora service or exits
+func loadApp() *aurora.Aurora {
+	app, err := aurora.New()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		os.Exit(exitError)
+	}
+	return app
+}
+
+// requireValidConfig exits with exitInvalidConfig unless the config paths
+// are valid
+func requireValidConfig(app *aurora.Aurora) {
+	if app.IsConfigValid() {
+		return
+	}
+	cfg := app.GetConfig()
+	fmt.Fprintf(os.Stderr, "Configuration is not valid:\n")
+	fmt.Fprintf(os.Stderr, "  Penumbra: %s\n", cfg.Status.PenumbraStatus)
+	fmt.Fprintf(os.Stderr, "  Mods: %s\n", cfg.Status.ModsStatus)
+	fmt.Fprintf(os.Stderr, "  Output: %s\n", cfg.Status.OutputStatus)
+	fmt.Fprintf(os.Stderr, "\nRun 'aurora config --reset' to fix\n")
+	os.Exit(exitInvalidConfig)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"aurora/pkg/aurora"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/dustin/go-humanize"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the backup in the output directory reads back intact",
+	Long: `Check the backup in the output directory reads back intact. With --target
+the copy on the configured remote target is downloaded and checked instead.`,
+	Args: cobra.NoArgs,
+	Run:  runVerifyCmd,
+}
+
+func init() {
+	verifyCmd.Flags().String("key-file", "", "file holding the encryption passphrase (default: config keyFile, $AURORA_PASSPHRASE or a prompt)")
+	verifyCmd.Flags().Bool("target", false, "verify the copy on the configured remote target")
+}
+
+func runVerifyCmd(cmd *cobra.Command, args []string) {
+	app := loadApp()
+
+	keyFile, err := cmd.Flags().GetString("key-file")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading key-file flag: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	remote, err := cmd.Flags().GetBool("target")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading target flag: %v\n", err)
+		os.Exit(exitError)
+	}
+	if remote && !app.GetTarget().IsRemote() {
+		fmt.Fprintf(os.Stderr, "Error: no remote target is configured (see aurora config set target)\n")
+		os.Exit(exitError)
+	}
+	verify := func(passphrase string) (aurora.VerifyResult, error) {
+		if remote {
+			return app.VerifyTarget(context.Background(), passphrase)
+		}
+		return app.VerifyBackup(passphrase)
+	}
+
+	// Only prompt when the backup turns out to be encrypted
+	passphrase, err := app.ResolvePassphrase(keyFile, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	result, err := verify(passphrase)
+	if errors.Is(err, aurora.ErrPassphraseRequired) && passphrase == "" {
+		passphrase = resolvePassphrase(app, keyFile, false)
+		result, err = verify(passphrase)
+	}
+	switch {
+	case err == nil:
+	case errors.Is(err, aurora.ErrNoBackup), errors.Is(err, aurora.ErrWrongPassphrase), errors.Is(err, aurora.ErrPassphraseRequired):
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	default:
+		fmt.Fprintf(os.Stderr, "Failed to verify backup: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	render(cmd, report{
+		data:  result,
+		rows:  verifyRows(result),
+		table: func(w io.Writer) { verifyTable(w, result) },
+	})
+	if !result.Valid {
+		os.Exit(exitError)
+	}
+}
+
+// verifyRows lists one csv record per archive
+func verifyRows(result aurora.VerifyResult) [][]string {
+	rows := [][]string{{"archive", "format", "size", "encrypted", "files", "error"}}
+	for _, archive := range result.Archives {
+		rows = append(rows, []string{
+			archive.Name,
+			archive.Format,
+			strconv.FormatInt(archive.Size, 10),
+			strconv.FormatBool(archive.Encrypted),
+			strconv.Itoa(archive.Files),
+			archive.Error,
+		})
+	}
+	return rows
+}
+
+func verifyTable(w io.Writer, result aurora.VerifyResult) {
+	data := [][]string{
+		{"Archive", "Size", "Files", "Status"},
+	}
+	for _, archive := range result.Archives {
+		status := "OK"
+		files := strconv.Itoa(archive.Files)
+		if !archive.ContentChecked {
+			files = "-" // checksum only
+		}
+		if archive.Error != "" {
+			status = abbreviatePath(archive.Error, 80)
+		}
+		data = append(data, []string{archive.Name, humanize.Bytes(uint64(archive.Size)), files, status})
+	}
+
+	table := tablewriter.NewTable(w)
+	table.Header(data[0])
+	table.Bulk(data[1:])
+	table.Render()
+
+	switch {
+	case result.ManifestError != "":
+		fmt.Fprintf(w, "Backup manifest unusable: %s\n", result.ManifestError)
+	case !result.Manifest:
+		fmt.Fprintf(w, "No backup manifest: archives were not checked against checksums\n")
+	}
+	if result.Valid {
+		fmt.Fprintf(w, "Backup is intact\n")
+	} else {
+		fmt.Fprintf(w, "Backup is damaged\n")
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"aurora/pkg/aurora"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Back up changed mods incrementally while mods and collections change",
+	Long: `Watch the mods folder and Penumbra's collections. Once changes settle for
+the quiet period, the changed mods selected for backup (and mods an edited
+collection newly selects) are backed up to a new folder under
+<output>/incremental. Stop with Ctrl+C.`,
+	Run: runWatchCmd,
+}
+
+func init() {
+	watchCmd.Flags().Duration("quiet-period", aurora.DefaultWatchQuiet, "how long mods must stay unchanged before a backup")
+	watchCmd.Flags().IntP("threads", "t", 0, "compress folders concurrently (default: config concurrency)")
+	watchCmd.Flags().String("key-file", "", "file holding the encryption passphrase (default: config keyFile, $AURORA_PASSPHRASE or a prompt)")
+}
+
+func runWatchCmd(cmd *cobra.Command, args []string) {
+	app := loadApp()
+	requireValidConfig(app)
+
+	quiet, err := cmd.Flags().GetDuration("quiet-period")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading quiet-period flag: %v\n", err)
+		os.Exit(exitError)
+	}
+	if quiet <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: --quiet-period must be positive, got %s\n", quiet)
+		os.Exit(exitError)
+	}
+
+	threads, err := cmd.Flags().GetInt("threads")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading thread flag: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	keyFile, err := cmd.Flags().GetString("key-file")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading key-file flag: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	// Asked once: every backup of the session uses it
+	passphrase := ""
+	if app.GetConfig().Encrypt {
+		passphrase = resolvePassphrase(app, keyFile, true)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err = app.Watch(ctx, aurora.WatchOptions{Quiet: quiet, Threads: threads, Passphrase: passphrase}, &watchObserver{cmd: cmd})
+	switch {
+	case err == nil:
+		fmt.Fprintf(os.Stderr, "Stopped watching\n")
+	case errors.Is(err, aurora.ErrInvalidConfig):
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitInvalidConfig)
+	default:
+		fmt.Fprintf(os.Stderr, "Failed to watch: %v\n", err)
+		os.Exit(exitError)
+	}
+}
+
+// watchObserver renders the backups of a watcher: notices and progress on
+// stderr, each result on stdout. The preview isn't shown.
+type watchObserver struct {
+	cmd      *cobra.Command
+	printer  *progressPrinter
+	finished int
+}
+
+func (o *watchObserver) Validated(aurora.BackupValidation) {}
+
+func (o *watchObserver) Notice(message string) {
+	fmt.Fprintln(os.Stderr, message)
+}
+
+func (o *watchObserver) Progress(progress aurora.BackupProgress) {
+	if o.printer != nil {
+		o.printer.update(progress)
+	}
+}
+
+func (o *watchObserver) BackupStarted(mods []string) {
+	fmt.Fprintf(os.Stderr, "Changed: %s\n", strings.Join(mods, ", "))
+	if !isMachineOutput(o.cmd) {
+		o.printer = newProgressPrinter(os.Stderr)
+	}
+}
+
+func (o *watchObserver) BackupFinished(result aurora.BackupResult, err error) {
+	if o.printer != nil {
+		o.printer.finish()
+		o.printer = nil
+	}
+	switch {
+	case err == nil:
+	case errors.Is(err, aurora.ErrNothingToBackup):
+		fmt.Fprintf(os.Stderr, "Nothing to back up\n")
+		return
+	case errors.Is(err, aurora.ErrBackupCancelled):
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "Incremental backup failed, retrying with the next change: %v\n", err)
+		return
+	}
+
+	// csv gets its header once
+	rows := [][]string{{"outputDir", "originalSize", "compressedSize", "ratio"}}
+	if o.finished > 0 {
+		rows = rows[1:]
+	}
+	o.finished++
+	render(o.cmd, report{
+		data: result,
+		rows: append(rows, []string{result.OutputDir, strconv.FormatUint(result.OriginalSize, 10), strconv.FormatUint(result.CompressedSize, 10), result.Ratio}),
+		table: func(w io.Writer) {
+			fmt.Fprintf(w, "Incremental backup written to: %s (%s, %s)\n", result.OutputDir, humanize.Bytes(result.CompressedSize), result.Ratio)
+			destinationsTable(w, result.Destinations)
+		},
+	})
+}
@@ -4,14 +4,15 @@ import (
 	"aurora/internal/logger"
 	"aurora/pkg/aurora"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	goruntime "runtime"
 	"sync"
+	"time"
 
-	"github.com/creativeyann17/go-delta/pkg/compress"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
@@ -21,11 +22,23 @@ type App struct {
 	aurora  *aurora.Aurora
 	initErr error // set when config loading failed at startup
 	version string
+
+	backupMu     sync.Mutex
+	cancelBackup context.CancelFunc // set while a backup runs
+	backupDone   chan struct{}      // closed when the running backup returns
+
+	schedulerOnce   sync.Once     // the page may reload: one scheduler
+	scheduleChanged chan struct{} // wakes the scheduler when the schedule is set
+	scheduleMu      sync.Mutex
+	lastScheduled   *ScheduleEvent // the last scheduled run, nil before one
 }
 
+// errBackupRunning is returned when a backup starts while another runs
+var errBackupRunning = errors.New("a backup is already running")
+
 // NewApp creates a new App instance
 func NewApp(version string) *App {
-	return &App{version: version}
+	return &App{version: version, scheduleChanged: make(chan struct{}, 1)}
 }
 
 // GetVersion returns the application version
@@ -37,7 +50,7 @@ func (a *App) GetVersion() string {
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 	logger.Init(logger.GetLogPath())
-	logger.Info("Aurora desktop app starting, version=%s", a.version)
+	logger.Logger().Info("Aurora desktop app starting", "version", a.version)
 	svc, err := aurora.New()
 	if err != nil {
 		// Don't crash the window: surface the error through GetConfig status
@@ -48,6 +61,31 @@ func (a *App) startup(ctx context.Context) {
 	a.aurora = svc
 }
 
+// domReady is called once the frontend loaded: scheduled backups start
+// then, so the window gets their events. Without a config they start once
+// ReloadConfig loads one.
+func (a *App) domReady(ctx context.Context) {
+	if a.aurora != nil {
+		a.schedulerOnce.Do(a.startScheduler)
+	}
+}
+
+// shutdown is called when the app is closing: a running backup is
+// cancelled so it can be resumed on the next launch
+func (a *App) shutdown(ctx context.Context) {
+	a.backupMu.Lock()
+	done := a.backupDone
+	a.backupMu.Unlock()
+	if a.CancelBackup() {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			logger.Warn("Backup did not stop in time on shutdown")
+		}
+	}
+	logger.Close()
+}
+
 // svc returns the aurora service or the startup error
 func (a *App) svc() (*aurora.Aurora, error) {
 	if a.aurora == nil {
@@ -87,6 +125,7 @@ func (a *App) ReloadConfig() (aurora.ConfigResult, error) {
 		if svc2, err2 := aurora.New(); err2 == nil {
 			a.aurora = svc2
 			a.initErr = nil
+			a.schedulerOnce.Do(a.startScheduler)
 			return a.aurora.GetConfig(), nil
 		}
 		return a.GetConfig(), err
@@ -111,6 +150,17 @@ func (a *App) BrowseDirectory(title string, defaultPath string) (string, error)
 	return runtime.OpenDirectoryDialog(a.ctx, opts)
 }
 
+// BrowseFile opens a file picker dialog
+func (a *App) BrowseFile(title string, defaultPath string) (string, error) {
+	opts := runtime.OpenDialogOptions{
+		Title: title,
+	}
+	if defaultPath != "" {
+		opts.DefaultDirectory = filepath.Dir(defaultPath)
+	}
+	return runtime.OpenFileDialog(a.ctx, opts)
+}
+
 // UpdateConfig updates the configuration paths
 func (a *App) UpdateConfig(penumbraPath, modsPath, outputPath string) error {
 	svc, err := a.svc()
@@ -179,6 +229,123 @@ func (a *App) SetCompression(compression string) error {
 	return svc.SetCompression(compression)
 }
 
+// SetFormat sets the backup archive format ("zip", "tar.zst" or "gdelta")
+func (a *App) SetFormat(format string) error {
+	svc, err := a.svc()
+	if err != nil {
+		return err
+	}
+	return svc.SetFormat(format)
+}
+
+// SetLayout sets the backup archive layout ("single", "collection" or "group")
+func (a *App) SetLayout(layout string) error {
+	svc, err := a.svc()
+	if err != nil {
+		return err
+	}
+	return svc.SetLayout(layout)
+}
+
+// SetMaxPartSize sets the largest backup archive in bytes (0 = no limit)
+func (a *App) SetMaxPartSize(size uint64) error {
+	svc, err := a.svc()
+	if err != nil {
+		return err
+	}
+	return svc.SetMaxPartSize(size)
+}
+
+// SetEncrypt turns backup encryption on or off
+func (a *App) SetEncrypt(encrypt bool) error {
+	svc, err := a.svc()
+	if err != nil {
+		return err
+	}
+	return svc.SetEncrypt(encrypt)
+}
+
+// SetKeyFile sets the file holding the backup passphrase ("" = none)
+func (a *App) SetKeyFile(path string) error {
+	svc, err := a.svc()
+	if err != nil {
+		return err
+	}
+	return svc.SetKeyFile(path)
+}
+
+// SetPreBackupHook sets the command run before each backup ("" = none)
+func (a *App) SetPreBackupHook(command string) error {
+	svc, err := a.svc()
+	if err != nil {
+		return err
+	}
+	return svc.SetPreBackupHook(command)
+}
+
+// SetPostBackupHook sets the command run after each backup ("" = none)
+func (a *App) SetPostBackupHook(command string) error {
+	svc, err := a.svc()
+	if err != nil {
+		return err
+	}
+	return svc.SetPostBackupHook(command)
+}
+
+// SetHookTimeout sets how many seconds each hook may run (0 = 10 minutes)
+func (a *App) SetHookTimeout(seconds int) error {
+	svc, err := a.svc()
+	if err != nil {
+		return err
+	}
+	return svc.SetHookTimeout(seconds)
+}
+
+// SetTarget sets where backups are uploaded, as a target URL ("" = the
+// output folder only)
+func (a *App) SetTarget(url string) error {
+	svc, err := a.svc()
+	if err != nil {
+		return err
+	}
+	target, err := aurora.ParseTarget(url)
+	if err != nil {
+		return err
+	}
+	return svc.SetTarget(target)
+}
+
+// AddMirror adds a directory or target URL every backup is copied to
+func (a *App) AddMirror(url string) error {
+	svc, err := a.svc()
+	if err != nil {
+		return err
+	}
+	mirror, err := aurora.ParseTarget(url)
+	if err != nil {
+		return err
+	}
+	return svc.AddMirror(mirror)
+}
+
+// RemoveMirror stops copying backups to the mirror, as listed in the config
+func (a *App) RemoveMirror(url string) error {
+	svc, err := a.svc()
+	if err != nil {
+		return err
+	}
+	return svc.RemoveMirror(url)
+}
+
+// SetRetention sets which incremental backups are kept (0 = no limit)
+func (a *App) SetRetention(retention aurora.Retention) error {
+	svc, err := a.svc()
+	if err != nil {
+		return err
+	}
+	return svc.SetRetention(retention)
+}
+
 // GetCollections returns all collections and mods
 func (a *App) GetCollections() (aurora.CollectionsResult, error) {
 	svc, err := a.svc()
@@ -242,113 +409,219 @@ func (a *App) OpenOutputFolder() error {
 	return nil
 }
 
-// BackupProgressEvent represents progress sent to frontend
-type BackupProgressEvent struct {
-	Percent float64 `json:"percent"`
-	Current string  `json:"current"`
-	Done    bool    `json:"done"`
-	Error   string  `json:"error,omitempty"`
+// RunBackup executes the backup operation with progress events. passphrase
+// is the one typed in the UI, "" when the key file or AURORA_PASSPHRASE
+// provides it or encryption is off.
+func (a *App) RunBackup(threads int, passphrase string) (*aurora.BackupResult, error) {
+	logger.Info("RunBackup started with threads=%d", threads)
+	return a.runBackup(threads, false, passphrase)
+}
+
+// ResumeBackup continues the interrupted backup with progress events
+func (a *App) ResumeBackup(threads int, passphrase string) (*aurora.BackupResult, error) {
+	logger.Info("ResumeBackup started with threads=%d", threads)
+	return a.runBackup(threads, true, passphrase)
 }
 
-// RunBackup executes the backup operation with progress events
-func (a *App) RunBackup(threads int) (*aurora.BackupResult, error) {
-	logger.Info("RunBackup started with threads=%d", threads)
+// FindDuplicates groups identical files across mods, emitting
+// "duplicates:progress" events as files are hashed
+func (a *App) FindDuplicates() (aurora.DuplicatesResult, error) {
+	svc, err := a.svc()
+	if err != nil {
+		return aurora.DuplicatesResult{}, err
+	}
+	return svc.FindDuplicates(a.ctx, func(hashed, total int) {
+		runtime.EventsEmit(a.ctx, "duplicates:progress", map[string]int{"hashed": hashed, "total": total})
+	})
+}
+
+// VerifyBackup checks the backup in the output directory reads back intact
+func (a *App) VerifyBackup(passphrase string) (aurora.VerifyResult, error) {
+	svc, err := a.svc()
+	if err != nil {
+		return aurora.VerifyResult{}, err
+	}
+	if passphrase == "" {
+		if passphrase, err = svc.ResolvePassphrase("", nil); err != nil {
+			return aurora.VerifyResult{}, err
+		}
+	}
+	return svc.VerifyBackup(passphrase)
+}
+
+// GetBackupReport reads the report of the backup set in dir ("" = the
+// output directory), resolving the passphrase like VerifyBackup
+func (a *App) GetBackupReport(dir, passphrase string) (aurora.BackupReport, error) {
+	svc, err := a.svc()
+	if err != nil {
+		return aurora.BackupReport{}, err
+	}
+	if passphrase == "" {
+		if passphrase, err = svc.ResolvePassphrase("", nil); err != nil {
+			return aurora.BackupReport{}, err
+		}
+	}
+	return svc.BackupReport(dir, passphrase)
+}
+
+// DecryptBackup writes plain copies of the encrypted backup set in dir
+// ("" = the output directory) to outDir, resolving the passphrase like
+// VerifyBackup
+func (a *App) DecryptBackup(dir, outDir, passphrase string) (aurora.DecryptResult, error) {
+	svc, err := a.svc()
+	if err != nil {
+		return aurora.DecryptResult{}, err
+	}
+	if passphrase == "" {
+		if passphrase, err = svc.ResolvePassphrase("", nil); err != nil {
+			return aurora.DecryptResult{}, err
+		}
+	}
+	return svc.DecryptBackup(dir, outDir, passphrase)
+}
+
+// GetDiskUsage breaks the space of the mods folder down by collection,
+// folder, file type and author
+func (a *App) GetDiskUsage() (aurora.DiskUsageResult, error) {
+	svc, err := a.svc()
+	if err != nil {
+		return aurora.DiskUsageResult{}, err
+	}
+	return svc.DiskUsage()
+}
+
+// GetHistory returns the changes of the limit most recent library
+// snapshots (0 for all), newest first
+func (a *App) GetHistory(limit int) (aurora.HistoryResult, error) {
+	svc, err := a.svc()
+	if err != nil {
+		return aurora.HistoryResult{}, err
+	}
+	return svc.History(limit)
+}
+
+// TakeSnapshot records the mod library now, for the history
+func (a *App) TakeSnapshot() (aurora.Snapshot, error) {
+	svc, err := a.svc()
+	if err != nil {
+		return aurora.Snapshot{}, err
+	}
+	return svc.TakeSnapshot(aurora.SnapshotManual)
+}
+
+// GetUnusedMods lists the mods no collection uses, for the Cleanup tab
+func (a *App) GetUnusedMods() (aurora.UnusedModsResult, error) {
+	svc, err := a.svc()
+	if err != nil {
+		return aurora.UnusedModsResult{}, err
+	}
+	return svc.UnusedMods()
+}
+
+// QuarantineMods moves unused mods out of the mods folder, archiving them
+// first when backup is set
+func (a *App) QuarantineMods(mods []string, backup bool, passphrase string) (aurora.Quarantine, error) {
+	svc, err := a.svc()
+	if err != nil {
+		return aurora.Quarantine{}, err
+	}
+	if backup && passphrase == "" {
+		if passphrase, err = svc.ResolvePassphrase("", nil); err != nil {
+			return aurora.Quarantine{}, err
+		}
+	}
+	return svc.QuarantineMods(a.ctx, aurora.QuarantineOptions{Mods: mods, Backup: backup, Passphrase: passphrase})
+}
+
+// GetQuarantines lists the quarantines, newest first
+func (a *App) GetQuarantines() ([]aurora.Quarantine, error) {
 	svc, err := a.svc()
 	if err != nil {
 		return nil, err
 	}
-	folders, err := svc.GetBackupFolders()
+	return svc.Quarantines()
+}
+
+// RestoreQuarantine moves the mods of a quarantine back
+func (a *App) RestoreQuarantine(id string) (aurora.Quarantine, error) {
+	svc, err := a.svc()
+	if err != nil {
+		return aurora.Quarantine{}, err
+	}
+	return svc.RestoreQuarantine(id)
+}
+
+// GetPendingBackup returns the interrupted backup the UI offers to resume
+// on launch, or nil when there is none
+func (a *App) GetPendingBackup() (*aurora.PendingBackup, error) {
+	svc, err := a.svc()
 	if err != nil {
 		return nil, err
 	}
-	if len(folders) == 0 {
-		logger.Warn("RunBackup: no mods to backup")
-		return nil, fmt.Errorf("no mods to backup")
+	return svc.PendingBackup()
+}
+
+// DiscardPendingBackup deletes the interrupted backup
+func (a *App) DiscardPendingBackup() error {
+	svc, err := a.svc()
+	if err != nil {
+		return err
 	}
+	return svc.DiscardPendingBackup()
+}
 
-	if threads < 0 {
-		threads = 0
+// GetWatchStatus returns the status of 'aurora watch'; nil when it never
+// ran. Active tells whether it still runs.
+func (a *App) GetWatchStatus() (*aurora.WatchStatus, error) {
+	status, err := aurora.ReadWatchStatus()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
 	}
+	if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (a *App) runBackup(threads int, resume bool, passphrase string) (*aurora.BackupResult, error) {
+	svc, err := a.svc()
+	if err != nil {
+		return nil, err
+	}
+	if passphrase == "" {
+		if passphrase, err = svc.ResolvePassphrase("", nil); err != nil {
+			return nil, err
+		}
+	}
+
+	a.backupMu.Lock()
+	if a.cancelBackup != nil {
+		a.backupMu.Unlock()
+		return nil, errBackupRunning
+	}
+	ctx, cancel := context.WithCancel(a.ctx)
+	done := make(chan struct{})
+	a.cancelBackup, a.backupDone = cancel, done
+	a.backupMu.Unlock()
+	defer func() {
+		a.backupMu.Lock()
+		a.cancelBackup, a.backupDone = nil, nil
+		a.backupMu.Unlock()
+		cancel()
+		close(done)
+	}()
 
 	// Emit initial progress
-	runtime.EventsEmit(a.ctx, "backup:progress", BackupProgressEvent{
+	a.emitProgress(aurora.BackupProgress{
 		Percent: 0,
 		Current: "Preparing backup...",
 		Done:    false,
 	})
 
-	outputDir := svc.GetConfig().OutputPath
-	opts := aurora.NewBackupOptions(folders, threads, svc.GetCompression(), outputDir, true)
-
-	// Progress is byte-weighted: file counting makes the bar crawl through
-	// big mods then leap across thousands of small files. Events arrive from
-	// several compression workers, hence the mutex.
-	var mu sync.Mutex
-	var totalFiles, completedFiles int64
-	var totalBytes, completedBytes uint64
-	inflight := make(map[string]int64) // bytes read so far per in-flight file
-	currentFile := ""
-
-	percentLocked := func() float64 {
-		if totalBytes == 0 {
-			if totalFiles == 0 {
-				return 0
-			}
-			return float64(completedFiles) / float64(totalFiles) * 100
-		}
-		bytes := completedBytes
-		for _, b := range inflight {
-			bytes += uint64(b)
-		}
-		return float64(bytes) / float64(totalBytes) * 100
-	}
-
-	progressCb := func(event compress.ProgressEvent) {
-		mu.Lock()
-		switch event.Type {
-		case compress.EventStart:
-			totalFiles = event.Total
-			totalBytes = event.TotalBytes
-			currentFile = "Starting..."
-
-		case compress.EventFileStart:
-			name := filepath.Base(event.FilePath)
-			if len(name) > 35 {
-				name = name[:32] + "..."
-			}
-			currentFile = name
-
-		case compress.EventFileProgress:
-			inflight[event.FilePath] = event.Current
-
-		case compress.EventFileComplete:
-			completedFiles++
-			completedBytes += uint64(event.Total)
-			delete(inflight, event.FilePath)
-
-		case compress.EventError:
-			delete(inflight, event.FilePath)
-
-		default:
-			mu.Unlock()
-			return
-		}
-		percent := percentLocked()
-		label := fmt.Sprintf("%s (%d/%d)", currentFile, completedFiles, totalFiles)
-		mu.Unlock()
-
-		runtime.EventsEmit(a.ctx, "backup:progress", BackupProgressEvent{
-			Percent: percent,
-			Current: label,
-			Done:    false,
-		})
-	}
-
-	result, err := compress.Compress(opts, progressCb)
-
+	opts := aurora.RunBackupOptions{Threads: threads, Resume: resume, Passphrase: passphrase}
+	backupResult, err := svc.RunBackup(ctx, opts, backupObserver{a})
 	if err != nil {
-		logger.Error("Backup failed: %v", err)
-		runtime.EventsEmit(a.ctx, "backup:progress", BackupProgressEvent{
+		a.emitProgress(aurora.BackupProgress{
 			Percent: 0,
 			Current: "",
 			Done:    true,
@@ -358,44 +631,41 @@ func (a *App) RunBackup(threads int) (*aurora.BackupResult, error) {
 	}
 
 	// Emit completion
-	runtime.EventsEmit(a.ctx, "backup:progress", BackupProgressEvent{
+	a.emitProgress(aurora.BackupProgress{
 		Percent: 100,
 		Current: "Complete!",
 		Done:    true,
 	})
+	return &backupResult, nil
+}
 
-	// Find actual output files created
-	outputDisplay := findBackupOutputFiles(outputDir)
-
-	backupResult := &aurora.BackupResult{
-		OutputPath:     outputDisplay,
-		OriginalSize:   result.OriginalSize,
-		CompressedSize: result.CompressedSize,
-		Ratio:          fmt.Sprintf("%.1f%%", float64(result.CompressedSize)/float64(result.OriginalSize)*100),
-	}
-	logger.Info("Backup completed: output=%s, ratio=%s", backupResult.OutputPath, backupResult.Ratio)
-	return backupResult, nil
+func (a *App) emitProgress(progress aurora.BackupProgress) {
+	runtime.EventsEmit(a.ctx, "backup:progress", progress)
 }
 
-// findBackupOutputFiles finds the backup files created in the output
-// directory ("" = current working directory) and returns a display string
-func findBackupOutputFiles(outputDir string) string {
-	// Check for multi-part files first (backup_part_01.zip, etc.)
-	pattern := filepath.Join(outputDir, "backup_part_*.zip")
-	matches, err := filepath.Glob(pattern)
-	if err == nil && len(matches) > 0 {
-		if len(matches) == 1 {
-			return filepath.Base(matches[0])
-		}
-		// Multiple files: show range
-		return fmt.Sprintf("backup_part_01.zip ... backup_part_%02d.zip", len(matches))
-	}
+// backupObserver forwards a backup run to the frontend as backup:progress
+// events; the preview was already shown in the Backup tab
+type backupObserver struct{ app *App }
 
-	// Fall back to single file
-	single := filepath.Join(outputDir, aurora.BackupOutputPath)
-	if _, err := os.Stat(single); err == nil {
-		return filepath.Base(single)
-	}
+func (o backupObserver) Validated(aurora.BackupValidation) {}
+
+func (o backupObserver) Notice(message string) {
+	o.app.emitProgress(aurora.BackupProgress{Current: message})
+}
+
+func (o backupObserver) Progress(progress aurora.BackupProgress) {
+	o.app.emitProgress(progress)
+}
 
-	return aurora.BackupOutputPath
+// CancelBackup stops the running backup; RunBackup then fails with
+// aurora.ErrBackupCancelled. Reports whether a backup was running.
+func (a *App) CancelBackup() bool {
+	a.backupMu.Lock()
+	defer a.backupMu.Unlock()
+	if a.cancelBackup == nil {
+		return false
+	}
+	logger.Info("Backup cancellation requested")
+	a.cancelBackup()
+	return true
 }
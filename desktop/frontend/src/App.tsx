@@ -16,13 +16,37 @@ declare global {
           RemoveInclusion: (inclusion: string) => Promise<void>
           SetConcurrency: (concurrency: number) => Promise<void>
           SetCompression: (compression: string) => Promise<void>
+          SetFormat: (format: string) => Promise<void>
+          SetLayout: (layout: string) => Promise<void>
+          SetMaxPartSize: (size: number) => Promise<void>
+          SetEncrypt: (encrypt: boolean) => Promise<void>
+          SetKeyFile: (path: string) => Promise<void>
+          SetPreBackupHook: (command: string) => Promise<void>
+          SetPostBackupHook: (command: string) => Promise<void>
+          SetHookTimeout: (seconds: number) => Promise<void>
+          SetTarget: (url: string) => Promise<void>
+          AddMirror: (url: string) => Promise<void>
+          RemoveMirror: (url: string) => Promise<void>
+          SetRetention: (retention: Retention) => Promise<void>
+          GetScheduleStatus: () => Promise<ScheduleStatus>
+          GetWatchStatus: () => Promise<WatchStatus | null>
+          SetSchedule: (schedule: Schedule) => Promise<void>
           GetFilterMatches: () => Promise<FilterMatches>
           OpenOutputFolder: () => Promise<void>
           GetCollections: () => Promise<CollectionsResult>
           ValidateBackup: () => Promise<BackupValidation>
-          RunBackup: (threads: number) => Promise<BackupResult>
+          RunBackup: (threads: number, passphrase: string) => Promise<BackupResult>
+          ResumeBackup: (threads: number, passphrase: string) => Promise<BackupResult>
+          CancelBackup: () => Promise<boolean>
+          GetPendingBackup: () => Promise<PendingBackup | null>
+          DiscardPendingBackup: () => Promise<void>
           BrowseDirectory: (title: string, defaultPath: string) => Promise<string>
+          BrowseFile: (title: string, defaultPath: string) => Promise<string>
           GetVersion: () => Promise<string>
+          GetUnusedMods: () => Promise<UnusedModsResult>
+          QuarantineMods: (mods: string[], backup: boolean, passphrase: string) => Promise<Quarantine>
+          GetQuarantines: () => Promise<Quarantine[]>
+          RestoreQuarantine: (id: string) => Promise<Quarantine>
         }
       }
     }
@@ -41,6 +65,20 @@ interface ConfigResult {
   inclusions: string[]
   concurrency: number
   compression: string
+  format: string
+  layout: string
+  maxPartSize: number
+  encrypt: boolean
+  keyFile: string
+  preBackupHook: string
+  postBackupHook: string
+  hookTimeout: number
+  target: string
+  mirrors: string[]
+  retention: Retention
+  schedule: Schedule
+  passphraseInEnv: boolean
+  configFile: string
   status: {
     valid: boolean
     penumbraStatus: string
@@ -49,12 +87,134 @@ interface ConfigResult {
   }
 }
 
+interface Retention {
+  incrementals: number // newest incremental backups kept, 0 = all
+  days: number // older incremental backups are deleted, 0 = never
+}
+
+interface Schedule {
+  mode: string // off, daily, weekly or startup
+  time: string // HH:MM
+  weekday: number // 0 = Sunday
+  maxAgeDays: number
+}
+
+interface ScheduleEvent {
+  at: string
+  state: string // started, done, failed or skipped
+  reason: string
+  result: BackupResult | null
+}
+
+interface ScheduleStatus {
+  schedule: Schedule
+  nextRun: string | null
+  lastBackup: string | null
+  lastRun: ScheduleEvent | null
+}
+
+interface WatchStatus {
+  pid: number
+  startedAt: string
+  updatedAt: string
+  modsPath: string
+  state: string
+  pendingMods: string[]
+  lastBackup?: {
+    at: string
+    mods: string[]
+    outputDir?: string
+    error?: string
+  }
+  active: boolean
+}
+
 interface FilterMatches {
   filters: Record<string, number>
   inclusions: Record<string, number>
   inclusionsAny: Record<string, number>
 }
 
+// Hook command input: saved on Enter or when leaving the field, a template
+// error is shown under it
+function HookInput({ value, placeholder, onSave }: {
+  value: string
+  placeholder: string
+  onSave: (command: string) => Promise<void>
+}) {
+  const [draft, setDraft] = useState(value)
+  const [error, setError] = useState('')
+
+  useEffect(() => setDraft(value), [value])
+
+  const save = async () => {
+    if (draft === value) return
+    try {
+      await onSave(draft)
+      setError('')
+    } catch (err) {
+      setError(String(err))
+    }
+  }
+
+  return (
+    <span className="hook-input">
+      <input
+        type="text"
+        value={draft}
+        onChange={(e) => setDraft(e.target.value)}
+        onBlur={save}
+        onKeyDown={(e) => e.key === 'Enter' && save()}
+        placeholder={placeholder}
+      />
+      {error && <span className="hook-error">{error}</span>}
+    </span>
+  )
+}
+
+// Mirrors of the settings: one row per mirror, and an input adding one on
+// Enter. Invalid directories and URLs show the error under the input.
+function MirrorList({ mirrors, onAdd, onRemove }: {
+  mirrors: string[]
+  onAdd: (url: string) => Promise<void>
+  onRemove: (url: string) => Promise<void>
+}) {
+  const [draft, setDraft] = useState('')
+  const [error, setError] = useState('')
+
+  const add = async () => {
+    if (!draft.trim()) return
+    try {
+      await onAdd(draft.trim())
+      setDraft('')
+      setError('')
+    } catch (err) {
+      setError(String(err))
+    }
+  }
+
+  return (
+    <span className="hook-input">
+      {mirrors.map((mirror) => (
+        <span key={mirror} className="mirror-item">
+          <span className="mirror-text">{mirror}</span>
+          <button className="filter-delete" onClick={() => onRemove(mirror)} title="Remove mirror">
+            ×
+          </button>
+        </span>
+      ))}
+      <input
+        type="text"
+        value={draft}
+        onChange={(e) => setDraft(e.target.value)}
+        onKeyDown={(e) => e.key === 'Enter' && add()}
+        placeholder="Add a folder or URL, then Enter"
+      />
+      {error && <span className="hook-error">{error}</span>}
+    </span>
+  )
+}
+
 // Single-select dropdown reusing the FilterMenu styling. Replaces native
 // <select>: its option popup is unstylable and renders white on Windows.
 function SelectDropdown({ value, options, onChange }: {
@@ -189,6 +349,11 @@ interface BackupValidation {
   totalSizeHuman: string
   estimatedSize: number
   estimatedSizeHuman: string
+  estimatedLow: number
+  estimatedHigh: number
+  estimatedRangeHuman: string
+  estimateSamples: number
+  estimateBackups: number
   availableSpace: number
   availableSpaceHuman: string
   hasEnoughSpace: boolean
@@ -199,6 +364,57 @@ interface BackupResult {
   originalSize: number
   compressedSize: number
   ratio: string
+  target?: string
+  destinations?: Destination[]
+  hookError?: string
+}
+
+interface Destination {
+  target: string
+  mirror: boolean
+  files: number
+  bytes: number
+  verified: boolean
+  error?: string
+}
+
+interface UnusedMod {
+  name: string
+  size: number
+  sizeHuman: string
+  modTime: string
+  includedBy?: string
+}
+
+interface UnusedModsResult {
+  mods: UnusedMod[]
+  size: number
+  sizeHuman: string
+}
+
+interface Quarantine {
+  id: string
+  createdAt: string
+  modsPath: string
+  mods: { name: string; size: number }[]
+  size: number
+  sizeHuman: string
+  dir: string
+  backupDir?: string
+}
+
+interface PendingBackup {
+  startedAt: string
+  doneMods: number
+  totalMods: number
+  encrypted: boolean
+}
+
+interface WorkerStatus {
+  mod: string
+  file: string
+  bytesDone: number
+  bytesTotal: number
 }
 
 interface BackupProgress {
@@ -206,9 +422,39 @@ interface BackupProgress {
   current: string
   done: boolean
   error?: string
+  bytesDone?: number
+  bytesTotal?: number
+  modsDone?: number
+  modsTotal?: number
+  compressedBytes?: number
+  bytesPerSec?: number
+  elapsedSeconds?: number
+  etaSeconds?: number // 0 until the rate is known
+  workers?: WorkerStatus[] | null
+}
+
+// Same units as the CLI (go-humanize: decimal, 1 kB = 1000 B)
+function formatBytes(bytes: number): string {
+  const units = ['B', 'kB', 'MB', 'GB', 'TB']
+  let value = bytes
+  let unit = 0
+  while (value >= 1000 && unit < units.length - 1) {
+    value /= 1000
+    unit++
+  }
+  return unit === 0 ? `${value} B` : `${value.toFixed(value < 10 ? 1 : 0)} ${units[unit]}`
 }
 
-type Tab = 'config' | 'collections' | 'backup'
+function formatDuration(seconds: number): string {
+  const h = Math.floor(seconds / 3600)
+  const m = Math.floor((seconds % 3600) / 60)
+  const s = Math.floor(seconds % 60)
+  if (h > 0) return `${h}h ${m}m`
+  if (m > 0) return `${m}m ${s}s`
+  return `${s}s`
+}
+
+type Tab = 'config' | 'collections' | 'backup' | 'cleanup'
 
 function App() {
   const [activeTab, setActiveTab] = useState<Tab>('config')
@@ -229,6 +475,7 @@ function App() {
   const [backupProgress, setBackupProgress] = useState<BackupProgress | null>(null)
   const [backupResult, setBackupResult] = useState<BackupResult | null>(null)
   const [backupError, setBackupError] = useState<string | null>(null)
+  const [pendingBackup, setPendingBackup] = useState<PendingBackup | null>(null)
 
   // Expanded collections
   const [expandedCollections, setExpandedCollections] = useState<Set<string>>(new Set())
@@ -236,11 +483,26 @@ function App() {
   // Version
   const [version, setVersion] = useState('')
 
+  // 'aurora watch' running next to the app
+  const [watchStatus, setWatchStatus] = useState<WatchStatus | null>(null)
+
   useEffect(() => {
     loadConfig()
     window.go.main.App.GetVersion().then(setVersion)
   }, [])
 
+  // The watcher rewrites its status file every 30s: poll at the same pace
+  useEffect(() => {
+    const refresh = () => {
+      window.go.main.App.GetWatchStatus()
+        .then(setWatchStatus)
+        .catch(() => setWatchStatus(null))
+    }
+    refresh()
+    const interval = setInterval(refresh, 30000)
+    return () => clearInterval(interval)
+  }, [])
+
   // Listen for backup progress events
   useEffect(() => {
     if (window.runtime?.EventsOn) {
@@ -258,6 +520,46 @@ function App() {
     }
   }, [])
 
+  // Scheduled backups open the progress modal like a manual one; skipped
+  // runs are reported in the error banner
+  useEffect(() => {
+    if (window.runtime?.EventsOn) {
+      const unsubscribe = window.runtime.EventsOn('schedule:run', (data) => {
+        const event = data as ScheduleEvent
+        switch (event.state) {
+          case 'started':
+            setPendingBackup(null)
+            setBackupRunning(true)
+            setBackupCancelling(false)
+            setShowBackupModal(true)
+            setBackupResult(null)
+            setBackupError(null)
+            setBackupProgress({ percent: 0, current: 'Scheduled backup starting...', done: false })
+            break
+          case 'done':
+            setBackupResult(event.result)
+            setBackupProgress({ percent: 100, current: 'Complete!', done: true })
+            break
+          case 'failed':
+            setBackupError(event.reason)
+            break
+          case 'skipped':
+            setError(`Scheduled backup skipped: ${event.reason}`)
+            break
+        }
+      })
+      return () => unsubscribe()
+    }
+  }, [])
+
+  // An interrupted backup is offered for resuming once on launch
+  useEffect(() => {
+    if (!config?.status.valid) return
+    window.go.main.App.GetPendingBackup()
+      .then(setPendingBackup)
+      .catch(() => setPendingBackup(null))
+  }, [config?.status.valid])
+
   useEffect(() => {
     if (activeTab === 'config' && config?.status.valid && !collections) {
       // The filter autocomplete (mod/collection suggestions) is built from
@@ -334,16 +636,49 @@ function App() {
   }
 
   const [showBackupModal, setShowBackupModal] = useState(false)
+  const [backupCancelling, setBackupCancelling] = useState(false)
+
+  // Encrypted backups ask for the passphrase unless the key file or
+  // AURORA_PASSPHRASE provides it; it is passed to the run, never saved
+  const [passphrasePrompt, setPassphrasePrompt] = useState<{ resume: boolean } | null>(null)
+  const [passphrase, setPassphrase] = useState('')
+  const [passphraseConfirm, setPassphraseConfirm] = useState('')
+
+  const requestBackup = (resume = false) => {
+    const encrypted = resume ? pendingBackup?.encrypted : config?.encrypt
+    if (encrypted && !config?.keyFile && !config?.passphraseInEnv) {
+      setPassphrase('')
+      setPassphraseConfirm('')
+      setPassphrasePrompt({ resume })
+      return
+    }
+    runBackup(resume, '')
+  }
 
-  const runBackup = async () => {
+  const submitPassphrase = () => {
+    if (!passphrasePrompt || !passphrase) return
+    if (!passphrasePrompt.resume && passphrase !== passphraseConfirm) return
+    const resume = passphrasePrompt.resume
+    setPassphrasePrompt(null)
+    runBackup(resume, passphrase)
+    setPassphrase('')
+    setPassphraseConfirm('')
+  }
+
+  const runBackup = async (resume: boolean, passphrase: string) => {
+    setPendingBackup(null)
     try {
       setBackupRunning(true)
+      setBackupCancelling(false)
       setShowBackupModal(true)
       setError(null)
       setBackupResult(null)
       setBackupError(null)
       setBackupProgress({ percent: 0, current: 'Starting...', done: false })
-      const result = await window.go.main.App.RunBackup(config?.concurrency ?? 0)
+      const threads = config?.concurrency ?? 0
+      const result = resume
+        ? await window.go.main.App.ResumeBackup(threads, passphrase)
+        : await window.go.main.App.RunBackup(threads, passphrase)
       setBackupResult(result)
       setBackupProgress({ percent: 100, current: 'Complete!', done: true })
     } catch (err) {
@@ -354,6 +689,20 @@ function App() {
     }
   }
 
+  const discardPendingBackup = async () => {
+    setPendingBackup(null)
+    try {
+      await window.go.main.App.DiscardPendingBackup()
+    } catch (err) {
+      setError(`Failed to discard interrupted backup: ${err}`)
+    }
+  }
+
+  const cancelBackup = async () => {
+    setBackupCancelling(true)
+    await window.go.main.App.CancelBackup()
+  }
+
   const closeBackupModal = () => {
     setShowBackupModal(false)
     setBackupProgress(null)
@@ -399,12 +748,31 @@ function App() {
                     <span className="result-label">Compression</span>
                     <span className="result-value">{backupResult.ratio}</span>
                   </div>
+                  {backupResult.destinations?.map((dest) => (
+                    <div key={dest.target} className="result-row">
+                      <span className="result-label">{dest.mirror ? 'Mirror' : 'Uploaded to'}</span>
+                      <span className={`result-value ${dest.error ? 'result-failed' : ''}`} title={dest.error || `${dest.files} files, checksums verified`}>
+                        {dest.error ? '✗' : '✓'} {dest.target}
+                      </span>
+                    </div>
+                  ))}
                 </div>
+                {backupResult.destinations?.filter((dest) => dest.error).map((dest) => (
+                  <p key={dest.target} className="progress-error">Copy to {dest.target} failed: {dest.error}</p>
+                ))}
+                {backupResult.hookError && <p className="progress-error">{backupResult.hookError}</p>}
                 <div className="modal-actions">
                   <button className="btn btn-secondary" onClick={() => window.go.main.App.OpenOutputFolder()}>Open folder</button>
                   <button className="btn" onClick={closeBackupModal}>OK</button>
                 </div>
               </>
+            ) : backupError && backupCancelling ? (
+              <>
+                <div className="progress-icon error">⚠</div>
+                <h3 className="progress-title">Backup Cancelled</h3>
+                <p className="progress-subtitle">Finished mods are kept: the backup can be resumed on the next launch.</p>
+                <button className="btn" onClick={closeBackupModal}>Close</button>
+              </>
             ) : backupError ? (
               <>
                 <div className="progress-icon error">⚠</div>
@@ -427,12 +795,105 @@ function App() {
                 </div>
                 <div className="progress-percent">{Math.round(backupProgress?.percent || 0)}%</div>
                 <p className="progress-status">{backupProgress?.current || 'Processing...'}</p>
+                {backupProgress?.modsTotal ? (
+                  <div className="progress-stats">
+                    <span>{formatBytes(backupProgress.bytesDone ?? 0)} / {formatBytes(backupProgress.bytesTotal ?? 0)}</span>
+                    <span>Mods {backupProgress.modsDone}/{backupProgress.modsTotal}</span>
+                    <span>Compressed {formatBytes(backupProgress.compressedBytes ?? 0)}</span>
+                    <span>Elapsed {formatDuration(backupProgress.elapsedSeconds ?? 0)}</span>
+                    {backupProgress.bytesPerSec ? (
+                      <>
+                        <span>{formatBytes(backupProgress.bytesPerSec)}/s</span>
+                        <span>ETA {formatDuration(backupProgress.etaSeconds ?? 0)}</span>
+                      </>
+                    ) : null}
+                  </div>
+                ) : null}
+                {backupProgress?.workers && backupProgress.workers.length > 0 && (
+                  <ul className="progress-workers">
+                    {backupProgress.workers.map((worker, i) => (
+                      <li key={i} title={`${worker.mod}/${worker.file}`}>
+                        <span className="worker-percent">
+                          {worker.bytesTotal ? Math.round(worker.bytesDone / worker.bytesTotal * 100) : 0}%
+                        </span>
+                        {worker.mod} › {worker.file}
+                      </li>
+                    ))}
+                  </ul>
+                )}
+                <button className="btn btn-secondary" onClick={cancelBackup} disabled={backupCancelling}>
+                  {backupCancelling ? 'Cancelling...' : 'Cancel'}
+                </button>
               </>
             )}
           </div>
         </div>
       )}
 
+      {/* Passphrase prompt for encrypted backups */}
+      {passphrasePrompt && (
+        <div className="overlay">
+          <div className="progress-modal">
+            <div className="progress-icon">🔒</div>
+            <h3 className="progress-title">Backup Passphrase</h3>
+            <p className="progress-subtitle">
+              {passphrasePrompt.resume
+                ? 'Enter the passphrase the interrupted backup was started with.'
+                : 'Archives are encrypted with this passphrase. It is not saved: keep it safe, the backup cannot be opened without it.'}
+            </p>
+            <input
+              type="password"
+              value={passphrase}
+              onChange={(e) => setPassphrase(e.target.value)}
+              onKeyDown={(e) => e.key === 'Enter' && submitPassphrase()}
+              placeholder="Passphrase"
+              autoFocus
+            />
+            {!passphrasePrompt.resume && (
+              <input
+                type="password"
+                value={passphraseConfirm}
+                onChange={(e) => setPassphraseConfirm(e.target.value)}
+                onKeyDown={(e) => e.key === 'Enter' && submitPassphrase()}
+                placeholder="Confirm passphrase"
+              />
+            )}
+            {!passphrasePrompt.resume && passphraseConfirm && passphrase !== passphraseConfirm && (
+              <p className="progress-error">Passphrases don't match</p>
+            )}
+            <div className="modal-actions">
+              <button className="btn btn-secondary" onClick={() => setPassphrasePrompt(null)}>Cancel</button>
+              <button
+                className="btn"
+                onClick={submitPassphrase}
+                disabled={!passphrase || (!passphrasePrompt.resume && passphrase !== passphraseConfirm)}
+              >
+                {passphrasePrompt.resume ? 'Resume' : 'Start Backup'}
+              </button>
+            </div>
+          </div>
+        </div>
+      )}
+
+      {/* Interrupted backup prompt */}
+      {pendingBackup && !showBackupModal && !passphrasePrompt && (
+        <div className="overlay">
+          <div className="progress-modal">
+            <div className="progress-icon">📦</div>
+            <h3 className="progress-title">Resume Backup?</h3>
+            <p className="progress-subtitle">
+              The backup started {new Date(pendingBackup.startedAt).toLocaleString()} was interrupted
+              after {pendingBackup.doneMods} of {pendingBackup.totalMods} mods.
+              Resuming reuses its finished archives once they are verified.
+            </p>
+            <div className="modal-actions">
+              <button className="btn btn-secondary" onClick={discardPendingBackup}>Discard</button>
+              <button className="btn" onClick={() => requestBackup(true)}>Resume</button>
+            </div>
+          </div>
+        </div>
+      )}
+
       <header className="header">
         <div className="header-icon">
           <svg viewBox="0 0 1024 1024" width="24" height="24">
@@ -454,6 +915,20 @@ function App() {
         <h1>Aurora</h1>
         {version && <span className="header-version">{version}</span>}
         <span className="header-tagline">Smart mod backups powered by your Penumbra collections</span>
+        {watchStatus?.active && (
+          <span
+            className="header-watch"
+            title={[
+              `aurora watch (PID ${watchStatus.pid}) is ${watchStatus.state}`,
+              watchStatus.pendingMods?.length ? `Changed: ${watchStatus.pendingMods.join(', ')}` : '',
+              watchStatus.lastBackup
+                ? `Last backup ${new Date(watchStatus.lastBackup.at).toLocaleString()}: ${watchStatus.lastBackup.error ?? watchStatus.lastBackup.mods.join(', ')}`
+                : '',
+            ].filter(Boolean).join('\n')}
+          >
+            {watchStatus.state === 'backing up' ? 'Backing up changes' : 'Watching for changes'}
+          </span>
+        )}
       </header>
 
       <nav className="tabs">
@@ -477,6 +952,13 @@ function App() {
         >
           💾 Backup
         </button>
+        <button
+          className={`tab ${activeTab === 'cleanup' ? 'active' : ''}`}
+          onClick={() => setActiveTab('cleanup')}
+          disabled={!config?.status.valid}
+        >
+          🧹 Cleanup
+        </button>
       </nav>
 
       <main className="content">
@@ -528,6 +1010,58 @@ function App() {
               await window.go.main.App.SetCompression(compression)
               await loadConfig()
             }}
+            setFormat={async (format) => {
+              await window.go.main.App.SetFormat(format)
+              await loadConfig()
+            }}
+            setLayout={async (layout) => {
+              await window.go.main.App.SetLayout(layout)
+              await loadConfig()
+            }}
+            setMaxPartSize={async (size) => {
+              await window.go.main.App.SetMaxPartSize(size)
+              await loadConfig()
+            }}
+            setEncrypt={async (encrypt) => {
+              await window.go.main.App.SetEncrypt(encrypt)
+              await loadConfig()
+            }}
+            setKeyFile={async (path) => {
+              await window.go.main.App.SetKeyFile(path)
+              await loadConfig()
+            }}
+            setPreBackupHook={async (command) => {
+              await window.go.main.App.SetPreBackupHook(command)
+              await loadConfig()
+            }}
+            setPostBackupHook={async (command) => {
+              await window.go.main.App.SetPostBackupHook(command)
+              await loadConfig()
+            }}
+            setHookTimeout={async (seconds) => {
+              await window.go.main.App.SetHookTimeout(seconds)
+              await loadConfig()
+            }}
+            setTarget={async (url) => {
+              await window.go.main.App.SetTarget(url)
+              await loadConfig()
+            }}
+            addMirror={async (url) => {
+              await window.go.main.App.AddMirror(url)
+              await loadConfig()
+            }}
+            removeMirror={async (url) => {
+              await window.go.main.App.RemoveMirror(url)
+              await loadConfig()
+            }}
+            setRetention={async (retention) => {
+              await window.go.main.App.SetRetention(retention)
+              await loadConfig()
+            }}
+            setSchedule={async (schedule) => {
+              await window.go.main.App.SetSchedule(schedule)
+              await loadConfig()
+            }}
           />
         )}
 
@@ -545,7 +1079,18 @@ function App() {
             backup={backup}
             loading={loading}
             backupRunning={backupRunning}
-            runBackup={runBackup}
+            runBackup={() => requestBackup()}
+          />
+        )}
+
+        {activeTab === 'cleanup' && (
+          <CleanupTab
+            config={config}
+            onChange={() => {
+              // Moved mods change the collections stats and the backup preview
+              setCollections(null)
+              setBackup(null)
+            }}
           />
         )}
       </main>
@@ -573,8 +1118,32 @@ interface ConfigTabProps {
   removeInclusion: (inclusion: string) => Promise<void>
   setConcurrency: (concurrency: number) => Promise<void>
   setCompression: (compression: string) => Promise<void>
+  setFormat: (format: string) => Promise<void>
+  setLayout: (layout: string) => Promise<void>
+  setMaxPartSize: (size: number) => Promise<void>
+  setEncrypt: (encrypt: boolean) => Promise<void>
+  setKeyFile: (path: string) => Promise<void>
+  setPreBackupHook: (command: string) => Promise<void>
+  setPostBackupHook: (command: string) => Promise<void>
+  setHookTimeout: (seconds: number) => Promise<void>
+  setTarget: (url: string) => Promise<void>
+  addMirror: (url: string) => Promise<void>
+  removeMirror: (url: string) => Promise<void>
+  setRetention: (retention: Retention) => Promise<void>
+  setSchedule: (schedule: Schedule) => Promise<void>
 }
 
+const weekdays = ['Sunday', 'Monday', 'Tuesday', 'Wednesday', 'Thursday', 'Friday', 'Saturday']
+
+// Max part size presets: 4 GB stays under the FAT32 file limit (4 GiB - 1)
+const partSizePresets = [
+  { value: '0', label: 'No limit' },
+  { value: '4000000000', label: '4 GB (FAT32)' },
+  { value: '2000000000', label: '2 GB' },
+  { value: '1000000000', label: '1 GB' },
+  { value: '500000000', label: '500 MB' },
+]
+
 function ConfigTab({
   config,
   loading,
@@ -595,6 +1164,19 @@ function ConfigTab({
   removeInclusion,
   setConcurrency,
   setCompression,
+  setFormat,
+  setLayout,
+  setMaxPartSize,
+  setEncrypt,
+  setKeyFile,
+  setPreBackupHook,
+  setPostBackupHook,
+  setHookTimeout,
+  setTarget,
+  addMirror,
+  removeMirror,
+  setRetention,
+  setSchedule,
 }: ConfigTabProps) {
   const [newFilter, setNewFilter] = useState('')
   const [suggestOpen, setSuggestOpen] = useState(false)
@@ -625,6 +1207,18 @@ function ConfigTab({
     return () => { cancelled = true }
   }, [config?.status.valid, config?.filters, config?.inclusions])
   const [compressionValue, setCompressionValue] = useState(config?.compression ?? 'normal')
+  const [scheduleStatus, setScheduleStatus] = useState<ScheduleStatus | null>(null)
+
+  // Next run and last backup, refreshed when the schedule changes
+  useEffect(() => {
+    window.go.main.App.GetScheduleStatus()
+      .then(setScheduleStatus)
+      .catch(() => setScheduleStatus(null))
+  }, [config?.schedule])
+
+  const schedule: Schedule = config?.schedule ?? { mode: 'off', time: '20:00', weekday: 0, maxAgeDays: 7 }
+  const retention: Retention = config?.retention ?? { incrementals: 0, days: 0 }
+  const updateSchedule = (change: Partial<Schedule>) => setSchedule({ ...schedule, ...change })
 
   // Sync concurrency value when config loads
   useEffect(() => {
@@ -650,6 +1244,12 @@ function ConfigTab({
     await setCompression(value)
   }
 
+  // A size set from the CLI that matches no preset is listed as is
+  const partSizeValue = String(config?.maxPartSize ?? 0)
+  const partSizeOptions = partSizePresets.some((p) => p.value === partSizeValue)
+    ? partSizePresets
+    : [...partSizePresets, { value: partSizeValue, label: formatBytes(Number(partSizeValue)) }]
+
   const handleAddFilter = async () => {
     if (newFilter.trim()) {
       await addFilter(newFilter.trim())
@@ -780,6 +1380,14 @@ function ConfigTab({
                   </span>
                 </span>
               </div>
+              {config?.configFile && (
+                <div className="field-row">
+                  <span className="field-label">Config file</span>
+                  <span className="field-value">
+                    <span className="field-path">{config.configFile}</span>
+                  </span>
+                </div>
+              )}
             </div>
             <div className="field">
               <span className="field-label">
@@ -807,6 +1415,208 @@ function ConfigTab({
                   ]}
                   onChange={handleCompressionChange}
                 />
+                <span className="field-label">
+                  Format
+                  <span className="help-badge tooltip-right" data-tooltip="ZIP: opens with any archiver (default).&#10;tar.zst: much faster on textures, opens with 7-Zip 23+, tar or zstd.&#10;go-delta: native go-delta archives, opened with the go-delta tool;&#10;verify only checks their checksums.">?</span>
+                </span>
+                <SelectDropdown
+                  value={config?.format || 'zip'}
+                  options={[
+                    { value: 'zip', label: 'ZIP' },
+                    { value: 'tar.zst', label: 'tar.zst' },
+                    { value: 'gdelta', label: 'go-delta' },
+                  ]}
+                  onChange={setFormat}
+                />
+              </span>
+            </div>
+            <div className="field">
+              <span className="field-label">
+                Archives
+                <span className="help-badge tooltip-right" data-tooltip="Single: one archive set for every mod (default).&#10;Per collection: one set per collection; mods several collections use go to backup_shared.&#10;Per folder: one set per top-level folder of the Penumbra mod selector.&#10;Mods without a collection or folder go to backup_other.">?</span>
+              </span>
+              <span className="field-value field-inline">
+                <SelectDropdown
+                  value={config?.layout || 'single'}
+                  options={[
+                    { value: 'single', label: 'Single' },
+                    { value: 'collection', label: 'Per collection' },
+                    { value: 'group', label: 'Per folder' },
+                  ]}
+                  onChange={setLayout}
+                />
+                <span className="field-label">
+                  Max part size
+                  <span className="help-badge tooltip-right" data-tooltip="Largest archive written. Use 4 GB for FAT32 USB sticks or your cloud upload limit.&#10;Mods are never split: a mod bigger than the limit gets an archive of its own.">?</span>
+                </span>
+                <SelectDropdown
+                  value={partSizeValue}
+                  options={partSizeOptions}
+                  onChange={(value) => setMaxPartSize(Number(value))}
+                />
+              </span>
+            </div>
+            <div className="field">
+              <span className="field-label">
+                Encryption
+                <span className="help-badge tooltip-right" data-tooltip="Encrypts archives and the manifest with a passphrase (AES-256).&#10;The passphrase is never saved in the config: it is asked before each backup,&#10;or read from a key file or the AURORA_PASSPHRASE environment variable.&#10;Without it the backup cannot be opened.">?</span>
+              </span>
+              <span className="field-value field-inline">
+                <SelectDropdown
+                  value={config?.encrypt ? 'on' : 'off'}
+                  options={[
+                    { value: 'off', label: 'Off' },
+                    { value: 'on', label: 'On' },
+                  ]}
+                  onChange={(value) => setEncrypt(value === 'on')}
+                />
+                {config?.encrypt && (
+                  <>
+                    <span className="field-label">Key file</span>
+                    <span className="key-file" title={config.keyFile}>
+                      {config.keyFile || (config.passphraseInEnv ? 'AURORA_PASSPHRASE' : 'Ask before each backup')}
+                    </span>
+                    <button
+                      className="browse-btn"
+                      onClick={async () => {
+                        const path = await window.go.main.App.BrowseFile('Select Passphrase Key File', config.keyFile)
+                        if (path) await setKeyFile(path)
+                      }}
+                      title="Browse..."
+                    >
+                      📁
+                    </button>
+                    {config.keyFile && (
+                      <button className="browse-btn" onClick={() => setKeyFile('')} title="Ask for the passphrase instead">
+                        ✕
+                      </button>
+                    )}
+                  </>
+                )}
+              </span>
+            </div>
+            <div className="field">
+              <span className="field-label">
+                Before backup
+                <span className="help-badge tooltip-right" data-tooltip="Command run before each backup, e.g. a script refusing while the game runs.&#10;If it fails or times out, the backup doesn't start.&#10;Variables: {{.OutputDir}}, {{.Resume}}. Use {{quote .OutputDir}} for paths with spaces.">?</span>
+              </span>
+              <span className="field-value field-inline field-hook">
+                <HookInput value={config?.preBackupHook ?? ''} placeholder="No command" onSave={setPreBackupHook} />
+              </span>
+            </div>
+            <div className="field">
+              <span className="field-label">
+                After backup
+                <span className="help-badge tooltip-right" data-tooltip="Command run after each backup, e.g. rsync to a NAS or a notification. Runs after failures too.&#10;Variables: {{.Status}} (success, failed, cancelled), {{.Error}}, {{.OutputDir}}, {{.Archives}},&#10;{{.Mods}}, {{.OriginalSize}}, {{.CompressedSize}}, {{.Ratio}}. {{bytes .CompressedSize}} formats a size.&#10;Its output goes to the log file.">?</span>
+              </span>
+              <span className="field-value field-inline field-hook">
+                <HookInput value={config?.postBackupHook ?? ''} placeholder="No command" onSave={setPostBackupHook} />
+                <span className="field-label">
+                  Timeout
+                  <span className="help-badge tooltip-right" data-tooltip="Seconds each hook may run before it is stopped.&#10;0 = 10 minutes.">?</span>
+                </span>
+                <input
+                  type="number"
+                  min="0"
+                  value={config?.hookTimeout ?? 0}
+                  onChange={(e) => setHookTimeout(parseInt(e.target.value) || 0)}
+                  className="concurrency-input"
+                />
+              </span>
+            </div>
+            <div className="field">
+              <span className="field-label">
+                Upload to
+                <span className="help-badge tooltip-right" data-tooltip="Copies each finished backup to a server, e.g.&#10;sftp://user@nas/backups?key=~/.ssh/id_ed25519 or s3://bucket/ff14?endpoint=https://minio.lan:9000.&#10;Passwords and keys come from AURORA_SFTP_PASSWORD, AURORA_S3_ACCESS_KEY and AURORA_S3_SECRET_KEY.&#10;Empty = the output folder only.">?</span>
+              </span>
+              <span className="field-value field-inline field-hook">
+                <HookInput value={config?.target ?? ''} placeholder="Output folder only" onSave={setTarget} />
+              </span>
+            </div>
+            <div className="field">
+              <span className="field-label">
+                Mirrors
+                <span className="help-badge tooltip-right" data-tooltip="Further copies of each backup, made after the upload: a folder on another disk or NAS,&#10;or a server URL like Upload to. Each copy is read back to check its checksums.&#10;A failed mirror is reported but doesn't fail the backup.">?</span>
+              </span>
+              <span className="field-value field-inline field-hook">
+                <MirrorList mirrors={config?.mirrors ?? []} onAdd={addMirror} onRemove={removeMirror} />
+              </span>
+            </div>
+            <div className="field">
+              <span className="field-label">
+                Keep incrementals
+                <span className="help-badge tooltip-right" data-tooltip="After each backup, older incremental backups are deleted from the output folder,&#10;the upload target and every mirror. The full backup is never deleted.&#10;0 = no limit.">?</span>
+              </span>
+              <span className="field-value field-inline">
+                <input
+                  type="number"
+                  min="0"
+                  value={retention.incrementals}
+                  onChange={(e) => setRetention({ ...retention, incrementals: parseInt(e.target.value) || 0 })}
+                  className="concurrency-input"
+                />
+                <span className="field-label">Days</span>
+                <input
+                  type="number"
+                  min="0"
+                  value={retention.days}
+                  onChange={(e) => setRetention({ ...retention, days: parseInt(e.target.value) || 0 })}
+                  className="concurrency-input"
+                />
+              </span>
+            </div>
+            <div className="field">
+              <span className="field-label">
+                Schedule
+                <span className="help-badge tooltip-right" data-tooltip="Backs up on its own while the app is open, with the current settings.&#10;A daily or weekly backup missed while the app was closed runs on the next start.&#10;Skipped when the configuration is invalid or the disk is too full;&#10;encrypted backups need a key file or AURORA_PASSPHRASE.">?</span>
+              </span>
+              <span className="field-value field-inline">
+                <SelectDropdown
+                  value={schedule.mode}
+                  options={[
+                    { value: 'off', label: 'Off' },
+                    { value: 'daily', label: 'Daily' },
+                    { value: 'weekly', label: 'Weekly' },
+                    { value: 'startup', label: 'On app start' },
+                  ]}
+                  onChange={(mode) => updateSchedule({ mode })}
+                />
+                {schedule.mode === 'weekly' && (
+                  <SelectDropdown
+                    value={String(schedule.weekday)}
+                    options={weekdays.map((day, i) => ({ value: String(i), label: day }))}
+                    onChange={(day) => updateSchedule({ weekday: Number(day) })}
+                  />
+                )}
+                {(schedule.mode === 'daily' || schedule.mode === 'weekly') && (
+                  <input
+                    type="time"
+                    value={schedule.time}
+                    onChange={(e) => e.target.value && updateSchedule({ time: e.target.value })}
+                    className="concurrency-input schedule-time"
+                  />
+                )}
+                {schedule.mode === 'startup' && (
+                  <>
+                    <span className="field-label">If last backup older than</span>
+                    <input
+                      type="number"
+                      min="1"
+                      value={schedule.maxAgeDays}
+                      onChange={(e) => updateSchedule({ maxAgeDays: parseInt(e.target.value) || 1 })}
+                      className="concurrency-input"
+                    />
+                    <span>days</span>
+                  </>
+                )}
+                {scheduleStatus?.nextRun && (
+                  <span className="key-file">Next: {new Date(scheduleStatus.nextRun).toLocaleString()}</span>
+                )}
+                {schedule.mode !== 'off' && scheduleStatus?.lastRun?.state === 'skipped' && (
+                  <span className="key-file" title={scheduleStatus.lastRun.reason}>
+                    Last run skipped: {scheduleStatus.lastRun.reason}
+                  </span>
+                )}
               </span>
             </div>
             <div className="actions">
@@ -1204,7 +2014,10 @@ function BackupTab({ backup, loading, backupRunning, runBackup }: BackupTabProps
           <div className="stat-value">{backup.estimatedSizeHuman} / {backup.availableSpaceHuman}</div>
           <div className="stat-label">
             Est. / Available
-            <span className="help-badge tooltip-left" data-tooltip="Estimated backup size vs available disk space">?</span>
+            <span
+              className="help-badge tooltip-left"
+              data-tooltip={`Estimated backup size (likely ${backup.estimatedRangeHuman}, from ${backup.estimateSamples} sampled files${backup.estimateBackups > 0 ? ` and ${backup.estimateBackups} past backups` : ''}) vs available disk space. The top of the range must fit.`}
+            >?</span>
           </div>
         </div>
       </div>
@@ -1253,4 +2066,212 @@ function BackupTab({ backup, loading, backupRunning, runBackup }: BackupTabProps
   )
 }
 
+interface CleanupTabProps {
+  config: ConfigResult | null
+  onChange: () => void
+}
+
+// Unused mods with their size and last change: selected ones move to a
+// quarantine (archived first when asked), which can be undone
+function CleanupTab({ config, onChange }: CleanupTabProps) {
+  const [unused, setUnused] = useState<UnusedModsResult | null>(null)
+  const [quarantines, setQuarantines] = useState<Quarantine[]>([])
+  const [selected, setSelected] = useState<Set<string>>(new Set())
+  const [search, setSearch] = useState('')
+  const [archive, setArchive] = useState(true)
+  const [passphrase, setPassphrase] = useState('')
+  const [busy, setBusy] = useState(false)
+  const [error, setError] = useState('')
+  const [notice, setNotice] = useState('')
+
+  const load = async () => {
+    try {
+      setError('')
+      const [mods, list] = await Promise.all([
+        window.go.main.App.GetUnusedMods(),
+        window.go.main.App.GetQuarantines(),
+      ])
+      setUnused(mods)
+      setQuarantines(list)
+      setSelected(new Set())
+    } catch (err) {
+      setError(`Failed to list unused mods: ${err}`)
+    }
+  }
+
+  useEffect(() => {
+    load()
+  }, [])
+
+  const filteredMods = useMemo(() => {
+    const mods = unused?.mods || []
+    return search.trim() ? mods.filter(mod => matchesSearch(mod.name, search)) : mods
+  }, [unused, search])
+
+  // The archive is encrypted like backups: ask for the passphrase unless the
+  // key file or AURORA_PASSPHRASE provides it
+  const needsPassphrase = archive && config?.encrypt && !config?.keyFile && !config?.passphraseInEnv
+  const selectedSize = (unused?.mods || []).filter(mod => selected.has(mod.name)).reduce((sum, mod) => sum + mod.size, 0)
+
+  const toggle = (name: string) => {
+    const next = new Set(selected)
+    if (next.has(name)) {
+      next.delete(name)
+    } else {
+      next.add(name)
+    }
+    setSelected(next)
+  }
+
+  const quarantine = async () => {
+    try {
+      setBusy(true)
+      setError('')
+      setNotice('')
+      const q = await window.go.main.App.QuarantineMods([...selected], archive, passphrase)
+      setNotice(`Quarantined ${q.mods.length} mods (${q.sizeHuman})${q.backupDir ? `, archived in ${q.backupDir}` : ''}`)
+      setPassphrase('')
+    } catch (err) {
+      setError(`Failed to quarantine mods: ${err}`)
+    } finally {
+      setBusy(false)
+      onChange()
+      await load()
+    }
+  }
+
+  const undo = async (id: string) => {
+    try {
+      setBusy(true)
+      setError('')
+      setNotice('')
+      const q = await window.go.main.App.RestoreQuarantine(id)
+      setNotice(`Restored ${q.mods.length} mods (${q.sizeHuman})`)
+    } catch (err) {
+      setError(`Failed to undo quarantine: ${err}`)
+    } finally {
+      setBusy(false)
+      onChange()
+      await load()
+    }
+  }
+
+  if (!unused) {
+    return error ? <div className="error-message">{error}</div> : <div className="loading">Looking for unused mods...</div>
+  }
+
+  return (
+    <div className="cleanup-tab">
+      <div className="stats">
+        <div className="stat-card">
+          <div className="stat-value">{unused.mods.length}</div>
+          <div className="stat-label">
+            Unused Mods
+            <span className="help-badge" data-tooltip="Mods no collection uses">?</span>
+          </div>
+        </div>
+        <div className="stat-card">
+          <div className="stat-value">{unused.sizeHuman}</div>
+          <div className="stat-label">
+            Unused Space
+            <span className="help-badge" data-tooltip="Disk space of the unused mods">?</span>
+          </div>
+        </div>
+        <div className="stat-card">
+          <div className="stat-value">{quarantines.length}</div>
+          <div className="stat-label">
+            Quarantines
+            <span className="help-badge tooltip-left" data-tooltip="Sets of mods moved out of the mods folder, which can be put back">?</span>
+          </div>
+        </div>
+      </div>
+
+      {error && <div className="error-message">{error}</div>}
+      {notice && <div className="cleanup-notice">{notice}</div>}
+
+      <div className="card">
+        <h2>Unused Mods</h2>
+        <div className="search-row">
+          <SearchInput
+            value={search}
+            onChange={setSearch}
+            placeholder="Search mods..."
+            resultCount={filteredMods.length}
+            totalCount={unused.mods.length}
+          />
+          <label className="checkbox-filter">
+            <input
+              type="checkbox"
+              checked={filteredMods.length > 0 && filteredMods.every(mod => selected.has(mod.name))}
+              onChange={(e) => {
+                const next = new Set(selected)
+                filteredMods.forEach(mod => (e.target.checked ? next.add(mod.name) : next.delete(mod.name)))
+                setSelected(next)
+              }}
+            />
+            <span>Select all</span>
+          </label>
+        </div>
+        <div className="backup-list">
+          {filteredMods.map((mod) => (
+            <label key={mod.name} className={`backup-item cleanup-item ${selected.has(mod.name) ? 'selected' : ''}`}>
+              <span className="checkbox-filter">
+                <input type="checkbox" checked={selected.has(mod.name)} onChange={() => toggle(mod.name)} />
+                <span className="mod-name">
+                  {mod.name}
+                  {mod.includedBy && <span style={{ color: 'var(--text-muted)', marginLeft: '0.5rem' }}>(filter inclusion: {mod.includedBy})</span>}
+                </span>
+              </span>
+              <span className="mod-size">
+                {new Date(mod.modTime).toLocaleDateString()} · {mod.sizeHuman}
+              </span>
+            </label>
+          ))}
+        </div>
+        <div className="actions">
+          <label className="checkbox-filter cleanup-archive">
+            <input type="checkbox" checked={archive} onChange={(e) => setArchive(e.target.checked)} />
+            <span>Archive to the output folder first</span>
+          </label>
+          {needsPassphrase && (
+            <input
+              type="password"
+              value={passphrase}
+              onChange={(e) => setPassphrase(e.target.value)}
+              placeholder="Encryption passphrase"
+            />
+          )}
+          <button
+            className="btn"
+            onClick={quarantine}
+            disabled={busy || selected.size === 0 || (needsPassphrase && !passphrase)}
+          >
+            {busy ? 'Working...' : `Quarantine ${selected.size} Mods (${formatBytes(selectedSize)})`}
+          </button>
+        </div>
+      </div>
+
+      {quarantines.length > 0 && (
+        <div className="card">
+          <h2>Quarantine</h2>
+          <div className="backup-list">
+            {quarantines.map((q) => (
+              <div key={q.id} className="backup-item">
+                <span className="mod-name" title={q.mods.map(mod => mod.name).join('\n')}>
+                  {new Date(q.createdAt).toLocaleString()} · {q.mods.length} mods
+                  {q.backupDir && <span style={{ color: 'var(--text-muted)', marginLeft: '0.5rem' }}>(archived)</span>}
+                </span>
+                <span className="cleanup-undo">
+                  <span className="mod-size">{q.sizeHuman}</span>
+                  <button className="btn btn-secondary" onClick={() => undo(q.id)} disabled={busy}>Undo</button>
+                </span>
+              </div>
+            ))}
+          </div>
+        </div>
+      )}
+    </div>
+  )
+}
+
 export default App
@@ -31,6 +31,8 @@ func main() {
 		},
 		BackgroundColour: &options.RGBA{R: 11, G: 15, B: 20, A: 1},
 		OnStartup:        app.startup,
+		OnDomReady:       app.domReady,
+		OnShutdown:       app.shutdown,
 		Bind: []interface{}{
 			app,
 		},
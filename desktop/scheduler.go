@@ -0,0 +1,175 @@
+package main
+
+import (
+	"aurora/internal/logger"
+	"aurora/pkg/aurora"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// scheduleCheckInterval is how often the scheduler compares the clock to
+// the next run. Polling the wall clock survives sleep and hibernation,
+// which a single long timer doesn't.
+const scheduleCheckInterval = time.Minute
+
+// States of a scheduled run, sent as schedule:run events
+const (
+	scheduleStarted = "started"
+	scheduleDone    = "done"
+	scheduleFailed  = "failed"
+	scheduleSkipped = "skipped"
+)
+
+// ScheduleEvent reports a scheduled backup to the frontend
+type ScheduleEvent struct {
+	At     time.Time            `json:"at"`
+	State  string               `json:"state"`  // started, done, failed or skipped
+	Reason string               `json:"reason"` // why it was skipped or failed
+	Result *aurora.BackupResult `json:"result"` // done: the completion summary
+}
+
+// ScheduleStatus is shown next to the schedule settings
+type ScheduleStatus struct {
+	Schedule   aurora.Schedule `json:"schedule"`
+	NextRun    *time.Time      `json:"nextRun"`    // nil unless daily or weekly
+	LastBackup *time.Time      `json:"lastBackup"` // nil without a backup
+	LastRun    *ScheduleEvent  `json:"lastRun"`    // the last scheduled run since the app started
+}
+
+// startScheduler runs scheduled backups in the background until the app
+// closes
+func (a *App) startScheduler() {
+	go a.runScheduler()
+}
+
+func (a *App) runScheduler() {
+	// A run missed while the app was closed, or an old backup, runs now
+	if last, _ := aurora.LastBackupTime(); a.aurora.GetSchedule().Due(last, time.Now()) {
+		a.runScheduledBackup()
+	}
+
+	ticker := time.NewTicker(scheduleCheckInterval)
+	defer ticker.Stop()
+	next, scheduled := a.aurora.GetSchedule().Next(time.Now())
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-a.scheduleChanged:
+			next, scheduled = a.aurora.GetSchedule().Next(time.Now())
+		case now := <-ticker.C:
+			if scheduled && !now.Before(next) {
+				a.runScheduledBackup()
+				next, scheduled = a.aurora.GetSchedule().Next(time.Now())
+			}
+		}
+	}
+}
+
+// runScheduledBackup runs a backup with the usual progress events, or
+// skips it when it can't run unattended. An interrupted backup is resumed
+// rather than discarded.
+func (a *App) runScheduledBackup() {
+	svc := a.aurora
+	if !svc.GetConfig().Status.Valid {
+		a.emitSchedule(ScheduleEvent{State: scheduleSkipped, Reason: "the configuration is not valid"})
+		return
+	}
+	pending, err := svc.PendingBackup()
+	if err != nil {
+		a.emitSchedule(ScheduleEvent{State: scheduleSkipped, Reason: err.Error()})
+		return
+	}
+
+	encrypted := svc.GetConfig().Encrypt
+	if pending != nil {
+		encrypted = pending.Encrypted
+	} else {
+		validation, err := svc.ValidateBackup()
+		if err != nil {
+			a.emitSchedule(ScheduleEvent{State: scheduleSkipped, Reason: err.Error()})
+			return
+		}
+		if !validation.HasEnoughSpace {
+			a.emitSchedule(ScheduleEvent{State: scheduleSkipped, Reason: fmt.Sprintf("not enough disk space: %s needed, %s available",
+				humanize.Bytes(validation.EstimatedHigh), validation.AvailableSpaceHuman)})
+			return
+		}
+	}
+	// Nobody is there to type the passphrase
+	if encrypted {
+		if passphrase, err := svc.ResolvePassphrase("", nil); err != nil || passphrase == "" {
+			a.emitSchedule(ScheduleEvent{State: scheduleSkipped, Reason: "encrypted backups need a key file or AURORA_PASSPHRASE to run on a schedule"})
+			return
+		}
+	}
+
+	a.emitSchedule(ScheduleEvent{State: scheduleStarted})
+	result, err := a.runBackup(0, pending != nil, "")
+	switch {
+	case err == nil:
+		a.emitSchedule(ScheduleEvent{State: scheduleDone, Result: result})
+	case errors.Is(err, errBackupRunning), errors.Is(err, aurora.ErrBackupRunning), errors.Is(err, aurora.ErrInvalidConfig),
+		errors.Is(err, aurora.ErrNoSpace), errors.Is(err, aurora.ErrNothingToBackup):
+		a.emitSchedule(ScheduleEvent{State: scheduleSkipped, Reason: err.Error()})
+	default:
+		a.emitSchedule(ScheduleEvent{State: scheduleFailed, Reason: err.Error()})
+	}
+}
+
+func (a *App) emitSchedule(event ScheduleEvent) {
+	event.At = time.Now()
+	switch event.State {
+	case scheduleSkipped:
+		logger.Warn("Scheduled backup skipped: %s", event.Reason)
+	case scheduleFailed:
+		logger.Error("Scheduled backup failed: %s", event.Reason)
+	default:
+		logger.Info("Scheduled backup %s", event.State)
+	}
+	a.scheduleMu.Lock()
+	a.lastScheduled = &event
+	a.scheduleMu.Unlock()
+	runtime.EventsEmit(a.ctx, "schedule:run", event)
+}
+
+// GetScheduleStatus returns the schedule with its next run and the last
+// backup
+func (a *App) GetScheduleStatus() (ScheduleStatus, error) {
+	svc, err := a.svc()
+	if err != nil {
+		return ScheduleStatus{}, err
+	}
+	status := ScheduleStatus{Schedule: svc.GetSchedule()}
+	if next, ok := status.Schedule.Next(time.Now()); ok {
+		status.NextRun = &next
+	}
+	if last, ok := aurora.LastBackupTime(); ok {
+		status.LastBackup = &last
+	}
+	a.scheduleMu.Lock()
+	status.LastRun = a.lastScheduled
+	a.scheduleMu.Unlock()
+	return status, nil
+}
+
+// SetSchedule sets the automatic backup schedule; the next run follows it
+// right away
+func (a *App) SetSchedule(schedule aurora.Schedule) error {
+	svc, err := a.svc()
+	if err != nil {
+		return err
+	}
+	if err := svc.SetSchedule(schedule); err != nil {
+		return err
+	}
+	select {
+	case a.scheduleChanged <- struct{}{}:
+	default: // a change is already pending
+	}
+	return nil
+}
@@ -2,7 +2,7 @@ package config
 
 import (
 	"aurora/internal/logger"
-	"aurora/internal/util"
+	"aurora/internal/paths"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,25 +10,78 @@ import (
 	"path/filepath"
 )
 
-// ConfigFile is the path to the config file (next to the executable)
-var ConfigFile = getConfigPath()
+// ConfigFile is the path to the config file (see paths.ConfigFile)
+var ConfigFile = paths.ConfigFile()
 
-func getConfigPath() string {
-	exe, err := os.Executable()
-	if err != nil {
-		return "config.json"
-	}
-	return filepath.Join(filepath.Dir(exe), "config.json")
+// SetConfigFile relocates the config file (--config flag)
+func SetConfigFile(path string) {
+	paths.SetConfigFile(path)
+	ConfigFile = paths.ConfigFile()
+}
+
+// SetProfile selects the config file of a profile (--profile flag)
+func SetProfile(name string) {
+	paths.SetProfile(name)
+	ConfigFile = paths.ConfigFile()
 }
 
 type Config struct {
+	Version     int `json:"version"` // Schema version, see CurrentVersion
 	Penumbra    PenumbraConfig
 	Mods        ModsConfig
-	Filters     []string `json:"filters"`    // Exclusions: matching mods are dropped from backups
-	Inclusions  []string `json:"inclusions"` // Matching mods are always backed up (wins over exclusions and missing collections)
-	Concurrency int      `json:"concurrency"`
-	Compression string   `json:"compression"` // "normal" (default) or "max"
-	Output      string   `json:"output"`      // Backup output directory ("" = current working directory)
+	Filters     []string        `json:"filters"`    // Exclusions: matching mods are dropped from backups
+	Inclusions  []string        `json:"inclusions"` // Matching mods are always backed up (wins over exclusions and missing collections)
+	Concurrency int             `json:"concurrency"`
+	Compression string          `json:"compression"` // "normal" (default) or "max"
+	Format      string          `json:"format"`      // Archive format: "zip" (default), "tar.zst" or "gdelta"
+	Output      string          `json:"output"`      // Backup output directory ("" = current working directory)
+	MaxPartSize uint64          `json:"maxPartSize"` // Largest archive in bytes (0 = no limit)
+	Layout      string          `json:"layout"`      // Archive split: "single" (default), "collection" or "group"
+	Encrypt     bool            `json:"encrypt"`     // Encrypt archives and manifests; the passphrase is never stored here
+	KeyFile     string          `json:"keyFile"`     // File holding the passphrase ("" = AURORA_PASSPHRASE or a prompt)
+	Hooks       HooksConfig     `json:"hooks"`
+	Schedule    ScheduleConfig  `json:"schedule"`  // Automatic backups of the desktop app
+	Target      TargetConfig    `json:"target"`    // Remote copy of each backup
+	Mirrors     []TargetConfig  `json:"mirrors"`   // Further copies of each backup, verified independently
+	Retention   RetentionConfig `json:"retention"` // Incremental backups kept in the output directory, the target and mirrors
+}
+
+// HooksConfig holds the shell commands run around backups. Commands are Go
+// templates over the backup's variables (see aurora.HookVars).
+type HooksConfig struct {
+	PreBackup  string `json:"preBackup"`  // Run before a backup; failing or timing out cancels it
+	PostBackup string `json:"postBackup"` // Run after a backup, whether it succeeded or not
+	Timeout    int    `json:"timeout"`    // Seconds each hook may run (0 = 10 minutes)
+}
+
+// ScheduleConfig is when the desktop app backs up on its own
+type ScheduleConfig struct {
+	Mode       string `json:"mode"`       // "" or "off" (default), "daily", "weekly" or "startup"
+	Time       string `json:"time"`       // Local "HH:MM" of daily and weekly backups
+	Weekday    int    `json:"weekday"`    // Day of weekly backups, 0 = Sunday
+	MaxAgeDays int    `json:"maxAgeDays"` // startup: back up when the last backup is older (0 = 7 days)
+}
+
+// RetentionConfig is which incremental backups are kept. Full backups are
+// never deleted: a new one replaces the previous.
+type RetentionConfig struct {
+	Incrementals int `json:"incrementals"` // Newest incremental backups kept (0 = all)
+	Days         int `json:"days"`         // Incremental backups older than this many days are deleted (0 = never)
+}
+
+// TargetConfig is where finished backups are uploaded besides the output
+// directory. Credentials are never stored here: they come from the
+// environment (see aurora.Target).
+type TargetConfig struct {
+	Type       string `json:"type"`       // "" or "local" (default): output directory only, "dir", "sftp" or "s3"
+	Host       string `json:"host"`       // sftp: "host" or "host:port"
+	User       string `json:"user"`       // sftp
+	KeyFile    string `json:"keyFile"`    // sftp: private key file ("" = password from AURORA_SFTP_PASSWORD)
+	KnownHosts string `json:"knownHosts"` // sftp: known_hosts file ("" = ~/.ssh/known_hosts)
+	Endpoint   string `json:"endpoint"`   // s3: "https://host:port" of a compatible service ("" = AWS)
+	Region     string `json:"region"`     // s3 ("" = us-east-1)
+	Bucket     string `json:"bucket"`     // s3
+	Path       string `json:"path"`       // Directory (dir, sftp) or key prefix (s3) holding the backups
 }
 
 type PenumbraConfig struct {
@@ -47,18 +100,32 @@ type ConfigStatus struct {
 }
 
 func NewConfig(reset bool) (*Config, error) {
+	if profile := paths.Profile(); profile != "" {
+		if err := paths.ValidateProfile(profile); err != nil {
+			return nil, err
+		}
+	}
 	if err := createIfMissing(reset); err != nil {
 		return nil, err
 	}
-	var config Config
-
-	if err := util.ReadJSONFile(ConfigFile, &config); err != nil {
+	config, original, err := readConfigFile()
+	if err == nil && original != nil {
+		// Re-read under the lock: another process may have written since
+		err = withLock(func() error {
+			config, original, err = readConfigFile()
+			if err != nil || original == nil {
+				return err
+			}
+			return persistMigration(config, original)
+		})
+	}
+	if err != nil {
 		logger.Error("Failed to read config file: %v", err)
 		return nil, fmt.Errorf("read config file %s: %w", ConfigFile, err)
 	}
 
-	logger.Info("Config loaded: penumbra=%s, mods=%s", config.Penumbra.Path, config.Mods.Path)
-	return &config, nil
+	logger.Info("Config loaded: version=%d, penumbra=%s, mods=%s", config.Version, config.Penumbra.Path, config.Mods.Path)
+	return config, nil
 }
 
 func createIfMissing(reset bool) error {
@@ -69,7 +136,20 @@ func createIfMissing(reset bool) error {
 		defaultPenumbra = filepath.Join(homeDir, "AppData", "Roaming", "XIVLauncher", "pluginConfigs", "Penumbra")
 	}
 	if _, err := os.Stat(ConfigFile); errors.Is(err, os.ErrNotExist) || reset {
+		// The data directory may not exist yet (AURORA_HOME, user config dir)
+		if err := os.MkdirAll(filepath.Dir(ConfigFile), 0755); err != nil {
+			return fmt.Errorf("create config directory: %w", err)
+		}
+		// A new profile starts from the default config: only what differs
+		// has to be set
+		if profile := paths.Profile(); profile != "" && !reset && ConfigFile == paths.ProfileFile(profile) {
+			if base, _, err := readConfig(paths.DefaultConfigFile()); err == nil {
+				logger.Info("Creating profile %s from %s", profile, paths.DefaultConfigFile())
+				return base.Save()
+			}
+		}
 		config := Config{
+			Version: CurrentVersion,
 			Penumbra: PenumbraConfig{
 				Path: defaultPenumbra,
 			},
@@ -148,19 +228,86 @@ func hasModFolders(modsPath string) bool {
 	return false
 }
 
+// Update applies fn to the config as currently stored on disk and saves the
+// result, all under the config lock. Changing a config loaded earlier and
+// calling Save would drop whatever another process (CLI or desktop app)
+// wrote in between. Returns the updated config.
+func Update(fn func(c *Config)) (*Config, error) {
+	var config *Config
+	err := withLock(func() error {
+		var original []byte
+		var err error
+		config, original, err = readConfigFile()
+		if err != nil {
+			return fmt.Errorf("read config file %s: %w", ConfigFile, err)
+		}
+		if original != nil {
+			if err := persistMigration(config, original); err != nil {
+				return err
+			}
+		}
+		fn(config)
+		return config.write()
+	})
+	if err != nil {
+		logger.Error("Failed to update config file: %v", err)
+		return nil, err
+	}
+	return config, nil
+}
+
+// Save overwrites the config file with c
 func (c *Config) Save() error {
-	file, err := os.Create(ConfigFile)
+	return withLock(c.write)
+}
+
+// write atomically replaces ConfigFile: the content goes to a temp file in
+// the same directory, is fsynced, then renamed over the old file. A crash or
+// a full disk leaves either the old or the new config, never a truncated
+// one. Callers hold the config lock.
+func (c *Config) write() error {
+	c.Version = CurrentVersion
+	dir := filepath.Dir(ConfigFile)
+	file, err := os.CreateTemp(dir, filepath.Base(ConfigFile)+".*.tmp")
 	if err != nil {
 		logger.Error("Failed to create config file: %v", err)
 		return fmt.Errorf("create config file %s: %w", ConfigFile, err)
 	}
-	defer file.Close()
+	tmpPath := file.Name()
+	defer os.Remove(tmpPath) // no-op once renamed
 
 	encoder := json.NewEncoder(file)
 	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(c); err != nil {
+	err = encoder.Encode(c)
+	if err == nil {
+		err = file.Chmod(0644) // CreateTemp uses 0600
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, ConfigFile)
+	}
+	if err != nil {
 		logger.Error("Failed to write config file: %v", err)
 		return fmt.Errorf("write config file %s: %w", ConfigFile, err)
 	}
+
+	syncDir(dir)
 	return nil
 }
+
+// syncDir flushes a directory entry change (the rename) to disk. Best
+// effort: Windows can't open directories for syncing, and the rename itself
+// already happened.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"aurora/internal/paths"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 )
 
@@ -124,3 +127,116 @@ func TestConfigStatus(t *testing.T) {
 		}
 	})
 }
+
+func TestSaveIsAtomic(t *testing.T) {
+	path := useConfigFile(t, `{}`)
+
+	cfg := &Config{Filters: []string{"xx-"}}
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 || entries[0].Name() != "config.json" {
+		names := []string{}
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("expected only config.json left behind, got %v", names)
+	}
+
+	loaded, err := NewConfig(false)
+	if err != nil {
+		t.Fatalf("NewConfig failed: %v", err)
+	}
+	if len(loaded.Filters) != 1 || loaded.Filters[0] != "xx-" {
+		t.Errorf("expected saved filters, got %v", loaded.Filters)
+	}
+}
+
+func TestProfile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(paths.EnvHome, home)
+	t.Setenv(paths.EnvConfig, "")
+	t.Setenv(paths.EnvProfile, "offsite")
+	previous := ConfigFile
+	ConfigFile = paths.ConfigFile()
+	t.Cleanup(func() { ConfigFile = previous })
+	defaultConfig := `{"version":1,"filters":["xx-"]}`
+	os.WriteFile(filepath.Join(home, "config.json"), []byte(defaultConfig), 0644)
+
+	// A new profile starts from the default config, then goes its own way
+	cfg, err := NewConfig(false)
+	if err != nil {
+		t.Fatalf("NewConfig failed: %v", err)
+	}
+	if ConfigFile != filepath.Join(home, "profiles", "offsite.json") || len(cfg.Filters) != 1 || cfg.Version != CurrentVersion {
+		t.Errorf("expected the default config copied to the profile, got %s: %+v", ConfigFile, cfg)
+	}
+	if _, err := Update(func(c *Config) { c.Target = TargetConfig{Type: "dir", Path: "/mnt/offsite"} }); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(home, "config.json")); string(content) != defaultConfig {
+		t.Errorf("expected the default config untouched, got %s", content)
+	}
+
+	t.Setenv(paths.EnvProfile, "../offsite")
+	if _, err := NewConfig(false); err == nil {
+		t.Error("expected a profile name leaving the profiles folder refused")
+	}
+}
+
+func TestUpdateKeepsConcurrentChanges(t *testing.T) {
+	useConfigFile(t, `{"version":1}`)
+
+	// This process loaded the config before another one added an inclusion
+	stale, err := NewConfig(false)
+	if err != nil {
+		t.Fatalf("NewConfig failed: %v", err)
+	}
+	if _, err := Update(func(c *Config) { c.Inclusions = append(c.Inclusions, "Other") }); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	updated, err := Update(func(c *Config) { c.Filters = append(c.Filters, "Mine") })
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if len(stale.Inclusions) != 0 {
+		t.Fatal("expected the stale copy to miss the other inclusion")
+	}
+	if len(updated.Inclusions) != 1 || len(updated.Filters) != 1 {
+		t.Errorf("expected both changes kept, got filters=%v inclusions=%v", updated.Filters, updated.Inclusions)
+	}
+}
+
+func TestUpdateConcurrentWriters(t *testing.T) {
+	useConfigFile(t, `{"version":1}`)
+
+	const writers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := Update(func(c *Config) { c.Filters = append(c.Filters, fmt.Sprintf("f%02d", i)) })
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+	}
+
+	cfg, err := NewConfig(false)
+	if err != nil {
+		t.Fatalf("NewConfig failed: %v", err)
+	}
+	if len(cfg.Filters) != writers {
+		t.Errorf("expected %d filters, got %d: %v", writers, len(cfg.Filters), cfg.Filters)
+	}
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"aurora/internal/logger"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// The CLI and the desktop app may run at the same time and both write
+// config.json. Writers serialize on a lock file next to it: creating it with
+// O_EXCL is atomic on every platform, unlike advisory locks.
+var (
+	lockTimeout = 5 * time.Second       // give up waiting after this
+	lockStale   = 30 * time.Second      // a lock this old belongs to a crashed process
+	lockRetry   = 50 * time.Millisecond // poll interval while waiting
+)
+
+// lockPath returns the lock file guarding ConfigFile
+func lockPath() string {
+	return ConfigFile + ".lock"
+}
+
+// withLock runs fn while holding the config lock
+func withLock(fn func() error) error {
+	lockFile := lockPath()
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		file, err := os.OpenFile(lockFile, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(file, "%d\n", os.Getpid())
+			file.Close()
+			break
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("lock config file %s: %w", ConfigFile, err)
+		}
+
+		if info, err := os.Stat(lockFile); err == nil && time.Since(info.ModTime()) > lockStale {
+			takeStaleLock(lockFile)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("config file %s is locked by another Aurora process (delete %s if none is running)", ConfigFile, lockFile)
+		}
+		time.Sleep(lockRetry)
+	}
+	defer os.Remove(lockFile)
+
+	return fn()
+}
+
+// takeStaleLock moves a stale lock aside under a unique name, then checks
+// the moved file again: another waiter may have taken the stale lock over
+// since it was seen, and what was moved is that waiter's fresh lock, which
+// is put back. Removing the lock by name would delete the fresh one.
+func takeStaleLock(lockFile string) {
+	aside := fmt.Sprintf("%s.stale-%d-%d", lockFile, os.Getpid(), time.Now().UnixNano())
+	if err := os.Rename(lockFile, aside); err != nil {
+		return
+	}
+	if info, err := os.Stat(aside); err == nil && time.Since(info.ModTime()) <= lockStale {
+		putLockBack(aside, lockFile)
+		return
+	}
+	logger.Warn("Removing stale config lock: %s", lockFile)
+	os.Remove(aside)
+}
+
+// putLockBack restores a fresh lock moved aside by mistake. It links rather
+// than renames: a third process may have created the lock since, and a
+// rename would replace it. Then that lock stays and the moved one is dropped.
+func putLockBack(aside, lockFile string) {
+	if err := os.Link(aside, lockFile); err != nil && !errors.Is(err, os.ErrExist) {
+		logger.Warn("Failed to restore config lock %s: %v", lockFile, err)
+	}
+	os.Remove(aside)
+}
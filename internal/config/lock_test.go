@@ -0,0 +1,103 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// shortLockTimings makes lock waits fast for the duration of a test
+func shortLockTimings(t *testing.T) {
+	t.Helper()
+	timeout, stale := lockTimeout, lockStale
+	lockTimeout, lockStale = 200*time.Millisecond, time.Hour
+	t.Cleanup(func() { lockTimeout, lockStale = timeout, stale })
+}
+
+func TestWithLock(t *testing.T) {
+	t.Run("lock file removed afterwards", func(t *testing.T) {
+		useConfigFile(t, `{}`)
+
+		ran := false
+		if err := withLock(func() error { ran = true; return nil }); err != nil {
+			t.Fatalf("withLock failed: %v", err)
+		}
+		if !ran {
+			t.Error("expected fn to run")
+		}
+		if _, err := os.Stat(lockPath()); err == nil {
+			t.Error("expected lock file removed")
+		}
+	})
+
+	t.Run("times out while another process holds the lock", func(t *testing.T) {
+		useConfigFile(t, `{}`)
+		shortLockTimings(t)
+		os.WriteFile(lockPath(), []byte("1234\n"), 0644)
+
+		err := withLock(func() error {
+			t.Error("fn should not run without the lock")
+			return nil
+		})
+		if err == nil || !strings.Contains(err.Error(), "locked by another Aurora process") {
+			t.Errorf("expected lock timeout error, got %v", err)
+		}
+		if _, err := os.Stat(lockPath()); err != nil {
+			t.Error("expected the other process' lock file left in place")
+		}
+	})
+
+	t.Run("stale lock is taken over", func(t *testing.T) {
+		useConfigFile(t, `{}`)
+		shortLockTimings(t)
+		os.WriteFile(lockPath(), []byte("1234\n"), 0644)
+		old := time.Now().Add(-2 * time.Hour)
+		os.Chtimes(lockPath(), old, old)
+
+		ran := false
+		if err := withLock(func() error { ran = true; return nil }); err != nil {
+			t.Fatalf("withLock failed: %v", err)
+		}
+		if !ran {
+			t.Error("expected fn to run after removing the stale lock")
+		}
+		if matches, _ := filepath.Glob(lockPath() + ".stale-*"); len(matches) != 0 {
+			t.Errorf("expected the stale lock removed, found %v", matches)
+		}
+	})
+
+	// Another waiter saw the same stale lock and replaced it first: the
+	// takeover must not delete the lock it now holds
+	t.Run("a lock taken over meanwhile is left in place", func(t *testing.T) {
+		useConfigFile(t, `{}`)
+		shortLockTimings(t)
+		os.WriteFile(lockPath(), []byte("1234\n"), 0644)
+
+		takeStaleLock(lockPath())
+		if content, err := os.ReadFile(lockPath()); err != nil || string(content) != "1234\n" {
+			t.Errorf("expected the other process' lock file back, got %q, %v", content, err)
+		}
+		if matches, _ := filepath.Glob(lockPath() + ".stale-*"); len(matches) != 0 {
+			t.Errorf("expected nothing left aside, found %v", matches)
+		}
+	})
+
+	// A third process created the lock while it was aside: putting the
+	// moved one back must not replace it
+	t.Run("a lock put back leaves a newer one alone", func(t *testing.T) {
+		useConfigFile(t, `{}`)
+		aside := lockPath() + ".stale-1"
+		os.WriteFile(aside, []byte("1234\n"), 0644)
+		os.WriteFile(lockPath(), []byte("5678\n"), 0644)
+
+		putLockBack(aside, lockPath())
+		if content, err := os.ReadFile(lockPath()); err != nil || string(content) != "5678\n" {
+			t.Errorf("expected the newer lock kept, got %q, %v", content, err)
+		}
+		if _, err := os.Stat(aside); err == nil {
+			t.Error("expected the moved lock dropped")
+		}
+	})
+}
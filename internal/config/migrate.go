@@ -0,0 +1,212 @@
+package config
+
+import (
+	"aurora/internal/logger"
+	"aurora/internal/util"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CurrentVersion is the config schema version written by this build.
+// Bump it together with a new step in migrations.
+const CurrentVersion = 9
+
+// migrations[v] upgrades a version v document to version v+1. Steps work on
+// the raw key/value map so they can rename or reshape keys the current
+// Config struct no longer knows about.
+var migrations = []func(doc map[string]json.RawMessage) error{
+	migrateV0ToV1,
+	addedKeysOnly, // v2: maxPartSize, layout
+	addedKeysOnly, // v3: encrypt, keyFile
+	addedKeysOnly, // v4: format
+	addedKeysOnly, // v5: hooks
+	addedKeysOnly, // v6: schedule
+	addedKeysOnly, // v7: target
+	addedKeysOnly, // v8: mirrors
+	addedKeysOnly, // v9: retention
+}
+
+// migrateV0ToV1 upgrades unversioned files. Those went through three
+// layouts (paths, filters and concurrency; then inclusions; then compression
+// and output) that all share key names, so only defaults need filling in.
+func migrateV0ToV1(doc map[string]json.RawMessage) error {
+	if raw, ok := doc["compression"]; !ok || isEmptyJSON(raw) {
+		doc["compression"] = json.RawMessage(strconv.Quote("normal"))
+	}
+	return nil
+}
+
+// addedKeysOnly upgrades a document to a version that only added keys.
+// Missing keys decode to zero values, which keep the behavior from before
+// the key existed, so there is nothing to change.
+func addedKeysOnly(doc map[string]json.RawMessage) error {
+	return nil
+}
+
+func isEmptyJSON(raw json.RawMessage) bool {
+	s := string(bytes.TrimSpace(raw))
+	return s == "null" || s == `""`
+}
+
+// schemaKeys maps every top-level key of the current schema to the field it
+// decodes into
+func schemaKeys(c *Config) map[string]any {
+	return map[string]any{
+		"version":     &c.Version,
+		"Penumbra":    &c.Penumbra,
+		"Mods":        &c.Mods,
+		"filters":     &c.Filters,
+		"inclusions":  &c.Inclusions,
+		"concurrency": &c.Concurrency,
+		"compression": &c.Compression,
+		"format":      &c.Format,
+		"output":      &c.Output,
+		"maxPartSize": &c.MaxPartSize,
+		"layout":      &c.Layout,
+		"encrypt":     &c.Encrypt,
+		"keyFile":     &c.KeyFile,
+		"hooks":       &c.Hooks,
+		"schedule":    &c.Schedule,
+		"target":      &c.Target,
+		"mirrors":     &c.Mirrors,
+		"retention":   &c.Retention,
+	}
+}
+
+// readConfigFile reads ConfigFile, upgrading older schema versions in
+// memory. When a migration ran, the file content as it was before is
+// returned too so persistMigration can keep it as a backup.
+func readConfigFile() (config *Config, original []byte, err error) {
+	return readConfig(ConfigFile)
+}
+
+// readConfig is readConfigFile for the config file at path
+func readConfig(path string) (config *Config, original []byte, err error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(util.StripBOM(content), &doc); err != nil {
+		return nil, nil, err
+	}
+	if doc == nil {
+		doc = map[string]json.RawMessage{}
+	}
+	canonicalizeKeys(doc)
+
+	migrated, err := migrate(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	config, err = decode(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if migrated {
+		return config, content, nil
+	}
+	return config, nil, nil
+}
+
+// persistMigration writes a migrated config, keeping the pre-migration file
+// as <config>.bak. Callers hold the config lock.
+func persistMigration(config *Config, original []byte) error {
+	backupFile := ConfigFile + ".bak"
+	if err := os.WriteFile(backupFile, original, 0644); err != nil {
+		return fmt.Errorf("back up config file to %s: %w", backupFile, err)
+	}
+	if err := config.write(); err != nil {
+		return err
+	}
+	logger.Info("Config upgraded to version %d, previous file kept at %s", CurrentVersion, backupFile)
+	return nil
+}
+
+// canonicalizeKeys renames keys that differ from the schema only by case.
+// encoding/json matches keys case-insensitively, so hand-edited files with
+// "penumbra" instead of "Penumbra" have always loaded; keep them working.
+// A case variant next to its canonical key is left alone and reported as
+// unknown by decode.
+func canonicalizeKeys(doc map[string]json.RawMessage) {
+	known := slices.Collect(maps.Keys(schemaKeys(&Config{})))
+	for key, raw := range doc {
+		for _, canonical := range known {
+			if key == canonical || !strings.EqualFold(key, canonical) {
+				continue
+			}
+			if _, exists := doc[canonical]; !exists {
+				doc[canonical] = raw
+				delete(doc, key)
+			}
+		}
+	}
+}
+
+// schemaVersion returns the document version, 0 for unversioned files
+func schemaVersion(doc map[string]json.RawMessage) (int, error) {
+	raw, ok := doc["version"]
+	if !ok {
+		return 0, nil
+	}
+	var version int
+	if err := json.Unmarshal(raw, &version); err != nil || version < 0 {
+		return 0, fmt.Errorf("key \"version\": invalid schema version %s", raw)
+	}
+	return version, nil
+}
+
+// migrate upgrades doc in place to CurrentVersion and reports whether any
+// step ran
+func migrate(doc map[string]json.RawMessage) (bool, error) {
+	from, err := schemaVersion(doc)
+	if err != nil {
+		return false, err
+	}
+	if from > CurrentVersion {
+		return false, fmt.Errorf("config version %d is newer than the supported version %d, update Aurora", from, CurrentVersion)
+	}
+
+	for v := from; v < CurrentVersion; v++ {
+		if err := migrations[v](doc); err != nil {
+			return false, fmt.Errorf("migrate config from version %d: %w", v, err)
+		}
+		logger.Info("Config migrated from version %d to %d", v, v+1)
+	}
+	doc["version"] = json.RawMessage(strconv.Itoa(CurrentVersion))
+	return from < CurrentVersion, nil
+}
+
+// decode strictly decodes a current-version document. Every unknown or
+// malformed key is reported at once, not just the first one.
+func decode(doc map[string]json.RawMessage) (*Config, error) {
+	var config Config
+	targets := schemaKeys(&config)
+
+	var errs []error
+	for _, key := range slices.Sorted(maps.Keys(doc)) {
+		target, ok := targets[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown key %q", key))
+			continue
+		}
+		decoder := json.NewDecoder(bytes.NewReader(doc[key]))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(target); err != nil {
+			errs = append(errs, fmt.Errorf("key %q: %w", key, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
@@ -0,0 +1,300 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// useConfigFile points ConfigFile at a temp file holding content
+func useConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	previous := ConfigFile
+	ConfigFile = path
+	t.Cleanup(func() { ConfigFile = previous })
+	return path
+}
+
+func TestMigrateHistoricalLayouts(t *testing.T) {
+	tests := []struct {
+		name            string
+		content         string
+		wantFilters     []string
+		wantInclusions  []string
+		wantCompression string
+		wantOutput      string
+	}{
+		{
+			name:            "unversioned: paths, filters and concurrency",
+			content:         `{"Penumbra":{"path":"/penumbra"},"Mods":{"path":"/mods"},"filters":["xx-"],"concurrency":2}`,
+			wantFilters:     []string{"xx-"},
+			wantCompression: "normal",
+		},
+		{
+			name:            "unversioned: with inclusions",
+			content:         `{"Penumbra":{"path":"/penumbra"},"Mods":{"path":"/mods"},"filters":null,"inclusions":["Keep"],"concurrency":2}`,
+			wantInclusions:  []string{"Keep"},
+			wantCompression: "normal",
+		},
+		{
+			name:            "unversioned: with compression and output",
+			content:         `{"Penumbra":{"path":"/penumbra"},"Mods":{"path":"/mods"},"filters":[],"inclusions":[],"concurrency":2,"compression":"max","output":"/backups"}`,
+			wantFilters:     []string{},
+			wantInclusions:  []string{},
+			wantCompression: "max",
+			wantOutput:      "/backups",
+		},
+		{
+			name:            "unversioned: empty compression gets the default",
+			content:         `{"Penumbra":{"path":"/penumbra"},"Mods":{"path":"/mods"},"concurrency":2,"compression":""}`,
+			wantCompression: "normal",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := useConfigFile(t, tt.content)
+
+			cfg, err := NewConfig(false)
+			if err != nil {
+				t.Fatalf("NewConfig failed: %v", err)
+			}
+
+			if cfg.Version != CurrentVersion {
+				t.Errorf("expected version %d, got %d", CurrentVersion, cfg.Version)
+			}
+			if cfg.Penumbra.Path != "/penumbra" || cfg.Mods.Path != "/mods" {
+				t.Errorf("paths not preserved: penumbra=%q mods=%q", cfg.Penumbra.Path, cfg.Mods.Path)
+			}
+			if cfg.Concurrency != 2 {
+				t.Errorf("expected concurrency 2, got %d", cfg.Concurrency)
+			}
+			if strings.Join(cfg.Filters, ",") != strings.Join(tt.wantFilters, ",") {
+				t.Errorf("expected filters %v, got %v", tt.wantFilters, cfg.Filters)
+			}
+			if strings.Join(cfg.Inclusions, ",") != strings.Join(tt.wantInclusions, ",") {
+				t.Errorf("expected inclusions %v, got %v", tt.wantInclusions, cfg.Inclusions)
+			}
+			if cfg.Compression != tt.wantCompression {
+				t.Errorf("expected compression %q, got %q", tt.wantCompression, cfg.Compression)
+			}
+			if cfg.Output != tt.wantOutput {
+				t.Errorf("expected output %q, got %q", tt.wantOutput, cfg.Output)
+			}
+
+			backup, err := os.ReadFile(path + ".bak")
+			if err != nil {
+				t.Fatalf("expected backup of the original file: %v", err)
+			}
+			if string(backup) != tt.content {
+				t.Errorf("backup does not match the original file: %s", backup)
+			}
+
+			// The upgraded file loads again without another migration
+			os.Remove(path + ".bak")
+			if _, err := NewConfig(false); err != nil {
+				t.Fatalf("reloading migrated config failed: %v", err)
+			}
+			if _, err := os.Stat(path + ".bak"); err == nil {
+				t.Error("expected no backup when loading a current-version file")
+			}
+		})
+	}
+}
+
+func TestLoadCurrentVersion(t *testing.T) {
+	content := `{"version":` + strconv.Itoa(CurrentVersion) + `,"Penumbra":{"path":"/penumbra"},"Mods":{"path":"/mods"},"filters":null,"inclusions":null,"concurrency":0,"compression":"normal","output":""}`
+	path := useConfigFile(t, content)
+
+	cfg, err := NewConfig(false)
+	if err != nil {
+		t.Fatalf("NewConfig failed: %v", err)
+	}
+	if cfg.Version != CurrentVersion {
+		t.Errorf("expected version %d, got %d", CurrentVersion, cfg.Version)
+	}
+
+	if _, err := os.Stat(path + ".bak"); err == nil {
+		t.Error("expected no backup when no migration ran")
+	}
+	raw, _ := os.ReadFile(path)
+	if string(raw) != content {
+		t.Error("expected current-version file to be left untouched")
+	}
+}
+
+// TestMigrateAddedKeys loads a file from before each version that only
+// added keys, already holding those keys as a newer build wrote them
+func TestMigrateAddedKeys(t *testing.T) {
+	if len(migrations) != CurrentVersion {
+		t.Fatalf("expected a migration step per version, got %d steps for version %d", len(migrations), CurrentVersion)
+	}
+
+	tests := []struct {
+		version int
+		keys    string
+		check   func(cfg *Config) bool
+	}{
+		{
+			version: 2,
+			keys:    `"maxPartSize":4000000000,"layout":"collection"`,
+			check:   func(cfg *Config) bool { return cfg.MaxPartSize == 4000000000 && cfg.Layout == "collection" },
+		},
+		{
+			version: 3,
+			keys:    `"encrypt":true,"keyFile":"/key"`,
+			check:   func(cfg *Config) bool { return cfg.Encrypt && cfg.KeyFile == "/key" },
+		},
+		{
+			version: 4,
+			keys:    `"format":"tar.zst"`,
+			check:   func(cfg *Config) bool { return cfg.Format == "tar.zst" },
+		},
+		{
+			version: 5,
+			keys:    `"hooks":{"preBackup":"sync"}`,
+			check:   func(cfg *Config) bool { return cfg.Hooks.PreBackup == "sync" },
+		},
+		{
+			version: 6,
+			keys:    `"schedule":{"mode":"daily","time":"03:00"}`,
+			check:   func(cfg *Config) bool { return cfg.Schedule.Mode == "daily" && cfg.Schedule.Time == "03:00" },
+		},
+		{
+			version: 7,
+			keys:    `"target":{"type":"dir","path":"/nas"}`,
+			check:   func(cfg *Config) bool { return cfg.Target.Type == "dir" && cfg.Target.Path == "/nas" },
+		},
+		{
+			version: 8,
+			keys:    `"mirrors":[{"type":"dir","path":"/usb"}]`,
+			check:   func(cfg *Config) bool { return len(cfg.Mirrors) == 1 && cfg.Mirrors[0].Path == "/usb" },
+		},
+		{
+			version: 9,
+			keys:    `"retention":{"incrementals":10,"days":30}`,
+			check:   func(cfg *Config) bool { return cfg.Retention.Incrementals == 10 && cfg.Retention.Days == 30 },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run("version "+strconv.Itoa(tt.version), func(t *testing.T) {
+			content := `{"version":` + strconv.Itoa(tt.version-1) + `,"Penumbra":{"path":"/penumbra"},"Mods":{"path":"/mods"},"compression":"normal",` + tt.keys + `}`
+			path := useConfigFile(t, content)
+
+			cfg, err := NewConfig(false)
+			if err != nil {
+				t.Fatalf("NewConfig failed: %v", err)
+			}
+			if cfg.Version != CurrentVersion {
+				t.Errorf("expected version %d, got %d", CurrentVersion, cfg.Version)
+			}
+			if !tt.check(cfg) {
+				t.Errorf("expected the added keys kept, got %+v", cfg)
+			}
+			if backup, err := os.ReadFile(path + ".bak"); err != nil || string(backup) != content {
+				t.Errorf("expected the version %d file kept as a backup, got %q, %v", tt.version-1, backup, err)
+			}
+		})
+	}
+}
+
+func TestLoadCaseInsensitiveKeys(t *testing.T) {
+	useConfigFile(t, `{"version":1,"penumbra":{"path":"/penumbra"},"MODS":{"path":"/mods"}}`)
+
+	cfg, err := NewConfig(false)
+	if err != nil {
+		t.Fatalf("NewConfig failed: %v", err)
+	}
+	if cfg.Penumbra.Path != "/penumbra" || cfg.Mods.Path != "/mods" {
+		t.Errorf("case variants not accepted: penumbra=%q mods=%q", cfg.Penumbra.Path, cfg.Mods.Path)
+	}
+}
+
+func TestLoadRejectsInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "newer version",
+			content: `{"version":99}`,
+			want:    []string{"newer than the supported version"},
+		},
+		{
+			name:    "malformed version",
+			content: `{"version":"one"}`,
+			want:    []string{`key "version"`},
+		},
+		{
+			name:    "unknown keys are all reported",
+			content: `{"version":1,"colour":"red","threads":4}`,
+			want:    []string{`unknown key "colour"`, `unknown key "threads"`},
+		},
+		{
+			name:    "malformed value",
+			content: `{"version":1,"concurrency":"four"}`,
+			want:    []string{`key "concurrency"`},
+		},
+		{
+			name:    "unknown nested key",
+			content: `{"version":1,"Penumbra":{"path":"/p","extra":true}}`,
+			want:    []string{`key "Penumbra"`, "extra"},
+		},
+		{
+			name:    "duplicate key with different case",
+			content: `{"version":1,"filters":[],"Filters":[]}`,
+			want:    []string{`unknown key "Filters"`},
+		},
+		{
+			name:    "not a JSON object",
+			content: `[1, 2]`,
+			want:    []string{"read config file"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := useConfigFile(t, tt.content)
+
+			_, err := NewConfig(false)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("expected error to mention %q, got: %v", want, err)
+				}
+			}
+
+			raw, _ := os.ReadFile(path)
+			if string(raw) != tt.content {
+				t.Error("expected invalid file to be left untouched")
+			}
+		})
+	}
+}
+
+func TestSaveWritesCurrentVersion(t *testing.T) {
+	useConfigFile(t, `{}`)
+
+	cfg := &Config{Penumbra: PenumbraConfig{Path: "/penumbra"}}
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := NewConfig(false)
+	if err != nil {
+		t.Fatalf("NewConfig failed: %v", err)
+	}
+	if loaded.Version != CurrentVersion {
+		t.Errorf("expected version %d, got %d", CurrentVersion, loaded.Version)
+	}
+}
@@ -1,67 +1,150 @@
 package logger
 
 import (
+	"aurora/internal/paths"
+	"context"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
-	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
 
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
+// Log formats
+const (
+	FormatText = "text" // key=value pairs
+	FormatJSON = "json" // one JSON object per line
+)
+
+// StderrFile as Options.File logs to stderr instead of a file
+const StderrFile = "-"
+
+// Options configures the logger
+type Options struct {
+	File   string     // rotated by lumberjack; StderrFile = stderr
+	Level  slog.Level // records below it are dropped
+	Format string     // FormatText or FormatJSON, "" = text
+}
+
 var (
-	enabled     bool
-	infoLogger  *log.Logger
-	warnLogger  *log.Logger
-	errorLogger *log.Logger
+	mu     sync.Mutex
+	closer io.Closer // the open log file, nil for stderr
+	// Until Configure runs every record is dropped, as in tests
+	current = slog.New(slog.DiscardHandler)
 )
 
-// Init initializes the logger with lumberjack for log rotation
-// Call this only in desktop mode
+// Init logs info and above to logPath as text
 func Init(logPath string) {
-	lj := &lumberjack.Logger{
-		Filename:   logPath,
-		MaxSize:    5, // MB
-		MaxBackups: 0,
-		MaxAge:     0,
-		Compress:   false,
+	if err := Configure(Options{File: logPath, Level: slog.LevelInfo}); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to set up logging: %v\n", err)
 	}
+}
 
-	flags := log.Ldate | log.Ltime | log.Lshortfile
-
-	infoLogger = log.New(lj, "INFO  ", flags)
-	warnLogger = log.New(lj, "WARN  ", flags)
-	errorLogger = log.New(lj, "ERROR ", flags)
+// Configure (re)directs the logger. Log files are rotated at 5 MB, the
+// previous file being kept alongside.
+func Configure(opts Options) error {
+	var w io.Writer
+	var c io.Closer
+	if opts.File == StderrFile {
+		w = os.Stderr
+	} else {
+		lj := &lumberjack.Logger{
+			Filename:   opts.File,
+			MaxSize:    5, // MB
+			MaxBackups: 0,
+			MaxAge:     0,
+			Compress:   false,
+		}
+		w, c = lj, lj
+	}
 
-	enabled = true
+	handlerOpts := &slog.HandlerOptions{AddSource: true, Level: opts.Level, ReplaceAttr: shortSource}
+	var handler slog.Handler
+	switch opts.Format {
+	case "", FormatText:
+		handler = slog.NewTextHandler(w, handlerOpts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	default:
+		return fmt.Errorf("unknown log format %q (text or json)", opts.Format)
+	}
 
-	// Visual separator between sessions
-	infoLogger.Output(2, "========================================")
+	mu.Lock()
+	previous := closer
+	current, closer = slog.New(handler), c
+	mu.Unlock()
+	if previous != nil {
+		previous.Close()
+	}
 	Info("Logger initialized")
+	return nil
 }
 
-// Info logs an info message
-func Info(format string, v ...any) {
-	if !enabled {
+// ParseLevel reads a level name: debug, info, warn or error
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q (debug, info, warn or error)", name)
+	}
+	return level, nil
+}
+
+// shortSource logs the file name of the caller without its directory, as
+// the log always did
+func shortSource(groups []string, a slog.Attr) slog.Attr {
+	if source, ok := a.Value.Any().(*slog.Source); ok && a.Key == slog.SourceKey {
+		file := source.File[strings.LastIndexAny(source.File, `/\`)+1:]
+		return slog.String(slog.SourceKey, fmt.Sprintf("%s:%d", file, source.Line))
+	}
+	return a
+}
+
+// Logger returns the structured logger, for records with fields:
+//
+//	logger.Logger().Info("Backup completed", "mods", mods, "ratio", ratio)
+func Logger() *slog.Logger {
+	mu.Lock()
+	defer mu.Unlock()
+	return current
+}
+
+// logf formats a record and logs it with the caller of the exported
+// function as its source
+func logf(level slog.Level, format string, v ...any) {
+	l := Logger()
+	ctx := context.Background()
+	if !l.Enabled(ctx, level) {
 		return
 	}
-	infoLogger.Output(2, fmt.Sprintf(format, v...))
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:]) // skip Callers, logf and its caller
+	record := slog.NewRecord(time.Now(), level, fmt.Sprintf(format, v...), pcs[0])
+	l.Handler().Handle(ctx, record)
+}
+
+// Debug logs a debug message, dropped unless the level is debug
+func Debug(format string, v ...any) {
+	logf(slog.LevelDebug, format, v...)
+}
+
+// Info logs an info message
+func Info(format string, v ...any) {
+	logf(slog.LevelInfo, format, v...)
 }
 
 // Warn logs a warning message
 func Warn(format string, v ...any) {
-	if !enabled {
-		return
-	}
-	warnLogger.Output(2, fmt.Sprintf(format, v...))
+	logf(slog.LevelWarn, format, v...)
 }
 
 // Error logs an error message
 func Error(format string, v ...any) {
-	if !enabled {
-		return
-	}
-	errorLogger.Output(2, fmt.Sprintf(format, v...))
+	logf(slog.LevelError, format, v...)
 }
 
 // LogIfErr logs an error if err is not nil and returns true if there was an error
@@ -69,24 +152,23 @@ func LogIfErr(err error, context string) bool {
 	if err == nil {
 		return false
 	}
-	if enabled {
-		errorLogger.Output(2, fmt.Sprintf("%s: %v", context, err))
-	}
+	logf(slog.LevelError, "%s: %v", context, err)
 	return true
 }
 
-// Close can be called to ensure logs are flushed (lumberjack handles this automatically)
+// Close logs the end of the session and closes the log file
 func Close() {
-	if enabled {
-		Info("Logger closing")
+	Info("Logger closing")
+	mu.Lock()
+	defer mu.Unlock()
+	if closer != nil {
+		closer.Close()
+		closer = nil
 	}
+	current = slog.New(slog.DiscardHandler)
 }
 
-// GetLogPath returns the default log path next to the executable
+// GetLogPath returns the log path in the data directory (see paths.DataDir)
 func GetLogPath() string {
-	exe, err := os.Executable()
-	if err != nil {
-		return "aurora.log"
-	}
-	return filepath.Join(filepath.Dir(exe), "aurora.log")
+	return paths.LogFile()
 }
@@ -0,0 +1,107 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// configure logs to a temp file for the test and returns its path
+func configure(t *testing.T, opts Options) string {
+	t.Helper()
+	opts.File = filepath.Join(t.TempDir(), "aurora.log")
+	if err := Configure(opts); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(Close)
+	return opts.File
+}
+
+func readLog(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestLevels(t *testing.T) {
+	t.Run("drops records below the level", func(t *testing.T) {
+		path := configure(t, Options{Level: slog.LevelWarn})
+		Debug("debug %d", 1)
+		Info("info %d", 2)
+		Warn("warn %d", 3)
+		Error("error %d", 4)
+
+		out := readLog(t, path)
+		for _, dropped := range []string{"debug 1", "info 2", "Logger initialized"} {
+			if strings.Contains(out, dropped) {
+				t.Errorf("expected %q dropped:\n%s", dropped, out)
+			}
+		}
+		for _, kept := range []string{`level=WARN source=logger_test.go:`, `msg="warn 3"`, `msg="error 4"`} {
+			if !strings.Contains(out, kept) {
+				t.Errorf("expected %q in:\n%s", kept, out)
+			}
+		}
+	})
+
+	t.Run("debug keeps everything", func(t *testing.T) {
+		path := configure(t, Options{Level: slog.LevelDebug})
+		Debug("debug %d", 1)
+		if LogIfErr(nil, "nothing") || !LogIfErr(errors.New("boom"), "saving") {
+			t.Error("expected LogIfErr to report errors only")
+		}
+
+		out := readLog(t, path)
+		for _, want := range []string{`level=DEBUG`, `msg="debug 1"`, `msg="saving: boom"`} {
+			if !strings.Contains(out, want) {
+				t.Errorf("expected %q in:\n%s", want, out)
+			}
+		}
+	})
+}
+
+func TestJSONFormat(t *testing.T) {
+	path := configure(t, Options{Level: slog.LevelInfo, Format: FormatJSON})
+	Info("Backup of %d mods", 3)
+	Logger().Warn("Mod skipped", "mod", "Body", "size", 0)
+
+	lines := strings.Split(strings.TrimSpace(readLog(t, path)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 records, got:\n%s", strings.Join(lines, "\n"))
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil {
+		t.Fatal(err)
+	}
+	// The source is the caller of Info, not the logger package
+	if record["msg"] != "Backup of 3 mods" || !strings.HasPrefix(record["source"].(string), "logger_test.go:") {
+		t.Errorf("unexpected record %v", record)
+	}
+	if err := json.Unmarshal([]byte(lines[2]), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["level"] != "WARN" || record["mod"] != "Body" || record["size"] != float64(0) {
+		t.Errorf("expected the fields in the record, got %v", record)
+	}
+}
+
+func TestConfigureErrors(t *testing.T) {
+	if err := Configure(Options{File: StderrFile, Format: "xml"}); err == nil {
+		t.Error("expected an unknown format refused")
+	}
+	for name, want := range map[string]slog.Level{"debug": slog.LevelDebug, "INFO": slog.LevelInfo, "warn": slog.LevelWarn, "error": slog.LevelError} {
+		if level, err := ParseLevel(name); err != nil || level != want {
+			t.Errorf("level %q: expected %v, got %v, %v", name, want, level, err)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("expected an unknown level refused")
+	}
+}
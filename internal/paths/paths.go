@@ -0,0 +1,199 @@
+package paths
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Environment variables relocating Aurora's files
+const (
+	EnvConfig  = "AURORA_CONFIG"  // path to config.json
+	EnvHome    = "AURORA_HOME"    // data directory: config, log, caches, indexes
+	EnvProfile = "AURORA_PROFILE" // profile whose config file is used (see ProfileFile)
+)
+
+const appDirName = "Aurora"
+
+var (
+	configOverride  string // --config flag, wins over the environment
+	profileOverride string // --profile flag, wins over AURORA_PROFILE
+
+	// Swapped in tests
+	executable = os.Executable
+	writable   = isWritable
+
+	defaultOnce sync.Once
+	defaultPath string
+)
+
+// SetConfigFile relocates the config file, typically from the --config flag.
+// Unless AURORA_HOME is set, the data directory follows the config file.
+func SetConfigFile(path string) {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	configOverride = path
+}
+
+// SetProfile selects a profile, typically from the --profile flag
+func SetProfile(name string) {
+	profileOverride = name
+}
+
+// Profile returns the selected profile: --profile, then AURORA_PROFILE;
+// "" is none
+func Profile() string {
+	if profileOverride != "" {
+		return profileOverride
+	}
+	return os.Getenv(EnvProfile)
+}
+
+// ValidateProfile reports a profile name that can't name a file
+func ValidateProfile(name string) error {
+	if name == "" || name != filepath.Base(name) || !filepath.IsLocal(name) || strings.ContainsAny(name, `/\:`) {
+		return fmt.Errorf("invalid profile %q: use a name like \"nas\" or \"offsite\"", name)
+	}
+	return nil
+}
+
+// ConfigFile returns the config file path: --config, then AURORA_CONFIG,
+// then the file of the selected profile, then DefaultConfigFile.
+func ConfigFile() string {
+	if configOverride != "" {
+		return configOverride
+	}
+	if path := os.Getenv(EnvConfig); path != "" {
+		return path
+	}
+	if profile := Profile(); profile != "" {
+		return ProfileFile(profile)
+	}
+	return DefaultConfigFile()
+}
+
+// DefaultConfigFile returns config.json in DataDir, the config used when
+// no other is chosen
+func DefaultConfigFile() string {
+	return filepath.Join(DataDir(), "config.json")
+}
+
+// ProfilesDir returns the directory holding the config file of each
+// profile. Profiles share the data directory: log, caches and history.
+func ProfilesDir() string {
+	return filepath.Join(DataDir(), "profiles")
+}
+
+// ProfileFile returns the config file of a profile
+func ProfileFile(name string) string {
+	return filepath.Join(ProfilesDir(), name+".json")
+}
+
+// Profiles returns the names of the profiles with a config file, sorted
+func Profiles() []string {
+	entries, err := os.ReadDir(ProfilesDir())
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), ".json"); ok && entry.Type().IsRegular() {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// DataDir returns the directory holding the log, caches and indexes:
+// AURORA_HOME, then the directory of an explicitly chosen config file, then
+// the default location (see defaultDir).
+func DataDir() string {
+	if home := os.Getenv(EnvHome); home != "" {
+		return home
+	}
+	if configOverride != "" {
+		return filepath.Dir(configOverride)
+	}
+	if path := os.Getenv(EnvConfig); path != "" {
+		return filepath.Dir(path)
+	}
+	defaultOnce.Do(func() { defaultPath = defaultDir() })
+	return defaultPath
+}
+
+// LogFile returns the log file path inside DataDir
+func LogFile() string {
+	return filepath.Join(DataDir(), "aurora.log")
+}
+
+// ManifestsDir returns the directory keeping the manifest of every finished
+// backup, which size estimates learn from
+func ManifestsDir() string {
+	return filepath.Join(DataDir(), "manifests")
+}
+
+// WatchStatusFile returns the status file of a running aurora watch,
+// which the desktop app reads
+func WatchStatusFile() string {
+	return filepath.Join(DataDir(), "watch.json")
+}
+
+// APITokenFile returns the file holding the token of aurora serve, kept
+// out of config.json so sharing the config doesn't leak it
+func APITokenFile() string {
+	return filepath.Join(DataDir(), "api_token")
+}
+
+// HashCacheFile returns the cache of file checksums, which spares
+// analyses rehashing unchanged mod files
+func HashCacheFile() string {
+	return filepath.Join(DataDir(), "hashes.json")
+}
+
+// QuarantineDir returns the directory holding mods moved out of the mods
+// folder by aurora cleanup, one directory per quarantine
+func QuarantineDir() string {
+	return filepath.Join(DataDir(), "quarantine")
+}
+
+// SnapshotsDir returns the directory keeping the snapshots of the mod
+// library that aurora history compares
+func SnapshotsDir() string {
+	return filepath.Join(DataDir(), "snapshots")
+}
+
+// defaultDir keeps files next to the executable (portable installs, the
+// historical location) unless that directory is read-only, as with package
+// managed installs; then it falls back to the OS user config directory
+// (XDG_CONFIG_HOME on Linux, %AppData% on Windows).
+func defaultDir() string {
+	exe, err := executable()
+	if err != nil {
+		return "."
+	}
+	exeDir := filepath.Dir(exe)
+	if writable(exeDir) {
+		return exeDir
+	}
+	userDir, err := os.UserConfigDir()
+	if err != nil {
+		return exeDir
+	}
+	return filepath.Join(userDir, appDirName)
+}
+
+// isWritable reports whether files can be created in dir
+func isWritable(dir string) bool {
+	file, err := os.CreateTemp(dir, ".aurora-write-test-*")
+	if err != nil {
+		return false
+	}
+	file.Close()
+	os.Remove(file.Name())
+	return true
+}
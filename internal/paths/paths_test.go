@@ -0,0 +1,160 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// resetPaths clears the --config and --profile overrides and the cached
+// default directory
+func resetPaths(t *testing.T) {
+	t.Helper()
+	t.Setenv(EnvConfig, "")
+	t.Setenv(EnvHome, "")
+	t.Setenv(EnvProfile, "")
+	reset := func() {
+		configOverride = ""
+		profileOverride = ""
+		defaultOnce = sync.Once{}
+		defaultPath = ""
+		executable = func() (string, error) { return "/opt/aurora/aurora", nil }
+		writable = func(string) bool { return true }
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestConfigFile(t *testing.T) {
+	t.Run("defaults next to the executable", func(t *testing.T) {
+		resetPaths(t)
+
+		if got, want := ConfigFile(), filepath.Join("/opt/aurora", "config.json"); got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+		if got, want := LogFile(), filepath.Join("/opt/aurora", "aurora.log"); got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	})
+
+	t.Run("AURORA_HOME relocates everything", func(t *testing.T) {
+		resetPaths(t)
+		t.Setenv(EnvHome, "/data/aurora")
+
+		if got, want := ConfigFile(), filepath.Join("/data/aurora", "config.json"); got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+		if got, want := LogFile(), filepath.Join("/data/aurora", "aurora.log"); got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+		if got, want := HashCacheFile(), filepath.Join("/data/aurora", "hashes.json"); got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+		if got, want := QuarantineDir(), filepath.Join("/data/aurora", "quarantine"); got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+		if got, want := SnapshotsDir(), filepath.Join("/data/aurora", "snapshots"); got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	})
+
+	t.Run("AURORA_CONFIG wins over AURORA_HOME for the config file", func(t *testing.T) {
+		resetPaths(t)
+		t.Setenv(EnvHome, "/data/aurora")
+		t.Setenv(EnvConfig, "/etc/aurora/custom.json")
+
+		if got := ConfigFile(); got != "/etc/aurora/custom.json" {
+			t.Errorf("expected AURORA_CONFIG path, got %q", got)
+		}
+		if got := DataDir(); got != "/data/aurora" {
+			t.Errorf("expected data dir to stay on AURORA_HOME, got %q", got)
+		}
+	})
+
+	t.Run("data dir follows AURORA_CONFIG without AURORA_HOME", func(t *testing.T) {
+		resetPaths(t)
+		t.Setenv(EnvConfig, "/etc/aurora/custom.json")
+
+		if got := DataDir(); got != "/etc/aurora" {
+			t.Errorf("expected config file directory, got %q", got)
+		}
+	})
+
+	t.Run("flag wins over the environment", func(t *testing.T) {
+		resetPaths(t)
+		t.Setenv(EnvConfig, "/etc/aurora/custom.json")
+		dir := t.TempDir()
+		SetConfigFile(filepath.Join(dir, "flag.json"))
+
+		if got, want := ConfigFile(), filepath.Join(dir, "flag.json"); got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+		if got := DataDir(); got != dir {
+			t.Errorf("expected data dir to follow the flag, got %q", got)
+		}
+	})
+
+	t.Run("a profile has a config file of its own", func(t *testing.T) {
+		resetPaths(t)
+		t.Setenv(EnvHome, "/data/aurora")
+		t.Setenv(EnvProfile, "nas")
+
+		if got, want := ConfigFile(), filepath.Join("/data/aurora", "profiles", "nas.json"); got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+		SetProfile("offsite")
+		if got, want := ConfigFile(), filepath.Join("/data/aurora", "profiles", "offsite.json"); got != want {
+			t.Errorf("expected the flag to win, got %q", got)
+		}
+		if got := DataDir(); got != "/data/aurora" {
+			t.Errorf("expected profiles to share the data dir, got %q", got)
+		}
+		t.Setenv(EnvConfig, "/etc/aurora/custom.json")
+		if got := ConfigFile(); got != "/etc/aurora/custom.json" {
+			t.Errorf("expected AURORA_CONFIG to win over a profile, got %q", got)
+		}
+	})
+
+	t.Run("read-only executable dir falls back to the user config dir", func(t *testing.T) {
+		resetPaths(t)
+		userDir := t.TempDir()
+		t.Setenv("XDG_CONFIG_HOME", userDir)
+		t.Setenv("HOME", userDir)
+		t.Setenv("AppData", userDir)
+		writable = func(string) bool { return false }
+
+		got := DataDir()
+		if filepath.Base(got) != appDirName || got == "/opt/aurora" {
+			t.Errorf("expected user config fallback, got %q", got)
+		}
+	})
+}
+
+func TestProfiles(t *testing.T) {
+	resetPaths(t)
+	home := t.TempDir()
+	t.Setenv(EnvHome, home)
+	if got := Profiles(); len(got) != 0 {
+		t.Errorf("expected no profiles, got %v", got)
+	}
+	os.MkdirAll(ProfilesDir(), 0755)
+	for _, name := range []string{"offsite.json", "nas.json", "notes.txt"} {
+		os.WriteFile(filepath.Join(ProfilesDir(), name), []byte("{}"), 0644)
+	}
+	if got := Profiles(); !slices.Equal(got, []string{"nas", "offsite"}) {
+		t.Errorf("expected the profiles sorted, got %v", got)
+	}
+
+	for _, name := range []string{"nas", "off-site 2"} {
+		if err := ValidateProfile(name); err != nil {
+			t.Errorf("expected %q accepted, got %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "..", "../nas", "a/b", `a\b`, "c:nas"} {
+		if err := ValidateProfile(name); err == nil {
+			t.Errorf("expected %q refused", name)
+		}
+	}
+}
@@ -4,11 +4,13 @@ import (
 	"aurora/internal/config"
 	"aurora/internal/logger"
 	"aurora/internal/util"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"slices"
+	"strings"
 )
 
 type PenumbraRepository struct {
@@ -16,6 +18,15 @@ type PenumbraRepository struct {
 	Mods        []PenumbraMod
 	Collections []PenumbraCollection
 	Stats       PenumbraStats
+	Warnings    []PenumbraWarning // only loads with sizes check mod folders
+}
+
+// PenumbraWarning is a mod folder left out of the mods, or a path inside
+// a mod that couldn't be read (Path != "")
+type PenumbraWarning struct {
+	Mod     string
+	Path    string
+	Message string
 }
 
 type PenumbraStats struct {
@@ -29,6 +40,8 @@ type PenumbraMod struct {
 	Name        string
 	Collections []*PenumbraCollection
 	Size        uint64
+	Folder      string // Penumbra mod selector folder, "" = root
+	Author      string // from the mod's meta.json, "" = unknown
 }
 
 type PenumbraCollection struct {
@@ -45,7 +58,27 @@ type collectionSettings struct {
 	Enabled bool `json:"Enabled"`
 }
 
-const collectionsFolder = "collections"
+// modMeta is the part of a mod's meta.json Aurora reads
+type modMeta struct {
+	Author string `json:"Author"`
+}
+
+// sortOrder is Penumbra's sort_order.json: where each mod sits in the mod
+// selector, as "Folder/Sub Folder/Display Name"
+type sortOrder struct {
+	Data map[string]string `json:"Data"`
+}
+
+const (
+	collectionsFolder = "collections"
+	sortOrderFile     = "sort_order.json"
+	modMetaFile       = "meta.json"
+)
+
+// CollectionsDir returns the directory holding Penumbra's collections
+func CollectionsDir(config *config.Config) string {
+	return filepath.Join(config.Penumbra.Path, collectionsFolder)
+}
 
 func NewPenumbraRepository(config *config.Config) (*PenumbraRepository, error) {
 	return newRepository(config, true)
@@ -60,7 +93,7 @@ func NewPenumbraRepositoryNoSizes(config *config.Config) (*PenumbraRepository, e
 }
 
 func newRepository(config *config.Config, withSizes bool) (*PenumbraRepository, error) {
-	mods, err := loadMods(config, withSizes)
+	mods, warnings, err := loadMods(config, withSizes)
 	if err != nil {
 		return nil, err
 	}
@@ -68,11 +101,14 @@ func newRepository(config *config.Config, withSizes bool) (*PenumbraRepository,
 	if err != nil {
 		return nil, err
 	}
+	loadSortFolders(mods, config)
+	loadAuthors(mods, config)
 	repo := PenumbraRepository{
 		path:        config.Penumbra.Path,
 		Mods:        mods,
 		Collections: collections,
 		Stats:       PenumbraStats{},
+		Warnings:    warnings,
 	}
 	for i, mod := range mods {
 		repo.Stats.TotalDiskSize += mod.Size
@@ -93,14 +129,15 @@ func newRepository(config *config.Config, withSizes bool) (*PenumbraRepository,
 	return &repo, nil
 }
 
-func loadMods(config *config.Config, withSizes bool) ([]PenumbraMod, error) {
+func loadMods(config *config.Config, withSizes bool) ([]PenumbraMod, []PenumbraWarning, error) {
 	entries, err := os.ReadDir(config.Mods.Path)
 	if err != nil {
 		logger.Error("Failed to read mods directory: %v", err)
-		return nil, fmt.Errorf("read mods directory %s: %w", config.Mods.Path, err)
+		return nil, nil, fmt.Errorf("read mods directory %s: %w", config.Mods.Path, err)
 	}
 
 	mods := make([]PenumbraMod, 0, len(entries))
+	var warnings []PenumbraWarning
 	for _, entry := range entries {
 		if !entry.IsDir() {
 			continue
@@ -110,13 +147,20 @@ func loadMods(config *config.Config, withSizes bool) ([]PenumbraMod, error) {
 		if withSizes {
 			modFullPath := filepath.Join(config.Mods.Path, modName)
 			var err error
-			size, err = getModSize(modFullPath)
+			var unreadable []PenumbraWarning
+			size, unreadable, err = getModSize(modFullPath)
+			for i := range unreadable {
+				unreadable[i].Mod = modName
+			}
+			warnings = append(warnings, unreadable...)
 			if err != nil {
 				logger.Warn("Failed to get mod size for %s: %v", modName, err)
+				warnings = append(warnings, PenumbraWarning{Mod: modName, Message: fmt.Sprintf("skipped, size unknown: %v", err)})
 				continue
 			}
 			if size == 0 {
 				logger.Info("Skipping mod with size 0: %s", modName)
+				warnings = append(warnings, PenumbraWarning{Mod: modName, Message: "skipped, size 0"})
 				continue
 			}
 		}
@@ -132,11 +176,11 @@ func loadMods(config *config.Config, withSizes bool) ([]PenumbraMod, error) {
 		return 0
 	})
 
-	return mods, nil
+	return mods, warnings, nil
 }
 
 func loadCollections(mods []PenumbraMod, config *config.Config) ([]PenumbraCollection, error) {
-	path := filepath.Join(config.Penumbra.Path, collectionsFolder)
+	path := CollectionsDir(config)
 	entries, err := os.ReadDir(path)
 	if err != nil {
 		logger.Error("Failed to read penumbra collections folder: %v", err)
@@ -163,6 +207,7 @@ func loadCollections(mods []PenumbraMod, config *config.Config) ([]PenumbraColle
 					}
 				}
 			}
+			logger.Debug("Collection %s: %d enabled mods", penumbraCollection.Name, len(penumbraCollection.Mods))
 			collections = append(collections, penumbraCollection)
 		}
 	}
@@ -170,6 +215,42 @@ func loadCollections(mods []PenumbraMod, config *config.Config) ([]PenumbraColle
 	return collections, nil
 }
 
+// loadSortFolders sets the selector folder of each mod from sort_order.json.
+// The file is optional: without it every mod sits at the root.
+func loadSortFolders(mods []PenumbraMod, config *config.Config) {
+	path := filepath.Join(config.Penumbra.Path, sortOrderFile)
+	var order sortOrder
+	if err := util.ReadJSONFile(path, &order); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			logger.Warn("Failed to read penumbra sort order %s: %v", path, err)
+		}
+		return
+	}
+	for i := range mods {
+		if sortPath, ok := order.Data[mods[i].Name]; ok {
+			if slash := strings.LastIndex(sortPath, "/"); slash > 0 {
+				mods[i].Folder = sortPath[:slash]
+			}
+		}
+	}
+}
+
+// loadAuthors sets the author of each mod from its meta.json. Mods without
+// one, or with one that can't be read, keep an unknown author.
+func loadAuthors(mods []PenumbraMod, config *config.Config) {
+	for i := range mods {
+		path := filepath.Join(config.Mods.Path, mods[i].Path, modMetaFile)
+		var meta modMeta
+		if err := util.ReadJSONFile(path, &meta); err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				logger.Warn("Failed to read mod meta %s: %v", path, err)
+			}
+			continue
+		}
+		mods[i].Author = strings.TrimSpace(meta.Author)
+	}
+}
+
 func findModByName(mods []PenumbraMod, name string) *PenumbraMod {
 	for i := range mods {
 		if mods[i].Name == name {
@@ -179,18 +260,23 @@ func findModByName(mods []PenumbraMod, name string) *PenumbraMod {
 	return nil
 }
 
-func getModSize(root string) (uint64, error) {
+// getModSize adds up the files under root. Paths it can't read are left
+// out and returned as warnings, without their mod.
+func getModSize(root string) (uint64, []PenumbraWarning, error) {
 	var size uint64
+	var unreadable []PenumbraWarning
 
 	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			logger.Warn("Cannot access path %s: %v", path, err)
+			unreadable = append(unreadable, PenumbraWarning{Path: path, Message: fmt.Sprintf("cannot access: %v", err)})
 			return nil
 		}
 		if !d.IsDir() {
 			info, err := d.Info()
 			if err != nil {
 				logger.Warn("Cannot get file info for %s: %v", path, err)
+				unreadable = append(unreadable, PenumbraWarning{Path: path, Message: fmt.Sprintf("cannot get file info: %v", err)})
 				return nil
 			}
 			size += uint64(info.Size())
@@ -198,5 +284,5 @@ func getModSize(root string) (uint64, error) {
 		return nil
 	})
 
-	return size, err
+	return size, unreadable, err
 }
@@ -70,6 +70,9 @@ func TestLoadMods(t *testing.T) {
 		if repo.Mods[0].Name != "HasContent" {
 			t.Errorf("expected HasContent, got %s", repo.Mods[0].Name)
 		}
+		if len(repo.Warnings) != 1 || repo.Warnings[0].Mod != "EmptyMod" || repo.Warnings[0].Path != "" {
+			t.Errorf("expected a warning for the skipped mod, got %+v", repo.Warnings)
+		}
 	})
 
 	t.Run("skips files in mods directory", func(t *testing.T) {
@@ -158,3 +161,79 @@ func TestNewPenumbraRepositoryErrors(t *testing.T) {
 		}
 	})
 }
+
+func TestLoadSortFolders(t *testing.T) {
+	modsDir := t.TempDir()
+	penumbraDir := t.TempDir()
+	os.MkdirAll(filepath.Join(penumbraDir, "collections"), 0755)
+	for _, name := range []string{"Body", "Hair", "Loose"} {
+		os.MkdirAll(filepath.Join(modsDir, name), 0755)
+		os.WriteFile(filepath.Join(modsDir, name, "data.txt"), []byte("content"), 0644)
+	}
+	cfg := &config.Config{
+		Penumbra: config.PenumbraConfig{Path: penumbraDir},
+		Mods:     config.ModsConfig{Path: modsDir},
+	}
+
+	t.Run("mods sit at the root without sort_order.json", func(t *testing.T) {
+		repo, err := NewPenumbraRepository(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, mod := range repo.Mods {
+			if mod.Folder != "" {
+				t.Errorf("expected %s at the root, got %q", mod.Name, mod.Folder)
+			}
+		}
+	})
+
+	t.Run("reads selector folders", func(t *testing.T) {
+		os.WriteFile(filepath.Join(penumbraDir, "sort_order.json"), []byte(`{
+			"Data": {"Body": "Bodies/Female/My Body", "Hair": "Hair/Long Hair", "Loose": "Loose"},
+			"EmptyFolders": []
+		}`), 0644)
+		repo, err := NewPenumbraRepository(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]string{"Body": "Bodies/Female", "Hair": "Hair", "Loose": ""}
+		for _, mod := range repo.Mods {
+			if mod.Folder != want[mod.Name] {
+				t.Errorf("%s: expected folder %q, got %q", mod.Name, want[mod.Name], mod.Folder)
+			}
+		}
+	})
+}
+
+func TestLoadAuthors(t *testing.T) {
+	modsDir := t.TempDir()
+	penumbraDir := t.TempDir()
+	os.MkdirAll(filepath.Join(penumbraDir, "collections"), 0755)
+	metas := map[string]string{
+		"Body":   `{"FileVersion": 3, "Name": "My Body", "Author": " Someone "}`,
+		"Broken": `{"Author": `,
+		"Hair":   "",
+	}
+	for name, meta := range metas {
+		os.MkdirAll(filepath.Join(modsDir, name), 0755)
+		os.WriteFile(filepath.Join(modsDir, name, "data.txt"), []byte("content"), 0644)
+		if meta != "" {
+			os.WriteFile(filepath.Join(modsDir, name, "meta.json"), []byte(meta), 0644)
+		}
+	}
+	cfg := &config.Config{
+		Penumbra: config.PenumbraConfig{Path: penumbraDir},
+		Mods:     config.ModsConfig{Path: modsDir},
+	}
+
+	repo, err := NewPenumbraRepository(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"Body": "Someone", "Broken": "", "Hair": ""}
+	for _, mod := range repo.Mods {
+		if mod.Author != want[mod.Name] {
+			t.Errorf("%s: expected author %q, got %q", mod.Name, want[mod.Name], mod.Author)
+		}
+	}
+}
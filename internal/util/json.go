@@ -13,8 +13,10 @@ func ReadJSONFile(path string, target interface{}) error {
 		return err
 	}
 
-	// strip BOM if present
-	contentBytes = bytes.TrimPrefix(contentBytes, []byte("\xEF\xBB\xBF"))
+	return json.Unmarshal(StripBOM(contentBytes), target)
+}
 
-	return json.Unmarshal(contentBytes, target)
+// StripBOM removes a leading UTF-8 byte order mark (Windows editors add one)
+func StripBOM(content []byte) []byte {
+	return bytes.TrimPrefix(content, []byte("\xEF\xBB\xBF"))
 }
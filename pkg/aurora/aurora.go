@@ -2,15 +2,27 @@ package aurora
 
 import (
 	"aurora/internal/config"
+	"aurora/internal/paths"
 	"aurora/internal/repository"
+	"os"
 	"slices"
+	"sync/atomic"
 
 	"github.com/dustin/go-humanize"
 )
 
-// Aurora is the main service providing all operations
+// Aurora is the main service providing all operations. It is safe for
+// concurrent use: a config change swaps in a new config, and readers work
+// on the one they loaded.
 type Aurora struct {
-	cfg *config.Config
+	cfg atomic.Pointer[config.Config]
+}
+
+// newAurora returns an Aurora using cfg
+func newAurora(cfg *config.Config) *Aurora {
+	a := &Aurora{}
+	a.cfg.Store(cfg)
+	return a
 }
 
 // New creates a new Aurora instance
@@ -24,7 +36,7 @@ func NewWithReset(reset bool) (*Aurora, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Aurora{cfg: cfg}, nil
+	return newAurora(cfg), nil
 }
 
 // ReloadConfig reloads the configuration from disk
@@ -33,21 +45,37 @@ func (a *Aurora) ReloadConfig() error {
 	if err != nil {
 		return err
 	}
-	a.cfg = cfg
+	a.cfg.Store(cfg)
 	return nil
 }
 
 // GetConfig returns the current configuration
 func (a *Aurora) GetConfig() ConfigResult {
-	status := a.cfg.Status()
+	cfg := a.Config()
+	status := cfg.Status()
 	return ConfigResult{
-		PenumbraPath: a.cfg.Penumbra.Path,
-		ModsPath:     a.cfg.Mods.Path,
-		OutputPath:   a.cfg.Output,
-		Filters:      a.cfg.Filters,
-		Inclusions:   a.cfg.Inclusions,
-		Concurrency:  a.cfg.Concurrency,
-		Compression:  a.GetCompression(),
+		PenumbraPath:    cfg.Penumbra.Path,
+		ModsPath:        cfg.Mods.Path,
+		OutputPath:      cfg.Output,
+		Filters:         cfg.Filters,
+		Inclusions:      cfg.Inclusions,
+		Concurrency:     cfg.Concurrency,
+		Compression:     a.GetCompression(),
+		Format:          a.GetFormat(),
+		Layout:          a.GetLayout(),
+		MaxPartSize:     a.GetMaxPartSize(),
+		Encrypt:         cfg.Encrypt,
+		KeyFile:         cfg.KeyFile,
+		PassphraseInEnv: os.Getenv(EnvPassphrase) != "",
+		PreBackupHook:   cfg.Hooks.PreBackup,
+		PostBackupHook:  cfg.Hooks.PostBackup,
+		HookTimeout:     cfg.Hooks.Timeout,
+		Schedule:        a.GetSchedule(),
+		Target:          a.GetTarget().String(),
+		Mirrors:         a.mirrorURLs(),
+		Retention:       a.GetRetention(),
+		Profile:         paths.Profile(),
+		ConfigFile:      config.ConfigFile,
 		Status: ConfigStatus{
 			Valid:          status.Valid,
 			PenumbraStatus: status.Penumbra,
@@ -59,23 +87,53 @@ func (a *Aurora) GetConfig() ConfigResult {
 
 // UpdateConfig updates the configuration paths
 func (a *Aurora) UpdateConfig(penumbraPath, modsPath, outputPath string) error {
-	a.cfg.Penumbra.Path = penumbraPath
-	a.cfg.Mods.Path = modsPath
-	a.cfg.Output = outputPath
-	if err := a.cfg.Save(); err != nil {
+	return a.updateConfig(func(cfg *config.Config) {
+		cfg.Penumbra.Path = penumbraPath
+		cfg.Mods.Path = modsPath
+		cfg.Output = outputPath
+	})
+}
+
+// SetPenumbraPath sets the Penumbra config folder
+func (a *Aurora) SetPenumbraPath(path string) error {
+	return a.updateConfig(func(cfg *config.Config) {
+		cfg.Penumbra.Path = path
+	})
+}
+
+// SetModsPath sets the Penumbra mods folder
+func (a *Aurora) SetModsPath(path string) error {
+	return a.updateConfig(func(cfg *config.Config) {
+		cfg.Mods.Path = path
+	})
+}
+
+// SetOutputPath sets the backup output folder ("" = current working directory)
+func (a *Aurora) SetOutputPath(path string) error {
+	return a.updateConfig(func(cfg *config.Config) {
+		cfg.Output = path
+	})
+}
+
+// updateConfig applies fn to the config stored on disk (not to a.Config(),
+// which may be stale if another process wrote since) and keeps the result
+func (a *Aurora) updateConfig(fn func(cfg *config.Config)) error {
+	cfg, err := config.Update(fn)
+	if err != nil {
 		return err
 	}
-	return a.ReloadConfig()
+	a.cfg.Store(cfg)
+	return nil
 }
 
 // IsConfigValid returns whether the current config is valid
 func (a *Aurora) IsConfigValid() bool {
-	return a.cfg.Status().Valid
+	return a.Config().Status().Valid
 }
 
 // GetCollections returns all collections and mods
 func (a *Aurora) GetCollections() (CollectionsResult, error) {
-	repo, err := repository.NewPenumbraRepository(a.cfg)
+	repo, err := repository.NewPenumbraRepository(a.Config())
 	if err != nil {
 		return CollectionsResult{}, err
 	}
@@ -129,49 +187,43 @@ func (a *Aurora) GetCollections() (CollectionsResult, error) {
 	}, nil
 }
 
-// Config returns the internal config (for CLI compatibility)
+// Config returns the internal config (for CLI compatibility). Config
+// changes store a new one rather than modifying it: load it once and read
+// that copy, it won't change underneath.
 func (a *Aurora) Config() *config.Config {
-	return a.cfg
+	return a.cfg.Load()
 }
 
 // AddFilter adds a new filter pattern
 func (a *Aurora) AddFilter(filter string) error {
-	if slices.Contains(a.cfg.Filters, filter) {
-		return nil
-	}
-	a.cfg.Filters = append(a.cfg.Filters, filter)
-	return a.cfg.Save()
+	return a.updateConfig(func(cfg *config.Config) {
+		if !slices.Contains(cfg.Filters, filter) {
+			cfg.Filters = append(cfg.Filters, filter)
+		}
+	})
 }
 
 // RemoveFilter removes a filter pattern
 func (a *Aurora) RemoveFilter(filter string) error {
-	for i, f := range a.cfg.Filters {
-		if f == filter {
-			a.cfg.Filters = append(a.cfg.Filters[:i], a.cfg.Filters[i+1:]...)
-			return a.cfg.Save()
-		}
-	}
-	return nil
+	return a.updateConfig(func(cfg *config.Config) {
+		cfg.Filters = slices.DeleteFunc(cfg.Filters, func(f string) bool { return f == filter })
+	})
 }
 
 // AddInclusion adds a new inclusion pattern
 func (a *Aurora) AddInclusion(inclusion string) error {
-	if slices.Contains(a.cfg.Inclusions, inclusion) {
-		return nil
-	}
-	a.cfg.Inclusions = append(a.cfg.Inclusions, inclusion)
-	return a.cfg.Save()
+	return a.updateConfig(func(cfg *config.Config) {
+		if !slices.Contains(cfg.Inclusions, inclusion) {
+			cfg.Inclusions = append(cfg.Inclusions, inclusion)
+		}
+	})
 }
 
 // RemoveInclusion removes an inclusion pattern
 func (a *Aurora) RemoveInclusion(inclusion string) error {
-	for i, f := range a.cfg.Inclusions {
-		if f == inclusion {
-			a.cfg.Inclusions = append(a.cfg.Inclusions[:i], a.cfg.Inclusions[i+1:]...)
-			return a.cfg.Save()
-		}
-	}
-	return nil
+	return a.updateConfig(func(cfg *config.Config) {
+		cfg.Inclusions = slices.DeleteFunc(cfg.Inclusions, func(f string) bool { return f == inclusion })
+	})
 }
 
 // SetConcurrency sets the concurrency level for backups
@@ -179,13 +231,14 @@ func (a *Aurora) SetConcurrency(concurrency int) error {
 	if concurrency < 0 {
 		concurrency = 0
 	}
-	a.cfg.Concurrency = concurrency
-	return a.cfg.Save()
+	return a.updateConfig(func(cfg *config.Config) {
+		cfg.Concurrency = concurrency
+	})
 }
 
 // GetConcurrency returns the current concurrency setting
 func (a *Aurora) GetConcurrency() int {
-	return a.cfg.Concurrency
+	return a.Config().Concurrency
 }
 
 // SetCompression sets the backup compression preset ("normal" or "max")
@@ -193,13 +246,14 @@ func (a *Aurora) SetCompression(compression string) error {
 	if compression != CompressionMax {
 		compression = CompressionNormal
 	}
-	a.cfg.Compression = compression
-	return a.cfg.Save()
+	return a.updateConfig(func(cfg *config.Config) {
+		cfg.Compression = compression
+	})
 }
 
 // GetCompression returns the current compression preset, normalized
 func (a *Aurora) GetCompression() string {
-	if a.cfg.Compression == CompressionMax {
+	if a.Config().Compression == CompressionMax {
 		return CompressionMax
 	}
 	return CompressionNormal
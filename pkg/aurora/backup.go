@@ -3,6 +3,7 @@ package aurora
 import (
 	"aurora/internal/logger"
 	"aurora/internal/repository"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -14,34 +15,55 @@ import (
 // BackupOutputPath is the base filename for backup archives
 const BackupOutputPath = "backup_part.zip"
 
-// Compression presets exposed in settings
+// Compression presets exposed in settings, mapped to levels per archive
+// format (see CompressionLevel)
 const (
 	CompressionMax    = "max"    // ZIP level 9: smallest archives, ~2x slower
 	CompressionNormal = "normal" // ZIP level 5: ~5% bigger archives, fast
 )
 
-// CompressionLevel maps a compression preset to a go-delta ZIP level.
-// Unknown or empty presets fall back to normal (the default).
-func CompressionLevel(preset string) int {
-	if preset == CompressionMax {
-		return 9
-	}
-	return 5
-}
-
 // NewBackupOptions creates compress options for backup with standard settings.
-// outputDir is the destination directory ("" = current working directory).
-func NewBackupOptions(folders []string, threads int, compression, outputDir string, quiet bool) *compress.Options {
+// outputDir is the destination directory ("" = current working directory);
+// the output name carries the archive format.
+func NewBackupOptions(folders []string, threads int, format, compression, outputDir string, quiet bool) *compress.Options {
+	f := formatByName(format)
 	return &compress.Options{
-		OutputPath:   filepath.Join(outputDir, BackupOutputPath),
+		OutputPath:   filepath.Join(outputDir, singleArchive+f.ext),
 		Files:        folders,
 		MaxThreads:   threads,
-		Level:        CompressionLevel(compression),
-		UseZipFormat: true,
+		Level:        CompressionLevel(f.name, compression),
+		UseZipFormat: f.name == FormatZip,
 		Quiet:        quiet,
 	}
 }
 
+// NewBackupResult summarizes a finished backup written to outputDir
+// ("" = current working directory)
+func NewBackupResult(outputDir string, originalSize, compressedSize uint64) BackupResult {
+	return BackupResult{
+		OutputPath:     FindBackupOutputFiles(outputDir),
+		OutputDir:      outputDir,
+		OriginalSize:   originalSize,
+		CompressedSize: compressedSize,
+		Ratio:          compressionRatio(originalSize, compressedSize),
+	}
+}
+
+// FindBackupOutputFiles finds the backup files created in the output
+// directory ("" = current working directory) and returns a display string.
+// Understands every layout (see ListBackupArchives).
+func FindBackupOutputFiles(outputDir string) string {
+	archives := ListBackupArchives(outputDir)
+	switch len(archives) {
+	case 0:
+		return BackupOutputPath
+	case 1:
+		return archives[0]
+	}
+	// Multiple files: show range
+	return fmt.Sprintf("%s ... %s (%d archives)", archives[0], archives[len(archives)-1], len(archives))
+}
+
 // hasPrefixFold reports whether s starts with prefix, ignoring case.
 // Filters are case-insensitive to match the search bars' behavior.
 func hasPrefixFold(s, prefix string) bool {
@@ -128,15 +150,33 @@ func inBackupSet(mod *repository.PenumbraMod, filters, inclusions []string) (sel
 
 // ValidateBackup returns a preview of what will be backed up
 func (a *Aurora) ValidateBackup() (BackupValidation, error) {
-	repo, err := repository.NewPenumbraRepository(a.cfg)
+	return a.validateBackup(nil)
+}
+
+// validateBackup is ValidateBackup limited to the mod folder names in only
+// (nil = every mod), the preview of an incremental backup
+func (a *Aurora) validateBackup(only map[string]bool) (BackupValidation, error) {
+	cfg := a.Config()
+	repo, err := repository.NewPenumbraRepository(cfg)
 	if err != nil {
 		return BackupValidation{}, err
 	}
 
 	items := []BackupItem{}
+	warnings := []BackupWarning{}
 	var totalSize uint64
+	var folders []string
+
+	for _, warning := range repo.Warnings {
+		if only == nil || only[warning.Mod] {
+			warnings = append(warnings, BackupWarning{Mod: warning.Mod, Path: warning.Path, Message: warning.Message})
+		}
+	}
 
 	for _, mod := range repo.Mods {
+		if only != nil && !only[mod.Name] {
+			continue
+		}
 		// Get collection names
 		colNames := make([]string, len(mod.Collections))
 		for i, col := range mod.Collections {
@@ -153,7 +193,7 @@ func (a *Aurora) ValidateBackup() (BackupValidation, error) {
 			},
 		}
 
-		selected, excludedBy, includedBy := inBackupSet(&mod, a.cfg.Filters, a.cfg.Inclusions)
+		selected, excludedBy, includedBy := inBackupSet(&mod, cfg.Filters, cfg.Inclusions)
 		item.IsFiltered = excludedBy != ""
 		item.FilteredBy = excludedBy
 		item.IsIncluded = includedBy != ""
@@ -164,21 +204,22 @@ func (a *Aurora) ValidateBackup() (BackupValidation, error) {
 			items = append(items, item)
 			if selected {
 				totalSize += mod.Size
+				folders = append(folders, filepath.Join(cfg.Mods.Path, mod.Name))
 			}
 		}
 	}
 
-	// Rough zstd/deflate estimate: ~25% of original (integer math; the old
-	// float32 conversion lost precision on large sizes)
-	estimated := totalSize / 4
+	format := formatByName(cfg.Format)
+	level := CompressionLevel(format.name, cfg.Compression)
+	estimate := estimateBackupSize(folders, format, level, pastManifests(format.name, level, learnFromBackups))
 
-	// Check available disk space in the backup output directory.
-	// Require 5% extra margin to avoid running out of space.
+	// Check available disk space in the backup output directory against
+	// the top of the estimate range, plus 5% for the file system.
 	// If detection fails, don't block the backup - report space as unknown.
 	availableSpace := uint64(0)
 	hasEnoughSpace := true
 	spaceKnown := false
-	outputDir := a.cfg.Output
+	outputDir := cfg.Output
 	if outputDir == "" {
 		outputDir, _ = os.Getwd()
 	}
@@ -186,7 +227,7 @@ func (a *Aurora) ValidateBackup() (BackupValidation, error) {
 		if avail, err := getDiskAvailable(outputDir); err == nil {
 			availableSpace = avail
 			spaceKnown = true
-			requiredSpace := uint64(float64(estimated) * 1.05)
+			requiredSpace := uint64(float64(estimate.high) * 1.05)
 			hasEnoughSpace = availableSpace >= requiredSpace
 		} else {
 			logger.Warn("Disk space detection failed for %s: %v", outputDir, err)
@@ -199,32 +240,52 @@ func (a *Aurora) ValidateBackup() (BackupValidation, error) {
 
 	validation := BackupValidation{
 		Items:               items,
+		Warnings:            warnings,
 		TotalSize:           totalSize,
 		TotalSizeHuman:      humanize.Bytes(totalSize),
-		EstimatedSize:       estimated,
-		EstimatedSizeHuman:  humanize.Bytes(estimated),
+		EstimatedSize:       estimate.size,
+		EstimatedSizeHuman:  humanize.Bytes(estimate.size),
+		EstimatedLow:        estimate.low,
+		EstimatedHigh:       estimate.high,
+		EstimatedRangeHuman: humanize.Bytes(estimate.low) + " - " + humanize.Bytes(estimate.high),
+		EstimateSamples:     estimate.samples,
+		EstimateBackups:     estimate.backups,
+		rawEstimate:         estimate.raw,
 		AvailableSpace:      availableSpace,
 		AvailableSpaceHuman: availableSpaceHuman,
 		HasEnoughSpace:      hasEnoughSpace,
 	}
 
-	logger.Info("Backup validation: %d items, total=%s, estimated=%s, available=%s, hasSpace=%v",
-		len(items), validation.TotalSizeHuman, validation.EstimatedSizeHuman,
-		validation.AvailableSpaceHuman, validation.HasEnoughSpace)
+	logger.Logger().Info("Backup validation", "items", len(items), "warnings", len(warnings),
+		"total", validation.TotalSizeHuman, "estimated", validation.EstimatedSizeHuman, "range", validation.EstimatedRangeHuman,
+		"samples", estimate.samples, "pastBackups", estimate.backups,
+		"available", validation.AvailableSpaceHuman, "hasSpace", validation.HasEnoughSpace)
 
 	return validation, nil
 }
 
 // GetBackupFolders returns the list of mod folders that should be backed up
 func (a *Aurora) GetBackupFolders() ([]string, error) {
-	repo, err := repository.NewPenumbraRepository(a.cfg)
+	groups, err := a.backupGroups(LayoutSingle)
 	if err != nil {
 		return nil, err
 	}
+	return groups[0].Folders, nil
+}
 
-	folders := []string{}
-	for _, mod := range repo.Mods {
-		selected, excludedBy, includedBy := inBackupSet(&mod, a.cfg.Filters, a.cfg.Inclusions)
+// backupGroups returns the mod folders that should be backed up, split into
+// archive groups for layout
+func (a *Aurora) backupGroups(layout string) ([]archiveGroup, error) {
+	cfg := a.Config()
+	repo, err := repository.NewPenumbraRepository(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var mods []*repository.PenumbraMod
+	for i := range repo.Mods {
+		mod := &repo.Mods[i]
+		selected, excludedBy, includedBy := inBackupSet(mod, cfg.Filters, cfg.Inclusions)
 		if !selected {
 			if excludedBy != "" && len(mod.Collections) > 0 {
 				logger.Info("Mod excluded: %s (by %s)", mod.Name, excludedBy)
@@ -235,11 +296,14 @@ func (a *Aurora) GetBackupFolders() ([]string, error) {
 		if includedBy != "" {
 			logger.Info("Mod included by inclusion filter: %s (by %s)", mod.Name, includedBy)
 		}
-		folders = append(folders, filepath.Join(a.cfg.Mods.Path, mod.Name))
+		mods = append(mods, mod)
 	}
 
-	logger.Info("GetBackupFolders: %d folders to backup", len(folders))
-	return folders, nil
+	groups := groupMods(mods, layout, cfg.Filters, func(mod *repository.PenumbraMod) string {
+		return filepath.Join(cfg.Mods.Path, mod.Name)
+	})
+	logger.Info("Backup selection: %d folders to backup in %d archive groups (%s layout)", len(mods), len(groups), normalizeLayout(layout))
+	return groups, nil
 }
 
 // GetFilterMatches reports how many mods each filter pattern matches,
@@ -247,33 +311,34 @@ func (a *Aurora) GetBackupFolders() ([]string, error) {
 // they are decisive (mod has no collection, or an exclusion would drop it).
 // A count of 0 signals a dead filter.
 func (a *Aurora) GetFilterMatches() (FilterMatches, error) {
+	cfg := a.Config()
 	// Size-free load: counting only needs names and collection membership,
 	// and walking every mod folder for sizes dominates load time
-	repo, err := repository.NewPenumbraRepositoryNoSizes(a.cfg)
+	repo, err := repository.NewPenumbraRepositoryNoSizes(cfg)
 	if err != nil {
 		return FilterMatches{}, err
 	}
 
 	result := FilterMatches{
-		Filters:       make(map[string]int, len(a.cfg.Filters)),
-		Inclusions:    make(map[string]int, len(a.cfg.Inclusions)),
-		InclusionsAny: make(map[string]int, len(a.cfg.Inclusions)),
+		Filters:       make(map[string]int, len(cfg.Filters)),
+		Inclusions:    make(map[string]int, len(cfg.Inclusions)),
+		InclusionsAny: make(map[string]int, len(cfg.Inclusions)),
 	}
-	for _, f := range a.cfg.Filters {
+	for _, f := range cfg.Filters {
 		result.Filters[f] = 0
 	}
-	for _, f := range a.cfg.Inclusions {
+	for _, f := range cfg.Inclusions {
 		result.Inclusions[f] = 0
 		result.InclusionsAny[f] = 0
 	}
 
 	for _, mod := range repo.Mods {
-		for _, f := range a.cfg.Filters {
+		for _, f := range cfg.Filters {
 			if matched, _ := isModFiltered(&mod, []string{f}); matched {
 				result.Filters[f]++
 			}
 		}
-		for _, f := range a.cfg.Inclusions {
+		for _, f := range cfg.Inclusions {
 			matched, _ := isModIncluded(&mod, []string{f})
 			if !matched {
 				continue
@@ -283,7 +348,7 @@ func (a *Aurora) GetFilterMatches() (FilterMatches, error) {
 				result.Inclusions[f]++
 				continue
 			}
-			if excluded, _ := isModFiltered(&mod, a.cfg.Filters); excluded {
+			if excluded, _ := isModFiltered(&mod, cfg.Filters); excluded {
 				result.Inclusions[f]++ // rescue case
 			}
 		}
@@ -140,7 +140,7 @@ func TestNewBackupOptions(t *testing.T) {
 		threads := 4
 		quiet := true
 
-		opts := NewBackupOptions(folders, threads, CompressionMax, "", quiet)
+		opts := NewBackupOptions(folders, threads, FormatZip, CompressionMax, "", quiet)
 
 		if opts.OutputPath != BackupOutputPath {
 			t.Errorf("expected OutputPath %s, got %s", BackupOutputPath, opts.OutputPath)
@@ -163,7 +163,7 @@ func TestNewBackupOptions(t *testing.T) {
 	})
 
 	t.Run("quiet false", func(t *testing.T) {
-		opts := NewBackupOptions([]string{}, 1, CompressionMax, "", false)
+		opts := NewBackupOptions([]string{}, 1, FormatZip, CompressionMax, "", false)
 
 		if opts.Quiet {
 			t.Error("expected Quiet false")
@@ -171,19 +171,34 @@ func TestNewBackupOptions(t *testing.T) {
 	})
 
 	t.Run("compression presets map to levels", func(t *testing.T) {
-		if got := NewBackupOptions(nil, 1, CompressionNormal, "", true).Level; got != 5 {
+		if got := NewBackupOptions(nil, 1, FormatZip, CompressionNormal, "", true).Level; got != 5 {
 			t.Errorf("expected normal preset Level 5, got %d", got)
 		}
-		if got := NewBackupOptions(nil, 1, CompressionMax, "", true).Level; got != 9 {
+		if got := NewBackupOptions(nil, 1, FormatZip, CompressionMax, "", true).Level; got != 9 {
 			t.Errorf("expected max preset Level 9, got %d", got)
 		}
-		if got := NewBackupOptions(nil, 1, "", "", true).Level; got != 5 {
+		if got := NewBackupOptions(nil, 1, FormatZip, "", "", true).Level; got != 5 {
 			t.Errorf("expected empty preset to default to Level 5 (normal), got %d", got)
 		}
-		if got := NewBackupOptions(nil, 1, "garbage", "", true).Level; got != 5 {
+		if got := NewBackupOptions(nil, 1, FormatZip, "garbage", "", true).Level; got != 5 {
 			t.Errorf("expected unknown preset to default to Level 5 (normal), got %d", got)
 		}
 	})
+
+	t.Run("formats set the output name and levels", func(t *testing.T) {
+		opts := NewBackupOptions(nil, 1, FormatTarZst, CompressionMax, "", true)
+		if opts.OutputPath != "backup_part.tar.zst" || opts.UseZipFormat || opts.Level != 19 {
+			t.Errorf("unexpected tar.zst options %+v", opts)
+		}
+		opts = NewBackupOptions(nil, 1, FormatDelta, CompressionNormal, "", true)
+		if opts.OutputPath != "backup_part.gdelta" || opts.UseZipFormat || opts.Level != 3 {
+			t.Errorf("unexpected go-delta native options %+v", opts)
+		}
+		opts = NewBackupOptions(nil, 1, "rar", CompressionNormal, "", true)
+		if opts.OutputPath != BackupOutputPath || !opts.UseZipFormat {
+			t.Errorf("expected an unknown format to default to zip, got %+v", opts)
+		}
+	})
 }
 
 func TestInBackupSet(t *testing.T) {
@@ -302,14 +317,14 @@ func TestFilterCaseInsensitive(t *testing.T) {
 
 func TestNewBackupOptionsOutputDir(t *testing.T) {
 	t.Run("empty output dir keeps relative path", func(t *testing.T) {
-		opts := NewBackupOptions(nil, 1, CompressionNormal, "", true)
+		opts := NewBackupOptions(nil, 1, FormatZip, CompressionNormal, "", true)
 		if opts.OutputPath != BackupOutputPath {
 			t.Errorf("expected %q, got %q", BackupOutputPath, opts.OutputPath)
 		}
 	})
 
 	t.Run("output dir is joined into the path", func(t *testing.T) {
-		opts := NewBackupOptions(nil, 1, CompressionNormal, "/tmp/backups", true)
+		opts := NewBackupOptions(nil, 1, FormatZip, CompressionNormal, "/tmp/backups", true)
 		want := "/tmp/backups/" + BackupOutputPath
 		if opts.OutputPath != want {
 			t.Errorf("expected %q, got %q", want, opts.OutputPath)
@@ -0,0 +1,399 @@
+package aurora
+
+import (
+	"aurora/internal/logger"
+	"aurora/internal/paths"
+	"aurora/internal/repository"
+	"aurora/internal/util"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/creativeyann17/go-delta/pkg/compress"
+	"github.com/dustin/go-humanize"
+)
+
+// Errors of the cleanup flow, wrapped with the mod or quarantine at fault
+var (
+	ErrModNotFound        = errors.New("mod not found")
+	ErrModInUse           = errors.New("mod is used by a collection")
+	ErrQuarantineNotFound = errors.New("quarantine not found")
+	ErrRestoreConflict    = errors.New("a mod of the same name is in the mods folder")
+)
+
+// QuarantineArchiveDir is the directory under the output directory holding
+// the archives made before quarantines, one directory each named after its
+// quarantine
+const QuarantineArchiveDir = "quarantine"
+
+const (
+	quarantineFile    = "quarantine.json"
+	quarantineModsDir = "mods"
+)
+
+// UnusedMod is a mod no collection references
+type UnusedMod struct {
+	Name       string    `json:"name"`
+	Size       uint64    `json:"size"`
+	SizeHuman  string    `json:"sizeHuman"`
+	ModTime    time.Time `json:"modTime"`              // of its newest file
+	IncludedBy string    `json:"includedBy,omitempty"` // inclusion filter keeping it in backups
+}
+
+// UnusedModsResult lists the mods no collection references
+type UnusedModsResult struct {
+	Mods      []UnusedMod `json:"mods"` // largest first
+	Size      uint64      `json:"size"`
+	SizeHuman string      `json:"sizeHuman"`
+}
+
+// QuarantineOptions selects the mods QuarantineMods moves
+type QuarantineOptions struct {
+	Mods       []string // mod folder names, each unused
+	Backup     bool     // archive the mods to the output directory first
+	Passphrase string   // encrypts the archive when encryption is on (see ResolvePassphrase)
+}
+
+// Quarantine is a set of mods moved out of the mods folder together, kept
+// until it is restored or its directory deleted
+type Quarantine struct {
+	ID        string           `json:"id"` // creation time, 20060102-150405.000000
+	CreatedAt time.Time        `json:"createdAt"`
+	ModsPath  string           `json:"modsPath"` // where the mods came from
+	Mods      []QuarantinedMod `json:"mods"`
+	Size      uint64           `json:"size"`
+	SizeHuman string           `json:"sizeHuman"`
+	Dir       string           `json:"dir"`                 // holding the mods
+	BackupDir string           `json:"backupDir,omitempty"` // archive made first, "" = none
+}
+
+// QuarantinedMod is a mod of a Quarantine
+type QuarantinedMod struct {
+	Name string `json:"name"`
+	Size uint64 `json:"size"`
+}
+
+// UnusedMods lists the mods of the mods folder no collection references,
+// the candidates for a quarantine. Fails with ErrInvalidConfig.
+func (a *Aurora) UnusedMods() (UnusedModsResult, error) {
+	cfg := a.Config()
+	if !cfg.Status().Valid {
+		return UnusedModsResult{}, ErrInvalidConfig
+	}
+	repo, err := repository.NewPenumbraRepository(cfg)
+	if err != nil {
+		return UnusedModsResult{}, err
+	}
+	result := UnusedModsResult{Mods: []UnusedMod{}}
+	for i := range repo.Mods {
+		mod := &repo.Mods[i]
+		if len(mod.Collections) > 0 {
+			continue
+		}
+		_, includedBy := isModIncluded(mod, cfg.Inclusions)
+		result.Mods = append(result.Mods, UnusedMod{
+			Name:       mod.Name,
+			Size:       mod.Size,
+			SizeHuman:  humanize.Bytes(mod.Size),
+			ModTime:    newestModTime(filepath.Join(cfg.Mods.Path, mod.Path)),
+			IncludedBy: includedBy,
+		})
+		result.Size += mod.Size
+	}
+	slices.SortFunc(result.Mods, func(a, b UnusedMod) int {
+		return cmp.Or(cmp.Compare(b.Size, a.Size), strings.Compare(a.Name, b.Name))
+	})
+	result.SizeHuman = humanize.Bytes(result.Size)
+	return result, nil
+}
+
+// newestModTime returns the modification time of the newest file under
+// root, the folder's own when it holds none
+func newestModTime(root string) time.Time {
+	var newest time.Time
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+		return nil
+	})
+	if newest.IsZero() {
+		if info, err := os.Stat(root); err == nil {
+			newest = info.ModTime()
+		}
+	}
+	return newest
+}
+
+// QuarantineMods moves unused mods out of the mods folder into a new
+// quarantine, after archiving them to the output directory when asked.
+// Every mod is checked before anything moves: it fails with
+// ErrModNotFound or ErrModInUse, ErrInvalidConfig, or ErrPassphraseRequired
+// when the archive must be encrypted. A failed move stops the quarantine
+// with the mods moved so far, which it returns with the error.
+func (a *Aurora) QuarantineMods(ctx context.Context, opts QuarantineOptions) (Quarantine, error) {
+	cfg := a.Config()
+	if !cfg.Status().Valid {
+		return Quarantine{}, ErrInvalidConfig
+	}
+	if len(opts.Mods) == 0 {
+		return Quarantine{}, fmt.Errorf("no mods to quarantine")
+	}
+	repo, err := repository.NewPenumbraRepositoryNoSizes(cfg)
+	if err != nil {
+		return Quarantine{}, err
+	}
+	var names, folders []string
+	for _, name := range opts.Mods {
+		if slices.Contains(names, name) {
+			continue
+		}
+		i := slices.IndexFunc(repo.Mods, func(m repository.PenumbraMod) bool { return m.Name == name })
+		if i < 0 {
+			return Quarantine{}, fmt.Errorf("%w: %s", ErrModNotFound, name)
+		}
+		if len(repo.Mods[i].Collections) > 0 {
+			return Quarantine{}, fmt.Errorf("%w: %s", ErrModInUse, name)
+		}
+		names = append(names, name)
+		folders = append(folders, filepath.Join(cfg.Mods.Path, repo.Mods[i].Path))
+	}
+	if opts.Backup && cfg.Encrypt && opts.Passphrase == "" {
+		return Quarantine{}, ErrPassphraseRequired
+	}
+
+	now := time.Now()
+	q := Quarantine{
+		ID:        now.Format("20060102-150405.000000"),
+		CreatedAt: now,
+		ModsPath:  cfg.Mods.Path,
+		Mods:      []QuarantinedMod{},
+	}
+	q.Dir = filepath.Join(paths.QuarantineDir(), q.ID)
+	if err := os.MkdirAll(filepath.Dir(q.Dir), 0755); err != nil {
+		return Quarantine{}, fmt.Errorf("create quarantine: %w", err)
+	}
+	if err := os.Mkdir(q.Dir, 0755); err != nil {
+		return Quarantine{}, fmt.Errorf("create quarantine: %w", err)
+	}
+	if opts.Backup {
+		q.BackupDir, err = a.archiveMods(ctx, folders, q.ID, opts.Passphrase)
+		if err != nil {
+			os.RemoveAll(q.Dir)
+			return Quarantine{}, fmt.Errorf("back up mods: %w", err)
+		}
+	}
+
+	// The record is rewritten after each move, so a crash never strands a
+	// mod in a quarantine that doesn't list it
+	var moveErr error
+	for i, name := range names {
+		size, _ := scanFolder(folders[i])
+		if moveErr = moveDir(folders[i], filepath.Join(q.Dir, quarantineModsDir, name)); moveErr != nil {
+			moveErr = fmt.Errorf("move %s: %w", name, moveErr)
+			break
+		}
+		q.Mods = append(q.Mods, QuarantinedMod{Name: name, Size: size})
+		q.Size += size
+		q.SizeHuman = humanize.Bytes(q.Size)
+		if err := q.save(); err != nil {
+			return q, err
+		}
+		logger.Info("Quarantined mod %s in %s", name, q.Dir)
+	}
+	if len(q.Mods) == 0 {
+		os.RemoveAll(q.Dir)
+		return Quarantine{}, moveErr
+	}
+	return q, moveErr
+}
+
+// archiveMods writes folders to an archive of their own under the output
+// directory, in the configured format, and returns its directory
+func (a *Aurora) archiveMods(ctx context.Context, folders []string, id, passphrase string) (string, error) {
+	cfg := a.Config()
+	dir := filepath.Join(cfg.Output, QuarantineArchiveDir, id)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	if !cfg.Encrypt {
+		passphrase = ""
+	}
+	opts := NewBackupOptions(folders, cfg.Concurrency, a.GetFormat(), a.GetCompression(), dir, true)
+	if _, _, err := compressBatch(ctx, opts, passphrase, func(compress.ProgressEvent) {}); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	if cfg.Encrypt {
+		if err := encryptArchives(dir, passphrase); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+	logger.Info("Archived %d mods to %s before quarantine", len(folders), dir)
+	return dir, nil
+}
+
+func (q Quarantine) save() error {
+	data, err := json.MarshalIndent(q, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(q.Dir, quarantineFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write quarantine record: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("write quarantine record: %w", err)
+	}
+	return nil
+}
+
+// Quarantines lists the quarantines, newest first
+func (a *Aurora) Quarantines() ([]Quarantine, error) {
+	entries, err := os.ReadDir(paths.QuarantineDir())
+	if errors.Is(err, fs.ErrNotExist) {
+		return []Quarantine{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	quarantines := []Quarantine{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		q, err := loadQuarantine(entry.Name())
+		if err != nil {
+			logger.Warn("Skipping quarantine %s: %v", entry.Name(), err)
+			continue
+		}
+		quarantines = append(quarantines, q)
+	}
+	slices.SortFunc(quarantines, func(a, b Quarantine) int { return strings.Compare(b.ID, a.ID) })
+	return quarantines, nil
+}
+
+func loadQuarantine(id string) (Quarantine, error) {
+	dir := filepath.Join(paths.QuarantineDir(), id)
+	var q Quarantine
+	if err := util.ReadJSONFile(filepath.Join(dir, quarantineFile), &q); err != nil {
+		return Quarantine{}, err
+	}
+	q.Dir = dir // follows AURORA_HOME moves
+	return q, nil
+}
+
+// RestoreQuarantine moves the mods of quarantine id back to the mods
+// folder and deletes the quarantine; its archive, if any, is kept. Fails
+// with ErrQuarantineNotFound, or ErrRestoreConflict before moving
+// anything when the mods folder has a mod of the same name.
+func (a *Aurora) RestoreQuarantine(id string) (Quarantine, error) {
+	cfg := a.Config()
+	if !cfg.Status().Valid {
+		return Quarantine{}, ErrInvalidConfig
+	}
+	if id == "" || filepath.Base(id) != id {
+		return Quarantine{}, fmt.Errorf("%w: %s", ErrQuarantineNotFound, id)
+	}
+	q, err := loadQuarantine(id)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Quarantine{}, fmt.Errorf("%w: %s", ErrQuarantineNotFound, id)
+	}
+	if err != nil {
+		return Quarantine{}, err
+	}
+	for _, mod := range q.Mods {
+		if _, err := os.Lstat(filepath.Join(cfg.Mods.Path, mod.Name)); err == nil {
+			return Quarantine{}, fmt.Errorf("%w: %s", ErrRestoreConflict, mod.Name)
+		}
+	}
+	for i, mod := range q.Mods {
+		if err := moveDir(filepath.Join(q.Dir, quarantineModsDir, mod.Name), filepath.Join(cfg.Mods.Path, mod.Name)); err != nil {
+			// Keep the record true to what is left
+			restored := q.Mods[:i]
+			q.Mods = q.Mods[i:]
+			for _, m := range restored {
+				q.Size -= m.Size
+			}
+			q.SizeHuman = humanize.Bytes(q.Size)
+			q.save()
+			return q, fmt.Errorf("restore %s: %w", mod.Name, err)
+		}
+		logger.Info("Restored mod %s from quarantine %s", mod.Name, q.ID)
+	}
+	if err := os.RemoveAll(q.Dir); err != nil {
+		logger.Warn("Failed to remove quarantine %s: %v", q.Dir, err)
+	}
+	return q, nil
+}
+
+// moveDir moves a directory, copying it when it goes to another drive
+func moveDir(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	err := os.Rename(src, dst)
+	if err == nil || !errors.Is(err, errCrossDevice) {
+		return err
+	}
+	if err := copyDir(src, dst); err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// copyDir copies the tree at src to dst, keeping file modes and times
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		if err := out.Close(); err != nil {
+			return err
+		}
+		return os.Chtimes(target, info.ModTime(), info.ModTime())
+	})
+}
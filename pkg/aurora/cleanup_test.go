@@ -0,0 +1,170 @@
+package aurora
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// cleanupMods puts "Body Mod" in a collection, and "Unused Mod" and
+// "Old Mod" in none
+var (
+	cleanupMods = map[string]map[string]string{
+		"Unused Mod": {"a.tex": "data"},
+		"Body Mod":   {"chara/a.tex": "texture"},
+		"Old Mod":    {"chara/a.tex": "old texture data"},
+	}
+	cleanupCollections = map[string][]string{"Main": {"Body Mod"}}
+)
+
+func TestUnusedMods(t *testing.T) {
+	a := newTestAurora(t, cleanupMods, cleanupCollections)
+	configure(t, a.AddInclusion("Unused"))
+	old := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(a.Config().Mods.Path, "Old Mod", "chara", "a.tex"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	result, err := a.UnusedMods()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Mods) != 2 || result.Size != 20 {
+		t.Fatalf("expected 2 unused mods of 20 bytes, got %+v", result)
+	}
+	oldMod, unused := result.Mods[0], result.Mods[1]
+	if oldMod.Name != "Old Mod" || oldMod.Size != 16 || oldMod.IncludedBy != "" {
+		t.Errorf("expected the largest mod first, got %+v", oldMod)
+	}
+	if !oldMod.ModTime.Equal(old) {
+		t.Errorf("unexpected modification time %v", oldMod.ModTime)
+	}
+	if unused.Name != "Unused Mod" || unused.IncludedBy != "Unused" {
+		t.Errorf("expected the inclusion keeping Unused Mod, got %+v", unused)
+	}
+}
+
+func TestQuarantineMods(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("checks every mod before moving any", func(t *testing.T) {
+		a := newTestAurora(t, cleanupMods, cleanupCollections)
+		if _, err := a.QuarantineMods(ctx, QuarantineOptions{Mods: []string{"Old Mod", "Body Mod"}}); !errors.Is(err, ErrModInUse) {
+			t.Errorf("expected ErrModInUse, got %v", err)
+		}
+		if _, err := a.QuarantineMods(ctx, QuarantineOptions{Mods: []string{"Old Mod", "Missing"}}); !errors.Is(err, ErrModNotFound) {
+			t.Errorf("expected ErrModNotFound, got %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(a.Config().Mods.Path, "Old Mod")); err != nil {
+			t.Error("expected no mod moved")
+		}
+		configure(t, a.SetEncrypt(true))
+		if _, err := a.QuarantineMods(ctx, QuarantineOptions{Mods: []string{"Old Mod"}, Backup: true}); !errors.Is(err, ErrPassphraseRequired) {
+			t.Errorf("expected ErrPassphraseRequired, got %v", err)
+		}
+	})
+
+	t.Run("moves mods out and restores them", func(t *testing.T) {
+		a := newTestAurora(t, cleanupMods, cleanupCollections)
+		q, err := a.QuarantineMods(ctx, QuarantineOptions{Mods: []string{"Old Mod", "Unused Mod", "Old Mod"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []QuarantinedMod{{Name: "Old Mod", Size: 16}, {Name: "Unused Mod", Size: 4}}
+		if !slices.Equal(q.Mods, want) || q.Size != 20 || q.BackupDir != "" {
+			t.Errorf("unexpected quarantine %+v", q)
+		}
+		if _, err := os.Stat(filepath.Join(a.Config().Mods.Path, "Old Mod")); !errors.Is(err, os.ErrNotExist) {
+			t.Error("expected Old Mod out of the mods folder")
+		}
+		if got, _ := os.ReadFile(filepath.Join(q.Dir, "mods", "Old Mod", "chara", "a.tex")); string(got) != "old texture data" {
+			t.Errorf("expected the mod in the quarantine, got %q", got)
+		}
+		if unused, _ := a.UnusedMods(); len(unused.Mods) != 0 {
+			t.Errorf("expected no unused mod left, got %+v", unused.Mods)
+		}
+
+		quarantines, err := a.Quarantines()
+		if err != nil || len(quarantines) != 1 || quarantines[0].ID != q.ID || !slices.Equal(quarantines[0].Mods, want) {
+			t.Fatalf("expected the quarantine listed, got %+v, %v", quarantines, err)
+		}
+
+		os.MkdirAll(filepath.Join(a.Config().Mods.Path, "Unused Mod"), 0755)
+		if _, err := a.RestoreQuarantine(q.ID); !errors.Is(err, ErrRestoreConflict) {
+			t.Errorf("expected ErrRestoreConflict, got %v", err)
+		}
+		os.Remove(filepath.Join(a.Config().Mods.Path, "Unused Mod"))
+		if _, err := a.RestoreQuarantine(q.ID); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := os.ReadFile(filepath.Join(a.Config().Mods.Path, "Old Mod", "chara", "a.tex")); string(got) != "old texture data" {
+			t.Errorf("expected Old Mod back, got %q", got)
+		}
+		if quarantines, _ := a.Quarantines(); len(quarantines) != 0 {
+			t.Errorf("expected the quarantine gone, got %+v", quarantines)
+		}
+		if _, err := a.RestoreQuarantine(q.ID); !errors.Is(err, ErrQuarantineNotFound) {
+			t.Errorf("expected ErrQuarantineNotFound, got %v", err)
+		}
+		if _, err := a.RestoreQuarantine("../" + q.ID); !errors.Is(err, ErrQuarantineNotFound) {
+			t.Errorf("expected a path to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("archives mods first when asked", func(t *testing.T) {
+		fastScrypt(t)
+		a := newTestAurora(t, cleanupMods, cleanupCollections)
+		configure(t, a.SetFormat(FormatTarZst), a.SetEncrypt(true))
+		q, err := a.QuarantineMods(ctx, QuarantineOptions{Mods: []string{"Old Mod"}, Backup: true, Passphrase: "secret"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q.BackupDir != filepath.Join(a.Config().Output, QuarantineArchiveDir, q.ID) {
+			t.Errorf("unexpected archive directory %q", q.BackupDir)
+		}
+		archive := filepath.Join(q.BackupDir, singleArchive+".tar.zst"+EncryptedExt)
+		r, err := OpenBackupFile(archive, "secret")
+		if err != nil {
+			t.Fatalf("expected an encrypted archive: %v", err)
+		}
+		r.Close()
+		if _, err := a.RestoreQuarantine(q.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(archive); err != nil {
+			t.Error("expected the archive kept after a restore")
+		}
+	})
+
+	t.Run("gives back-to-back quarantines their own ids", func(t *testing.T) {
+		a := newTestAurora(t, cleanupMods, cleanupCollections)
+		first, err := a.QuarantineMods(ctx, QuarantineOptions{Mods: []string{"Old Mod"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := a.QuarantineMods(ctx, QuarantineOptions{Mods: []string{"Unused Mod"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first.ID == second.ID {
+			t.Errorf("expected distinct ids, got %q twice", first.ID)
+		}
+	})
+}
+
+// TestCopyDir covers the fallback of moveDir across drives
+func TestCopyDir(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "Mod")
+	os.MkdirAll(filepath.Join(src, "chara"), 0755)
+	os.WriteFile(filepath.Join(src, "chara", "a.tex"), []byte("texture"), 0644)
+	dst := filepath.Join(t.TempDir(), "moved", "Mod")
+	if err := copyDir(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dst, "chara", "a.tex")); string(got) != "texture" {
+		t.Errorf("expected the tree copied, got %q", got)
+	}
+}
@@ -0,0 +1,565 @@
+package aurora
+
+import (
+	"aurora/internal/logger"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/creativeyann17/go-delta/pkg/compress"
+	"github.com/dustin/go-humanize"
+)
+
+// ErrBackupCancelled is returned when a backup is stopped through its context
+var ErrBackupCancelled = errors.New("backup cancelled")
+
+// ErrNoPendingBackup is returned when resuming and there is nothing to resume
+var ErrNoPendingBackup = errors.New("no interrupted backup to resume")
+
+// ErrFinalizingBackup is returned when starting over or discarding a backup
+// interrupted while its parts were moved into place: it must be resumed
+var ErrFinalizingBackup = errors.New("an interrupted backup was moving its archives into place, resume it to finish")
+
+// batchTargetBytes bounds the mods compressed in one go-delta call. An
+// archive is only readable once its call finishes, so a batch is the most
+// work an interruption loses.
+var batchTargetBytes uint64 = 1 << 30
+
+// backupLayout is how a backup splits its archives
+type backupLayout struct {
+	name        string         // see LayoutSingle
+	groups      []archiveGroup // nil = a single group of every folder
+	maxPartSize uint64         // 0 = no limit
+	incremental bool           // only some mods, in a directory of their own (see IncrementalDir)
+}
+
+// startBackup runs a new backup of opts.Files, discarding any interrupted one.
+// Mods are compressed in batches staged under StagingDir and recorded in a
+// journal, so a failed or cancelled run can continue with resumeBackup; the parts
+// replace the previous backup once every batch is done. validation is the
+// preview of the backup: its uncalibrated size estimate is recorded in the
+// manifest, its items and warnings in the report. A passphrase ("" = none)
+// encrypts the parts, the manifest and the report.
+// progressCb (may be nil) receives throttled snapshots from any goroutine.
+func startBackup(ctx context.Context, opts *compress.Options, layout backupLayout, validation BackupValidation, passphrase string, progressCb func(BackupProgress)) (BackupResult, error) {
+	outputDir := filepath.Dir(opts.OutputPath)
+	staging := stagingPath(outputDir)
+	if previous, err := loadJournal(outputDir); err == nil && previous != nil && previous.Finalizing {
+		return BackupResult{}, ErrFinalizingBackup
+	}
+	if err := os.RemoveAll(staging); err != nil {
+		return BackupResult{}, fmt.Errorf("remove interrupted backup %s: %w", staging, err)
+	}
+	if err := os.MkdirAll(staging, 0755); err != nil {
+		return BackupResult{}, fmt.Errorf("create staging dir %s: %w", staging, err)
+	}
+
+	j := &journal{
+		Version:     journalVersion,
+		StartedAt:   time.Now(),
+		Format:      formatOfOptions(opts).name,
+		Level:       opts.Level,
+		Estimate:    validation.rawEstimate,
+		Folders:     opts.Files,
+		Layout:      normalizeLayout(layout.name),
+		MaxPartSize: layout.maxPartSize,
+		Groups:      layout.groups,
+		Encrypted:   passphrase != "",
+		Incremental: layout.incremental,
+		Items:       validation.Items,
+		Warnings:    validation.Warnings,
+	}
+	if err := j.save(outputDir); err != nil {
+		return BackupResult{}, err
+	}
+	return runJournal(ctx, j, opts, passphrase, progressCb)
+}
+
+// resumeBackup continues the interrupted backup in the directory of
+// opts.OutputPath. Finished batches are kept after their archives pass
+// verification; the mods, format, compression level and layout come from
+// the journal, so opts.Files, the format and opts.Level are ignored. An encrypted backup
+// needs the passphrase it was started with.
+func resumeBackup(ctx context.Context, opts *compress.Options, passphrase string, progressCb func(BackupProgress)) (BackupResult, error) {
+	outputDir := filepath.Dir(opts.OutputPath)
+	j, err := loadJournal(outputDir)
+	if err != nil {
+		return BackupResult{}, err
+	}
+	if j == nil {
+		return BackupResult{}, ErrNoPendingBackup
+	}
+	if err := j.checkPassphrase(outputDir, passphrase); err != nil {
+		return BackupResult{}, err
+	}
+
+	// A finalizing backup's parts may be in the output directory already:
+	// finalizeBackup checks them as it moves the rest
+	if !j.Finalizing {
+		j.verify(outputDir)
+		if err := j.save(outputDir); err != nil {
+			return BackupResult{}, err
+		}
+	}
+	logger.Info("Resuming backup started %s: %d/%d mods already done",
+		j.StartedAt.Format(time.DateTime), len(j.doneFolders()), len(j.Folders))
+	return runJournal(ctx, j, opts, passphrase, progressCb)
+}
+
+// runJournal compresses the folders j has not finished yet, then moves the
+// staged parts into place
+func runJournal(ctx context.Context, j *journal, opts *compress.Options, passphrase string, progressCb func(BackupProgress)) (BackupResult, error) {
+	outputDir := filepath.Dir(opts.OutputPath)
+	staging := stagingPath(outputDir)
+	removeUnfinishedBatches(staging, j)
+
+	var finishedCompressed uint64
+	for _, batch := range j.Batches {
+		finishedCompressed += batch.CompressedSize
+	}
+	tracker := newProgressTracker(len(j.Folders), len(j.doneFolders()), finishedCompressed)
+	emit := func() {
+		if progressCb != nil {
+			progressCb(tracker.snapshot())
+		}
+	}
+
+	pending := j.pendingFolders()
+	sizes := make(map[string]uint64, len(pending))
+	for _, folder := range pending {
+		bytes, files := scanFolder(folder)
+		sizes[folder] = bytes
+		tracker.addMod(folder, files, bytes)
+	}
+	emit()
+
+	// A batch never spans groups: its archives belong to one group
+	type plannedBatch struct {
+		group   string
+		folders []string
+	}
+	var batches []plannedBatch
+	target := batchTarget(j.MaxPartSize)
+	for _, group := range j.groups() {
+		var folders []string
+		var folderSizes []uint64
+		for _, folder := range group.Folders {
+			if slices.Contains(pending, folder) {
+				folders = append(folders, folder)
+				folderSizes = append(folderSizes, sizes[folder])
+			}
+		}
+		for _, batch := range planBatches(folders, folderSizes, target) {
+			batches = append(batches, plannedBatch{group: group.Archive, folders: batch})
+		}
+	}
+
+	// Batch dirs carry the run: a cancelled go-delta call still winding
+	// down writes to and then deletes its own dir, never one of this run
+	run := time.Now().Format("20060102-150405.000000")
+	for _, planned := range batches {
+		if ctx.Err() != nil {
+			return BackupResult{}, ErrBackupCancelled
+		}
+
+		folders := planned.folders
+		batch := journalBatch{Dir: nextBatchDir(j, run), Group: planned.group, Folders: folders}
+		batchDir := filepath.Join(staging, batch.Dir)
+		if err := os.MkdirAll(batchDir, 0755); err != nil {
+			return BackupResult{}, fmt.Errorf("create batch dir %s: %w", batchDir, err)
+		}
+		logger.Debug("Compressing backup batch %s: %d mods of group %q", batch.Dir, len(folders), planned.group)
+
+		format := j.format()
+		batchOpts := *opts
+		batchOpts.Files = folders
+		batchOpts.Level = j.Level
+		batchOpts.UseZipFormat = format.name == FormatZip
+		batchOpts.OutputPath = filepath.Join(batchDir, singleArchive+format.ext)
+
+		// Compressors don't report compressed sizes until the batch ends:
+		// sample the archives being written instead
+		sampling := make(chan struct{})
+		go func() {
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-sampling:
+					return
+				case <-ticker.C:
+					tracker.setBatchCompressed(archivesSize(batchDir))
+				}
+			}
+		}()
+		original, compressed, err := compressBatch(ctx, &batchOpts, passphrase, func(event compress.ProgressEvent) {
+			if tracker.handle(event) {
+				emit()
+			}
+		})
+		close(sampling)
+		if err != nil {
+			if !errors.Is(err, ErrBackupCancelled) {
+				os.RemoveAll(batchDir)
+			}
+			return BackupResult{}, err
+		}
+
+		// tar.zst and ZIP batches come out encrypted; gdelta archives come
+		// plain from go-delta, encrypted (and the plain copies deleted)
+		// before the batch counts as done, so a resumed backup never keeps
+		// plain parts
+		if j.Encrypted {
+			if err := encryptArchives(batchDir, passphrase); err != nil {
+				os.RemoveAll(batchDir)
+				return BackupResult{}, err
+			}
+		}
+		batch.Parts, err = describeParts(batchDir)
+		if err != nil {
+			return BackupResult{}, fmt.Errorf("record batch %s: %w", batch.Dir, err)
+		}
+		batch.OriginalSize, batch.CompressedSize = original, compressed
+		j.Batches = append(j.Batches, batch)
+		if err := j.save(outputDir); err != nil {
+			return BackupResult{}, err
+		}
+		tracker.batchDone(folders, compressed)
+		emit()
+		logger.Info("Backup batch %s done: %d mods", batch.Dir, len(folders))
+	}
+
+	groups, checksums, err := finalizeBackup(outputDir, j)
+	if err != nil {
+		return BackupResult{}, err
+	}
+	var parts []string
+	for _, group := range groups {
+		parts = append(parts, group.Parts...)
+	}
+	var originalSize, compressedSize uint64
+	for _, batch := range j.Batches {
+		originalSize += batch.OriginalSize
+		compressedSize += batch.CompressedSize
+	}
+
+	mods := make([]string, len(j.Folders))
+	for i, folder := range j.Folders {
+		mods[i] = filepath.Base(folder)
+	}
+	manifest := BackupManifest{
+		Version:        manifestVersion,
+		StartedAt:      j.StartedAt,
+		FinishedAt:     time.Now(),
+		Format:         j.format().name,
+		Level:          j.Level,
+		Mods:           mods,
+		Parts:          parts,
+		OriginalSize:   originalSize,
+		CompressedSize: compressedSize,
+		Estimate:       j.Estimate,
+		Layout:         j.Layout,
+		MaxPartSize:    j.MaxPartSize,
+		Groups:         groups,
+		Encrypted:      j.Encrypted,
+		Incremental:    j.Incremental,
+		Checksums:      checksums,
+	}
+	// Until the manifest lists the new set, the finalizing journal is what
+	// describes outputDir: a rerun finishes from it. The previous archives
+	// the new set doesn't replace go after.
+	if err := writeManifest(outputDir, manifest, passphrase); err != nil {
+		return BackupResult{}, err
+	}
+	if err := removeStaleArchives(outputDir, parts); err != nil {
+		return BackupResult{}, err
+	}
+	if err := os.RemoveAll(staging); err != nil {
+		logger.Warn("Failed to remove staging dir %s: %v", staging, err)
+	}
+	// The history is a side record, as with TakeSnapshot: the backup on
+	// disk is complete whether it is kept or not
+	if err := keepManifest(manifest); err != nil {
+		logger.Warn("Failed to keep the backup manifest in the history: %v", err)
+	}
+	writeReport(outputDir, newBackupReport(j, manifest, outputDir), passphrase)
+	return NewBackupResult(outputDir, originalSize, compressedSize), nil
+}
+
+// compressBatch writes one batch in the format of opts.OutputPath until it
+// finishes or ctx is done: a tar.zst here, encrypted on the way when
+// passphrase isn't "", as is a ZIP with a passphrase; plain ZIPs and
+// gdelta archives through go-delta, which only writes plain files.
+// go-delta can't be interrupted, so on cancellation it returns
+// ErrBackupCancelled at once, drops further progress events, and deletes
+// the batch's partial archives; compression winds down in the background
+// and a last cleanup of the batch dir runs when it stops. The batch dir
+// must be unique to the run (see nextBatchDir), as a backup can start
+// again before that.
+func compressBatch(ctx context.Context, opts *compress.Options, passphrase string, progressCb func(compress.ProgressEvent)) (originalSize, compressedSize uint64, err error) {
+	var cancelled atomic.Bool
+	callback := func(event compress.ProgressEvent) {
+		if !cancelled.Load() {
+			progressCb(event)
+		}
+	}
+
+	type outcome struct {
+		originalSize, compressedSize uint64
+		err                          error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		switch format := formatOfOptions(opts).name; {
+		case format == FormatTarZst:
+			original, compressed, err := writeTarZst(ctx, opts, passphrase, callback)
+			done <- outcome{originalSize: original, compressedSize: compressed, err: err}
+			return
+		case format == FormatZip && passphrase != "":
+			original, compressed, err := writeEncryptedZip(ctx, opts, passphrase, callback)
+			done <- outcome{originalSize: original, compressedSize: compressed, err: err}
+			return
+		}
+		result, err := compress.Compress(opts, callback)
+		if err != nil {
+			done <- outcome{err: err}
+			return
+		}
+		done <- outcome{originalSize: result.OriginalSize, compressedSize: result.CompressedSize}
+	}()
+
+	select {
+	case out := <-done:
+		return out.originalSize, out.compressedSize, out.err
+	case <-ctx.Done():
+	}
+
+	// Finished just as the cancel came in: keep the batch
+	select {
+	case out := <-done:
+		return out.originalSize, out.compressedSize, out.err
+	default:
+	}
+
+	cancelled.Store(true)
+	batchDir := filepath.Dir(opts.OutputPath)
+	logger.Warn("Backup cancelled, removing partial archives in %s", batchDir)
+	os.RemoveAll(batchDir) // may fail while go-delta holds the files open
+	go func() {
+		<-done
+		os.RemoveAll(batchDir)
+		logger.Info("Cancelled backup stopped")
+	}()
+	return 0, 0, ErrBackupCancelled
+}
+
+// batchTarget returns the batch size for a max part size (0 = no limit):
+// a batch is compressed on its own, so its archives hold at most its files
+func batchTarget(maxPartSize uint64) uint64 {
+	if maxPartSize == 0 {
+		return batchTargetBytes
+	}
+	limit := uint64(float64(maxPartSize) * (1 - partSizeHeadroom))
+	return max(min(batchTargetBytes, limit), 1)
+}
+
+// planBatches groups folders in order into batches of about target bytes;
+// a folder bigger than target gets a batch of its own
+func planBatches(folders []string, sizes []uint64, target uint64) [][]string {
+	var batches [][]string
+	var current []string
+	var currentSize uint64
+	for i, folder := range folders {
+		if len(current) > 0 && currentSize+sizes[i] > target {
+			batches = append(batches, current)
+			current, currentSize = nil, 0
+		}
+		current = append(current, folder)
+		currentSize += sizes[i]
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+	return batches
+}
+
+// nextBatchDir returns a batch dir name of run no finished batch uses
+func nextBatchDir(j *journal, run string) string {
+	for n := len(j.Batches); ; n++ {
+		name := fmt.Sprintf("batch_%s_%03d", run, n)
+		if !slices.ContainsFunc(j.Batches, func(b journalBatch) bool { return b.Dir == name }) {
+			return name
+		}
+	}
+}
+
+// removeUnfinishedBatches deletes batch dirs the journal doesn't list:
+// leftovers of the batch that was running when the backup stopped
+func removeUnfinishedBatches(staging string, j *journal) {
+	entries, _ := os.ReadDir(staging)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if !slices.ContainsFunc(j.Batches, func(b journalBatch) bool { return b.Dir == entry.Name() }) {
+			os.RemoveAll(filepath.Join(staging, entry.Name()))
+		}
+	}
+}
+
+// finalizeBackup moves the staged parts into outputDir, numbered across the
+// batches of each group and over same-named archives of the previous
+// backup, and returns the archives of every group with their SHA-256.
+// The names are recorded in the journal, marked finalizing, before the
+// first move: a rerun moves the parts left and keeps those already moved.
+// The staging dir stays until the new manifest is written, and other
+// archives of the previous backup for removeStaleArchives.
+func finalizeBackup(outputDir string, j *journal) ([]ManifestGroup, map[string]string, error) {
+	staging := stagingPath(outputDir)
+
+	if !j.Finalizing {
+		for _, group := range j.groups() {
+			var count int
+			for _, batch := range j.Batches {
+				if batch.archive() == group.Archive {
+					count += len(batch.Parts)
+				}
+			}
+			names := partNames(group.Archive, j.format().ext, count)
+			for i := range j.Batches {
+				if j.Batches[i].archive() != group.Archive {
+					continue
+				}
+				for k := range j.Batches[i].Parts {
+					j.Batches[i].Parts[k].Dest = names[0]
+					if j.Encrypted {
+						j.Batches[i].Parts[k].Dest += EncryptedExt
+					}
+					names = names[1:]
+				}
+			}
+		}
+		j.Finalizing = true
+		if err := j.save(outputDir); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var groups []ManifestGroup
+	checksums := make(map[string]string)
+	for _, group := range j.groups() {
+		var names []string
+		for _, batch := range j.Batches {
+			if batch.archive() != group.Archive {
+				continue
+			}
+			for _, part := range batch.Parts {
+				src, dest := filepath.Join(staging, batch.Dir, part.Name), filepath.Join(outputDir, part.Dest)
+				if err := os.Rename(src, dest); err != nil {
+					if !errors.Is(err, os.ErrNotExist) {
+						return nil, nil, fmt.Errorf("move backup part %s: %w", src, err)
+					}
+					// Moved by the interrupted run
+					if err := verifyPart(dest, part); err != nil {
+						return nil, nil, fmt.Errorf("move backup part %s: %w", src, err)
+					}
+				}
+				names = append(names, part.Dest)
+				checksums[part.Dest] = part.SHA256
+				// Only a mod bigger than the limit can do this: mods aren't split
+				if j.MaxPartSize > 0 && uint64(part.Size) > j.MaxPartSize {
+					logger.Warn("Backup archive %s is %s, over the max part size of %s: it holds a mod bigger than the limit",
+						part.Dest, humanize.Bytes(uint64(part.Size)), humanize.Bytes(j.MaxPartSize))
+				}
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+
+		mods := make([]string, len(group.Folders))
+		for i, folder := range group.Folders {
+			mods[i] = filepath.Base(folder)
+		}
+		groups = append(groups, ManifestGroup{Name: group.Name, Mods: mods, Parts: names})
+	}
+	return groups, checksums, nil
+}
+
+// removeStaleArchives deletes the archives in outputDir, of any layout,
+// that parts doesn't list: those of the previous backup
+func removeStaleArchives(outputDir string, parts []string) error {
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !isBackupArchive(entry.Name()) || slices.Contains(parts, entry.Name()) {
+			continue
+		}
+		part := filepath.Join(outputDir, entry.Name())
+		if err := os.Remove(part); err != nil {
+			return fmt.Errorf("remove previous backup %s: %w", part, err)
+		}
+	}
+	return nil
+}
+
+// encryptArchives replaces the plain archives of a batch dir with encrypted
+// copies
+func encryptArchives(batchDir, passphrase string) error {
+	if passphrase == "" {
+		return ErrPassphraseRequired
+	}
+	names, err := stagedArchives(batchDir)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if strings.HasSuffix(name, EncryptedExt) {
+			continue // encrypted as it was written
+		}
+		if err := encryptInPlace(filepath.Join(batchDir, name), passphrase); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// archivesSize returns the size of the archives in a batch dir
+func archivesSize(batchDir string) uint64 {
+	names, _ := stagedArchives(batchDir)
+	var size uint64
+	for _, name := range names {
+		if info, err := os.Stat(filepath.Join(batchDir, name)); err == nil {
+			size += uint64(info.Size())
+		}
+	}
+	return size
+}
+
+// scanFolder returns the size and file count of a mod folder
+func scanFolder(root string) (bytes uint64, files int64) {
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			logger.Warn("Cannot access path %s: %v", path, err)
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			bytes += uint64(info.Size())
+			files++
+		}
+		return nil
+	})
+	return bytes, files
+}
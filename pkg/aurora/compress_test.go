@@ -0,0 +1,391 @@
+package aurora
+
+import (
+	"aurora/internal/config"
+	"aurora/internal/paths"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// stageBatch writes a finished batch with the given archives to the
+// staging dir of outputDir and returns its journal entry
+func stageBatch(t *testing.T, outputDir, dir string, folders []string, archives map[string]string) journalBatch {
+	t.Helper()
+	batchDir := filepath.Join(stagingPath(outputDir), dir)
+	if err := os.MkdirAll(batchDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range archives {
+		if err := os.WriteFile(filepath.Join(batchDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	parts, err := describeParts(batchDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return journalBatch{Dir: dir, Folders: folders, Parts: parts, OriginalSize: 100, CompressedSize: 40}
+}
+
+func TestPlanBatches(t *testing.T) {
+	folders := []string{"a", "b", "c", "d", "e"}
+	sizes := []uint64{40, 50, 200, 10, 10}
+
+	got := planBatches(folders, sizes, 100)
+	want := [][]string{{"a", "b"}, {"c"}, {"d", "e"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("planBatches = %v, want %v", got, want)
+	}
+
+	if got := planBatches(nil, nil, 100); got != nil {
+		t.Errorf("expected no batches for no folders, got %v", got)
+	}
+}
+
+func TestNextBatchDir(t *testing.T) {
+	j := &journal{Batches: []journalBatch{{Dir: "batch_000"}, {Dir: "batch_run1_001"}}}
+	if got := nextBatchDir(j, "run1"); got != "batch_run1_002" {
+		t.Errorf("expected the next free number, got %q", got)
+	}
+	// A resumed run never reuses the dir of a batch the previous run
+	// may still be writing
+	if first, second := nextBatchDir(&journal{}, "run1"), nextBatchDir(&journal{}, "run2"); first == second {
+		t.Errorf("expected the runs to get their own dirs, both got %q", first)
+	}
+}
+
+func TestJournal(t *testing.T) {
+	t.Run("round trips and tracks pending folders", func(t *testing.T) {
+		outputDir := t.TempDir()
+		os.MkdirAll(stagingPath(outputDir), 0755)
+
+		j := &journal{
+			Version:   journalVersion,
+			StartedAt: time.Now().Truncate(time.Second),
+			Level:     9,
+			Folders:   []string{"a", "b", "c"},
+		}
+		j.Batches = append(j.Batches, stageBatch(t, outputDir, "batch_000", []string{"a"}, map[string]string{"backup_part.zip": "one"}))
+		if err := j.save(outputDir); err != nil {
+			t.Fatal(err)
+		}
+
+		loaded, err := loadJournal(outputDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !loaded.StartedAt.Equal(j.StartedAt) {
+			t.Errorf("loaded StartedAt = %v, want %v", loaded.StartedAt, j.StartedAt)
+		}
+		loaded.StartedAt = j.StartedAt
+		if !reflect.DeepEqual(loaded, j) {
+			t.Errorf("loaded journal = %+v, want %+v", loaded, j)
+		}
+		if got := loaded.pendingFolders(); !reflect.DeepEqual(got, []string{"b", "c"}) {
+			t.Errorf("pendingFolders = %v", got)
+		}
+	})
+
+	t.Run("missing journal is not an error", func(t *testing.T) {
+		j, err := loadJournal(t.TempDir())
+		if j != nil || err != nil {
+			t.Errorf("loadJournal = %v, %v; want nil, nil", j, err)
+		}
+	})
+
+	t.Run("verify drops batches with missing or changed parts", func(t *testing.T) {
+		outputDir := t.TempDir()
+		j := &journal{Version: journalVersion, Folders: []string{"a", "b", "c"}}
+		j.Batches = []journalBatch{
+			stageBatch(t, outputDir, "batch_000", []string{"a"}, map[string]string{"backup_part.zip": "intact"}),
+			stageBatch(t, outputDir, "batch_001", []string{"b"}, map[string]string{"backup_part.zip": "original"}),
+			stageBatch(t, outputDir, "batch_002", []string{"c"}, map[string]string{"backup_part.zip": "gone"}),
+		}
+		// Same size, different content: only the checksum catches it
+		os.WriteFile(filepath.Join(stagingPath(outputDir), "batch_001", "backup_part.zip"), []byte("tampered"), 0644)
+		os.Remove(filepath.Join(stagingPath(outputDir), "batch_002", "backup_part.zip"))
+
+		j.verify(outputDir)
+
+		if len(j.Batches) != 1 || j.Batches[0].Dir != "batch_000" {
+			t.Fatalf("expected only batch_000 kept, got %+v", j.Batches)
+		}
+		if got := j.pendingFolders(); !reflect.DeepEqual(got, []string{"b", "c"}) {
+			t.Errorf("pendingFolders = %v", got)
+		}
+		if _, err := os.Stat(filepath.Join(stagingPath(outputDir), "batch_001")); err == nil {
+			t.Error("expected the failed batch dir removed")
+		}
+	})
+}
+
+func TestResumeBackup(t *testing.T) {
+	t.Run("fails without an interrupted backup", func(t *testing.T) {
+		opts := NewBackupOptions(nil, 1, FormatZip, CompressionNormal, t.TempDir(), true)
+		_, err := resumeBackup(context.Background(), opts, "", nil)
+		if !errors.Is(err, ErrNoPendingBackup) {
+			t.Errorf("expected ErrNoPendingBackup, got %v", err)
+		}
+	})
+
+	t.Run("finishes a backup whose batches are all done", func(t *testing.T) {
+		t.Setenv(paths.EnvHome, t.TempDir())
+		outputDir := t.TempDir()
+		// The previous backup is replaced, including parts the new one lacks
+		for _, name := range []string{"backup_part_01.zip", "backup_part_02.zip", "backup_part_03.zip", "backup_part_04.zip"} {
+			os.WriteFile(filepath.Join(outputDir, name), []byte("previous"), 0644)
+		}
+
+		j := &journal{Version: journalVersion, Folders: []string{"a", "b"}}
+		j.Batches = []journalBatch{
+			stageBatch(t, outputDir, "batch_000", []string{"a"}, map[string]string{"backup_part.zip": "first"}),
+			stageBatch(t, outputDir, "batch_001", []string{"b"}, map[string]string{"backup_part_01.zip": "second", "backup_part_02.zip": "third"}),
+		}
+		// Leftover of the batch that was running when the backup stopped
+		os.MkdirAll(filepath.Join(stagingPath(outputDir), "batch_002"), 0755)
+		if err := j.save(outputDir); err != nil {
+			t.Fatal(err)
+		}
+
+		var progress []BackupProgress
+		opts := NewBackupOptions(nil, 1, FormatZip, CompressionNormal, outputDir, true)
+		result, err := resumeBackup(context.Background(), opts, "", func(p BackupProgress) {
+			progress = append(progress, p)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for name, want := range map[string]string{
+			"backup_part_01.zip": "first",
+			"backup_part_02.zip": "second",
+			"backup_part_03.zip": "third",
+		} {
+			content, err := os.ReadFile(filepath.Join(outputDir, name))
+			if err != nil || string(content) != want {
+				t.Errorf("%s = %q (%v), want %q", name, content, err, want)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(outputDir, "backup_part_04.zip")); err == nil {
+			t.Error("expected the part the new backup lacks removed")
+		}
+		if _, err := os.Stat(stagingPath(outputDir)); err == nil {
+			t.Error("expected staging dir removed")
+		}
+		if result.OriginalSize != 200 || result.CompressedSize != 80 || result.Ratio != "40.0%" {
+			t.Errorf("unexpected result %+v", result)
+		}
+		manifest, err := ReadManifest(outputDir, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(manifest.Parts, []string{"backup_part_01.zip", "backup_part_02.zip", "backup_part_03.zip"}) ||
+			!reflect.DeepEqual(manifest.Mods, []string{"a", "b"}) || manifest.CompressedSize != 80 {
+			t.Errorf("unexpected manifest %+v", manifest)
+		}
+		if history := pastManifests(FormatZip, 0, learnFromBackups); len(history) != 1 {
+			t.Errorf("expected the manifest kept in the history, got %d", len(history))
+		}
+		if len(progress) != 1 || progress[0].ModsDone != 2 || progress[0].ModsTotal != 2 || progress[0].CompressedBytes != 80 {
+			t.Errorf("expected a single snapshot of the finished batches, got %+v", progress)
+		}
+	})
+
+	t.Run("keeps the journal until the manifest is written", func(t *testing.T) {
+		t.Setenv(paths.EnvHome, t.TempDir())
+		outputDir := t.TempDir()
+		for _, name := range []string{"backup_part_01.zip", "backup_part_02.zip", "backup_part_03.zip"} {
+			os.WriteFile(filepath.Join(outputDir, name), []byte("previous"), 0644)
+		}
+		// A directory in the way of the manifest fails its write
+		blocked := filepath.Join(outputDir, ManifestFile, "blocked")
+		os.MkdirAll(blocked, 0755)
+
+		j := &journal{Version: journalVersion, Folders: []string{"a", "b"}}
+		j.Batches = []journalBatch{
+			stageBatch(t, outputDir, "batch_000", []string{"a"}, map[string]string{"backup_part.zip": "first"}),
+			stageBatch(t, outputDir, "batch_001", []string{"b"}, map[string]string{"backup_part.zip": "second"}),
+		}
+		if err := j.save(outputDir); err != nil {
+			t.Fatal(err)
+		}
+		opts := NewBackupOptions(nil, 1, FormatZip, CompressionNormal, outputDir, true)
+		if _, err := resumeBackup(context.Background(), opts, "", nil); err == nil {
+			t.Fatal("expected the manifest write to fail")
+		}
+		if _, err := os.Stat(filepath.Join(outputDir, "backup_part_03.zip")); err != nil {
+			t.Errorf("expected the previous part kept: %v", err)
+		}
+		a := newAurora(&config.Config{Output: outputDir})
+		if pending, err := a.PendingBackup(); err != nil || pending == nil || !pending.Finalizing {
+			t.Fatalf("expected a finalizing backup pending, got %+v, %v", pending, err)
+		}
+		if _, err := startBackup(context.Background(), opts, backupLayout{}, BackupValidation{}, "", nil); !errors.Is(err, ErrFinalizingBackup) {
+			t.Errorf("expected ErrFinalizingBackup starting over, got %v", err)
+		}
+		if err := a.DiscardPendingBackup(); !errors.Is(err, ErrFinalizingBackup) {
+			t.Errorf("expected ErrFinalizingBackup discarding, got %v", err)
+		}
+
+		// As if the run stopped after moving the first part only
+		j, _ = loadJournal(outputDir)
+		left := j.Batches[1].Parts[0]
+		os.Rename(filepath.Join(outputDir, left.Dest), filepath.Join(stagingPath(outputDir), j.Batches[1].Dir, left.Name))
+		os.RemoveAll(filepath.Join(outputDir, ManifestFile))
+		if _, err := resumeBackup(context.Background(), opts, "", nil); err != nil {
+			t.Fatal(err)
+		}
+		for name, want := range map[string]string{"backup_part_01.zip": "first", "backup_part_02.zip": "second"} {
+			if content, _ := os.ReadFile(filepath.Join(outputDir, name)); string(content) != want {
+				t.Errorf("%s = %q, want %q", name, content, want)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(outputDir, "backup_part_03.zip")); err == nil {
+			t.Error("expected the part the new backup lacks removed")
+		}
+		if _, err := os.Stat(stagingPath(outputDir)); err == nil {
+			t.Error("expected staging dir removed")
+		}
+	})
+}
+
+func TestResumeGroupedBackup(t *testing.T) {
+	t.Setenv(paths.EnvHome, t.TempDir())
+	outputDir := t.TempDir()
+	// A previous single layout backup is replaced by the grouped one
+	os.WriteFile(filepath.Join(outputDir, "backup_part.zip"), []byte("previous"), 0644)
+
+	j := &journal{
+		Version:     journalVersion,
+		Folders:     []string{"/mods/a", "/mods/b", "/mods/c"},
+		Layout:      LayoutCollection,
+		MaxPartSize: 4,
+		Groups: []archiveGroup{
+			{Name: "Main", Archive: "backup_collection_Main", Folders: []string{"/mods/a", "/mods/c"}},
+			{Name: "Shared", Archive: "backup_shared", Folders: []string{"/mods/b"}},
+		},
+	}
+	main1 := stageBatch(t, outputDir, "batch_000", []string{"/mods/a"}, map[string]string{"backup_part.zip": "m1"})
+	main1.Group = "backup_collection_Main"
+	shared := stageBatch(t, outputDir, "batch_001", []string{"/mods/b"}, map[string]string{"backup_part.zip": "s"})
+	shared.Group = "backup_shared"
+	main2 := stageBatch(t, outputDir, "batch_002", []string{"/mods/c"}, map[string]string{"backup_part.zip": "too big"})
+	main2.Group = "backup_collection_Main"
+	j.Batches = []journalBatch{main1, shared, main2}
+	j.save(outputDir)
+
+	opts := NewBackupOptions(nil, 1, FormatZip, CompressionNormal, outputDir, true)
+	if _, err := resumeBackup(context.Background(), opts, "", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{
+		"backup_collection_Main_01.zip": "m1",
+		"backup_collection_Main_02.zip": "too big", // a mod over the limit still gets backed up
+		"backup_shared.zip":             "s",
+	} {
+		content, err := os.ReadFile(filepath.Join(outputDir, name))
+		if err != nil || string(content) != want {
+			t.Errorf("%s = %q (%v), want %q", name, content, err, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "backup_part.zip")); err == nil {
+		t.Error("expected the previous backup removed")
+	}
+
+	manifest, err := ReadManifest(outputDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantGroups := []ManifestGroup{
+		{Name: "Main", Mods: []string{"a", "c"}, Parts: []string{"backup_collection_Main_01.zip", "backup_collection_Main_02.zip"}},
+		{Name: "Shared", Mods: []string{"b"}, Parts: []string{"backup_shared.zip"}},
+	}
+	if manifest.Layout != LayoutCollection || manifest.MaxPartSize != 4 || !reflect.DeepEqual(manifest.Groups, wantGroups) {
+		t.Errorf("unexpected manifest %+v", manifest)
+	}
+	if got := FindBackupOutputFiles(outputDir); got != "backup_collection_Main_01.zip ... backup_shared.zip (3 archives)" {
+		t.Errorf("unexpected output files %q", got)
+	}
+}
+
+func TestResumeEncryptedBackup(t *testing.T) {
+	fastScrypt(t)
+	t.Setenv(paths.EnvHome, t.TempDir())
+	outputDir := t.TempDir()
+
+	j := &journal{Version: journalVersion, Folders: []string{"/mods/a"}, Encrypted: true}
+	batchDir := filepath.Join(stagingPath(outputDir), "batch_000")
+	os.MkdirAll(batchDir, 0755)
+	os.WriteFile(filepath.Join(batchDir, "backup_part.zip"), []byte("zip content"), 0644)
+	if err := encryptArchives(batchDir, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	parts, _ := describeParts(batchDir)
+	j.Batches = []journalBatch{{Dir: "batch_000", Folders: []string{"/mods/a"}, Parts: parts}}
+	j.save(outputDir)
+
+	opts := NewBackupOptions(nil, 1, FormatZip, CompressionNormal, outputDir, true)
+	if _, err := resumeBackup(context.Background(), opts, "", nil); !errors.Is(err, ErrPassphraseRequired) {
+		t.Errorf("expected ErrPassphraseRequired, got %v", err)
+	}
+	if _, err := resumeBackup(context.Background(), opts, "wrong", nil); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("expected ErrWrongPassphrase, got %v", err)
+	}
+	if _, err := resumeBackup(context.Background(), opts, "secret", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := OpenBackupFile(filepath.Join(outputDir, "backup_part.zip"+EncryptedExt), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(content) != "zip content" {
+		t.Errorf("expected the archive decrypted, got %q", content)
+	}
+
+	if _, err := ReadManifest(outputDir, ""); !errors.Is(err, ErrPassphraseRequired) {
+		t.Errorf("expected the manifest to need the passphrase, got %v", err)
+	}
+	manifest, err := ReadManifest(outputDir, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !manifest.Encrypted || !reflect.DeepEqual(manifest.Parts, []string{"backup_part.zip.enc"}) || manifest.Checksums["backup_part.zip.enc"] == "" {
+		t.Errorf("unexpected manifest %+v", manifest)
+	}
+}
+
+func TestPendingBackup(t *testing.T) {
+	outputDir := t.TempDir()
+	a := newAurora(&config.Config{Output: outputDir})
+
+	pending, err := a.PendingBackup()
+	if err != nil || pending != nil {
+		t.Fatalf("PendingBackup = %v, %v; want nil, nil", pending, err)
+	}
+
+	j := &journal{Version: journalVersion, Folders: []string{"a", "b", "c"}}
+	j.Batches = []journalBatch{stageBatch(t, outputDir, "batch_000", []string{"a", "b"}, map[string]string{"backup_part.zip": "x"})}
+	j.save(outputDir)
+
+	pending, err = a.PendingBackup()
+	if err != nil || pending == nil || pending.DoneMods != 2 || pending.TotalMods != 3 {
+		t.Fatalf("PendingBackup = %+v, %v", pending, err)
+	}
+
+	if err := a.DiscardPendingBackup(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(stagingPath(outputDir)); err == nil {
+		t.Error("expected staging dir removed")
+	}
+}
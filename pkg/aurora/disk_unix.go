@@ -14,3 +14,6 @@ func getDiskAvailable(path string) (uint64, error) {
 	// Bavail = blocks available to unprivileged users
 	return stat.Bavail * uint64(stat.Bsize), nil
 }
+
+// errCrossDevice is the error of a rename to another file system
+var errCrossDevice error = syscall.EXDEV
@@ -34,3 +34,7 @@ func getDiskAvailable(path string) (uint64, error) {
 
 	return freeBytesAvailable, nil
 }
+
+// errCrossDevice is the error of a rename to another drive
+// (ERROR_NOT_SAME_DEVICE)
+var errCrossDevice error = syscall.Errno(17)
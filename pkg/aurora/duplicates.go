@@ -0,0 +1,258 @@
+package aurora
+
+import (
+	"aurora/internal/logger"
+	"cmp"
+	"context"
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/dustin/go-humanize"
+)
+
+// DuplicatesResult reports identical files across the mods of the mods
+// folder
+type DuplicatesResult struct {
+	ScannedFiles     int             `json:"scannedFiles"`
+	ScannedSize      uint64          `json:"scannedSize"`
+	HashedFiles      int             `json:"hashedFiles"`  // files sharing their size with another, the only possible duplicates
+	SkippedFiles     int             `json:"skippedFiles"` // unreadable files, see the log
+	WastedBytes      uint64          `json:"wastedBytes"`  // every copy but one of each set
+	WastedBytesHuman string          `json:"wastedBytesHuman"`
+	Sets             []DuplicateSet  `json:"sets"`  // most wasted bytes first
+	Types            []DuplicateType `json:"types"` // most wasted bytes first
+	Pairs            []DuplicatePair `json:"pairs"` // most shared bytes first
+}
+
+// DuplicateSet is a group of identical files
+type DuplicateSet struct {
+	SHA256      string          `json:"sha256"`
+	Size        uint64          `json:"size"` // of one copy
+	WastedBytes uint64          `json:"wastedBytes"`
+	Files       []DuplicateFile `json:"files"`
+}
+
+// DuplicateFile is a copy in a DuplicateSet
+type DuplicateFile struct {
+	Mod  string `json:"mod"`
+	Path string `json:"path"` // slash-separated, inside the mod
+}
+
+// DuplicateType sums the duplicates of a file extension
+type DuplicateType struct {
+	Extension   string `json:"extension"` // lower case with the dot, "" for none
+	Sets        int    `json:"sets"`
+	Files       int    `json:"files"`
+	WastedBytes uint64 `json:"wastedBytes"`
+}
+
+// DuplicatePair sums the files two mods have in common: each is a copy one
+// of them could do without. ModA and ModB are the same for copies inside
+// one mod. Pairs overlap when a file is in three mods or more, so their
+// bytes add up to more than DuplicatesResult.WastedBytes.
+type DuplicatePair struct {
+	ModA        string `json:"modA"`
+	ModB        string `json:"modB"`
+	Files       int    `json:"files"`
+	WastedBytes uint64 `json:"wastedBytes"`
+}
+
+// scannedFile is a file of a mod found by FindDuplicates
+type scannedFile struct {
+	DuplicateFile
+	path string
+	info fs.FileInfo
+	hash string
+}
+
+// FindDuplicates groups the identical files of the mods in the mods
+// folder. Only files sharing their size with another one are hashed, and
+// unchanged files come from the hash cache. progress, when not nil, is
+// called as files are hashed. Fails with ErrInvalidConfig, or ctx's error
+// when cancelled.
+func (a *Aurora) FindDuplicates(ctx context.Context, progress func(hashed, total int)) (DuplicatesResult, error) {
+	cfg := a.Config()
+	if !cfg.Status().Valid {
+		return DuplicatesResult{}, ErrInvalidConfig
+	}
+	var result DuplicatesResult
+	bySize := map[int64][]*scannedFile{}
+	root := cfg.Mods.Path
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			logger.Warn("Skipping %s: %v", path, err)
+			result.SkippedFiles++
+			return nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		mod, inMod, ok := strings.Cut(filepath.ToSlash(rel), "/")
+		if !ok {
+			return nil // not in a mod
+		}
+		info, err := d.Info()
+		if err != nil {
+			result.SkippedFiles++
+			return nil
+		}
+		result.ScannedFiles++
+		result.ScannedSize += uint64(info.Size())
+		// Empty files waste nothing
+		if info.Size() > 0 {
+			bySize[info.Size()] = append(bySize[info.Size()], &scannedFile{DuplicateFile: DuplicateFile{Mod: mod, Path: inMod}, path: path, info: info})
+		}
+		return nil
+	})
+	if err != nil {
+		return DuplicatesResult{}, err
+	}
+
+	var candidates []*scannedFile
+	for _, files := range bySize {
+		if len(files) > 1 {
+			candidates = append(candidates, files...)
+		}
+	}
+	cache := loadHashCache()
+	if err := a.hashFiles(ctx, cache, candidates, progress); err != nil {
+		return DuplicatesResult{}, err
+	}
+	if err := cache.save(root); err != nil {
+		logger.Warn("Failed to save hash cache: %v", err)
+	}
+
+	byHash := map[string][]*scannedFile{}
+	for _, file := range candidates {
+		if file.hash == "" {
+			result.SkippedFiles++
+			continue
+		}
+		result.HashedFiles++
+		byHash[file.hash] = append(byHash[file.hash], file)
+	}
+	types := map[string]*DuplicateType{}
+	pairs := map[[2]string]*DuplicatePair{}
+	for hash, files := range byHash {
+		if len(files) < 2 {
+			continue
+		}
+		size := uint64(files[0].info.Size())
+		set := DuplicateSet{SHA256: hash, Size: size, WastedBytes: size * uint64(len(files)-1)}
+		copies := map[string]int{}
+		for _, file := range files {
+			set.Files = append(set.Files, file.DuplicateFile)
+			copies[file.Mod]++
+		}
+		slices.SortFunc(set.Files, func(a, b DuplicateFile) int {
+			return cmp.Or(strings.Compare(a.Mod, b.Mod), strings.Compare(a.Path, b.Path))
+		})
+		result.Sets = append(result.Sets, set)
+		result.WastedBytes += set.WastedBytes
+
+		ext := strings.ToLower(filepath.Ext(files[0].Path))
+		if types[ext] == nil {
+			types[ext] = &DuplicateType{Extension: ext}
+		}
+		types[ext].Sets++
+		types[ext].Files += len(files)
+		types[ext].WastedBytes += set.WastedBytes
+
+		mods := make([]string, 0, len(copies))
+		for mod := range copies {
+			mods = append(mods, mod)
+		}
+		slices.Sort(mods)
+		addPair := func(modA, modB string, n int) {
+			key := [2]string{modA, modB}
+			if pairs[key] == nil {
+				pairs[key] = &DuplicatePair{ModA: modA, ModB: modB}
+			}
+			pairs[key].Files += n
+			pairs[key].WastedBytes += size * uint64(n)
+		}
+		for i, modA := range mods {
+			if copies[modA] > 1 {
+				addPair(modA, modA, copies[modA]-1)
+			}
+			for _, modB := range mods[i+1:] {
+				addPair(modA, modB, 1)
+			}
+		}
+	}
+
+	result.WastedBytesHuman = humanize.Bytes(result.WastedBytes)
+	slices.SortFunc(result.Sets, func(a, b DuplicateSet) int {
+		return cmp.Or(cmp.Compare(b.WastedBytes, a.WastedBytes), strings.Compare(a.SHA256, b.SHA256))
+	})
+	for _, t := range types {
+		result.Types = append(result.Types, *t)
+	}
+	slices.SortFunc(result.Types, func(a, b DuplicateType) int {
+		return cmp.Or(cmp.Compare(b.WastedBytes, a.WastedBytes), strings.Compare(a.Extension, b.Extension))
+	})
+	for _, p := range pairs {
+		result.Pairs = append(result.Pairs, *p)
+	}
+	slices.SortFunc(result.Pairs, func(a, b DuplicatePair) int {
+		return cmp.Or(cmp.Compare(b.WastedBytes, a.WastedBytes), strings.Compare(a.ModA, b.ModA), strings.Compare(a.ModB, b.ModB))
+	})
+	logger.Info("Found %d sets of duplicate files in %s, wasting %s", len(result.Sets), root, result.WastedBytesHuman)
+	return result, nil
+}
+
+// hashFiles sets the hash of files with the configured concurrency. A file
+// that can't be read keeps an empty hash.
+func (a *Aurora) hashFiles(ctx context.Context, cache *hashCache, files []*scannedFile, progress func(hashed, total int)) error {
+	threads := a.Config().Concurrency
+	if threads <= 0 {
+		threads = runtime.NumCPU()
+	}
+	jobs := make(chan *scannedFile)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	hashed := 0
+	for range threads {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range jobs {
+				hash, err := cache.hash(file.path, file.info)
+				if err != nil {
+					logger.Warn("Failed to hash %s: %v", file.path, err)
+				}
+				file.hash = hash
+				mu.Lock()
+				hashed++
+				if progress != nil {
+					progress(hashed, len(files))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	var err error
+	for _, file := range files {
+		select {
+		case jobs <- file:
+			continue
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		break
+	}
+	close(jobs)
+	wg.Wait()
+	return err
+}
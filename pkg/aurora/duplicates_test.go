@@ -0,0 +1,97 @@
+package aurora
+
+import (
+	"aurora/internal/config"
+	"aurora/internal/paths"
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	t.Run("rejects an invalid config", func(t *testing.T) {
+		a := newAurora(&config.Config{})
+		if _, err := a.FindDuplicates(context.Background(), nil); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("expected ErrInvalidConfig, got %v", err)
+		}
+	})
+
+	texture := "shared texture data" // 19 bytes
+	a := newTestAurora(t, map[string]map[string]string{
+		"Mod A": {"chara/body.tex": texture, "chara/body.mdl": "model A", "copy/body.tex": texture},
+		"Mod B": {"textures/skin.TEX": texture, "body.mdl": "model B", "empty.json": ""},
+		"Mod C": {"sound.scd": "sound", "notes.txt": "model A"},
+	}, nil)
+	var calls, lastTotal int
+	result, err := a.FindDuplicates(context.Background(), func(hashed, total int) { calls, lastTotal = hashed, total })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("scans every file and hashes size collisions only", func(t *testing.T) {
+		if result.ScannedFiles != 8 || result.HashedFiles != 6 || result.SkippedFiles != 0 {
+			t.Errorf("unexpected counts %+v", result)
+		}
+		if calls != 6 || lastTotal != 6 {
+			t.Errorf("expected progress up to 6 files, got %d/%d", calls, lastTotal)
+		}
+	})
+
+	t.Run("groups identical files", func(t *testing.T) {
+		if len(result.Sets) != 2 {
+			t.Fatalf("expected 2 sets, got %+v", result.Sets)
+		}
+		textures := result.Sets[0]
+		want := []DuplicateFile{{"Mod A", "chara/body.tex"}, {"Mod A", "copy/body.tex"}, {"Mod B", "textures/skin.TEX"}}
+		if !slices.Equal(textures.Files, want) || textures.Size != 19 || textures.WastedBytes != 38 {
+			t.Errorf("unexpected texture set %+v", textures)
+		}
+		if models := result.Sets[1]; len(models.Files) != 2 || models.WastedBytes != 7 {
+			t.Errorf("unexpected model set %+v", models)
+		}
+		if result.WastedBytes != 45 {
+			t.Errorf("expected 45 wasted bytes, got %d", result.WastedBytes)
+		}
+	})
+
+	t.Run("sums waste per file type", func(t *testing.T) {
+		want := []DuplicateType{{Extension: ".tex", Sets: 1, Files: 3, WastedBytes: 38}, {Extension: ".mdl", Sets: 1, Files: 2, WastedBytes: 7}}
+		if !slices.Equal(result.Types, want) {
+			t.Errorf("expected %+v, got %+v", want, result.Types)
+		}
+	})
+
+	t.Run("sums waste per mod pair", func(t *testing.T) {
+		want := []DuplicatePair{
+			{ModA: "Mod A", ModB: "Mod A", Files: 1, WastedBytes: 19},
+			{ModA: "Mod A", ModB: "Mod B", Files: 1, WastedBytes: 19},
+			{ModA: "Mod A", ModB: "Mod C", Files: 1, WastedBytes: 7},
+		}
+		if !slices.Equal(result.Pairs, want) {
+			t.Errorf("expected %+v, got %+v", want, result.Pairs)
+		}
+	})
+
+	t.Run("keeps the hashes for the next run", func(t *testing.T) {
+		if _, err := os.Stat(paths.HashCacheFile()); err != nil {
+			t.Fatalf("expected a hash cache: %v", err)
+		}
+		if cache := loadHashCache(); len(cache.files) != 6 {
+			t.Errorf("expected 6 cached hashes, got %d", len(cache.files))
+		}
+		again, err := a.FindDuplicates(context.Background(), nil)
+		if err != nil || again.WastedBytes != result.WastedBytes {
+			t.Errorf("expected the same result, got %+v, %v", again, err)
+		}
+	})
+
+	t.Run("stops when cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := a.FindDuplicates(ctx, nil); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	})
+}
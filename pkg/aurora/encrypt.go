@@ -0,0 +1,443 @@
+package aurora
+
+import (
+	"aurora/internal/config"
+	"aurora/internal/logger"
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Backup encryption, modeled on age's passphrase recipient and STREAM
+// payload: a per-file random salt and scrypt derive the keys, a header MAC
+// tells a wrong passphrase from tampering, and the content is sealed with
+// AES-256-GCM in 64 KiB chunks whose nonce carries a counter and a last
+// chunk flag, so reordered, truncated or extended files fail to open.
+//
+// File layout: magic | log2(scrypt N) | salt | header MAC | chunks
+
+// EncryptedExt is appended to the name of encrypted archives and manifests
+const EncryptedExt = ".enc"
+
+// EnvPassphrase holds the backup passphrase for unattended runs
+const EnvPassphrase = "AURORA_PASSPHRASE"
+
+var (
+	// ErrPassphraseRequired is returned when encryption is on and no
+	// passphrase was given
+	ErrPassphraseRequired = errors.New("backup encryption is on but no passphrase was given")
+	// ErrWrongPassphrase is returned when a file was encrypted with another passphrase
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+	// ErrTampered is returned when encrypted content fails authentication:
+	// the file was corrupted, truncated or modified
+	ErrTampered = errors.New("encrypted file is corrupted or was modified")
+)
+
+var encryptionMagic = []byte("AURENC01")
+
+const (
+	saltSize     = 16
+	macSize      = sha256.Size
+	headerSize   = len("AURENC01") + 1 + saltSize + macSize
+	chunkSize    = 64 << 10
+	nonceSize    = 12
+	lastChunkBit = 1
+)
+
+// scryptLogN is log2 of the scrypt work factor, stored in each header.
+// 2^15 (32 MiB, tens of milliseconds) matches age's interactive setting.
+var scryptLogN byte = 15
+
+// deriveKeys turns a passphrase and salt into the header MAC key and the
+// payload key
+func deriveKeys(passphrase string, salt []byte, logN byte) (macKey, payloadKey []byte, err error) {
+	if logN < 10 || logN > 22 {
+		return nil, nil, fmt.Errorf("%w: scrypt work factor 2^%d out of range", ErrTampered, logN)
+	}
+	secret, err := scrypt.Key([]byte(passphrase), salt, 1<<logN, 8, 1, 32)
+	if err != nil {
+		return nil, nil, err
+	}
+	if macKey, err = hkdf.Key(sha256.New, secret, nil, "aurora header", 32); err != nil {
+		return nil, nil, err
+	}
+	if payloadKey, err = hkdf.Key(sha256.New, secret, nil, "aurora payload", 32); err != nil {
+		return nil, nil, err
+	}
+	return macKey, payloadKey, nil
+}
+
+func headerMAC(macKey, header []byte) []byte {
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(header)
+	return mac.Sum(nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce is an 11-byte big-endian chunk counter and the last chunk flag
+func chunkNonce(counter uint64, last bool) []byte {
+	nonce := make([]byte, nonceSize)
+	binary.BigEndian.PutUint64(nonce[3:11], counter)
+	if last {
+		nonce[11] = lastChunkBit
+	}
+	return nonce
+}
+
+// encryptWriter seals what is written to it into w. Close writes the last
+// chunk and must be called.
+type encryptWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	buf     []byte
+	counter uint64
+}
+
+// newEncryptWriter writes the header for a fresh salt to w
+func newEncryptWriter(w io.Writer, passphrase string) (*encryptWriter, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	macKey, payloadKey, err := deriveKeys(passphrase, salt, scryptLogN)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(payloadKey)
+	if err != nil {
+		return nil, err
+	}
+
+	header := append(append(append([]byte{}, encryptionMagic...), scryptLogN), salt...)
+	header = append(header, headerMAC(macKey, header)...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, aead: aead, buf: make([]byte, 0, 2*chunkSize)}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	e.buf = append(e.buf, p...)
+	// Keep the last full chunk back: only Close knows it is the last one
+	for len(e.buf) > chunkSize {
+		if err := e.seal(e.buf[:chunkSize], false); err != nil {
+			return 0, err
+		}
+		e.buf = append(e.buf[:0], e.buf[chunkSize:]...)
+	}
+	return len(p), nil
+}
+
+func (e *encryptWriter) Close() error {
+	return e.seal(e.buf, true)
+}
+
+func (e *encryptWriter) seal(chunk []byte, last bool) error {
+	sealed := e.aead.Seal(nil, chunkNonce(e.counter, last), chunk, nil)
+	e.counter++
+	_, err := e.w.Write(sealed)
+	return err
+}
+
+// decryptReader opens the chunks of an encrypted stream as they are read
+type decryptReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	buf     []byte // opened, not yet read
+	counter uint64
+	done    bool
+}
+
+// newDecryptReader checks the header of an encrypted stream against
+// passphrase. Content errors surface from Read as ErrTampered.
+func newDecryptReader(r io.Reader, passphrase string) (io.Reader, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("%w: header too short", ErrTampered)
+	}
+	if !bytes.Equal(header[:len(encryptionMagic)], encryptionMagic) {
+		return nil, fmt.Errorf("%w: not an Aurora encrypted file", ErrTampered)
+	}
+	logN := header[len(encryptionMagic)]
+	salt := header[len(encryptionMagic)+1 : len(encryptionMagic)+1+saltSize]
+	macKey, payloadKey, err := deriveKeys(passphrase, salt, logN)
+	if err != nil {
+		return nil, err
+	}
+	// A tampered salt or work factor also lands here: both look like
+	// another passphrase
+	if !hmac.Equal(headerMAC(macKey, header[:headerSize-macSize]), header[headerSize-macSize:]) {
+		return nil, ErrWrongPassphrase
+	}
+	aead, err := newGCM(payloadKey)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{r: bufio.NewReaderSize(r, chunkSize+aead.Overhead()+1), aead: aead}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+// next opens the following chunk: a short chunk, or a full one the stream
+// ends after, must be the last
+func (d *decryptReader) next() error {
+	sealed := make([]byte, chunkSize+d.aead.Overhead())
+	n, err := io.ReadFull(d.r, sealed)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return err
+	}
+	last := n < len(sealed)
+	if !last {
+		if _, err := d.r.Peek(1); err == io.EOF {
+			last = true
+		}
+	}
+	// The first chunk may be empty (empty file), others can't
+	if n < d.aead.Overhead() || (last && n == d.aead.Overhead() && d.counter > 0) {
+		return fmt.Errorf("%w: truncated", ErrTampered)
+	}
+	chunk, err := d.aead.Open(sealed[:0], chunkNonce(d.counter, last), sealed[:n], nil)
+	if err != nil {
+		return fmt.Errorf("%w: chunk %d fails authentication", ErrTampered, d.counter)
+	}
+	d.counter++
+	d.buf = chunk
+	d.done = last
+	return nil
+}
+
+// encryptFile writes an encrypted copy of src to dst
+func encryptFile(src, dst, passphrase string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	writer, err := newEncryptWriter(out, passphrase)
+	if err == nil {
+		_, err = io.Copy(writer, in)
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("encrypt %s: %w", src, err)
+	}
+	return nil
+}
+
+// encryptInPlace replaces path with its encrypted copy, path+EncryptedExt
+func encryptInPlace(path, passphrase string) error {
+	if err := encryptFile(path, path+EncryptedExt, passphrase); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// checkPassphrase reports ErrWrongPassphrase when the encrypted file at
+// path was written with another passphrase
+func checkPassphrase(path, passphrase string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = newDecryptReader(file, passphrase)
+	return err
+}
+
+// OpenBackupFile opens an archive or manifest of a backup set, decrypting
+// it when its name ends in EncryptedExt. passphrase is only needed then.
+func OpenBackupFile(path, passphrase string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, EncryptedExt) {
+		return file, nil
+	}
+	if passphrase == "" {
+		file.Close()
+		return nil, ErrPassphraseRequired
+	}
+	reader, err := newDecryptReader(file, passphrase)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{reader, file}, nil
+}
+
+// DecryptFile writes the content of the encrypted file src to dst. It goes
+// through a temporary file, so a wrong passphrase or a damaged file leaves
+// no partial dst.
+func DecryptFile(src, dst, passphrase string) error {
+	in, err := OpenBackupFile(src, passphrase)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp := dst + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, dst)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("decrypt %s: %w", src, err)
+	}
+	return nil
+}
+
+// DecryptBackup writes plain copies of the encrypted archives, manifest and
+// report of the backup set in dir ("" = the output directory) to outDir, so
+// they open with any archiver, or tar and zstd. outDir can't be dir: plain
+// archives there would join the set.
+func (a *Aurora) DecryptBackup(dir, outDir, passphrase string) (DecryptResult, error) {
+	if dir == "" {
+		dir = a.Config().Output
+	}
+	if sameDir(dir, outDir) {
+		return DecryptResult{}, fmt.Errorf("decrypt into %s: pick a directory other than the backup's", outDir)
+	}
+	var encrypted []string
+	for _, name := range backupFiles(dir) {
+		if strings.HasSuffix(name, EncryptedExt) {
+			encrypted = append(encrypted, name)
+		}
+	}
+	if len(encrypted) == 0 {
+		if len(ListBackupArchives(dir)) == 0 {
+			return DecryptResult{}, ErrNoBackup
+		}
+		return DecryptResult{}, fmt.Errorf("the backup in %s is not encrypted", dir)
+	}
+	if passphrase == "" {
+		return DecryptResult{}, ErrPassphraseRequired
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return DecryptResult{}, fmt.Errorf("create %s: %w", outDir, err)
+	}
+
+	result := DecryptResult{Dir: outDir}
+	for _, name := range encrypted {
+		plain := strings.TrimSuffix(name, EncryptedExt)
+		dst := filepath.Join(outDir, plain)
+		if err := DecryptFile(filepath.Join(dir, name), dst, passphrase); err != nil {
+			if errors.Is(err, ErrWrongPassphrase) {
+				return DecryptResult{}, ErrWrongPassphrase
+			}
+			return result, err
+		}
+		file := DecryptedFile{Name: plain}
+		if info, err := os.Stat(dst); err == nil {
+			file.Size = info.Size()
+		}
+		result.Files = append(result.Files, file)
+	}
+	logger.Info("Decrypted %d files of the backup in %s to %s", len(result.Files), dir, outDir)
+	return result, nil
+}
+
+// sameDir reports whether two paths name the same directory
+func sameDir(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
+// ResolvePassphrase finds the backup passphrase: the key file (the flag's,
+// else the configured one), then AURORA_PASSPHRASE, then prompt (may be
+// nil). A key file holds the passphrase on its first line. Returns "" when
+// none is available.
+func (a *Aurora) ResolvePassphrase(keyFile string, prompt func() (string, error)) (string, error) {
+	if keyFile == "" {
+		keyFile = a.Config().KeyFile
+	}
+	if keyFile != "" {
+		content, err := os.ReadFile(keyFile)
+		if err != nil {
+			return "", fmt.Errorf("read key file %s: %w", keyFile, err)
+		}
+		passphrase, _, _ := strings.Cut(string(content), "\n")
+		passphrase = strings.TrimRight(passphrase, "\r")
+		if passphrase == "" {
+			return "", fmt.Errorf("key file %s is empty", keyFile)
+		}
+		logger.Info("Backup passphrase read from key file %s", keyFile)
+		return passphrase, nil
+	}
+	if passphrase := os.Getenv(EnvPassphrase); passphrase != "" {
+		logger.Info("Backup passphrase read from %s", EnvPassphrase)
+		return passphrase, nil
+	}
+	if prompt != nil {
+		return prompt()
+	}
+	return "", nil
+}
+
+// SetEncrypt turns backup encryption on or off
+func (a *Aurora) SetEncrypt(encrypt bool) error {
+	return a.updateConfig(func(cfg *config.Config) {
+		cfg.Encrypt = encrypt
+	})
+}
+
+// SetKeyFile sets the file holding the backup passphrase ("" = none)
+func (a *Aurora) SetKeyFile(path string) error {
+	return a.updateConfig(func(cfg *config.Config) {
+		cfg.KeyFile = path
+	})
+}
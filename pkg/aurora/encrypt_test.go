@@ -0,0 +1,273 @@
+package aurora
+
+import (
+	"aurora/internal/config"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// fastScrypt lowers the scrypt work factor for the test: the format and
+// checks are the same, derivations just take microseconds
+func fastScrypt(t *testing.T) {
+	t.Helper()
+	previous := scryptLogN
+	scryptLogN = 10
+	t.Cleanup(func() { scryptLogN = previous })
+}
+
+func encryptBytes(t *testing.T, plain []byte, passphrase string) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	writer, err := newEncryptWriter(&out, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Odd write sizes: chunking must not depend on them
+	for rest := plain; len(rest) > 0; {
+		n := min(len(rest), 1000+len(rest)%7)
+		writer.Write(rest[:n])
+		rest = rest[n:]
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func decryptBytes(sealed []byte, passphrase string) ([]byte, error) {
+	reader, err := newDecryptReader(bytes.NewReader(sealed), passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
+
+func TestEncryption(t *testing.T) {
+	fastScrypt(t)
+
+	t.Run("round trips any size", func(t *testing.T) {
+		for _, size := range []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 3*chunkSize + 5} {
+			plain := randomBytes(t, size)
+			got, err := decryptBytes(encryptBytes(t, plain, "secret"), "secret")
+			if err != nil {
+				t.Fatalf("size %d: %v", size, err)
+			}
+			if !bytes.Equal(got, plain) {
+				t.Errorf("size %d: decrypted content differs", size)
+			}
+		}
+	})
+
+	t.Run("salts every file", func(t *testing.T) {
+		plain := []byte("same content")
+		if bytes.Equal(encryptBytes(t, plain, "secret"), encryptBytes(t, plain, "secret")) {
+			t.Error("expected two encryptions to differ")
+		}
+	})
+
+	t.Run("rejects a wrong passphrase", func(t *testing.T) {
+		sealed := encryptBytes(t, []byte("mods"), "secret")
+		if _, err := decryptBytes(sealed, "Secret"); !errors.Is(err, ErrWrongPassphrase) {
+			t.Errorf("expected ErrWrongPassphrase, got %v", err)
+		}
+	})
+
+	t.Run("detects tampered ciphertext", func(t *testing.T) {
+		plain := randomBytes(t, 2*chunkSize+100)
+		sealed := encryptBytes(t, plain, "secret")
+
+		tests := map[string]func([]byte) []byte{
+			"flipped byte": func(b []byte) []byte { b[headerSize+chunkSize+10] ^= 1; return b },
+			"truncated at a chunk boundary": func(b []byte) []byte {
+				return b[:headerSize+2*(chunkSize+16)]
+			},
+			"truncated mid chunk": func(b []byte) []byte { return b[:len(b)-5] },
+			"extended":            func(b []byte) []byte { return append(b, 0) },
+			"chunks swapped": func(b []byte) []byte {
+				first := headerSize
+				second := headerSize + chunkSize + 16
+				swapped := append([]byte{}, b...)
+				copy(swapped[first:], b[second:second+chunkSize+16])
+				copy(swapped[second:], b[first:first+chunkSize+16])
+				return swapped
+			},
+		}
+		for name, tamper := range tests {
+			t.Run(name, func(t *testing.T) {
+				_, err := decryptBytes(tamper(append([]byte{}, sealed...)), "secret")
+				if !errors.Is(err, ErrTampered) {
+					t.Errorf("expected ErrTampered, got %v", err)
+				}
+			})
+		}
+	})
+
+	t.Run("detects a tampered header", func(t *testing.T) {
+		sealed := encryptBytes(t, []byte("mods"), "secret")
+		sealed[len(encryptionMagic)+3] ^= 1 // salt
+		if _, err := decryptBytes(sealed, "secret"); err == nil {
+			t.Error("expected a tampered salt to fail")
+		}
+		if _, err := decryptBytes([]byte("PK\x03\x04 plain zip"), "secret"); !errors.Is(err, ErrTampered) {
+			t.Errorf("expected a plain file rejected, got %v", err)
+		}
+	})
+}
+
+func TestOpenBackupFile(t *testing.T) {
+	fastScrypt(t)
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "backup_part.zip")
+	os.WriteFile(plain, []byte("zip content"), 0644)
+	if err := encryptInPlace(plain, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(plain); err == nil {
+		t.Error("expected the plain archive removed")
+	}
+
+	reader, err := OpenBackupFile(plain+EncryptedExt, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(content) != "zip content" {
+		t.Errorf("expected the archive decrypted, got %q", content)
+	}
+	if _, err := OpenBackupFile(plain+EncryptedExt, ""); !errors.Is(err, ErrPassphraseRequired) {
+		t.Errorf("expected ErrPassphraseRequired, got %v", err)
+	}
+	if _, err := OpenBackupFile(plain+EncryptedExt, "wrong"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("expected ErrWrongPassphrase, got %v", err)
+	}
+}
+
+func TestDecryptBackup(t *testing.T) {
+	fastScrypt(t)
+	a := newTestAurora(t, reportMods, reportCollections)
+	configure(t, a.SetFormat(FormatTarZst), a.AddInclusion("Unused"), a.AddFilter("xx-"), a.SetEncrypt(true))
+	if _, err := a.RunBackup(context.Background(), RunBackupOptions{Passphrase: "secret"}, &recordingObserver{}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("writes plain copies of the set", func(t *testing.T) {
+		outDir := filepath.Join(t.TempDir(), "plain")
+		result, err := a.DecryptBackup("", outDir, "secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, file := range result.Files {
+			names = append(names, file.Name)
+		}
+		for _, want := range []string{"backup_part.tar.zst", ManifestFile, ReportFile} {
+			if !slices.Contains(names, want) {
+				t.Errorf("expected %s among %v", want, names)
+			}
+		}
+		if files, err := verifyArchive(filepath.Join(outDir, "backup_part.tar.zst"), "", ""); err != nil || files != 2 {
+			t.Errorf("expected the plain archive to hold 2 files, got %d, %v", files, err)
+		}
+		if _, err := ReadManifest(outDir, ""); err != nil {
+			t.Errorf("expected a plain manifest: %v", err)
+		}
+	})
+
+	t.Run("refuses a wrong passphrase or the backup's own directory", func(t *testing.T) {
+		outDir := filepath.Join(t.TempDir(), "plain")
+		if _, err := a.DecryptBackup("", outDir, "wrong"); !errors.Is(err, ErrWrongPassphrase) {
+			t.Errorf("expected ErrWrongPassphrase, got %v", err)
+		}
+		if entries, _ := os.ReadDir(outDir); len(entries) != 0 {
+			t.Errorf("expected nothing written, got %d files", len(entries))
+		}
+		if _, err := a.DecryptBackup("", "", "secret"); err == nil {
+			t.Error("expected the output directory refused")
+		}
+		if _, err := a.DecryptBackup(t.TempDir(), outDir, "secret"); !errors.Is(err, ErrNoBackup) {
+			t.Errorf("expected ErrNoBackup, got %v", err)
+		}
+	})
+
+	t.Run("keeps no mod names in the local manifest copy", func(t *testing.T) {
+		manifests := pastManifests(FormatTarZst, CompressionLevel(FormatTarZst, a.GetCompression()), learnFromBackups)
+		if len(manifests) != 1 || manifests[0].CompressedSize == 0 {
+			t.Fatalf("expected the sizes kept, got %+v", manifests)
+		}
+		if len(manifests[0].Mods) != 0 || len(manifests[0].Checksums) != 0 {
+			t.Errorf("expected the names dropped, got %+v", manifests[0])
+		}
+	})
+}
+
+// TestEncryptedZipBackup backs up in the default format: go-delta can't
+// encrypt, so Aurora writes encrypted ZIPs itself
+func TestEncryptedZipBackup(t *testing.T) {
+	fastScrypt(t)
+	a := newTestAurora(t, reportMods, reportCollections)
+	configure(t, a.AddInclusion("Unused"), a.SetEncrypt(true))
+	if _, err := a.RunBackup(context.Background(), RunBackupOptions{Passphrase: "secret"}, &recordingObserver{}); err != nil {
+		t.Fatal(err)
+	}
+	if archives := ListBackupArchives(a.Config().Output); !slices.Equal(archives, []string{"backup_part.zip" + EncryptedExt}) {
+		t.Errorf("expected one encrypted ZIP, got %v", archives)
+	}
+	result, err := a.VerifyBackup("secret")
+	if err != nil || !result.Valid || result.Archives[0].Files != 3 {
+		t.Errorf("expected the set to verify with 3 files, got %+v, %v", result, err)
+	}
+}
+
+func TestResolvePassphrase(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key.txt")
+	os.WriteFile(keyFile, []byte("from file\r\nignored line\n"), 0600)
+	prompt := func() (string, error) { return "typed", nil }
+
+	t.Run("key file first", func(t *testing.T) {
+		t.Setenv(EnvPassphrase, "from env")
+		a := newAurora(&config.Config{KeyFile: keyFile})
+		if got, _ := a.ResolvePassphrase("", prompt); got != "from file" {
+			t.Errorf("expected the configured key file, got %q", got)
+		}
+		other := filepath.Join(dir, "other.txt")
+		os.WriteFile(other, []byte("from flag"), 0600)
+		if got, _ := a.ResolvePassphrase(other, prompt); got != "from flag" {
+			t.Errorf("expected the flag's key file to win, got %q", got)
+		}
+	})
+
+	t.Run("then the environment, then the prompt", func(t *testing.T) {
+		a := newAurora(&config.Config{})
+		t.Setenv(EnvPassphrase, "from env")
+		if got, _ := a.ResolvePassphrase("", prompt); got != "from env" {
+			t.Errorf("expected %s, got %q", EnvPassphrase, got)
+		}
+		t.Setenv(EnvPassphrase, "")
+		if got, _ := a.ResolvePassphrase("", prompt); got != "typed" {
+			t.Errorf("expected the prompt, got %q", got)
+		}
+		if got, err := a.ResolvePassphrase("", nil); got != "" || err != nil {
+			t.Errorf("expected no passphrase without a prompt, got %q, %v", got, err)
+		}
+	})
+
+	t.Run("rejects missing or empty key files", func(t *testing.T) {
+		a := newAurora(&config.Config{})
+		if _, err := a.ResolvePassphrase(filepath.Join(dir, "missing"), nil); err == nil {
+			t.Error("expected an error for a missing key file")
+		}
+		empty := filepath.Join(dir, "empty")
+		os.WriteFile(empty, []byte("\n"), 0600)
+		if _, err := a.ResolvePassphrase(empty, nil); err == nil {
+			t.Error("expected an error for an empty key file")
+		}
+	})
+}
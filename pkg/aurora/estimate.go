@@ -0,0 +1,229 @@
+package aurora
+
+import (
+	"aurora/internal/logger"
+	"compress/flate"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Backup size estimation. Compressing samples the way the archive format
+// does (deflate for ZIPs, zstd otherwise) at the same level predicts archive
+// size far better than a flat ratio: textures (already block-compressed)
+// barely shrink while models and metadata do.
+
+// sampledExtensions get a ratio of their own; other files share one
+var sampledExtensions = []string{".tex", ".atex", ".mdl", ".mtrl", ".scd", ".pbd", ".avfx", ".pap", ".tmb", ".sklb", ".shpk", ".json"}
+
+const otherExtension = "other"
+
+const (
+	samplesPerMod       = 2         // candidates per mod and extension
+	samplesPerExtension = 16        // files compressed per extension
+	sampleBytes         = 128 << 10 // read from the start of each sample
+	learnFromBackups    = 10        // past backups calibrating the estimate
+
+	// Extensions without a sample fall back to the historical flat ratio,
+	// with a wide uncertainty
+	fallbackRatio       = 0.25
+	fallbackUncertainty = 0.25
+
+	// Per-file ZIP overhead besides the name: local header (30 bytes) and
+	// central directory entry (46 bytes)
+	zipEntryOverhead = 30 + 46
+	// Per-file tar overhead once compressed: the 512-byte header and padding
+	// are mostly zeros
+	tarEntryOverhead = 64
+)
+
+// sizeEstimate is a predicted backup size with its confidence range
+type sizeEstimate struct {
+	size, low, high uint64
+	raw             uint64 // before calibration, recorded in manifests
+	samples         int    // files sampled
+	backups         int    // past backups used for calibration
+}
+
+// extensionClass maps a file to its ratio bucket
+func extensionClass(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	if slices.Contains(sampledExtensions, ext) {
+		return ext
+	}
+	return otherExtension
+}
+
+// estimateBackupSize predicts the compressed size of folders in format at
+// level, calibrated with the manifests of past backups made the same way
+func estimateBackupSize(folders []string, format archiveFormat, level int, history []BackupManifest) sizeEstimate {
+	sizes := make(map[string]uint64)
+	candidates := make(map[string][]string)
+	var overhead, original uint64
+
+	for _, folder := range folders {
+		perMod := make(map[string]int)
+		parent := filepath.Dir(folder)
+		filepath.WalkDir(folder, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			class := extensionClass(path)
+			sizes[class] += uint64(info.Size())
+			original += uint64(info.Size())
+			if rel, err := filepath.Rel(parent, path); err == nil {
+				overhead += entryOverhead(format, len(rel))
+			}
+			if info.Size() > 0 && perMod[class] < samplesPerMod {
+				perMod[class]++
+				candidates[class] = append(candidates[class], path)
+			}
+			return nil
+		})
+	}
+
+	estimate := sizeEstimate{}
+	var raw, margin float64
+	for class, size := range sizes {
+		// Spread the samples across mods rather than taking the first ones
+		picked := candidates[class]
+		if len(picked) > samplesPerExtension {
+			step := float64(len(picked)) / samplesPerExtension
+			spread := make([]string, 0, samplesPerExtension)
+			for i := range samplesPerExtension {
+				spread = append(spread, picked[int(float64(i)*step)])
+			}
+			picked = spread
+		}
+
+		var ratios []float64
+		var read, compressed int64
+		for _, path := range picked {
+			n, out, err := compressSample(path, format, level)
+			if err != nil || n == 0 {
+				continue
+			}
+			read += n
+			compressed += out
+			ratios = append(ratios, float64(out)/float64(n))
+		}
+		estimate.samples += len(ratios)
+
+		ratio, uncertainty := fallbackRatio, fallbackUncertainty
+		if len(ratios) > 0 {
+			ratio = float64(compressed) / float64(read)
+			uncertainty = sampleUncertainty(ratios)
+		}
+		raw += float64(size) * ratio
+		margin += float64(size) * uncertainty
+	}
+	raw += float64(overhead)
+
+	low := math.Max(raw-margin, float64(overhead))
+	// Deflate and zstd fall back to storing: an archive hardly outgrows its files
+	high := math.Min(raw+margin, float64(original+overhead))
+
+	estimate.raw = uint64(raw)
+	factors := calibrationFactors(history)
+	estimate.backups = len(factors)
+	if len(factors) > 0 {
+		var sum float64
+		for _, f := range factors {
+			sum += f
+		}
+		raw *= sum / float64(len(factors))
+		low *= slices.Min(factors)
+		high *= slices.Max(factors)
+	}
+	estimate.size = uint64(raw)
+	estimate.low = uint64(math.Min(low, raw))
+	estimate.high = uint64(math.Max(high, raw))
+	return estimate
+}
+
+// entryOverhead is what storing one file named name adds to an archive
+func entryOverhead(format archiveFormat, name int) uint64 {
+	if format.name == FormatZip {
+		return zipEntryOverhead + 2*uint64(name) // name stored twice
+	}
+	return tarEntryOverhead + uint64(name)
+}
+
+// compressSample compresses the start of a file like format does and
+// returns the bytes read and the compressed size
+func compressSample(path string, format archiveFormat, level int) (read, compressed int64, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	var out countingWriter
+	var writer io.WriteCloser
+	if format.name == FormatZip {
+		writer, err = flate.NewWriter(&out, level)
+	} else {
+		writer, err = zstd.NewWriter(&out, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)), zstd.WithEncoderConcurrency(1))
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	read, err = io.Copy(writer, io.LimitReader(file, sampleBytes))
+	if err != nil {
+		logger.Warn("Cannot sample %s: %v", path, err)
+		return 0, 0, err
+	}
+	if err := writer.Close(); err != nil {
+		return 0, 0, err
+	}
+	return read, out.n, nil
+}
+
+// sampleUncertainty is twice the standard error of the sample ratios (a
+// ~95% interval), at least 2% to account for what the samples missed
+func sampleUncertainty(ratios []float64) float64 {
+	const floor = 0.02
+	if len(ratios) < 2 {
+		return math.Max(floor, fallbackUncertainty/2)
+	}
+	var mean float64
+	for _, r := range ratios {
+		mean += r
+	}
+	mean /= float64(len(ratios))
+	var variance float64
+	for _, r := range ratios {
+		variance += (r - mean) * (r - mean)
+	}
+	variance /= float64(len(ratios) - 1)
+	return math.Max(floor, 2*math.Sqrt(variance/float64(len(ratios))))
+}
+
+// calibrationFactors returns how far off past estimates were: actual
+// compressed size over the uncalibrated estimate
+func calibrationFactors(history []BackupManifest) []float64 {
+	var factors []float64
+	for _, manifest := range history {
+		if manifest.Estimate > 0 && manifest.CompressedSize > 0 {
+			factors = append(factors, float64(manifest.CompressedSize)/float64(manifest.Estimate))
+		}
+	}
+	return factors
+}
+
+type countingWriter struct{ n int64 }
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,114 @@
+package aurora
+
+import (
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeMod creates a mod folder with the given files
+func writeMod(t *testing.T, dir, name string, files map[string][]byte) string {
+	t.Helper()
+	folder := filepath.Join(dir, name)
+	for file, content := range files {
+		path := filepath.Join(folder, file)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return folder
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	t.Helper()
+	data := make([]byte, n)
+	rand.Read(data)
+	return data
+}
+
+func TestExtensionClass(t *testing.T) {
+	tests := map[string]string{
+		"chara/body.tex":   ".tex",
+		"chara/BODY.MDL":   ".mdl",
+		"sound/music.scd":  ".scd",
+		"meta.json":        ".json",
+		"readme.txt":       otherExtension,
+		"no_extension_bin": otherExtension,
+	}
+	for path, want := range tests {
+		if got := extensionClass(path); got != want {
+			t.Errorf("extensionClass(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestEstimateBackupSize(t *testing.T) {
+	const size = 200 << 10
+	dir := t.TempDir()
+	// Textures are already compressed (random data); models are repetitive
+	textures := writeMod(t, dir, "Textures", map[string][]byte{
+		"a.tex": randomBytes(t, size),
+		"b.tex": randomBytes(t, size),
+	})
+	models := writeMod(t, dir, "Models", map[string][]byte{
+		"a.mdl": []byte(strings.Repeat("vertex 0.0 1.0 2.0\n", size/19)),
+		"b.mdl": []byte(strings.Repeat("vertex 3.0 4.0 5.0\n", size/19)),
+	})
+
+	t.Run("follows the compressibility of each file type", func(t *testing.T) {
+		tex := estimateBackupSize([]string{textures}, formatByName(FormatZip), 5, nil)
+		mdl := estimateBackupSize([]string{models}, formatByName(FormatZip), 5, nil)
+
+		if tex.size < 2*size*95/100 {
+			t.Errorf("expected random textures to barely compress, got %d of %d", tex.size, 2*size)
+		}
+		if mdl.size > 2*size/10 {
+			t.Errorf("expected repetitive models to compress well, got %d of %d", mdl.size, 2*size)
+		}
+		if tex.samples != 2 || mdl.samples != 2 {
+			t.Errorf("expected every file sampled, got %d and %d", tex.samples, mdl.samples)
+		}
+		for _, e := range []sizeEstimate{tex, mdl} {
+			if e.low > e.size || e.size > e.high {
+				t.Errorf("expected low <= size <= high, got %d <= %d <= %d", e.low, e.size, e.high)
+			}
+		}
+		// Stored files can't grow the archive much past its contents
+		if tex.high > 2*size+1024 {
+			t.Errorf("expected high bound capped near the original size, got %d", tex.high)
+		}
+	})
+
+	t.Run("calibrates with past backups", func(t *testing.T) {
+		plain := estimateBackupSize([]string{models}, formatByName(FormatZip), 5, nil)
+		history := []BackupManifest{
+			{Level: 5, Estimate: 1000, CompressedSize: 1200},
+			{Level: 5, Estimate: 1000, CompressedSize: 1400},
+			{Level: 5, CompressedSize: 999}, // no estimate recorded: ignored
+		}
+		calibrated := estimateBackupSize([]string{models}, formatByName(FormatZip), 5, history)
+
+		if calibrated.backups != 2 {
+			t.Errorf("expected 2 backups used, got %d", calibrated.backups)
+		}
+		if calibrated.raw != plain.raw {
+			t.Errorf("expected the raw estimate unchanged, got %d and %d", calibrated.raw, plain.raw)
+		}
+		want := float64(plain.raw) * 1.3
+		if got := float64(calibrated.size); got < want*0.99 || got > want*1.01 {
+			t.Errorf("expected size scaled by the mean factor 1.3 (%.0f), got %.0f", want, got)
+		}
+		if calibrated.high < uint64(float64(plain.high)*1.39) {
+			t.Errorf("expected the high bound scaled by the worst factor, got %d from %d", calibrated.high, plain.high)
+		}
+	})
+
+	t.Run("nothing to back up", func(t *testing.T) {
+		if e := estimateBackupSize(nil, formatByName(FormatZip), 5, nil); e.size != 0 || e.high != 0 {
+			t.Errorf("expected an empty estimate, got %+v", e)
+		}
+	})
+}
@@ -0,0 +1,332 @@
+package aurora
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"aurora/internal/config"
+	"aurora/internal/logger"
+	"compress/flate"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/creativeyann17/go-delta/pkg/compress"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Archive formats exposed in settings
+const (
+	FormatZip    = "zip"     // go-delta ZIP: opens with any archiver (default)
+	FormatTarZst = "tar.zst" // zstd compressed tarball: much faster on textures
+	FormatDelta  = "gdelta"  // go-delta native format: opened with go-delta
+)
+
+// archiveFormat is how one format names its archives and maps the
+// compression presets to its levels
+type archiveFormat struct {
+	name   string
+	ext    string
+	normal int
+	max    int
+}
+
+// archiveFormats lists the formats, the default first. ZIP levels are
+// deflate's (1-9), the others zstd's (1-22).
+var archiveFormats = []archiveFormat{
+	{name: FormatZip, ext: ".zip", normal: 5, max: 9},
+	{name: FormatTarZst, ext: ".tar.zst", normal: 3, max: 19},
+	{name: FormatDelta, ext: ".gdelta", normal: 3, max: 19},
+}
+
+// formatByName returns the format called name, ZIP when unknown or empty
+func formatByName(name string) archiveFormat {
+	for _, format := range archiveFormats {
+		if format.name == name {
+			return format
+		}
+	}
+	return archiveFormats[0]
+}
+
+// formatOfFile returns the format of an archive file name, encrypted or not
+func formatOfFile(name string) (archiveFormat, bool) {
+	name = strings.TrimSuffix(name, EncryptedExt)
+	for _, format := range archiveFormats {
+		if strings.HasSuffix(name, format.ext) {
+			return format, true
+		}
+	}
+	return archiveFormat{}, false
+}
+
+// formatOfOptions returns the format compress options write, told by the
+// output name (see NewBackupOptions)
+func formatOfOptions(opts *compress.Options) archiveFormat {
+	if format, ok := formatOfFile(opts.OutputPath); ok {
+		return format
+	}
+	return archiveFormats[0]
+}
+
+// normalizeFormat returns a known format name, defaulting to ZIP
+func normalizeFormat(name string) string {
+	return formatByName(name).name
+}
+
+// CompressionLevel maps a compression preset to the level of an archive
+// format. Unknown or empty presets fall back to normal (the default), unknown
+// formats to ZIP.
+func CompressionLevel(format, preset string) int {
+	f := formatByName(format)
+	if preset == CompressionMax {
+		return f.max
+	}
+	return f.normal
+}
+
+// SetFormat sets the archive format ("zip", "tar.zst" or "gdelta")
+func (a *Aurora) SetFormat(format string) error {
+	return a.updateConfig(func(cfg *config.Config) {
+		cfg.Format = normalizeFormat(format)
+	})
+}
+
+// GetFormat returns the current archive format, normalized
+func (a *Aurora) GetFormat() string {
+	return normalizeFormat(a.Config().Format)
+}
+
+// tarProgressStep is how often the archives Aurora writes itself report
+// progress within a file
+const tarProgressStep = 4 << 20
+
+// writeTarZst writes the folders of opts to opts.OutputPath as a zstd
+// compressed tarball, stopping when ctx is done. Files are stored under
+// their mod folder name, like go-delta's ZIPs, and reported with the same
+// progress events. With a passphrase the tarball is encrypted as it is
+// written, to opts.OutputPath+EncryptedExt: no plain copy touches the disk.
+func writeTarZst(ctx context.Context, opts *compress.Options, passphrase string, progressCb func(compress.ProgressEvent)) (originalSize, compressedSize uint64, err error) {
+	out, err := createArchive(opts.OutputPath, passphrase)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer out.file.Close()
+
+	threads := opts.MaxThreads
+	if threads <= 0 {
+		threads = runtime.GOMAXPROCS(0) // like go-delta: 0 = every core
+	}
+	encoder, err := zstd.NewWriter(out,
+		zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(opts.Level)),
+		zstd.WithEncoderConcurrency(threads))
+	if err != nil {
+		return 0, 0, err
+	}
+	writer := tar.NewWriter(encoder)
+	originalSize, err = walkArchive(ctx, opts.Files, func(path, name string) (uint64, error) {
+		return addTarFile(writer, path, name, progressCb)
+	}, progressCb)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return 0, 0, err
+	}
+	if err := encoder.Close(); err != nil {
+		return 0, 0, err
+	}
+	if err := out.close(); err != nil {
+		return 0, 0, err
+	}
+	return originalSize, uint64(out.counter.n), nil
+}
+
+// writeEncryptedZip writes the folders of opts to
+// opts.OutputPath+EncryptedExt as a ZIP encrypted as it is written, laid out
+// like go-delta's. go-delta only writes plain files, which would leave a
+// plain copy of every encrypted ZIP on disk until it was encrypted.
+func writeEncryptedZip(ctx context.Context, opts *compress.Options, passphrase string, progressCb func(compress.ProgressEvent)) (originalSize, compressedSize uint64, err error) {
+	out, err := createArchive(opts.OutputPath, passphrase)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer out.file.Close()
+
+	writer := zip.NewWriter(out)
+	writer.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(w, opts.Level)
+	})
+	originalSize, err = walkArchive(ctx, opts.Files, func(path, name string) (uint64, error) {
+		return addZipFile(writer, path, name, progressCb)
+	}, progressCb)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return 0, 0, err
+	}
+	if err := out.close(); err != nil {
+		return 0, 0, err
+	}
+	return originalSize, uint64(out.counter.n), nil
+}
+
+// archiveFile is an archive being written, through the encryption when
+// there is a passphrase, counting the bytes of the archive itself
+type archiveFile struct {
+	file    *os.File
+	sink    io.Writer
+	sealer  *encryptWriter
+	counter countingWriter
+}
+
+// createArchive creates path, or path+EncryptedExt with a passphrase
+func createArchive(path, passphrase string) (*archiveFile, error) {
+	if passphrase != "" {
+		path += EncryptedExt
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	out := &archiveFile{file: file, sink: file}
+	if passphrase != "" {
+		if out.sealer, err = newEncryptWriter(file, passphrase); err != nil {
+			file.Close()
+			return nil, err
+		}
+		out.sink = out.sealer
+	}
+	return out, nil
+}
+
+func (a *archiveFile) Write(p []byte) (int, error) {
+	a.counter.Write(p)
+	return a.sink.Write(p)
+}
+
+// close seals the last chunk of an encrypted archive and closes the file
+func (a *archiveFile) close() error {
+	if a.sealer != nil {
+		if err := a.sealer.Close(); err != nil {
+			return err
+		}
+	}
+	return a.file.Close()
+}
+
+// walkArchive calls add for every file of folders, named by its path from
+// the parent of its mod folder, and returns the bytes added. Unreadable
+// paths are skipped with an error event.
+func walkArchive(ctx context.Context, folders []string, add func(path, name string) (uint64, error), progressCb func(compress.ProgressEvent)) (uint64, error) {
+	var total uint64
+	for _, folder := range folders {
+		parent := filepath.Dir(folder)
+		err := filepath.WalkDir(folder, func(path string, d fs.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return ErrBackupCancelled
+			}
+			if err != nil {
+				logger.Warn("Cannot access path %s: %v", path, err)
+				progressCb(compress.ProgressEvent{Type: compress.EventError, FilePath: path})
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(parent, path)
+			if err != nil {
+				return err
+			}
+			size, err := add(path, filepath.ToSlash(rel))
+			total += size
+			return err
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	return total, nil
+}
+
+// addTarFile stores one file. A file that can't be opened is skipped with
+// an error event; a failure once its header is written breaks the archive.
+func addTarFile(writer *tar.Writer, path, name string, progressCb func(compress.ProgressEvent)) (uint64, error) {
+	file, info, ok := openArchiveFile(path, progressCb)
+	if !ok {
+		return 0, nil
+	}
+	defer file.Close()
+
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return 0, fmt.Errorf("add %s: %w", path, err)
+	}
+	header.Name = name
+	header.Format = tar.FormatPAX // long and non-ASCII mod names
+	if err := writer.WriteHeader(header); err != nil {
+		return 0, fmt.Errorf("add %s: %w", path, err)
+	}
+	return copyArchiveFile(writer, file, path, info.Size(), progressCb)
+}
+
+// addZipFile stores one file deflated, like addTarFile
+func addZipFile(writer *zip.Writer, path, name string, progressCb func(compress.ProgressEvent)) (uint64, error) {
+	file, info, ok := openArchiveFile(path, progressCb)
+	if !ok {
+		return 0, nil
+	}
+	defer file.Close()
+
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return 0, fmt.Errorf("add %s: %w", path, err)
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+	entry, err := writer.CreateHeader(header)
+	if err != nil {
+		return 0, fmt.Errorf("add %s: %w", path, err)
+	}
+	return copyArchiveFile(entry, file, path, info.Size(), progressCb)
+}
+
+// openArchiveFile opens a file to archive, reporting an error event when
+// it can't be read
+func openArchiveFile(path string, progressCb func(compress.ProgressEvent)) (*os.File, fs.FileInfo, bool) {
+	file, err := os.Open(path)
+	if err == nil {
+		var info fs.FileInfo
+		if info, err = file.Stat(); err == nil {
+			return file, info, true
+		}
+		file.Close()
+	}
+	logger.Warn("Cannot read %s, skipping it: %v", path, err)
+	progressCb(compress.ProgressEvent{Type: compress.EventError, FilePath: path})
+	return nil, nil, false
+}
+
+// copyArchiveFile copies size bytes of file into an archive entry,
+// reporting progress every tarProgressStep
+func copyArchiveFile(w io.Writer, file io.Reader, path string, size int64, progressCb func(compress.ProgressEvent)) (uint64, error) {
+	progressCb(compress.ProgressEvent{Type: compress.EventFileStart, FilePath: path, Total: size})
+	var done int64
+	for done < size {
+		n, err := io.CopyN(w, file, min(tarProgressStep, size-done))
+		done += n
+		if err != nil {
+			return uint64(done), fmt.Errorf("add %s: %w", path, err)
+		}
+		progressCb(compress.ProgressEvent{Type: compress.EventFileProgress, FilePath: path, Current: done, Total: size})
+	}
+	progressCb(compress.ProgressEvent{Type: compress.EventFileComplete, FilePath: path, Total: size})
+	return uint64(size), nil
+}
@@ -0,0 +1,208 @@
+package aurora
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/creativeyann17/go-delta/pkg/compress"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestArchiveFormats(t *testing.T) {
+	t.Run("files tell their format", func(t *testing.T) {
+		tests := map[string]string{
+			"backup_part.zip":                   FormatZip,
+			"backup_part_02.tar.zst":            FormatTarZst,
+			"backup_collection_Main.gdelta.enc": FormatDelta,
+			"backup_part.zip.enc":               FormatZip,
+		}
+		for name, want := range tests {
+			if got, ok := formatOfFile(name); !ok || got.name != want {
+				t.Errorf("formatOfFile(%q) = %q, want %q", name, got.name, want)
+			}
+		}
+		for _, name := range []string{"backup_part.tar", "backup_part.zst", "backup_manifest.json.enc"} {
+			if _, ok := formatOfFile(name); ok {
+				t.Errorf("expected %q not to be an archive", name)
+			}
+		}
+	})
+
+	t.Run("presets map per format", func(t *testing.T) {
+		tests := []struct {
+			format, preset string
+			want           int
+		}{
+			{FormatZip, CompressionNormal, 5},
+			{FormatZip, CompressionMax, 9},
+			{FormatTarZst, CompressionNormal, 3},
+			{FormatTarZst, CompressionMax, 19},
+			{FormatDelta, CompressionMax, 19},
+			{"", "", 5},
+		}
+		for _, tt := range tests {
+			if got := CompressionLevel(tt.format, tt.preset); got != tt.want {
+				t.Errorf("CompressionLevel(%q, %q) = %d, want %d", tt.format, tt.preset, got, tt.want)
+			}
+		}
+	})
+}
+
+// writeMods creates mod folders under a temp dir: mod name -> file -> content
+func writeMods(t *testing.T, mods map[string]map[string]string) (folders []string) {
+	t.Helper()
+	root := t.TempDir()
+	for mod, files := range mods {
+		for name, content := range files {
+			path := filepath.Join(root, mod, name)
+			os.MkdirAll(filepath.Dir(path), 0755)
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		folders = append(folders, filepath.Join(root, mod))
+	}
+	return folders
+}
+
+// readTarZst returns the files of a tar.zst archive: name -> content
+func readTarZst(t *testing.T, path string) map[string]string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	decoder, err := zstd.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer decoder.Close()
+
+	files := make(map[string]string)
+	reader := tar.NewReader(decoder)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(reader)
+		files[header.Name] = string(content)
+	}
+}
+
+func TestWriteTarZst(t *testing.T) {
+	folders := writeMods(t, map[string]map[string]string{
+		"Body Mod": {"a.tex": "texture", "sub/b.mdl": "model"},
+		"Hair":     {"meta.json": "{}"},
+	})
+
+	t.Run("stores files under their mod folder", func(t *testing.T) {
+		opts := NewBackupOptions(folders, 2, FormatTarZst, CompressionNormal, t.TempDir(), true)
+		var completed int
+		original, compressed, err := writeTarZst(context.Background(), opts, "", func(event compress.ProgressEvent) {
+			if event.Type == compress.EventFileComplete {
+				completed++
+			}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := map[string]string{"Body Mod/a.tex": "texture", "Body Mod/sub/b.mdl": "model", "Hair/meta.json": "{}"}
+		got := readTarZst(t, opts.OutputPath)
+		if len(got) != len(want) {
+			t.Errorf("expected %d files, got %v", len(want), got)
+		}
+		for name, content := range want {
+			if got[name] != content {
+				t.Errorf("%s = %q, want %q", name, got[name], content)
+			}
+		}
+		info, _ := os.Stat(opts.OutputPath)
+		if original != 14 || compressed != uint64(info.Size()) || completed != 3 {
+			t.Errorf("got original %d, compressed %d (file %d), %d files completed", original, compressed, info.Size(), completed)
+		}
+	})
+
+	t.Run("encrypts as it writes", func(t *testing.T) {
+		fastScrypt(t)
+		opts := NewBackupOptions(folders, 1, FormatTarZst, CompressionNormal, t.TempDir(), true)
+		if _, _, err := writeTarZst(context.Background(), opts, "secret", func(compress.ProgressEvent) {}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(opts.OutputPath); err == nil {
+			t.Error("expected no plain tarball")
+		}
+		plain := filepath.Join(t.TempDir(), "backup_part.tar.zst")
+		if err := DecryptFile(opts.OutputPath+EncryptedExt, plain, "secret"); err != nil {
+			t.Fatal(err)
+		}
+		if got := readTarZst(t, plain); len(got) != 3 {
+			t.Errorf("expected 3 files once decrypted, got %v", got)
+		}
+	})
+
+	t.Run("stops when cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		opts := NewBackupOptions(folders, 1, FormatTarZst, CompressionNormal, t.TempDir(), true)
+		_, _, err := writeTarZst(ctx, opts, "", func(compress.ProgressEvent) {})
+		if !errors.Is(err, ErrBackupCancelled) {
+			t.Errorf("expected ErrBackupCancelled, got %v", err)
+		}
+	})
+}
+
+func TestWriteEncryptedZip(t *testing.T) {
+	fastScrypt(t)
+	folders := writeMods(t, map[string]map[string]string{
+		"Body Mod": {"a.tex": "texture", "sub/b.mdl": "model"},
+		"Hair":     {"meta.json": "{}"},
+	})
+	opts := NewBackupOptions(folders, 1, FormatZip, CompressionNormal, t.TempDir(), true)
+	original, _, err := writeEncryptedZip(context.Background(), opts, "secret", func(compress.ProgressEvent) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(opts.OutputPath); err == nil {
+		t.Error("expected no plain ZIP")
+	}
+	if original != 14 {
+		t.Errorf("expected 14 bytes archived, got %d", original)
+	}
+
+	plain := filepath.Join(t.TempDir(), "backup_part.zip")
+	if err := DecryptFile(opts.OutputPath+EncryptedExt, plain, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := zip.OpenReader(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	want := map[string]string{"Body Mod/a.tex": "texture", "Body Mod/sub/b.mdl": "model", "Hair/meta.json": "{}"}
+	if len(reader.File) != len(want) {
+		t.Errorf("expected %d files, got %d", len(want), len(reader.File))
+	}
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		if want[file.Name] != string(content) {
+			t.Errorf("%s = %q, want %q", file.Name, content, want[file.Name])
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package aurora
+
+import (
+	"aurora/internal/logger"
+	"aurora/internal/paths"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// hashCacheVersion changes when older caches can't be trusted anymore
+const hashCacheVersion = 1
+
+// hashCache remembers the SHA-256 of files by path, size and modification
+// time, so analyses only rehash files that changed since the last run. It
+// is safe for concurrent use.
+type hashCache struct {
+	mu    sync.Mutex
+	file  string
+	files map[string]hashCacheEntry
+	seen  map[string]bool
+	dirty bool
+}
+
+// hashCacheFile is the JSON form of a hashCache
+type hashCacheFile struct {
+	Version int                       `json:"version"`
+	Files   map[string]hashCacheEntry `json:"files"` // by absolute path
+}
+
+type hashCacheEntry struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"modTime"` // Unix nanoseconds
+	SHA256  string `json:"sha256"`
+}
+
+// loadHashCache reads the cache at paths.HashCacheFile(). A missing,
+// unreadable or outdated cache starts empty.
+func loadHashCache() *hashCache {
+	c := &hashCache{file: paths.HashCacheFile(), files: map[string]hashCacheEntry{}, seen: map[string]bool{}}
+	data, err := os.ReadFile(c.file)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Warn("Failed to read hash cache %s, rehashing: %v", c.file, err)
+		}
+		return c
+	}
+	var stored hashCacheFile
+	if err := json.Unmarshal(data, &stored); err != nil || stored.Version != hashCacheVersion {
+		logger.Warn("Ignoring outdated or damaged hash cache %s", c.file)
+		return c
+	}
+	if stored.Files != nil {
+		c.files = stored.Files
+	}
+	return c
+}
+
+// hash returns the SHA-256 of the file at path, whose info the caller
+// already has, from the cache when the file is unchanged
+func (c *hashCache) hash(path string, info fs.FileInfo) (string, error) {
+	key, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	entry, ok := c.files[key]
+	c.seen[key] = true
+	c.mu.Unlock()
+	if ok && entry.Size == info.Size() && entry.ModTime == info.ModTime().UnixNano() {
+		return entry.SHA256, nil
+	}
+
+	logger.Debug("Hashing %s: new or changed since the last run", path)
+	sum, err := fileSHA256(path)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	c.files[key] = hashCacheEntry{Size: info.Size(), ModTime: info.ModTime().UnixNano(), SHA256: sum}
+	c.dirty = true
+	c.mu.Unlock()
+	return sum, nil
+}
+
+// save writes the cache back, forgetting the files under root that weren't
+// hashed in this run: they were deleted or aren't duplicate candidates
+// anymore. Entries of other directories are kept.
+func (c *hashCache) save(root string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if prefix, err := filepath.Abs(root); err == nil {
+		prefix += string(filepath.Separator)
+		for key := range c.files {
+			if strings.HasPrefix(key, prefix) && !c.seen[key] {
+				delete(c.files, key)
+				c.dirty = true
+			}
+		}
+	}
+	if !c.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(hashCacheFile{Version: hashCacheVersion, Files: c.files})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.file), 0755); err != nil {
+		return err
+	}
+	tmp := c.file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, c.file); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	c.dirty = false
+	return nil
+}
@@ -0,0 +1,79 @@
+package aurora
+
+import (
+	"aurora/internal/paths"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHashCache(t *testing.T) {
+	t.Setenv(paths.EnvHome, t.TempDir())
+	root := t.TempDir()
+	path := filepath.Join(root, "Mod", "a.tex")
+	other := filepath.Join(root, "Mod", "b.tex")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	os.WriteFile(path, []byte("texture"), 0644)
+	os.WriteFile(other, []byte("other"), 0644)
+	want, _ := fileSHA256(path)
+
+	hashOf := func(c *hashCache, path string) string {
+		t.Helper()
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum, err := c.hash(path, info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sum
+	}
+
+	cache := loadHashCache()
+	if got := hashOf(cache, path); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	hashOf(cache, other)
+	if err := cache.save(root); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("serves unchanged files from the cache", func(t *testing.T) {
+		cache := loadHashCache()
+		key, _ := filepath.Abs(path)
+		entry := cache.files[key]
+		entry.SHA256 = "cached"
+		cache.files[key] = entry
+		if got := hashOf(cache, path); got != "cached" {
+			t.Errorf("expected the cached sum, got %s", got)
+		}
+	})
+
+	t.Run("rehashes changed files", func(t *testing.T) {
+		os.WriteFile(path, []byte("new texture"), 0644)
+		later := time.Now().Add(time.Minute)
+		os.Chtimes(path, later, later)
+		want, _ := fileSHA256(path)
+		cache := loadHashCache()
+		if got := hashOf(cache, path); got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+		// other wasn't hashed in this run
+		if err := cache.save(root); err != nil {
+			t.Fatal(err)
+		}
+		key, _ := filepath.Abs(other)
+		if _, ok := loadHashCache().files[key]; ok {
+			t.Error("expected files not hashed in the run to be forgotten")
+		}
+	})
+
+	t.Run("starts over from a damaged cache", func(t *testing.T) {
+		os.WriteFile(paths.HashCacheFile(), []byte("{"), 0644)
+		if cache := loadHashCache(); len(cache.files) != 0 {
+			t.Errorf("expected an empty cache, got %d entries", len(cache.files))
+		}
+	})
+}
@@ -0,0 +1,410 @@
+package aurora
+
+import (
+	"aurora/internal/logger"
+	"aurora/internal/paths"
+	"aurora/internal/repository"
+	"aurora/internal/util"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// snapshotVersion changes when older snapshots can't be compared anymore
+const snapshotVersion = 1
+
+// maxSnapshots bounds the history: older snapshots are deleted
+const maxSnapshots = 200
+
+// Reasons a snapshot was taken for
+const (
+	SnapshotBackup = "backup" // after a successful backup
+	SnapshotManual = "manual" // aurora history snapshot
+)
+
+// Snapshot is the state of the mod library at one point in time, as
+// aurora history compares it. It doesn't keep file contents: a mod's Hash
+// covers the path, size and modification time of its files.
+type Snapshot struct {
+	Version     int           `json:"version"`
+	ID          string        `json:"id"` // taken time, 20060102-150405.000000
+	TakenAt     time.Time     `json:"takenAt"`
+	Reason      string        `json:"reason"`
+	Collections []string      `json:"collections"`
+	Mods        []SnapshotMod `json:"mods"`
+}
+
+// SnapshotMod is one mod of a Snapshot
+type SnapshotMod struct {
+	Name        string   `json:"name"`
+	Files       int      `json:"files"`
+	Size        uint64   `json:"size"`
+	Hash        string   `json:"hash"`
+	Collections []string `json:"collections"` // using it
+}
+
+// SnapshotSummary describes a snapshot without its mods
+type SnapshotSummary struct {
+	ID          string    `json:"id"`
+	TakenAt     time.Time `json:"takenAt"`
+	Reason      string    `json:"reason"`
+	Mods        int       `json:"mods"`
+	Collections int       `json:"collections"`
+	Size        uint64    `json:"size"`
+	SizeHuman   string    `json:"sizeHuman"`
+}
+
+// HistoryResult is the timeline of the mod library. Entries compare each
+// snapshot with the one before it, newest first; snapshots without
+// changes have none.
+type HistoryResult struct {
+	Snapshots []SnapshotSummary `json:"snapshots"` // newest first
+	Entries   []HistoryEntry    `json:"entries"`
+}
+
+// HistoryEntry lists what changed between two snapshots
+type HistoryEntry struct {
+	From           string             `json:"from"` // snapshot IDs
+	To             string             `json:"to"`
+	TakenAt        time.Time          `json:"takenAt"` // of To
+	Reason         string             `json:"reason"`  // of To
+	Added          []ModChange        `json:"added"`
+	Removed        []ModChange        `json:"removed"`
+	Updated        []ModChange        `json:"updated"`
+	Collections    []CollectionChange `json:"collections"`
+	SizeDelta      int64              `json:"sizeDelta"`
+	SizeDeltaHuman string             `json:"sizeDeltaHuman"` // signed
+}
+
+// ModChange is a mod added, removed or updated between two snapshots.
+// OldSize is 0 for an added mod, Size for a removed one.
+type ModChange struct {
+	Name         string `json:"name"`
+	OldSize      uint64 `json:"oldSize"`
+	OldSizeHuman string `json:"oldSizeHuman"`
+	Size         uint64 `json:"size"`
+	SizeHuman    string `json:"sizeHuman"`
+}
+
+// CollectionChange is a collection created, deleted or whose mods changed
+// between two snapshots
+type CollectionChange struct {
+	Name    string   `json:"name"`
+	Created bool     `json:"created"`
+	Deleted bool     `json:"deleted"`
+	Added   []string `json:"added"` // mods
+	Removed []string `json:"removed"`
+}
+
+func (e HistoryEntry) empty() bool {
+	return len(e.Added) == 0 && len(e.Removed) == 0 && len(e.Updated) == 0 && len(e.Collections) == 0
+}
+
+// TakeSnapshot records the mods of the mods folder, their collections
+// and a fingerprint of their files in paths.SnapshotsDir, dropping the
+// oldest snapshots past maxSnapshots. Fails with ErrInvalidConfig.
+func (a *Aurora) TakeSnapshot(reason string) (Snapshot, error) {
+	cfg := a.Config()
+	if !cfg.Status().Valid {
+		return Snapshot{}, ErrInvalidConfig
+	}
+	repo, err := repository.NewPenumbraRepositoryNoSizes(cfg)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	now := time.Now()
+	snapshot := Snapshot{
+		Version:     snapshotVersion,
+		ID:          now.Format("20060102-150405.000000"),
+		TakenAt:     now,
+		Reason:      reason,
+		Collections: []string{},
+		Mods:        []SnapshotMod{},
+	}
+	for _, col := range repo.Collections {
+		snapshot.Collections = append(snapshot.Collections, col.Name)
+	}
+	slices.Sort(snapshot.Collections)
+	for _, mod := range repo.Mods {
+		snap := fingerprintMod(filepath.Join(cfg.Mods.Path, mod.Path))
+		snap.Name = mod.Name
+		snap.Collections = []string{}
+		for _, col := range mod.Collections {
+			snap.Collections = append(snap.Collections, col.Name)
+		}
+		slices.Sort(snap.Collections)
+		snapshot.Mods = append(snapshot.Mods, snap)
+	}
+	slices.SortFunc(snapshot.Mods, func(a, b SnapshotMod) int { return strings.Compare(a.Name, b.Name) })
+
+	if err := saveSnapshot(snapshot); err != nil {
+		return Snapshot{}, fmt.Errorf("save snapshot: %w", err)
+	}
+	pruneSnapshots()
+	logger.Info("Took %s snapshot %s of %d mods", reason, snapshot.ID, len(snapshot.Mods))
+	return snapshot, nil
+}
+
+// fingerprintMod counts the files and size of the mod at root and hashes
+// their relative path, size and modification time: a changed file changes
+// the hash without reading any file
+func fingerprintMod(root string) SnapshotMod {
+	var mod SnapshotMod
+	var lines []string
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			logger.Warn("Cannot access path %s: %v", path, err)
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		mod.Files++
+		mod.Size += uint64(info.Size())
+		lines = append(lines, fmt.Sprintf("%s\x00%d\x00%d", filepath.ToSlash(rel), info.Size(), info.ModTime().UnixNano()))
+		return nil
+	})
+	slices.Sort(lines)
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	mod.Hash = hex.EncodeToString(sum[:])
+	return mod
+}
+
+func saveSnapshot(snapshot Snapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	dir := paths.SnapshotsDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, snapshot.ID+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// snapshotIDs lists the stored snapshots, oldest first
+func snapshotIDs() ([]string, error) {
+	entries, err := os.ReadDir(paths.SnapshotsDir())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, entry := range entries {
+		if id, ok := strings.CutSuffix(entry.Name(), ".json"); ok && !entry.IsDir() {
+			ids = append(ids, id)
+		}
+	}
+	// IDs are the taken time: sorted is oldest first
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// pruneSnapshots deletes the oldest snapshots past maxSnapshots
+func pruneSnapshots() {
+	ids, err := snapshotIDs()
+	if err != nil || len(ids) <= maxSnapshots {
+		return
+	}
+	for _, id := range ids[:len(ids)-maxSnapshots] {
+		if err := os.Remove(filepath.Join(paths.SnapshotsDir(), id+".json")); err != nil {
+			logger.Warn("Failed to delete old snapshot %s: %v", id, err)
+		}
+	}
+}
+
+// History compares the stored snapshots, each with the one before it, and
+// returns the changes of the limit most recent snapshots (0 for all).
+// Unreadable or outdated snapshots are skipped.
+func (a *Aurora) History(limit int) (HistoryResult, error) {
+	ids, err := snapshotIDs()
+	if err != nil {
+		return HistoryResult{}, fmt.Errorf("read snapshots: %w", err)
+	}
+	if limit > 0 && len(ids) > limit+1 {
+		// One more snapshot to compare the oldest kept one with
+		ids = ids[len(ids)-limit-1:]
+	}
+
+	var snapshots []Snapshot
+	for _, id := range ids {
+		var snapshot Snapshot
+		if err := util.ReadJSONFile(filepath.Join(paths.SnapshotsDir(), id+".json"), &snapshot); err != nil || snapshot.Version != snapshotVersion {
+			logger.Warn("Skipping unreadable or outdated snapshot %s", id)
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	result := HistoryResult{Snapshots: []SnapshotSummary{}, Entries: []HistoryEntry{}}
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		if limit > 0 && len(result.Snapshots) == limit {
+			break
+		}
+		result.Snapshots = append(result.Snapshots, summarizeSnapshot(snapshots[i]))
+		if i == 0 {
+			break
+		}
+		if entry := compareSnapshots(snapshots[i-1], snapshots[i]); !entry.empty() {
+			result.Entries = append(result.Entries, entry)
+		}
+	}
+	return result, nil
+}
+
+func summarizeSnapshot(snapshot Snapshot) SnapshotSummary {
+	summary := SnapshotSummary{
+		ID:          snapshot.ID,
+		TakenAt:     snapshot.TakenAt,
+		Reason:      snapshot.Reason,
+		Mods:        len(snapshot.Mods),
+		Collections: len(snapshot.Collections),
+	}
+	for _, mod := range snapshot.Mods {
+		summary.Size += mod.Size
+	}
+	summary.SizeHuman = humanize.Bytes(summary.Size)
+	return summary
+}
+
+// compareSnapshots lists what changed from old to new
+func compareSnapshots(old, new Snapshot) HistoryEntry {
+	entry := HistoryEntry{
+		From:        old.ID,
+		To:          new.ID,
+		TakenAt:     new.TakenAt,
+		Reason:      new.Reason,
+		Added:       []ModChange{},
+		Removed:     []ModChange{},
+		Updated:     []ModChange{},
+		Collections: []CollectionChange{},
+	}
+
+	oldMods := map[string]SnapshotMod{}
+	for _, mod := range old.Mods {
+		oldMods[mod.Name] = mod
+	}
+	newMods := map[string]SnapshotMod{}
+	for _, mod := range new.Mods {
+		newMods[mod.Name] = mod
+		before, ok := oldMods[mod.Name]
+		switch {
+		case !ok:
+			entry.Added = append(entry.Added, modChange(mod.Name, 0, mod.Size))
+		case before.Hash != mod.Hash:
+			entry.Updated = append(entry.Updated, modChange(mod.Name, before.Size, mod.Size))
+		}
+		entry.SizeDelta += int64(mod.Size) - int64(before.Size)
+	}
+	for _, mod := range old.Mods {
+		if _, ok := newMods[mod.Name]; !ok {
+			entry.Removed = append(entry.Removed, modChange(mod.Name, mod.Size, 0))
+			entry.SizeDelta -= int64(mod.Size)
+		}
+	}
+
+	oldMembers := collectionMembers(old)
+	newMembers := collectionMembers(new)
+	for _, name := range new.Collections {
+		before, ok := oldMembers[name]
+		change := CollectionChange{Name: name, Created: !ok, Added: []string{}, Removed: []string{}}
+		for mod := range newMembers[name] {
+			if !before[mod] {
+				change.Added = append(change.Added, mod)
+			}
+		}
+		for mod := range before {
+			if !newMembers[name][mod] {
+				change.Removed = append(change.Removed, mod)
+			}
+		}
+		if change.Created || len(change.Added) > 0 || len(change.Removed) > 0 {
+			slices.Sort(change.Added)
+			slices.Sort(change.Removed)
+			entry.Collections = append(entry.Collections, change)
+		}
+	}
+	for _, name := range old.Collections {
+		if _, ok := newMembers[name]; !ok {
+			entry.Collections = append(entry.Collections, CollectionChange{Name: name, Deleted: true, Added: []string{}, Removed: []string{}})
+		}
+	}
+	slices.SortFunc(entry.Collections, func(a, b CollectionChange) int { return strings.Compare(a.Name, b.Name) })
+
+	// Heaviest first, like the other analyses
+	for _, changes := range [][]ModChange{entry.Added, entry.Removed, entry.Updated} {
+		slices.SortFunc(changes, func(a, b ModChange) int {
+			return cmp.Or(cmp.Compare(max(b.Size, b.OldSize), max(a.Size, a.OldSize)), strings.Compare(a.Name, b.Name))
+		})
+	}
+	entry.SizeDeltaHuman = humanize.Bytes(uint64(abs(entry.SizeDelta)))
+	if entry.SizeDelta > 0 {
+		entry.SizeDeltaHuman = "+" + entry.SizeDeltaHuman
+	} else if entry.SizeDelta < 0 {
+		entry.SizeDeltaHuman = "-" + entry.SizeDeltaHuman
+	}
+	return entry
+}
+
+func modChange(name string, oldSize, size uint64) ModChange {
+	return ModChange{
+		Name:         name,
+		OldSize:      oldSize,
+		OldSizeHuman: humanize.Bytes(oldSize),
+		Size:         size,
+		SizeHuman:    humanize.Bytes(size),
+	}
+}
+
+// collectionMembers returns the mods of each collection of snapshot;
+// every collection has an entry, even without mods
+func collectionMembers(snapshot Snapshot) map[string]map[string]bool {
+	members := map[string]map[string]bool{}
+	for _, name := range snapshot.Collections {
+		members[name] = map[string]bool{}
+	}
+	for _, mod := range snapshot.Mods {
+		for _, name := range mod.Collections {
+			if members[name] == nil {
+				members[name] = map[string]bool{}
+			}
+			members[name][mod.Name] = true
+		}
+	}
+	return members
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
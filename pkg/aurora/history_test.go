@@ -0,0 +1,188 @@
+package aurora
+
+import (
+	"aurora/internal/paths"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestTakeSnapshot(t *testing.T) {
+	a := newTestAurora(t, unusedMod, map[string][]string{"Main": {"Unused Mod"}})
+
+	snapshot, err := a.TakeSnapshot(SnapshotManual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Reason != SnapshotManual || !slices.Equal(snapshot.Collections, []string{"Main"}) {
+		t.Errorf("unexpected snapshot %+v", snapshot)
+	}
+	if len(snapshot.Mods) != 1 {
+		t.Fatalf("expected 1 mod, got %+v", snapshot.Mods)
+	}
+	mod := snapshot.Mods[0]
+	if mod.Name != "Unused Mod" || mod.Files != 1 || mod.Size != 4 || mod.Hash == "" || !slices.Equal(mod.Collections, []string{"Main"}) {
+		t.Errorf("unexpected mod %+v", mod)
+	}
+	if _, err := os.Stat(filepath.Join(paths.SnapshotsDir(), snapshot.ID+".json")); err != nil {
+		t.Errorf("expected the snapshot saved: %v", err)
+	}
+
+	t.Run("the hash follows file changes", func(t *testing.T) {
+		again, err := a.TakeSnapshot(SnapshotManual)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again.Mods[0].Hash != mod.Hash {
+			t.Error("expected the same hash for unchanged files")
+		}
+		os.WriteFile(filepath.Join(a.Config().Mods.Path, "Unused Mod", "a.tex"), []byte("new data"), 0644)
+		changed, err := a.TakeSnapshot(SnapshotManual)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed.Mods[0].Hash == mod.Hash {
+			t.Error("expected another hash for a changed file")
+		}
+	})
+
+	t.Run("a successful backup takes one", func(t *testing.T) {
+		a := newTestAurora(t, unusedMod, nil)
+		configure(t, a.SetFormat(FormatTarZst), a.AddInclusion("Unused"))
+		if _, err := a.RunBackup(context.Background(), RunBackupOptions{}, &recordingObserver{}); err != nil {
+			t.Fatal(err)
+		}
+		history, err := a.History(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history.Snapshots) != 1 || history.Snapshots[0].Reason != SnapshotBackup {
+			t.Errorf("expected a backup snapshot, got %+v", history.Snapshots)
+		}
+	})
+}
+
+func TestHistory(t *testing.T) {
+	t.Setenv(paths.EnvHome, t.TempDir())
+	a := &Aurora{}
+
+	t.Run("is empty without snapshots", func(t *testing.T) {
+		history, err := a.History(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history.Snapshots) != 0 || len(history.Entries) != 0 {
+			t.Errorf("expected an empty history, got %+v", history)
+		}
+	})
+
+	snapshots := []Snapshot{
+		{
+			ID: "20250101-100000.000000", Reason: SnapshotBackup, Collections: []string{"Main", "Old"},
+			Mods: []SnapshotMod{
+				{Name: "Body", Size: 100, Hash: "a", Collections: []string{"Main"}},
+				{Name: "Hair", Size: 50, Hash: "b", Collections: []string{"Main", "Old"}},
+				{Name: "Gone", Size: 30, Hash: "c", Collections: []string{}},
+			},
+		},
+		{
+			// Nothing changed
+			ID: "20250102-100000.000000", Reason: SnapshotManual, Collections: []string{"Main", "Old"},
+			Mods: []SnapshotMod{
+				{Name: "Body", Size: 100, Hash: "a", Collections: []string{"Main"}},
+				{Name: "Hair", Size: 50, Hash: "b", Collections: []string{"Main", "Old"}},
+				{Name: "Gone", Size: 30, Hash: "c", Collections: []string{}},
+			},
+		},
+		{
+			ID: "20250103-100000.000000", Reason: SnapshotBackup, Collections: []string{"Main", "New"},
+			Mods: []SnapshotMod{
+				{Name: "Body", Size: 120, Hash: "a2", Collections: []string{"Main", "New"}},
+				{Name: "Hair", Size: 50, Hash: "b", Collections: []string{}},
+				{Name: "Shoes", Size: 10, Hash: "d", Collections: []string{"Main"}},
+			},
+		},
+	}
+	for _, snapshot := range snapshots {
+		snapshot.Version = snapshotVersion
+		if err := saveSnapshot(snapshot); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("lists the changes newest first", func(t *testing.T) {
+		history, err := a.History(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history.Snapshots) != 3 || history.Snapshots[0].ID != "20250103-100000.000000" {
+			t.Fatalf("expected 3 snapshots newest first, got %+v", history.Snapshots)
+		}
+		if history.Snapshots[0].Size != 180 || history.Snapshots[0].Mods != 3 {
+			t.Errorf("unexpected summary %+v", history.Snapshots[0])
+		}
+		if len(history.Entries) != 1 {
+			t.Fatalf("expected the unchanged snapshot skipped, got %+v", history.Entries)
+		}
+
+		entry := history.Entries[0]
+		if entry.From != "20250102-100000.000000" || entry.To != "20250103-100000.000000" {
+			t.Errorf("unexpected snapshots compared: %s -> %s", entry.From, entry.To)
+		}
+		if len(entry.Added) != 1 || entry.Added[0].Name != "Shoes" || entry.Added[0].Size != 10 {
+			t.Errorf("expected Shoes added, got %+v", entry.Added)
+		}
+		if len(entry.Removed) != 1 || entry.Removed[0].Name != "Gone" || entry.Removed[0].OldSize != 30 {
+			t.Errorf("expected Gone removed, got %+v", entry.Removed)
+		}
+		if len(entry.Updated) != 1 || entry.Updated[0].Name != "Body" || entry.Updated[0].OldSize != 100 || entry.Updated[0].Size != 120 {
+			t.Errorf("expected Body updated, got %+v", entry.Updated)
+		}
+		if entry.SizeDelta != 0 || entry.SizeDeltaHuman != "0 B" {
+			t.Errorf("expected +20 +10 -30 to cancel out, got %d %q", entry.SizeDelta, entry.SizeDeltaHuman)
+		}
+
+		collections := map[string]CollectionChange{}
+		for _, change := range entry.Collections {
+			collections[change.Name] = change
+		}
+		if len(collections) != 3 {
+			t.Fatalf("expected Main, New and Old changed, got %+v", entry.Collections)
+		}
+		if main := collections["Main"]; main.Created || !slices.Equal(main.Added, []string{"Shoes"}) || !slices.Equal(main.Removed, []string{"Hair"}) {
+			t.Errorf("unexpected Main change %+v", main)
+		}
+		if created := collections["New"]; !created.Created || !slices.Equal(created.Added, []string{"Body"}) {
+			t.Errorf("expected New created with Body, got %+v", created)
+		}
+		if !collections["Old"].Deleted {
+			t.Errorf("expected Old deleted, got %+v", collections["Old"])
+		}
+	})
+
+	t.Run("limits to the most recent snapshots", func(t *testing.T) {
+		history, err := a.History(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history.Snapshots) != 1 || len(history.Entries) != 1 {
+			t.Errorf("expected the latest snapshot and its changes, got %+v", history)
+		}
+	})
+
+	t.Run("keeps at most maxSnapshots", func(t *testing.T) {
+		for i := range maxSnapshots {
+			saveSnapshot(Snapshot{Version: snapshotVersion, ID: "20240101-000000." + string(rune('a'+i%26)) + string(rune('a'+i/26))})
+		}
+		pruneSnapshots()
+		ids, err := snapshotIDs()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ids) != maxSnapshots || ids[len(ids)-1] != "20250103-100000.000000" {
+			t.Errorf("expected the %d newest snapshots kept, got %d ending with %s", maxSnapshots, len(ids), ids[len(ids)-1])
+		}
+	})
+}
@@ -0,0 +1,188 @@
+package aurora
+
+import (
+	"aurora/internal/config"
+	"aurora/internal/logger"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// ErrHookFailed is returned when the pre-backup hook fails or times out:
+// the backup doesn't start
+var ErrHookFailed = errors.New("backup hook failed")
+
+// DefaultHookTimeout bounds a hook when the config sets no timeout
+const DefaultHookTimeout = 10 * time.Minute
+
+// hookWaitDelay is how long a hook's output is drained after it is killed:
+// children it started may keep the pipes open
+const hookWaitDelay = 5 * time.Second
+
+// Hook names, as shown in logs and errors
+const (
+	hookPre  = "pre-backup"
+	hookPost = "post-backup"
+)
+
+// Backup statuses passed to the post-backup hook as {{.Status}}
+const (
+	HookStatusSuccess   = "success"
+	HookStatusFailed    = "failed"
+	HookStatusCancelled = "cancelled"
+)
+
+// HookVars are the variables a hook command templates, as {{.OutputDir}}.
+// Only the post-backup hook gets the backup's outcome.
+type HookVars struct {
+	Hook           string   // "pre-backup" or "post-backup"
+	OutputDir      string   // absolute output directory, also the hook's working directory
+	Resume         bool     // the run resumes an interrupted backup
+	Incremental    bool     // only changed mods are backed up (aurora watch)
+	Archives       []string // post: absolute paths of the backup set's archives
+	Mods           int      // post: mods in the backup
+	OriginalSize   uint64   // post: bytes before compression
+	CompressedSize uint64   // post: bytes written
+	Ratio          string   // post: compressed / original, as "42.0%"
+	Status         string   // post: "success", "failed" or "cancelled"
+	Error          string   // post: why the backup failed
+}
+
+// hookFuncs are available in hook templates: {{quote .OutputDir}} quotes a
+// value for the shell, {{bytes .CompressedSize}} formats a size
+var hookFuncs = template.FuncMap{
+	"quote": shellQuote,
+	"bytes": humanize.Bytes,
+}
+
+// parseHook parses a hook command template
+func parseHook(command string) (*template.Template, error) {
+	tmpl, err := template.New("hook").Funcs(hookFuncs).Option("missingkey=error").Parse(command)
+	if err != nil {
+		return nil, fmt.Errorf("parse hook command: %w", err)
+	}
+	return tmpl, nil
+}
+
+// expandHook renders a hook command with vars
+func expandHook(command string, vars HookVars) (string, error) {
+	tmpl, err := parseHook(command)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, vars); err != nil {
+		return "", fmt.Errorf("expand hook command: %w", err)
+	}
+	return b.String(), nil
+}
+
+// ValidateHook checks a hook command expands: unknown variables or a
+// template syntax error are reported before a backup runs into them
+func ValidateHook(command string) error {
+	_, err := expandHook(command, HookVars{})
+	return err
+}
+
+// SetPreBackupHook sets the command run before each backup ("" = none)
+func (a *Aurora) SetPreBackupHook(command string) error {
+	if err := ValidateHook(command); err != nil {
+		return err
+	}
+	return a.updateConfig(func(cfg *config.Config) {
+		cfg.Hooks.PreBackup = command
+	})
+}
+
+// SetPostBackupHook sets the command run after each backup ("" = none)
+func (a *Aurora) SetPostBackupHook(command string) error {
+	if err := ValidateHook(command); err != nil {
+		return err
+	}
+	return a.updateConfig(func(cfg *config.Config) {
+		cfg.Hooks.PostBackup = command
+	})
+}
+
+// SetHookTimeout sets how many seconds each hook may run (0 = DefaultHookTimeout)
+func (a *Aurora) SetHookTimeout(seconds int) error {
+	if seconds < 0 {
+		return fmt.Errorf("hook timeout must be 0 or more seconds, got %d", seconds)
+	}
+	return a.updateConfig(func(cfg *config.Config) {
+		cfg.Hooks.Timeout = seconds
+	})
+}
+
+// hookTimeout returns the configured hook timeout
+func (a *Aurora) hookTimeout() time.Duration {
+	cfg := a.Config()
+	if cfg.Hooks.Timeout > 0 {
+		return time.Duration(cfg.Hooks.Timeout) * time.Second
+	}
+	return DefaultHookTimeout
+}
+
+// hookVars returns the variables every hook of a run shares
+func (a *Aurora) hookVars(hook string, opts RunBackupOptions) HookVars {
+	cfg := a.Config()
+	outputDir, err := filepath.Abs(cfg.Output)
+	if err != nil {
+		outputDir = cfg.Output
+	}
+	return HookVars{Hook: hook, OutputDir: outputDir, Resume: opts.Resume, Incremental: !opts.Resume && len(opts.Mods) > 0}
+}
+
+// runHook runs command through the shell with vars, logging its output.
+// An empty command does nothing. It fails with ErrHookFailed when the
+// command exits non-zero or outlives timeout, and with ErrBackupCancelled
+// when ctx is done first.
+func runHook(ctx context.Context, command string, vars HookVars, timeout time.Duration) error {
+	if strings.TrimSpace(command) == "" {
+		return nil
+	}
+	line, err := expandHook(command, vars)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrHookFailed, vars.Hook, err)
+	}
+
+	hookCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := shellCommand(hookCtx, line)
+	cmd.Dir = vars.OutputDir
+	cmd.WaitDelay = hookWaitDelay
+
+	logger.Info("Running %s hook: %s", vars.Hook, line)
+	start := time.Now()
+	output, err := cmd.CombinedOutput()
+	var last string
+	for _, outputLine := range strings.Split(string(output), "\n") {
+		if outputLine = strings.TrimRight(outputLine, "\r"); strings.TrimSpace(outputLine) != "" {
+			logger.Info("[%s] %s", vars.Hook, outputLine)
+			last = outputLine
+		}
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		logger.Warn("%s hook stopped: backup cancelled", vars.Hook)
+		return ErrBackupCancelled
+	case errors.Is(hookCtx.Err(), context.DeadlineExceeded):
+		logger.Error("%s hook timed out after %s", vars.Hook, timeout)
+		return fmt.Errorf("%w: %s: timed out after %s", ErrHookFailed, vars.Hook, timeout)
+	case err != nil:
+		logger.Error("%s hook failed: %v", vars.Hook, err)
+		if last != "" {
+			return fmt.Errorf("%w: %s: %v (%s)", ErrHookFailed, vars.Hook, err, last)
+		}
+		return fmt.Errorf("%w: %s: %v", ErrHookFailed, vars.Hook, err)
+	}
+	logger.Info("%s hook done in %s", vars.Hook, time.Since(start).Round(time.Millisecond))
+	return nil
+}
@@ -0,0 +1,150 @@
+package aurora
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpandHook(t *testing.T) {
+	vars := HookVars{Hook: hookPost, OutputDir: "/backups/my mods", Mods: 12, CompressedSize: 1500000, Status: HookStatusSuccess}
+
+	t.Run("templates variables", func(t *testing.T) {
+		got, err := expandHook("notify {{.Status}}: {{.Mods}} mods, {{bytes .CompressedSize}} in {{quote .OutputDir}}", vars)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "notify success: 12 mods, 1.5 MB in " + shellQuote("/backups/my mods")
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("rejects unknown variables and bad syntax", func(t *testing.T) {
+		for _, command := range []string{"echo {{.Size}}", "echo {{.Mods"} {
+			if err := ValidateHook(command); err == nil {
+				t.Errorf("expected %q to be rejected", command)
+			}
+		}
+		if err := ValidateHook("rsync -a {{range .Archives}}{{quote .}} {{end}}nas:/backups"); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestRunHook(t *testing.T) {
+	vars := HookVars{Hook: hookPre, OutputDir: t.TempDir()}
+
+	t.Run("runs in the output directory", func(t *testing.T) {
+		if err := runHook(context.Background(), "echo {{.Hook}} > hook.txt", vars, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		content, err := os.ReadFile(filepath.Join(vars.OutputDir, "hook.txt"))
+		if err != nil || strings.TrimSpace(string(content)) != hookPre {
+			t.Errorf("got %q, %v", content, err)
+		}
+	})
+
+	t.Run("does nothing without a command", func(t *testing.T) {
+		if err := runHook(context.Background(), " ", vars, time.Minute); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("fails on a non-zero exit", func(t *testing.T) {
+		err := runHook(context.Background(), "echo game is running && exit 3", vars, time.Minute)
+		if !errors.Is(err, ErrHookFailed) || !strings.Contains(err.Error(), "game is running") {
+			t.Errorf("expected ErrHookFailed with the output, got %v", err)
+		}
+	})
+
+	t.Run("fails on timeout", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("needs sleep")
+		}
+		err := runHook(context.Background(), "exec sleep 5", vars, 50*time.Millisecond)
+		if !errors.Is(err, ErrHookFailed) || !strings.Contains(err.Error(), "timed out") {
+			t.Errorf("expected a timeout, got %v", err)
+		}
+	})
+
+	t.Run("quotes values the shell would act on", func(t *testing.T) {
+		t.Setenv(envHookHelper, "1")
+		t.Setenv("NAME", "expanded")
+		vars := vars
+		vars.Error = `say "hi" & 100% of %NAME% isn't $NAME (!)`
+		command := shellQuote(os.Args[0]) + " " + shellQuote("-test.run=^TestHookHelper$") + " -- {{quote .Error}}"
+		if err := runHook(context.Background(), command, vars, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		content, err := os.ReadFile(filepath.Join(vars.OutputDir, "args.txt"))
+		if err != nil || string(content) != vars.Error {
+			t.Errorf("got %q, %v, want %q", content, err, vars.Error)
+		}
+	})
+
+	t.Run("stops when cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := runHook(ctx, "echo hi", vars, time.Minute); !errors.Is(err, ErrBackupCancelled) {
+			t.Errorf("expected ErrBackupCancelled, got %v", err)
+		}
+	})
+}
+
+// envHookHelper makes TestHookHelper act as a hook's command
+const envHookHelper = "AURORA_TEST_HOOK_HELPER"
+
+// TestHookHelper writes the arguments after "--" to args.txt, one per line,
+// so a test can check what a hook's command received
+func TestHookHelper(t *testing.T) {
+	if os.Getenv(envHookHelper) != "1" {
+		t.Skip("only run as a hook")
+	}
+	args := os.Args
+	for i, arg := range args {
+		if arg == "--" {
+			args = args[i+1:]
+			break
+		}
+	}
+	if err := os.WriteFile("args.txt", []byte(strings.Join(args, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunBackupHooks(t *testing.T) {
+	t.Run("a failing pre-backup hook stops the backup", func(t *testing.T) {
+		a := newTestAurora(t, unusedMod, nil)
+		configure(t, a.SetPreBackupHook("exit 1"), a.SetPostBackupHook("echo ran > post.txt"))
+		observer := &recordingObserver{}
+		_, err := a.RunBackup(context.Background(), RunBackupOptions{}, observer)
+		if !errors.Is(err, ErrHookFailed) {
+			t.Errorf("expected ErrHookFailed, got %v", err)
+		}
+		if len(observer.validations) != 0 {
+			t.Error("expected no preview after the pre-backup hook failed")
+		}
+		if _, err := os.Stat(filepath.Join(a.Config().Output, "post.txt")); err == nil {
+			t.Error("expected no post-backup hook")
+		}
+	})
+
+	t.Run("the post-backup hook gets the outcome", func(t *testing.T) {
+		a := newTestAurora(t, unusedMod, nil)
+		configure(t, a.SetPostBackupHook("echo {{.Status}} {{.Mods}} > post.txt"))
+		_, err := a.RunBackup(context.Background(), RunBackupOptions{}, &recordingObserver{})
+		if !errors.Is(err, ErrNothingToBackup) {
+			t.Errorf("expected ErrNothingToBackup, got %v", err)
+		}
+		content, _ := os.ReadFile(filepath.Join(a.Config().Output, "post.txt"))
+		if strings.TrimSpace(string(content)) != "failed 0" {
+			t.Errorf("got %q", content)
+		}
+	})
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+
+package aurora
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+)
+
+// shellCommand runs line with sh
+func shellCommand(ctx context.Context, line string) *exec.Cmd {
+	return exec.CommandContext(ctx, "sh", "-c", line)
+}
+
+// shellQuote quotes s as one sh word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,40 @@
+//go:build windows
+
+package aurora
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// createNoWindow keeps a console from flashing up when the desktop app runs a hook
+const createNoWindow = 0x08000000
+
+// shellCommand runs line with cmd.exe. The command line is passed as is:
+// Go's argument escaping doesn't match cmd's quoting rules.
+func shellCommand(ctx context.Context, line string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "cmd.exe")
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CmdLine:       `cmd.exe /S /C "` + line + `"`,
+		HideWindow:    true,
+		CreationFlags: createNoWindow,
+	}
+	return cmd
+}
+
+// cmdEscaper puts a caret before each character cmd would act on. A caret
+// ahead of % also keeps %NAME% from expanding: cmd looks up "NAME^" instead.
+var cmdEscaper = strings.NewReplacer(
+	"^", "^^", `"`, `^"`, "%", "^%", "!", "^!",
+	"&", "^&", "|", "^|", "<", "^<", ">", "^>", "(", "^(", ")", "^)",
+)
+
+// shellQuote quotes s as one argument of the program cmd starts: it is
+// quoted for the program's command line parser, then escaped for cmd.
+// Quoted with plain double quotes, a value's own quotes would end the
+// quoting and its %NAME% would expand.
+func shellQuote(s string) string {
+	return cmdEscaper.Replace(syscall.EscapeArg(s))
+}
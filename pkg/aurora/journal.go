@@ -0,0 +1,323 @@
+package aurora
+
+import (
+	"aurora/internal/logger"
+	"aurora/internal/util"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// StagingDir holds the archives of a backup until it completes, with the
+// journal that lets an interrupted run resume. Its presence in the output
+// directory marks an incomplete backup.
+const StagingDir = "backup_incomplete"
+
+const (
+	journalFile    = "journal.json"
+	journalVersion = 1
+)
+
+// journal records the progress of a backup run so an interrupted run can
+// continue from its last finished batch
+type journal struct {
+	Version   int       `json:"version"`
+	StartedAt time.Time `json:"startedAt"`
+	Format    string    `json:"format,omitempty"`   // archive format, kept on resume; "" = zip
+	Level     int       `json:"level"`              // compression level, kept on resume
+	Estimate  uint64    `json:"estimate,omitempty"` // uncalibrated size estimate, for the manifest
+	Folders   []string  `json:"folders"`            // every mod folder of the backup, in order
+
+	// Archive layout, kept on resume. Journals without groups hold a
+	// single layout backup of Folders.
+	Layout      string         `json:"layout,omitempty"`
+	MaxPartSize uint64         `json:"maxPartSize,omitempty"` // 0 = no limit
+	Groups      []archiveGroup `json:"groups,omitempty"`
+	Encrypted   bool           `json:"encrypted,omitempty"`   // parts are encrypted once compressed
+	Incremental bool           `json:"incremental,omitempty"` // see backupLayout
+
+	// The backup preview, for the report (see BackupReport). Journals
+	// without items report the mods of Folders only.
+	Items    []BackupItem    `json:"items,omitempty"`
+	Warnings []BackupWarning `json:"warnings,omitempty"`
+
+	Batches []journalBatch `json:"batches"` // finished batches
+
+	// Finalizing is set once every batch is done, with the name each part
+	// takes in the output directory: parts are then moved there, over the
+	// previous backup's, and the journal stays until the manifest lists them
+	Finalizing bool `json:"finalizing,omitempty"`
+}
+
+// journalBatch is one finished compression call and the archives it wrote
+type journalBatch struct {
+	Dir            string        `json:"dir"`             // relative to the staging dir
+	Group          string        `json:"group,omitempty"` // archive prefix of its group, "" = single layout
+	Folders        []string      `json:"folders"`
+	Parts          []journalPart `json:"parts"`
+	OriginalSize   uint64        `json:"originalSize"`
+	CompressedSize uint64        `json:"compressedSize"`
+}
+
+type journalPart struct {
+	Name   string `json:"name"` // relative to the batch dir
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	Dest   string `json:"dest,omitempty"` // name in the output directory, set when finalizing
+}
+
+func stagingPath(outputDir string) string {
+	return filepath.Join(outputDir, StagingDir)
+}
+
+// loadJournal reads the journal of an interrupted backup in outputDir.
+// Returns nil without error when there is none.
+func loadJournal(outputDir string) (*journal, error) {
+	path := filepath.Join(stagingPath(outputDir), journalFile)
+	var j journal
+	if err := util.ReadJSONFile(path, &j); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read backup journal %s: %w", path, err)
+	}
+	if j.Version != journalVersion {
+		return nil, fmt.Errorf("backup journal %s: unsupported version %d", path, j.Version)
+	}
+	return &j, nil
+}
+
+// save writes the journal atomically: a crash mid-write must not lose the
+// record of batches that are already done
+func (j *journal) save(outputDir string) error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(stagingPath(outputDir), journalFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write backup journal: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("write backup journal: %w", err)
+	}
+	return nil
+}
+
+// groups returns the archive groups of the backup
+func (j *journal) groups() []archiveGroup {
+	if len(j.Groups) == 0 {
+		return []archiveGroup{{Archive: singleArchive, Folders: j.Folders}}
+	}
+	return j.Groups
+}
+
+// format returns the archive format of the backup
+func (j *journal) format() archiveFormat {
+	return formatByName(j.Format)
+}
+
+// archive returns the archive prefix of the group batch belongs to
+func (b journalBatch) archive() string {
+	if b.Group == "" {
+		return singleArchive
+	}
+	return b.Group
+}
+
+// checkPassphrase makes sure an encrypted backup resumes with the
+// passphrase its finished parts were encrypted with: a mix can't be restored
+func (j *journal) checkPassphrase(outputDir, passphrase string) error {
+	if !j.Encrypted {
+		return nil
+	}
+	if passphrase == "" {
+		return ErrPassphraseRequired
+	}
+	for _, batch := range j.Batches {
+		for _, part := range batch.Parts {
+			err := checkPassphrase(j.partPath(outputDir, batch, part), passphrase)
+			if errors.Is(err, ErrWrongPassphrase) {
+				return err
+			}
+			if err == nil {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// partPath returns where a finished part is: in its batch dir, or in the
+// output directory once a finalizing backup moved it
+func (j *journal) partPath(outputDir string, batch journalBatch, part journalPart) string {
+	staged := filepath.Join(stagingPath(outputDir), batch.Dir, part.Name)
+	if _, err := os.Stat(staged); err != nil && j.Finalizing && part.Dest != "" {
+		return filepath.Join(outputDir, part.Dest)
+	}
+	return staged
+}
+
+// doneFolders returns the folders covered by finished batches
+func (j *journal) doneFolders() []string {
+	var done []string
+	for _, batch := range j.Batches {
+		done = append(done, batch.Folders...)
+	}
+	return done
+}
+
+// pendingFolders returns the folders no finished batch covers, in order
+func (j *journal) pendingFolders() []string {
+	done := j.doneFolders()
+	var pending []string
+	for _, folder := range j.Folders {
+		if !slices.Contains(done, folder) {
+			pending = append(pending, folder)
+		}
+	}
+	return pending
+}
+
+// verify drops the finished batches whose archives are missing or changed
+// since they were written, so their mods get compressed again
+func (j *journal) verify(outputDir string) {
+	staging := stagingPath(outputDir)
+	kept := j.Batches[:0]
+	for _, batch := range j.Batches {
+		batchDir := filepath.Join(staging, batch.Dir)
+		if err := verifyBatch(batchDir, batch); err != nil {
+			logger.Warn("Backup batch %s failed verification, redoing it: %v", batch.Dir, err)
+			os.RemoveAll(batchDir)
+			continue
+		}
+		kept = append(kept, batch)
+	}
+	j.Batches = kept
+}
+
+func verifyBatch(batchDir string, batch journalBatch) error {
+	for _, part := range batch.Parts {
+		if err := verifyPart(filepath.Join(batchDir, part.Name), part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyPart checks the file at path has the size and checksum of part
+func verifyPart(path string, part journalPart) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Size() != part.Size {
+		return fmt.Errorf("%s: size %d, expected %d", filepath.Base(path), info.Size(), part.Size)
+	}
+	sum, err := fileSHA256(path)
+	if err != nil {
+		return err
+	}
+	if sum != part.SHA256 {
+		return fmt.Errorf("%s: checksum mismatch", filepath.Base(path))
+	}
+	return nil
+}
+
+// describeParts records the archives a batch wrote
+func describeParts(batchDir string) ([]journalPart, error) {
+	names, err := stagedArchives(batchDir)
+	if err != nil {
+		return nil, err
+	}
+	parts := make([]journalPart, 0, len(names))
+	for _, name := range names {
+		path := filepath.Join(batchDir, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		sum, err := fileSHA256(path)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, journalPart{Name: name, Size: info.Size(), SHA256: sum})
+	}
+	return parts, nil
+}
+
+// stagedArchives lists the archive names of any format in a batch dir,
+// encrypted or not, sorted
+func stagedArchives(batchDir string) ([]string, error) {
+	entries, err := os.ReadDir(batchDir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if _, ok := formatOfFile(entry.Name()); ok && !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// PendingBackup returns the interrupted backup waiting in the output
+// directory, or nil when there is none
+func (a *Aurora) PendingBackup() (*PendingBackup, error) {
+	j, err := loadJournal(a.Config().Output)
+	if err != nil || j == nil {
+		return nil, err
+	}
+	return &PendingBackup{
+		StartedAt:  j.StartedAt,
+		DoneMods:   len(j.doneFolders()),
+		TotalMods:  len(j.Folders),
+		Encrypted:  j.Encrypted,
+		Finalizing: j.Finalizing,
+	}, nil
+}
+
+// DiscardPendingBackup deletes the interrupted backup in the output
+// directory. A backup interrupted while finalizing can't be discarded: it
+// may have replaced parts of the previous backup already. Fails with
+// ErrBackupRunning while another process backs up there.
+func (a *Aurora) DiscardPendingBackup() error {
+	outputDir := a.Config().Output
+	lock, err := lockOutput(outputDir)
+	if err != nil {
+		return err
+	}
+	defer lock.release()
+	if j, err := loadJournal(outputDir); err == nil && j != nil && j.Finalizing {
+		return ErrFinalizingBackup
+	}
+	staging := stagingPath(outputDir)
+	logger.Info("Discarding interrupted backup: %s", staging)
+	if err := os.RemoveAll(staging); err != nil {
+		return fmt.Errorf("discard interrupted backup: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package aurora
+
+import (
+	"aurora/internal/config"
+	"aurora/internal/logger"
+	"aurora/internal/repository"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Archive layouts exposed in settings. Every mod is stored once: with
+// grouped layouts, mods several collections use go to a shared archive.
+const (
+	LayoutSingle     = "single"     // one archive set for every mod
+	LayoutCollection = "collection" // one archive set per collection
+	LayoutGroup      = "group"      // one archive set per top-level mod selector folder
+)
+
+// Archive name prefixes, without the .zip extension
+const (
+	singleArchive     = "backup_part"
+	collectionArchive = "backup_collection_"
+	groupArchive      = "backup_group_"
+	sharedArchive     = "backup_shared"
+	otherArchive      = "backup_other"
+)
+
+// partSizeHeadroom keeps batches below the max part size: ZIP headers and
+// stored (incompressible) files can make an archive slightly bigger than
+// its files
+const partSizeHeadroom = 0.01
+
+// archiveGroup is a set of mods written to archives of their own
+type archiveGroup struct {
+	Name    string   `json:"name"`    // collection or folder, "" for the single layout
+	Archive string   `json:"archive"` // archive name prefix, unique in a backup
+	Folders []string `json:"folders"`
+}
+
+// normalizeLayout maps unknown or empty layouts to LayoutSingle
+func normalizeLayout(layout string) string {
+	if layout == LayoutCollection || layout == LayoutGroup {
+		return layout
+	}
+	return LayoutSingle
+}
+
+// groupMods splits the selected mods into archive groups for layout.
+// folderOf maps a mod to its folder on disk; collections matching an
+// exclusion filter don't claim mods.
+func groupMods(mods []*repository.PenumbraMod, layout string, filters []string, folderOf func(*repository.PenumbraMod) string) []archiveGroup {
+	if normalizeLayout(layout) == LayoutSingle {
+		folders := make([]string, len(mods))
+		for i, mod := range mods {
+			folders[i] = folderOf(mod)
+		}
+		return []archiveGroup{{Archive: singleArchive, Folders: folders}}
+	}
+
+	byName := make(map[string][]string)
+	var shared, other []string
+	for _, mod := range mods {
+		var name string
+		if layout == LayoutCollection {
+			var claimed []string
+			for _, col := range mod.Collections {
+				excluded := slices.ContainsFunc(filters, func(f string) bool { return hasPrefixFold(col.Name, f) })
+				if !excluded && !slices.Contains(claimed, col.Name) {
+					claimed = append(claimed, col.Name)
+				}
+			}
+			if len(claimed) > 1 {
+				shared = append(shared, folderOf(mod))
+				continue
+			}
+			if len(claimed) == 1 {
+				name = claimed[0]
+			}
+		} else {
+			name, _, _ = strings.Cut(mod.Folder, "/")
+		}
+		if name == "" {
+			other = append(other, folderOf(mod))
+			continue
+		}
+		byName[name] = append(byName[name], folderOf(mod))
+	}
+
+	prefix := collectionArchive
+	if layout == LayoutGroup {
+		prefix = groupArchive
+	}
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var groups []archiveGroup
+	used := make(map[string]bool)
+	for _, name := range names {
+		archive := prefix + archiveSlug(name)
+		for n := 2; used[archive]; n++ {
+			archive = fmt.Sprintf("%s%s-%d", prefix, archiveSlug(name), n)
+		}
+		used[archive] = true
+		groups = append(groups, archiveGroup{Name: name, Archive: archive, Folders: byName[name]})
+	}
+	if len(shared) > 0 {
+		groups = append(groups, archiveGroup{Name: "Shared", Archive: sharedArchive, Folders: shared})
+	}
+	if len(other) > 0 {
+		groups = append(groups, archiveGroup{Name: "Other", Archive: otherArchive, Folders: other})
+	}
+	return groups
+}
+
+// archiveSlug turns a collection or folder name into a file name part:
+// letters, digits and dashes, other runs of characters become "_"
+func archiveSlug(name string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range name {
+		if r == '-' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			b.WriteRune(r)
+			underscore = false
+		} else if !underscore && b.Len() > 0 {
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+	slug := strings.TrimSuffix(b.String(), "_")
+	if slug == "" {
+		return "unnamed"
+	}
+	return slug
+}
+
+// partNames names the archives of a group with the format extension ext: a
+// lone archive keeps the bare prefix, several are numbered from 01
+func partNames(archive, ext string, count int) []string {
+	if count == 1 {
+		return []string{archive + ext}
+	}
+	names := make([]string, count)
+	for i := range names {
+		names[i] = fmt.Sprintf("%s_%02d%s", archive, i+1, ext)
+	}
+	return names
+}
+
+// isBackupArchive reports whether a file name is an archive of any layout
+// and format, encrypted or not
+func isBackupArchive(name string) bool {
+	format, ok := formatOfFile(name)
+	if !ok {
+		return false
+	}
+	base := strings.TrimSuffix(strings.TrimSuffix(name, EncryptedExt), format.ext)
+	if strings.HasPrefix(base, collectionArchive) || strings.HasPrefix(base, groupArchive) {
+		return true
+	}
+	for _, archive := range []string{singleArchive, sharedArchive, otherArchive} {
+		if base == archive || strings.HasPrefix(base, archive+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+// onlyMods keeps the folders of groups whose mod folder name is in only,
+// dropping groups left empty
+func onlyMods(groups []archiveGroup, only map[string]bool) []archiveGroup {
+	var kept []archiveGroup
+	for _, group := range groups {
+		var folders []string
+		for _, folder := range group.Folders {
+			if only[filepath.Base(folder)] {
+				folders = append(folders, folder)
+			}
+		}
+		if len(folders) > 0 {
+			group.Folders = folders
+			kept = append(kept, group)
+		}
+	}
+	return kept
+}
+
+// ListBackupArchives returns the archive names of the backup set in
+// outputDir ("" = current working directory): the manifest's order when
+// there is one, else every archive of any layout, sorted
+func ListBackupArchives(outputDir string) []string {
+	// An encrypted manifest can't be read here: the names tell enough
+	if manifest, err := ReadManifest(outputDir, ""); err == nil && len(manifest.Parts) > 0 {
+		var parts []string
+		for _, part := range manifest.Parts {
+			if _, err := os.Stat(filepath.Join(outputDir, part)); err == nil {
+				parts = append(parts, part)
+			}
+		}
+		if len(parts) == len(manifest.Parts) {
+			return parts
+		}
+		logger.Warn("Backup manifest in %s lists missing archives, listing the directory instead", outputDir)
+	}
+
+	dir := outputDir
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && isBackupArchive(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// SetLayout sets the backup archive layout ("single", "collection" or "group")
+func (a *Aurora) SetLayout(layout string) error {
+	layout = normalizeLayout(layout)
+	return a.updateConfig(func(cfg *config.Config) {
+		cfg.Layout = layout
+	})
+}
+
+// GetLayout returns the current archive layout, normalized
+func (a *Aurora) GetLayout() string {
+	return normalizeLayout(a.Config().Layout)
+}
+
+// SetMaxPartSize sets the largest archive size in bytes (0 = no limit)
+func (a *Aurora) SetMaxPartSize(size uint64) error {
+	return a.updateConfig(func(cfg *config.Config) {
+		cfg.MaxPartSize = size
+	})
+}
+
+// GetMaxPartSize returns the largest archive size in bytes (0 = no limit)
+func (a *Aurora) GetMaxPartSize() uint64 {
+	return a.Config().MaxPartSize
+}
@@ -0,0 +1,188 @@
+package aurora
+
+import (
+	"aurora/internal/repository"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeManifestFile(t *testing.T, dir string, manifest BackupManifest) {
+	t.Helper()
+	data, _ := json.Marshal(manifest)
+	if err := os.WriteFile(filepath.Join(dir, ManifestFile), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGroupMods(t *testing.T) {
+	main := &repository.PenumbraCollection{Name: "Main"}
+	alt := &repository.PenumbraCollection{Name: "Alt Outfits"}
+	test := &repository.PenumbraCollection{Name: "Test"}
+	mods := []*repository.PenumbraMod{
+		{Name: "Body", Collections: []*repository.PenumbraCollection{main}, Folder: "Bodies/Female"},
+		{Name: "Dress", Collections: []*repository.PenumbraCollection{alt}, Folder: "Gear"},
+		{Name: "Hair", Collections: []*repository.PenumbraCollection{main, alt}, Folder: "Hair"},
+		{Name: "Shoes", Collections: []*repository.PenumbraCollection{main, test}, Folder: "Gear"},
+		{Name: "Included", Folder: ""},
+	}
+	folderOf := func(mod *repository.PenumbraMod) string { return "/mods/" + mod.Name }
+
+	t.Run("single layout keeps every mod in order", func(t *testing.T) {
+		got := groupMods(mods, "", nil, folderOf)
+		want := []archiveGroup{{Archive: "backup_part", Folders: []string{"/mods/Body", "/mods/Dress", "/mods/Hair", "/mods/Shoes", "/mods/Included"}}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("groupMods = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("collection layout stores shared mods once", func(t *testing.T) {
+		// Test is excluded: Shoes only belongs to Main then
+		got := groupMods(mods, LayoutCollection, []string{"test"}, folderOf)
+		want := []archiveGroup{
+			{Name: "Alt Outfits", Archive: "backup_collection_Alt_Outfits", Folders: []string{"/mods/Dress"}},
+			{Name: "Main", Archive: "backup_collection_Main", Folders: []string{"/mods/Body", "/mods/Shoes"}},
+			{Name: "Shared", Archive: "backup_shared", Folders: []string{"/mods/Hair"}},
+			{Name: "Other", Archive: "backup_other", Folders: []string{"/mods/Included"}},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("groupMods = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("group layout uses top-level selector folders", func(t *testing.T) {
+		got := groupMods(mods, LayoutGroup, nil, folderOf)
+		want := []archiveGroup{
+			{Name: "Bodies", Archive: "backup_group_Bodies", Folders: []string{"/mods/Body"}},
+			{Name: "Gear", Archive: "backup_group_Gear", Folders: []string{"/mods/Dress", "/mods/Shoes"}},
+			{Name: "Hair", Archive: "backup_group_Hair", Folders: []string{"/mods/Hair"}},
+			{Name: "Other", Archive: "backup_other", Folders: []string{"/mods/Included"}},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("groupMods = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("names that slug alike get distinct archives", func(t *testing.T) {
+		mods := []*repository.PenumbraMod{
+			{Name: "A", Folder: "My Mods"},
+			{Name: "B", Folder: "My.Mods"},
+		}
+		got := groupMods(mods, LayoutGroup, nil, folderOf)
+		if len(got) != 2 || got[0].Archive != "backup_group_My_Mods" || got[1].Archive != "backup_group_My_Mods-2" {
+			t.Errorf("expected distinct archive names, got %+v", got)
+		}
+	})
+}
+
+func TestArchiveSlug(t *testing.T) {
+	tests := map[string]string{
+		"Main":              "Main",
+		"Alt Outfits":       "Alt_Outfits",
+		"  Gear / Shoes!  ": "Gear_Shoes",
+		"ÆØÅ":               "unnamed",
+		"2024-raid":         "2024-raid",
+	}
+	for name, want := range tests {
+		if got := archiveSlug(name); got != want {
+			t.Errorf("archiveSlug(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestPartNames(t *testing.T) {
+	if got := partNames("backup_part", ".zip", 1); !reflect.DeepEqual(got, []string{"backup_part.zip"}) {
+		t.Errorf("expected a bare name for a lone archive, got %v", got)
+	}
+	want := []string{"backup_group_Gear_01.zip", "backup_group_Gear_02.zip"}
+	if got := partNames("backup_group_Gear", ".zip", 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("partNames = %v, want %v", got, want)
+	}
+	want = []string{"backup_shared_01.tar.zst", "backup_shared_02.tar.zst"}
+	if got := partNames("backup_shared", ".tar.zst", 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("partNames = %v, want %v", got, want)
+	}
+}
+
+func TestIsBackupArchive(t *testing.T) {
+	tests := map[string]bool{
+		"backup_part.zip":                  true,
+		"backup_part_03.zip":               true,
+		"backup_collection_Main.zip":       true,
+		"backup_collection_Main_02.zip":    true,
+		"backup_group_Gear.zip":            true,
+		"backup_shared.zip":                true,
+		"backup_other_01.zip":              true,
+		"backup_part.tar.zst":              true,
+		"backup_group_Gear_02.gdelta.enc":  true,
+		"backup_part.tar":                  false,
+		"backup_partial.zip":               false,
+		"backup_manifest.json":             false,
+		"my_backup_part.zip":               false,
+		"backup_collection_Main.zip.wrong": false,
+	}
+	for name, want := range tests {
+		if got := isBackupArchive(name); got != want {
+			t.Errorf("isBackupArchive(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestListBackupArchives(t *testing.T) {
+	write := func(dir string, names ...string) {
+		for _, name := range names {
+			os.WriteFile(filepath.Join(dir, name), []byte("zip"), 0644)
+		}
+	}
+
+	t.Run("lists archives of any layout", func(t *testing.T) {
+		dir := t.TempDir()
+		write(dir, "backup_shared.zip", "backup_collection_Main_01.zip", "backup_collection_Main_02.zip", "notes.zip", "backup_manifest.json")
+		want := []string{"backup_collection_Main_01.zip", "backup_collection_Main_02.zip", "backup_shared.zip"}
+		if got := ListBackupArchives(dir); !reflect.DeepEqual(got, want) {
+			t.Errorf("ListBackupArchives = %v, want %v", got, want)
+		}
+		if got := FindBackupOutputFiles(dir); got != "backup_collection_Main_01.zip ... backup_shared.zip (3 archives)" {
+			t.Errorf("unexpected display %q", got)
+		}
+	})
+
+	t.Run("follows the manifest order", func(t *testing.T) {
+		dir := t.TempDir()
+		write(dir, "backup_group_Gear.zip", "backup_other.zip")
+		writeManifestFile(t, dir, BackupManifest{Parts: []string{"backup_other.zip", "backup_group_Gear.zip"}})
+		want := []string{"backup_other.zip", "backup_group_Gear.zip"}
+		if got := ListBackupArchives(dir); !reflect.DeepEqual(got, want) {
+			t.Errorf("ListBackupArchives = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("ignores a manifest listing missing archives", func(t *testing.T) {
+		dir := t.TempDir()
+		write(dir, "backup_part.zip")
+		writeManifestFile(t, dir, BackupManifest{Parts: []string{"backup_part_01.zip", "backup_part_02.zip"}})
+		if got := FindBackupOutputFiles(dir); got != "backup_part.zip" {
+			t.Errorf("expected the archive on disk, got %q", got)
+		}
+	})
+
+	t.Run("defaults to the single archive name", func(t *testing.T) {
+		if got := FindBackupOutputFiles(t.TempDir()); got != BackupOutputPath {
+			t.Errorf("expected %s, got %q", BackupOutputPath, got)
+		}
+	})
+}
+
+func TestBatchTarget(t *testing.T) {
+	if got := batchTarget(0); got != batchTargetBytes {
+		t.Errorf("expected the default batch size without a limit, got %d", got)
+	}
+	if got := batchTarget(100 << 20); got != 99<<20 {
+		t.Errorf("expected the limit minus headroom, got %d", got)
+	}
+	if got := batchTarget(4 << 40); got != batchTargetBytes {
+		t.Errorf("expected a huge limit to keep the default, got %d", got)
+	}
+}
@@ -0,0 +1,103 @@
+package aurora
+
+import (
+	"aurora/internal/logger"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrBackupRunning is returned when a backup starts while another process
+// runs one in the same output directory
+var ErrBackupRunning = errors.New("another backup is running in the output directory")
+
+// BackupLockFile, in the output directory, is held for the length of a
+// backup. The CLI, aurora watch, aurora serve and the desktop scheduler all
+// back up through RunBackup: a second run would discard the staging dir of
+// the first.
+const BackupLockFile = "backup.lock"
+
+// backupLockHeartbeat is how often the holder touches the lock. Backups run
+// for hours, so its age can't tell a crashed holder: a lock untouched for a
+// few heartbeats is.
+var backupLockHeartbeat = 30 * time.Second
+
+// backupLock is a held output directory lock
+type backupLock struct {
+	path string
+	stop chan struct{}
+	done chan struct{}
+}
+
+// lockOutput takes the backup lock of outputDir, failing with
+// ErrBackupRunning while another process holds it. A stale lock is moved
+// aside under a unique name and checked again: when another process took it
+// over first, what was moved is that process' fresh lock, which is linked
+// back without replacing a newer one.
+func lockOutput(outputDir string) (*backupLock, error) {
+	path := filepath.Join(outputDir, BackupLockFile)
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(file, "%d\n", os.Getpid())
+			file.Close()
+			break
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("lock output directory: %w", err)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil || !backupLockStale(info.ModTime()) {
+			return nil, ErrBackupRunning
+		}
+		aside := fmt.Sprintf("%s.stale-%d-%d", path, os.Getpid(), time.Now().UnixNano())
+		if err := os.Rename(path, aside); err != nil {
+			return nil, ErrBackupRunning
+		}
+		if info, err := os.Stat(aside); err == nil && !backupLockStale(info.ModTime()) {
+			os.Link(aside, path)
+			os.Remove(aside)
+			return nil, ErrBackupRunning
+		}
+		logger.Warn("Removing stale backup lock: %s", path)
+		os.Remove(aside)
+	}
+
+	lock := &backupLock{path: path, stop: make(chan struct{}), done: make(chan struct{})}
+	go lock.heartbeat()
+	return lock, nil
+}
+
+func backupLockStale(modTime time.Time) bool {
+	return time.Since(modTime) > 3*backupLockHeartbeat
+}
+
+// heartbeat touches the lock until it is released
+func (l *backupLock) heartbeat() {
+	defer close(l.done)
+	ticker := time.NewTicker(backupLockHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			now := time.Now()
+			if err := os.Chtimes(l.path, now, now); err != nil {
+				logger.Warn("Failed to refresh backup lock %s: %v", l.path, err)
+			}
+		}
+	}
+}
+
+// release stops the heartbeat and removes the lock
+func (l *backupLock) release() {
+	close(l.stop)
+	<-l.done
+	if err := os.Remove(l.path); err != nil {
+		logger.Warn("Failed to remove backup lock %s: %v", l.path, err)
+	}
+}
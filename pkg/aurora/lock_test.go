@@ -0,0 +1,84 @@
+package aurora
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockOutput(t *testing.T) {
+	t.Run("one holder at a time", func(t *testing.T) {
+		outputDir := t.TempDir()
+		lock, err := lockOutput(outputDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := lockOutput(outputDir); !errors.Is(err, ErrBackupRunning) {
+			t.Errorf("expected ErrBackupRunning, got %v", err)
+		}
+		lock.release()
+		if _, err := os.Stat(filepath.Join(outputDir, BackupLockFile)); err == nil {
+			t.Error("expected the lock removed")
+		}
+		lock, err = lockOutput(outputDir)
+		if err != nil {
+			t.Fatalf("expected the lock free again, got %v", err)
+		}
+		lock.release()
+	})
+
+	t.Run("takes a stale lock over", func(t *testing.T) {
+		outputDir := t.TempDir()
+		path := filepath.Join(outputDir, BackupLockFile)
+		os.WriteFile(path, []byte("1234\n"), 0644)
+		old := time.Now().Add(-time.Hour)
+		os.Chtimes(path, old, old)
+
+		lock, err := lockOutput(outputDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lock.release()
+		if matches, _ := filepath.Glob(path + ".stale-*"); len(matches) != 0 {
+			t.Errorf("expected the stale lock removed, found %v", matches)
+		}
+	})
+
+	t.Run("keeps the lock fresh while held", func(t *testing.T) {
+		previous := backupLockHeartbeat
+		backupLockHeartbeat = 10 * time.Millisecond
+		t.Cleanup(func() { backupLockHeartbeat = previous })
+		outputDir := t.TempDir()
+		lock, err := lockOutput(outputDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer lock.release()
+		time.Sleep(100 * time.Millisecond)
+		if _, err := lockOutput(outputDir); !errors.Is(err, ErrBackupRunning) {
+			t.Errorf("expected the held lock not taken as stale, got %v", err)
+		}
+	})
+
+	t.Run("refuses a backup while another holds the lock", func(t *testing.T) {
+		a := newTestAurora(t, unusedMod, nil)
+		configure(t, a.SetPreBackupHook("echo ran > pre.txt"))
+		lock, err := lockOutput(a.Config().Output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer lock.release()
+		if _, err := a.RunBackup(context.Background(), RunBackupOptions{}, &recordingObserver{}); !errors.Is(err, ErrBackupRunning) {
+			t.Errorf("expected ErrBackupRunning, got %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(a.Config().Output, "pre.txt")); err == nil {
+			t.Error("expected no pre-backup hook")
+		}
+		if err := a.DiscardPendingBackup(); !errors.Is(err, ErrBackupRunning) {
+			t.Errorf("expected discarding refused, got %v", err)
+		}
+	})
+}
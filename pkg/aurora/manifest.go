@@ -0,0 +1,228 @@
+package aurora
+
+import (
+	"aurora/internal/logger"
+	"aurora/internal/paths"
+	"aurora/internal/util"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ManifestFile describes the backup set it sits next to
+const ManifestFile = "backup_manifest.json"
+
+const manifestVersion = 1
+
+// maxManifests bounds the history of manifests: older ones are deleted
+const maxManifests = 100
+
+// BackupManifest records a finished backup. A copy is kept in the data dir
+// so size estimates can learn how far off they were.
+type BackupManifest struct {
+	Version        int       `json:"version"`
+	StartedAt      time.Time `json:"startedAt"`
+	FinishedAt     time.Time `json:"finishedAt"`
+	Format         string    `json:"format,omitempty"` // see FormatZip, "" = zip
+	Level          int       `json:"level"`            // compression level of the format
+	Mods           []string  `json:"mods"`             // mod folder names
+	Parts          []string  `json:"parts"`            // archive names, in order
+	OriginalSize   uint64    `json:"originalSize"`
+	CompressedSize uint64    `json:"compressedSize"`
+	Estimate       uint64    `json:"estimate,omitempty"` // uncalibrated sampled estimate, 0 = unknown
+
+	Layout      string          `json:"layout,omitempty"`      // see LayoutSingle, "" = single
+	MaxPartSize uint64          `json:"maxPartSize,omitempty"` // 0 = no limit
+	Groups      []ManifestGroup `json:"groups,omitempty"`      // which archives hold which mods
+
+	Encrypted   bool              `json:"encrypted,omitempty"`
+	Incremental bool              `json:"incremental,omitempty"` // only the mods changed since the last backup
+	Checksums   map[string]string `json:"checksums,omitempty"`   // archive name -> SHA-256 of the file
+}
+
+// ManifestGroup lists the archives of one collection or folder group
+type ManifestGroup struct {
+	Name  string   `json:"name"` // "" for the single layout
+	Mods  []string `json:"mods"`
+	Parts []string `json:"parts"`
+}
+
+// writeManifest saves the manifest next to the archives, encrypted with
+// passphrase unless it is "". It replaces the previous one in a single
+// rename.
+func writeManifest(outputDir string, manifest BackupManifest, passphrase string) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode backup manifest: %w", err)
+	}
+	path, other := filepath.Join(outputDir, ManifestFile), filepath.Join(outputDir, ManifestFile+EncryptedExt)
+	if passphrase != "" {
+		path, other = other, path
+	}
+	tmp := path + ".tmp"
+	if passphrase == "" {
+		err = os.WriteFile(tmp, data, 0644)
+	} else {
+		err = writeEncrypted(tmp, data, passphrase)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write backup manifest: %w", err)
+	}
+	// Drop the other form left by a previous backup
+	os.Remove(other)
+	return nil
+}
+
+// keepManifest adds the manifest to the history under paths.ManifestsDir,
+// dropping the oldest past maxManifests. The history stays readable, as
+// size estimates and the scheduler need it without a passphrase; for an
+// encrypted set it only keeps the sizes and times, not the names of mods
+// and archives.
+func keepManifest(manifest BackupManifest) error {
+	if manifest.Encrypted {
+		manifest.Mods, manifest.Parts, manifest.Groups, manifest.Checksums = nil, nil, nil, nil
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode backup manifest: %w", err)
+	}
+	dir := paths.ManifestsDir()
+	name := "backup-" + manifest.FinishedAt.UTC().Format("20060102-150405") + ".json"
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("keep backup manifest in %s: %w", dir, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+		return fmt.Errorf("keep backup manifest in %s: %w", dir, err)
+	}
+	pruneManifests()
+	return nil
+}
+
+// keptManifests lists the manifests of the history, oldest first
+func keptManifests() ([]string, error) {
+	entries, err := os.ReadDir(paths.ManifestsDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	// Names embed the finish time: sorted is oldest first
+	slices.Sort(names)
+	return names, nil
+}
+
+// pruneManifests deletes the oldest manifests past maxManifests, except
+// the newest of a full backup: the scheduler goes by it
+func pruneManifests() {
+	names, err := keptManifests()
+	if err != nil || len(names) <= maxManifests {
+		return
+	}
+	dir := paths.ManifestsDir()
+	lastFull := ""
+	for _, name := range slices.Backward(names) {
+		var manifest BackupManifest
+		if util.ReadJSONFile(filepath.Join(dir, name), &manifest) == nil && !manifest.Incremental {
+			lastFull = name
+			break
+		}
+	}
+	for _, name := range names[:len(names)-maxManifests] {
+		if name == lastFull {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			logger.Warn("Failed to delete old backup manifest %s: %v", name, err)
+		}
+	}
+}
+
+// ReadManifest reads the manifest of the backup set in outputDir,
+// decrypting it with passphrase when the set is encrypted
+func ReadManifest(outputDir, passphrase string) (BackupManifest, error) {
+	var manifest BackupManifest
+	path := filepath.Join(outputDir, ManifestFile)
+	err := util.ReadJSONFile(path, &manifest)
+	if errors.Is(err, os.ErrNotExist) {
+		if _, statErr := os.Stat(path + EncryptedExt); statErr == nil {
+			path += EncryptedExt
+			err = readEncryptedJSON(path, passphrase, &manifest)
+		}
+	}
+	if err != nil {
+		return BackupManifest{}, fmt.Errorf("read backup manifest %s: %w", path, err)
+	}
+	return manifest, nil
+}
+
+func writeEncrypted(path string, data []byte, passphrase string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	writer, err := newEncryptWriter(file, passphrase)
+	if err == nil {
+		_, err = writer.Write(data)
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func readEncryptedJSON(path, passphrase string, v any) error {
+	reader, err := OpenBackupFile(path, passphrase)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// pastManifests returns up to limit manifests of past backups made in
+// format at level, newest first
+func pastManifests(format string, level, limit int) []BackupManifest {
+	names, _ := keptManifests()
+	slices.Reverse(names) // newest first
+	dir := paths.ManifestsDir()
+
+	var manifests []BackupManifest
+	for _, name := range names {
+		if len(manifests) == limit {
+			break
+		}
+		var manifest BackupManifest
+		if err := util.ReadJSONFile(filepath.Join(dir, name), &manifest); err != nil {
+			logger.Warn("Skipping unreadable backup manifest %s: %v", name, err)
+			continue
+		}
+		if normalizeFormat(manifest.Format) == format && manifest.Level == level {
+			manifests = append(manifests, manifest)
+		}
+	}
+	return manifests
+}
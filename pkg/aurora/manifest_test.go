@@ -0,0 +1,52 @@
+package aurora
+
+import (
+	"aurora/internal/paths"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestKeepManifest(t *testing.T) {
+	t.Run("drops the oldest past maxManifests but the last full backup", func(t *testing.T) {
+		t.Setenv(paths.EnvHome, t.TempDir())
+		full := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+		if err := keepManifest(BackupManifest{Version: manifestVersion, FinishedAt: full}); err != nil {
+			t.Fatal(err)
+		}
+		for i := range maxManifests + 5 {
+			finished := full.Add(time.Duration(i+1) * time.Minute)
+			if err := keepManifest(BackupManifest{Version: manifestVersion, FinishedAt: finished, Incremental: true}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		names, err := keptManifests()
+		if err != nil || len(names) != maxManifests+1 {
+			t.Errorf("expected %d manifests kept, got %d, %v", maxManifests+1, len(names), err)
+		}
+		if last, ok := LastBackupTime(); !ok || !last.Equal(full) {
+			t.Errorf("expected the full backup kept, got %v, %v", last, ok)
+		}
+	})
+
+	t.Run("keeps no names of an encrypted set", func(t *testing.T) {
+		t.Setenv(paths.EnvHome, t.TempDir())
+		manifest := BackupManifest{Version: manifestVersion, FinishedAt: time.Now(), Encrypted: true, Mods: []string{"Body"}, Parts: []string{"backup_part.zip.enc"}}
+		if err := keepManifest(manifest); err != nil {
+			t.Fatal(err)
+		}
+		if kept := pastManifests(FormatZip, 0, 1); len(kept) != 1 || kept[0].Mods != nil || kept[0].Parts != nil {
+			t.Errorf("expected the manifest without names, got %+v", kept)
+		}
+	})
+
+	t.Run("fails when the history can't be written", func(t *testing.T) {
+		t.Setenv(paths.EnvHome, t.TempDir())
+		os.MkdirAll(paths.DataDir(), 0755)
+		os.WriteFile(paths.ManifestsDir(), []byte("in the way"), 0644)
+		if err := keepManifest(BackupManifest{Version: manifestVersion, FinishedAt: time.Now()}); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
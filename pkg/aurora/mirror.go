@@ -0,0 +1,79 @@
+package aurora
+
+import (
+	"aurora/internal/config"
+	"context"
+	"fmt"
+	"slices"
+)
+
+// GetMirrors returns where backups are copied besides the target
+func (a *Aurora) GetMirrors() []Target {
+	cfg := a.Config()
+	mirrors := make([]Target, len(cfg.Mirrors))
+	for i, mirror := range cfg.Mirrors {
+		mirrors[i] = Target(mirror)
+	}
+	return mirrors
+}
+
+func (a *Aurora) mirrorURLs() []string {
+	urls := []string{}
+	for _, mirror := range a.GetMirrors() {
+		urls = append(urls, mirror.String())
+	}
+	return urls
+}
+
+// AddMirror adds a copy of each backup to mirror. The output directory
+// itself can't be a mirror, and adding a mirror twice is a no-op.
+func (a *Aurora) AddMirror(mirror Target) error {
+	if err := mirror.Validate(); err != nil {
+		return err
+	}
+	if !mirror.IsRemote() {
+		return fmt.Errorf("invalid mirror: the output directory already holds the backup")
+	}
+	return a.updateConfig(func(cfg *config.Config) {
+		if !slices.Contains(cfg.Mirrors, config.TargetConfig(mirror)) {
+			cfg.Mirrors = append(cfg.Mirrors, config.TargetConfig(mirror))
+		}
+	})
+}
+
+// RemoveMirror stops copying backups to the mirror written as url
+func (a *Aurora) RemoveMirror(url string) error {
+	return a.updateConfig(func(cfg *config.Config) {
+		cfg.Mirrors = slices.DeleteFunc(cfg.Mirrors, func(m config.TargetConfig) bool { return Target(m).String() == url })
+	})
+}
+
+// copyToDestinations copies a finished backup to the target, then to each
+// mirror, reporting them in result.Destinations. A failed upload to the
+// target fails with ErrUploadFailed before any mirror is written; a failed
+// mirror is reported with a notice and doesn't stop the others. A copy
+// stopped through ctx fails with ErrBackupCancelled.
+func (a *Aurora) copyToDestinations(ctx context.Context, result *BackupResult, observer BackupObserver) error {
+	if target := a.GetTarget(); target.IsRemote() {
+		dest := a.copyBackup(ctx, target, false, result.OutputDir, observer)
+		result.Destinations = append(result.Destinations, dest)
+		if ctx.Err() != nil {
+			return ErrBackupCancelled
+		}
+		if dest.Error != "" {
+			return fmt.Errorf("%w: %s: %s", ErrUploadFailed, dest.Target, dest.Error)
+		}
+		result.Target = dest.Target
+	}
+	for _, mirror := range a.GetMirrors() {
+		dest := a.copyBackup(ctx, mirror, true, result.OutputDir, observer)
+		result.Destinations = append(result.Destinations, dest)
+		if ctx.Err() != nil {
+			return ErrBackupCancelled
+		}
+		if dest.Error != "" {
+			observer.Notice(fmt.Sprintf("Warning: copy to mirror %s failed: %s", dest.Target, dest.Error))
+		}
+	}
+	return nil
+}
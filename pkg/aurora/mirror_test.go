@@ -0,0 +1,135 @@
+package aurora
+
+import (
+	"aurora/internal/config"
+	"aurora/internal/paths"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMirrors(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(configFile, []byte("{}"), 0644)
+	config.SetConfigFile(configFile)
+	t.Cleanup(func() { config.SetConfigFile("") })
+	a := newAurora(&config.Config{})
+
+	nas := Target{Type: TargetDir, Path: t.TempDir()}
+	s3 := Target{Type: TargetS3, Bucket: "bucket"}
+	for _, mirror := range []Target{nas, s3, nas} {
+		if err := a.AddMirror(mirror); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := a.GetConfig().Mirrors; !slices.Equal(got, []string{nas.Path, "s3://bucket"}) {
+		t.Errorf("expected each mirror once, got %v", got)
+	}
+	if err := a.AddMirror(Target{Type: TargetLocal}); err == nil {
+		t.Error("expected the output directory to be rejected as a mirror")
+	}
+	if err := a.AddMirror(Target{Type: TargetS3}); err == nil {
+		t.Error("expected an invalid mirror to be rejected")
+	}
+
+	if err := a.RemoveMirror("s3://bucket"); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.GetMirrors(); !slices.Equal(got, []Target{nas}) {
+		t.Errorf("expected only %v left, got %v", nas, got)
+	}
+}
+
+func TestCopyToDestinations(t *testing.T) {
+	t.Setenv(paths.EnvHome, t.TempDir())
+	ctx := context.Background()
+	outputDir := t.TempDir()
+	writeBackupSet(t, outputDir, "")
+	target := Target{Type: TargetDir, Path: t.TempDir()}
+	mirror := Target{Type: TargetDir, Path: t.TempDir()}
+	unplugged := Target{Type: TargetDir, Path: filepath.Join(t.TempDir(), "missing")}
+
+	t.Run("copies to the target and every mirror", func(t *testing.T) {
+		a := newAurora(&config.Config{
+			Output:  outputDir,
+			Target:  config.TargetConfig(target),
+			Mirrors: []config.TargetConfig{config.TargetConfig(unplugged), config.TargetConfig(mirror)},
+		})
+		result := BackupResult{OutputDir: outputDir}
+		observer := &recordingObserver{}
+		if err := a.copyToDestinations(ctx, &result, observer); err != nil {
+			t.Fatal(err)
+		}
+		if result.Target != target.Path || len(result.Destinations) != 3 {
+			t.Fatalf("unexpected result %+v", result)
+		}
+		if dest := result.Destinations[0]; dest.Mirror || !dest.Verified || dest.Files != 3 {
+			t.Errorf("unexpected target copy %+v", dest)
+		}
+		if dest := result.Destinations[1]; !dest.Mirror || dest.Verified || !strings.Contains(dest.Error, "not available") {
+			t.Errorf("expected the unplugged mirror to fail, got %+v", dest)
+		}
+		if dest := result.Destinations[2]; !dest.Mirror || !dest.Verified || dest.Target != mirror.Path {
+			t.Errorf("expected the other mirror to be copied, got %+v", dest)
+		}
+		if !slices.ContainsFunc(observer.notices, func(n string) bool { return strings.HasPrefix(n, "Warning: copy to mirror") }) {
+			t.Errorf("expected a warning for the failed mirror, got %v", observer.notices)
+		}
+		verified, err := verifyDir(filepath.Join(mirror.Path, SetA), "")
+		if err != nil || !verified.Valid {
+			t.Errorf("expected the mirror to verify, got %+v, %v", verified, err)
+		}
+	})
+
+	t.Run("a failed target upload skips the mirrors", func(t *testing.T) {
+		a := newAurora(&config.Config{
+			Output:  outputDir,
+			Target:  config.TargetConfig(unplugged),
+			Mirrors: []config.TargetConfig{config.TargetConfig(mirror)},
+		})
+		result := BackupResult{OutputDir: outputDir}
+		err := a.copyToDestinations(ctx, &result, &recordingObserver{})
+		if !errors.Is(err, ErrUploadFailed) {
+			t.Errorf("expected ErrUploadFailed, got %v", err)
+		}
+		if result.Target != "" || len(result.Destinations) != 1 {
+			t.Errorf("unexpected result %+v", result)
+		}
+	})
+
+	t.Run("a cancelled upload is not reported as failed", func(t *testing.T) {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		for _, cfg := range []*config.Config{
+			{Output: outputDir, Target: config.TargetConfig(target)},
+			{Output: outputDir, Mirrors: []config.TargetConfig{config.TargetConfig(mirror)}},
+		} {
+			observer := &recordingObserver{}
+			err := newAurora(cfg).copyToDestinations(cancelled, &BackupResult{OutputDir: outputDir}, observer)
+			if !errors.Is(err, ErrBackupCancelled) {
+				t.Errorf("expected ErrBackupCancelled, got %v", err)
+			}
+			if slices.ContainsFunc(observer.notices, func(n string) bool { return strings.HasPrefix(n, "Warning:") }) {
+				t.Errorf("expected no warning, got %v", observer.notices)
+			}
+		}
+	})
+
+	t.Run("copies an incremental backup to its folder", func(t *testing.T) {
+		incrementalDir := filepath.Join(outputDir, "incremental", "20260301-120000")
+		os.MkdirAll(incrementalDir, 0755)
+		writeBackupSet(t, incrementalDir, "")
+		a := newAurora(&config.Config{Output: outputDir, Mirrors: []config.TargetConfig{config.TargetConfig(mirror)}})
+		result := BackupResult{OutputDir: incrementalDir}
+		if err := a.copyToDestinations(ctx, &result, &recordingObserver{}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(filepath.Join(mirror.Path, "incremental", "20260301-120000", SetA, ManifestFile)); err != nil {
+			t.Errorf("expected the set in the mirror's incremental folder: %v", err)
+		}
+	})
+}
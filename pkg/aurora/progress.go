@@ -0,0 +1,243 @@
+package aurora
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/creativeyann17/go-delta/pkg/compress"
+)
+
+// progressInterval throttles snapshots: go-delta reports every buffer it
+// reads, far more often than any frontend redraws
+const progressInterval = 100 * time.Millisecond
+
+// progressTracker aggregates go-delta events into BackupProgress snapshots.
+// Progress is byte-weighted: file counting makes the bar crawl through big
+// mods then leap across thousands of small files. Events arrive from
+// several compression workers, hence the mutex.
+type progressTracker struct {
+	mu       sync.Mutex
+	now      func() time.Time
+	started  time.Time
+	lastSent time.Time
+
+	folders   []string         // pending mod folders, to map files to mods
+	filesLeft map[string]int64 // per pending mod folder
+	modsDone  int
+	modsTotal int
+
+	totalFiles, completedFiles int64
+	totalBytes, completedBytes uint64
+	inflight                   map[string]*inflightFile
+	startSeq                   int64 // start order of in-flight files
+	current                    string
+
+	finishedCompressed uint64 // archives of finished batches
+	batchCompressed    uint64 // archive bytes of the running batch so far
+}
+
+type inflightFile struct {
+	mod, file  string
+	read, size uint64
+	seq        int64
+}
+
+// newProgressTracker starts tracking a backup of modsTotal mods, modsDone
+// of which are in finished batches, holding compressed archive bytes
+func newProgressTracker(modsTotal, modsDone int, compressed uint64) *progressTracker {
+	return &progressTracker{
+		now:                time.Now,
+		started:            time.Now(),
+		filesLeft:          make(map[string]int64),
+		modsDone:           modsDone,
+		modsTotal:          modsTotal,
+		inflight:           make(map[string]*inflightFile),
+		finishedCompressed: compressed,
+	}
+}
+
+// addMod registers a mod folder this run compresses
+func (t *progressTracker) addMod(folder string, files int64, bytes uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.folders = append(t.folders, folder)
+	t.filesLeft[folder] = files
+	t.totalFiles += files
+	t.totalBytes += bytes
+	if files == 0 {
+		t.modsDone++ // nothing to wait for
+	}
+}
+
+// handle records a go-delta event and reports whether a snapshot is due
+func (t *progressTracker) handle(event compress.ProgressEvent) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch event.Type {
+	case compress.EventFileStart:
+		t.startFile(event)
+
+	case compress.EventFileProgress:
+		file := t.inflight[event.FilePath]
+		if file == nil {
+			file = t.startFile(event)
+		}
+		file.read = uint64(event.Current)
+		if event.Total > 0 {
+			file.size = uint64(event.Total)
+		}
+
+	case compress.EventFileComplete:
+		size := uint64(event.Total)
+		if file := t.inflight[event.FilePath]; file != nil && size == 0 {
+			size = file.size
+		}
+		t.completedFiles++
+		t.completedBytes += size
+		t.finishFile(event.FilePath)
+		return true
+
+	case compress.EventError:
+		t.finishFile(event.FilePath)
+		return true
+
+	default:
+		return false
+	}
+	return t.dueLocked()
+}
+
+func (t *progressTracker) startFile(event compress.ProgressEvent) *inflightFile {
+	folder, rel := t.modOf(event.FilePath)
+	file := &inflightFile{
+		mod:  filepath.Base(folder),
+		file: rel,
+		size: uint64(max(event.Total, 0)),
+		seq:  t.startSeq,
+	}
+	t.startSeq++
+	t.inflight[event.FilePath] = file
+	t.current = filepath.Base(event.FilePath)
+	if len(t.current) > 35 {
+		t.current = t.current[:32] + "..."
+	}
+	return file
+}
+
+// finishFile drops a file from the in-flight set and closes its mod once
+// every file of it is through
+func (t *progressTracker) finishFile(path string) {
+	delete(t.inflight, path)
+	folder, _ := t.modOf(path)
+	if left, ok := t.filesLeft[folder]; ok && left > 0 {
+		t.filesLeft[folder] = left - 1
+		if left == 1 {
+			t.modsDone++
+		}
+	}
+}
+
+// modOf maps a file to its mod folder. go-delta may report paths as given
+// or relative to the folder's parent, so both forms are matched.
+func (t *progressTracker) modOf(path string) (folder, rel string) {
+	for _, f := range t.folders {
+		for _, prefix := range []string{f, filepath.Base(f)} {
+			if strings.HasPrefix(path, prefix+string(os.PathSeparator)) || strings.HasPrefix(path, prefix+"/") {
+				return f, path[len(prefix)+1:]
+			}
+		}
+	}
+	return "", path
+}
+
+// batchDone closes the mods of a finished batch, whatever their file
+// events said, and moves its archive size to the finished total
+func (t *progressTracker) batchDone(folders []string, compressed uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, folder := range folders {
+		if t.filesLeft[folder] > 0 {
+			t.filesLeft[folder] = 0
+			t.modsDone++
+		}
+	}
+	t.finishedCompressed += compressed
+	t.batchCompressed = 0
+}
+
+// setBatchCompressed records the archive bytes the running batch wrote so far
+func (t *progressTracker) setBatchCompressed(bytes uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.batchCompressed = bytes
+}
+
+func (t *progressTracker) dueLocked() bool {
+	now := t.now()
+	if now.Sub(t.lastSent) < progressInterval {
+		return false
+	}
+	t.lastSent = now
+	return true
+}
+
+// snapshot returns the current progress
+func (t *progressTracker) snapshot() BackupProgress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	bytesDone := t.completedBytes
+	workers := make([]WorkerStatus, 0, len(t.inflight))
+	files := make([]*inflightFile, 0, len(t.inflight))
+	for _, file := range t.inflight {
+		files = append(files, file)
+		bytesDone += file.read
+	}
+	bytesDone = min(bytesDone, t.totalBytes)
+	slices.SortFunc(files, func(a, b *inflightFile) int { return int(a.seq - b.seq) })
+	for _, file := range files {
+		workers = append(workers, WorkerStatus{Mod: file.mod, File: file.file, BytesDone: file.read, BytesTotal: file.size})
+	}
+
+	percent := 0.0
+	switch {
+	case t.totalBytes > 0:
+		percent = float64(bytesDone) / float64(t.totalBytes) * 100
+	case t.totalFiles > 0:
+		percent = float64(t.completedFiles) / float64(t.totalFiles) * 100
+	}
+
+	elapsed := t.now().Sub(t.started)
+	var rate float64
+	var eta int64
+	if elapsed >= time.Second && bytesDone > 0 {
+		rate = float64(bytesDone) / elapsed.Seconds()
+		eta = int64(float64(t.totalBytes-bytesDone) / rate)
+	}
+
+	current := t.current
+	if current == "" {
+		current = "Starting..."
+	}
+	return BackupProgress{
+		Percent:         percent,
+		Current:         fmt.Sprintf("%s (%d/%d)", current, t.completedFiles, t.totalFiles),
+		BytesDone:       bytesDone,
+		BytesTotal:      t.totalBytes,
+		FilesDone:       t.completedFiles,
+		FilesTotal:      t.totalFiles,
+		ModsDone:        t.modsDone,
+		ModsTotal:       t.modsTotal,
+		CompressedBytes: t.finishedCompressed + t.batchCompressed,
+		BytesPerSec:     rate,
+		ElapsedSeconds:  int64(elapsed.Seconds()),
+		ETASeconds:      eta,
+		Workers:         workers,
+	}
+}
@@ -0,0 +1,135 @@
+package aurora
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/creativeyann17/go-delta/pkg/compress"
+)
+
+// fakeClock drives a tracker's notion of time
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestTracker(clock *fakeClock, modsTotal, modsDone int) *progressTracker {
+	tracker := newProgressTracker(modsTotal, modsDone, 0)
+	tracker.now = func() time.Time { return clock.now }
+	tracker.started = clock.now
+	return tracker
+}
+
+func TestProgressTracker(t *testing.T) {
+	modA := filepath.Join("mods", "Mod A")
+	modB := filepath.Join("mods", "Mod B")
+	fileA1 := filepath.Join(modA, "chara", "a1.tex")
+	fileA2 := filepath.Join(modA, "a2.mdl")
+	fileB := filepath.Join(modB, "b.mtrl")
+
+	t.Run("weights progress by bytes and tracks workers", func(t *testing.T) {
+		clock := &fakeClock{now: time.Unix(1000, 0)}
+		tracker := newTestTracker(clock, 2, 0)
+		tracker.addMod(modA, 2, 900)
+		tracker.addMod(modB, 1, 100)
+
+		tracker.handle(compress.ProgressEvent{Type: compress.EventFileStart, FilePath: fileA1, Total: 800})
+		tracker.handle(compress.ProgressEvent{Type: compress.EventFileStart, FilePath: fileB, Total: 100})
+		tracker.handle(compress.ProgressEvent{Type: compress.EventFileProgress, FilePath: fileA1, Current: 400, Total: 800})
+		clock.advance(2 * time.Second)
+
+		p := tracker.snapshot()
+		if p.Percent != 40 || p.BytesDone != 400 || p.BytesTotal != 1000 {
+			t.Errorf("expected 400/1000 bytes (40%%), got %d/%d (%v%%)", p.BytesDone, p.BytesTotal, p.Percent)
+		}
+		if p.BytesPerSec != 200 || p.ElapsedSeconds != 2 || p.ETASeconds != 3 {
+			t.Errorf("expected 200 B/s, 2s elapsed, 3s left; got %v B/s, %ds, %ds", p.BytesPerSec, p.ElapsedSeconds, p.ETASeconds)
+		}
+		if len(p.Workers) != 2 {
+			t.Fatalf("expected 2 workers, got %+v", p.Workers)
+		}
+		want := WorkerStatus{Mod: "Mod A", File: filepath.Join("chara", "a1.tex"), BytesDone: 400, BytesTotal: 800}
+		if p.Workers[0] != want {
+			t.Errorf("first worker = %+v, want %+v", p.Workers[0], want)
+		}
+		if p.Workers[1].Mod != "Mod B" {
+			t.Errorf("second worker = %+v, want Mod B", p.Workers[1])
+		}
+	})
+
+	t.Run("counts mods once all their files are through", func(t *testing.T) {
+		clock := &fakeClock{now: time.Unix(1000, 0)}
+		tracker := newTestTracker(clock, 3, 1) // one mod in a finished batch
+		tracker.addMod(modA, 2, 900)
+		tracker.addMod(modB, 1, 100)
+
+		tracker.handle(compress.ProgressEvent{Type: compress.EventFileComplete, FilePath: fileA1, Total: 800})
+		if p := tracker.snapshot(); p.ModsDone != 1 || p.FilesDone != 1 {
+			t.Errorf("expected Mod A still running, got %d mods, %d files done", p.ModsDone, p.FilesDone)
+		}
+		tracker.handle(compress.ProgressEvent{Type: compress.EventError, FilePath: fileA2})
+		p := tracker.snapshot()
+		if p.ModsDone != 2 || p.ModsTotal != 3 {
+			t.Errorf("expected 2/3 mods done, got %d/%d", p.ModsDone, p.ModsTotal)
+		}
+		if p.BytesDone != 800 || len(p.Workers) != 0 {
+			t.Errorf("expected 800 bytes and no workers, got %d and %+v", p.BytesDone, p.Workers)
+		}
+	})
+
+	t.Run("matches paths relative to the mods folder", func(t *testing.T) {
+		tracker := newTestTracker(&fakeClock{}, 1, 0)
+		tracker.addMod(modB, 1, 100)
+		tracker.handle(compress.ProgressEvent{Type: compress.EventFileStart, FilePath: filepath.Join("Mod B", "b.mtrl")})
+		if p := tracker.snapshot(); len(p.Workers) != 1 || p.Workers[0].Mod != "Mod B" || p.Workers[0].File != "b.mtrl" {
+			t.Errorf("unexpected workers %+v", p.Workers)
+		}
+	})
+
+	t.Run("finished batches close their mods and add their archives", func(t *testing.T) {
+		tracker := newTestTracker(&fakeClock{}, 2, 0)
+		tracker.addMod(modA, 2, 900)
+		tracker.addMod(modB, 1, 100)
+
+		tracker.setBatchCompressed(300)
+		if p := tracker.snapshot(); p.CompressedBytes != 300 {
+			t.Errorf("expected 300 compressed bytes so far, got %d", p.CompressedBytes)
+		}
+		tracker.batchDone([]string{modA, modB}, 450)
+		p := tracker.snapshot()
+		if p.ModsDone != 2 || p.CompressedBytes != 450 {
+			t.Errorf("expected 2 mods and 450 compressed bytes, got %d and %d", p.ModsDone, p.CompressedBytes)
+		}
+	})
+
+	t.Run("throttles progress events but not completions", func(t *testing.T) {
+		clock := &fakeClock{now: time.Unix(1000, 0)}
+		tracker := newTestTracker(clock, 1, 0)
+		tracker.addMod(modA, 2, 900)
+
+		progress := compress.ProgressEvent{Type: compress.EventFileProgress, FilePath: fileA1, Current: 10, Total: 800}
+		if !tracker.handle(progress) {
+			t.Error("expected the first event to be due")
+		}
+		if tracker.handle(progress) {
+			t.Error("expected an event within the interval to be throttled")
+		}
+		clock.advance(progressInterval)
+		if !tracker.handle(progress) {
+			t.Error("expected an event after the interval to be due")
+		}
+		if !tracker.handle(compress.ProgressEvent{Type: compress.EventFileComplete, FilePath: fileA1, Total: 800}) {
+			t.Error("expected file completion to be due")
+		}
+	})
+
+	t.Run("no rate or ETA before any progress", func(t *testing.T) {
+		clock := &fakeClock{now: time.Unix(1000, 0)}
+		tracker := newTestTracker(clock, 1, 0)
+		tracker.addMod(modA, 2, 900)
+		clock.advance(5 * time.Second)
+		if p := tracker.snapshot(); p.BytesPerSec != 0 || p.ETASeconds != 0 || p.Percent != 0 {
+			t.Errorf("expected no rate yet, got %+v", p)
+		}
+	})
+}
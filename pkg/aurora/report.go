@@ -0,0 +1,426 @@
+package aurora
+
+import (
+	"aurora/internal/logger"
+	"aurora/internal/util"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// Report files written next to the archives of a backup set. The JSON
+// form holds the data and is encrypted like the manifest; the HTML and
+// Markdown forms are only written for plain sets, as they list the mods.
+const (
+	ReportFile         = "backup_report.json"
+	ReportHTMLFile     = "backup_report.html"
+	ReportMarkdownFile = "backup_report.md"
+)
+
+const reportVersion = 1
+
+// reportTimeLayout prints times in rendered reports
+const reportTimeLayout = "2006-01-02 15:04:05"
+
+// BackupReport describes a finished backup set: what went in and why,
+// what filters kept out, sizes and ratios, and what couldn't be read
+type BackupReport struct {
+	Version    int       `json:"version"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	Duration   string    `json:"duration"` // FinishedAt - StartedAt, pauses of a resumed backup included
+
+	Format      string `json:"format"` // see FormatZip
+	Level       int    `json:"level"`
+	Layout      string `json:"layout"` // see LayoutSingle
+	Encrypted   bool   `json:"encrypted"`
+	Incremental bool   `json:"incremental"`
+
+	OriginalSize        uint64 `json:"originalSize"`
+	OriginalSizeHuman   string `json:"originalSizeHuman"`
+	CompressedSize      uint64 `json:"compressedSize"`
+	CompressedSizeHuman string `json:"compressedSizeHuman"`
+	Ratio               string `json:"ratio"`
+
+	Archives []ReportArchive `json:"archives"`
+	Included []ReportMod     `json:"included"` // largest first
+	Excluded []ReportMod     `json:"excluded"` // dropped by a filter, largest first
+	Warnings []BackupWarning `json:"warnings"`
+
+	// Partial is set for sets without a report file, rebuilt from their
+	// manifest: included mods have no size, reason or ratio, and excluded
+	// mods and warnings are unknown
+	Partial bool `json:"partial"`
+}
+
+// ReportArchive is one archive of a backup set
+type ReportArchive struct {
+	Name      string `json:"name"`
+	Size      uint64 `json:"size"`
+	SizeHuman string `json:"sizeHuman"`
+}
+
+// ReportMod is a mod of a backup report. Mods compressed together share
+// a Ratio: compressors only report sizes per batch of mods.
+type ReportMod struct {
+	Name        string   `json:"name"`
+	Size        uint64   `json:"size"`
+	SizeHuman   string   `json:"sizeHuman"`
+	Collections []string `json:"collections"`
+	IncludedBy  string   `json:"includedBy,omitempty"` // inclusion filter putting it in the backup
+	ExcludedBy  string   `json:"excludedBy,omitempty"` // exclusion filter dropping it
+	Ratio       string   `json:"ratio,omitempty"`      // included mods only
+}
+
+// Reason tells why the mod is in the backup or out of it
+func (m ReportMod) Reason() string {
+	switch {
+	case m.ExcludedBy != "":
+		return fmt.Sprintf("exclusion filter %q", m.ExcludedBy)
+	case m.IncludedBy != "":
+		return fmt.Sprintf("inclusion filter %q", m.IncludedBy)
+	case len(m.Collections) > 0:
+		return "collections: " + strings.Join(m.Collections, ", ")
+	}
+	return ""
+}
+
+// compressionRatio prints compressed as a percentage of original
+func compressionRatio(original, compressed uint64) string {
+	if original == 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1f%%", float64(compressed)/float64(original)*100)
+}
+
+// newBackupReport builds the report of the backup j recorded, finished as
+// manifest describes, in outputDir
+func newBackupReport(j *journal, manifest BackupManifest, outputDir string) BackupReport {
+	report := newReportHeader(manifest, outputDir)
+
+	ratios := map[string]string{}
+	for _, batch := range j.Batches {
+		ratio := compressionRatio(batch.OriginalSize, batch.CompressedSize)
+		for _, folder := range batch.Folders {
+			ratios[filepath.Base(folder)] = ratio
+		}
+	}
+	if len(j.Items) == 0 {
+		// Journal of an older version: the mods without the preview
+		for _, mod := range manifest.Mods {
+			report.Included = append(report.Included, ReportMod{Name: mod, Collections: []string{}, Ratio: ratios[mod]})
+		}
+	}
+	for _, item := range j.Items {
+		mod := ReportMod{
+			Name:        item.Mod.Name,
+			Size:        item.Mod.Size,
+			SizeHuman:   item.Mod.SizeHuman,
+			Collections: item.Mod.Collections,
+			IncludedBy:  item.IncludedBy,
+			ExcludedBy:  item.FilteredBy,
+		}
+		if mod.Collections == nil {
+			mod.Collections = []string{}
+		}
+		if item.IsFiltered {
+			report.Excluded = append(report.Excluded, mod)
+			continue
+		}
+		mod.Ratio = ratios[mod.Name]
+		report.Included = append(report.Included, mod)
+	}
+	for _, mods := range [][]ReportMod{report.Included, report.Excluded} {
+		slices.SortFunc(mods, func(a, b ReportMod) int {
+			return cmp.Or(cmp.Compare(b.Size, a.Size), strings.Compare(a.Name, b.Name))
+		})
+	}
+	report.Warnings = append(report.Warnings, j.Warnings...)
+	return report
+}
+
+// newReportHeader fills the report fields the manifest knows
+func newReportHeader(manifest BackupManifest, outputDir string) BackupReport {
+	report := BackupReport{
+		Version:             reportVersion,
+		StartedAt:           manifest.StartedAt,
+		FinishedAt:          manifest.FinishedAt,
+		Duration:            manifest.FinishedAt.Sub(manifest.StartedAt).Round(time.Second).String(),
+		Format:              normalizeFormat(manifest.Format),
+		Level:               manifest.Level,
+		Layout:              normalizeLayout(manifest.Layout),
+		Encrypted:           manifest.Encrypted,
+		Incremental:         manifest.Incremental,
+		OriginalSize:        manifest.OriginalSize,
+		OriginalSizeHuman:   humanize.Bytes(manifest.OriginalSize),
+		CompressedSize:      manifest.CompressedSize,
+		CompressedSizeHuman: humanize.Bytes(manifest.CompressedSize),
+		Ratio:               compressionRatio(manifest.OriginalSize, manifest.CompressedSize),
+		Archives:            []ReportArchive{},
+		Included:            []ReportMod{},
+		Excluded:            []ReportMod{},
+		Warnings:            []BackupWarning{},
+	}
+	for _, part := range manifest.Parts {
+		archive := ReportArchive{Name: part}
+		if info, err := os.Stat(filepath.Join(outputDir, part)); err == nil {
+			archive.Size = uint64(info.Size())
+		}
+		archive.SizeHuman = humanize.Bytes(archive.Size)
+		report.Archives = append(report.Archives, archive)
+	}
+	return report
+}
+
+// writeReport saves the report next to the archives in outputDir: the
+// JSON form, encrypted with passphrase unless it is "", and the HTML and
+// Markdown forms for plain sets. Failures are logged: the backup itself
+// is done.
+func writeReport(outputDir string, report BackupReport, passphrase string) {
+	// Drop the forms left by a previous backup
+	for _, name := range reportFiles() {
+		os.Remove(filepath.Join(outputDir, name))
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		logger.Warn("Failed to encode backup report: %v", err)
+		return
+	}
+	path := filepath.Join(outputDir, ReportFile)
+	if passphrase != "" {
+		if err := writeEncrypted(path+EncryptedExt, data, passphrase); err != nil {
+			logger.Warn("Failed to write backup report: %v", err)
+		}
+		return
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		logger.Warn("Failed to write backup report: %v", err)
+		return
+	}
+
+	html, err := RenderReportHTML(report)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(outputDir, ReportHTMLFile), []byte(html), 0644)
+	}
+	if err == nil {
+		err = os.WriteFile(filepath.Join(outputDir, ReportMarkdownFile), []byte(RenderReportMarkdown(report)), 0644)
+	}
+	if err != nil {
+		logger.Warn("Failed to write backup report: %v", err)
+	}
+}
+
+// reportFiles lists every form of the report a set can hold
+func reportFiles() []string {
+	return []string{ReportFile, ReportFile + EncryptedExt, ReportHTMLFile, ReportMarkdownFile}
+}
+
+// isReportFile reports whether name is a form of the backup report
+func isReportFile(name string) bool {
+	return slices.Contains(reportFiles(), name)
+}
+
+// BackupReport reads the report of the backup set in dir ("" = the output
+// directory), decrypting it with passphrase when the set is encrypted.
+// Sets made before reports existed get a Partial report rebuilt from
+// their manifest. Fails with ErrNoBackup when dir holds no set, or
+// ErrPassphraseRequired or ErrWrongPassphrase.
+func (a *Aurora) BackupReport(dir, passphrase string) (BackupReport, error) {
+	if dir == "" {
+		dir = a.Config().Output
+	}
+	var report BackupReport
+	path := filepath.Join(dir, ReportFile)
+	err := util.ReadJSONFile(path, &report)
+	if errors.Is(err, os.ErrNotExist) {
+		if _, statErr := os.Stat(path + EncryptedExt); statErr == nil {
+			err = readEncryptedJSON(path+EncryptedExt, passphrase, &report)
+		}
+	}
+	if err == nil {
+		return report, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return BackupReport{}, fmt.Errorf("read backup report in %s: %w", dir, err)
+	}
+
+	manifest, err := ReadManifest(dir, passphrase)
+	if errors.Is(err, os.ErrNotExist) {
+		return BackupReport{}, fmt.Errorf("%w: %s", ErrNoBackup, dir)
+	}
+	if err != nil {
+		return BackupReport{}, err
+	}
+	report = newReportHeader(manifest, dir)
+	report.Partial = true
+	for _, mod := range manifest.Mods {
+		report.Included = append(report.Included, ReportMod{Name: mod, Collections: []string{}})
+	}
+	return report, nil
+}
+
+// RenderReportMarkdown renders report as a Markdown document
+func RenderReportMarkdown(report BackupReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Aurora backup report\n\n")
+	for _, line := range reportSummary(report) {
+		fmt.Fprintf(&b, "- **%s:** %s\n", line[0], markdownEscape(line[1]))
+	}
+
+	fmt.Fprintf(&b, "\n## Archives\n\n| Archive | Size |\n| --- | ---: |\n")
+	for _, archive := range report.Archives {
+		fmt.Fprintf(&b, "| %s | %s |\n", markdownEscape(archive.Name), archive.SizeHuman)
+	}
+
+	fmt.Fprintf(&b, "\n## Included mods (%d)\n\n", len(report.Included))
+	if report.Partial {
+		fmt.Fprintf(&b, "| Mod |\n| --- |\n")
+		for _, mod := range report.Included {
+			fmt.Fprintf(&b, "| %s |\n", markdownEscape(mod.Name))
+		}
+	} else {
+		fmt.Fprintf(&b, "| Mod | Size | Ratio | Why |\n| --- | ---: | ---: | --- |\n")
+		for _, mod := range report.Included {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", markdownEscape(mod.Name), mod.SizeHuman, mod.Ratio, markdownEscape(mod.Reason()))
+		}
+
+		fmt.Fprintf(&b, "\n## Excluded mods (%d)\n\n", len(report.Excluded))
+		if len(report.Excluded) == 0 {
+			fmt.Fprintf(&b, "No filter excluded a mod.\n")
+		} else {
+			fmt.Fprintf(&b, "| Mod | Size | Rule |\n| --- | ---: | --- |\n")
+			for _, mod := range report.Excluded {
+				fmt.Fprintf(&b, "| %s | %s | %s |\n", markdownEscape(mod.Name), mod.SizeHuman, markdownEscape(mod.Reason()))
+			}
+		}
+
+		fmt.Fprintf(&b, "\n## Warnings (%d)\n\n", len(report.Warnings))
+		if len(report.Warnings) == 0 {
+			fmt.Fprintf(&b, "None.\n")
+		}
+		for _, warning := range report.Warnings {
+			fmt.Fprintf(&b, "- %s\n", markdownEscape(warningText(warning)))
+		}
+	}
+	return b.String()
+}
+
+// RenderReportHTML renders report as a standalone HTML page
+func RenderReportHTML(report BackupReport) (string, error) {
+	var b bytes.Buffer
+	err := reportTemplate.Execute(&b, struct {
+		BackupReport
+		Summary  [][2]string
+		Warnings []string
+	}{report, reportSummary(report), warningTexts(report.Warnings)})
+	return b.String(), err
+}
+
+// reportSummary returns the label and value of each summary line
+func reportSummary(report BackupReport) [][2]string {
+	kind := "Full backup"
+	if report.Incremental {
+		kind = "Incremental backup"
+	}
+	details := []string{fmt.Sprintf("%s level %d", report.Format, report.Level), report.Layout + " layout"}
+	if report.Encrypted {
+		details = append(details, "encrypted")
+	}
+	mods := fmt.Sprintf("%d included", len(report.Included))
+	if !report.Partial {
+		mods += fmt.Sprintf(", %d excluded, %d warnings", len(report.Excluded), len(report.Warnings))
+	}
+	summary := [][2]string{
+		{"Finished", report.FinishedAt.Local().Format(reportTimeLayout)},
+		{"Took", report.Duration},
+		{"Backup", kind + ", " + strings.Join(details, ", ")},
+		{"Size", fmt.Sprintf("%s compressed to %s (%s)", report.OriginalSizeHuman, report.CompressedSizeHuman, report.Ratio)},
+		{"Mods", mods},
+	}
+	if report.Partial {
+		summary = append(summary, [2]string{"Note", "rebuilt from the manifest: mod sizes, reasons, exclusions and warnings are unknown"})
+	}
+	return summary
+}
+
+func warningText(warning BackupWarning) string {
+	if warning.Path != "" {
+		return fmt.Sprintf("%s: %s %s", warning.Mod, warning.Path, warning.Message)
+	}
+	return warning.Mod + ": " + warning.Message
+}
+
+func warningTexts(warnings []BackupWarning) []string {
+	texts := make([]string, len(warnings))
+	for i, warning := range warnings {
+		texts[i] = warningText(warning)
+	}
+	return texts
+}
+
+// markdownEscape keeps s from breaking a table or turning into markup
+var markdownEscape = strings.NewReplacer(
+	`\`, `\\`, "|", `\|`, "*", `\*`, "_", `\_`, "`", "\\`", "<", "&lt;", "\n", " ",
+).Replace
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Aurora backup report</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem auto; max-width: 60rem; padding: 0 1rem; color: #222; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 1.5rem; }
+th, td { border-bottom: 1px solid #ddd; padding: 0.3rem 0.6rem; text-align: left; }
+td.num, th.num { text-align: right; white-space: nowrap; }
+dt { font-weight: bold; float: left; clear: left; width: 6rem; }
+dd { margin: 0 0 0.3rem 6rem; }
+.warning { color: #a15c00; }
+</style>
+</head>
+<body>
+<h1>Aurora backup report</h1>
+<dl>
+{{range .Summary}}<dt>{{index . 0}}</dt><dd>{{index . 1}}</dd>
+{{end}}</dl>
+
+<h2>Archives</h2>
+<table>
+<tr><th>Archive</th><th class="num">Size</th></tr>
+{{range .Archives}}<tr><td>{{.Name}}</td><td class="num">{{.SizeHuman}}</td></tr>
+{{end}}</table>
+
+<h2>Included mods ({{len .Included}})</h2>
+<table>
+{{if .Partial}}<tr><th>Mod</th></tr>
+{{range .Included}}<tr><td>{{.Name}}</td></tr>
+{{end}}{{else}}<tr><th>Mod</th><th class="num">Size</th><th class="num">Ratio</th><th>Why</th></tr>
+{{range .Included}}<tr><td>{{.Name}}</td><td class="num">{{.SizeHuman}}</td><td class="num">{{.Ratio}}</td><td>{{.Reason}}</td></tr>
+{{end}}{{end}}</table>
+{{if not .Partial}}
+<h2>Excluded mods ({{len .Excluded}})</h2>
+{{if .Excluded}}<table>
+<tr><th>Mod</th><th class="num">Size</th><th>Rule</th></tr>
+{{range .Excluded}}<tr><td>{{.Name}}</td><td class="num">{{.SizeHuman}}</td><td>{{.Reason}}</td></tr>
+{{end}}</table>
+{{else}}<p>No filter excluded a mod.</p>
+{{end}}
+<h2>Warnings ({{len .Warnings}})</h2>
+{{if .Warnings}}<ul>
+{{range .Warnings}}<li class="warning">{{.}}</li>
+{{end}}</ul>
+{{else}}<p>None.</p>
+{{end}}{{end}}</body>
+</html>
+`))
@@ -0,0 +1,141 @@
+package aurora
+
+import (
+	"aurora/internal/paths"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// reportMods backs up "Body Mod" (collection Main) and "Unused Mod"
+// (inclusion), excluding "xx-Hat" (filter), with an empty "Empty Mod"
+// skipped
+var (
+	reportMods = map[string]map[string]string{
+		"Unused Mod": {"a.tex": "data"},
+		"Body Mod":   {"a.tex": "texture data"},
+		"xx-Hat":     {"a.tex": "hat"},
+		"Empty Mod":  {},
+	}
+	reportCollections = map[string][]string{"Main": {"Body Mod", "xx-Hat", "Empty Mod"}}
+)
+
+func TestBackupReport(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("a backup writes every form next to its archives", func(t *testing.T) {
+		a := newTestAurora(t, reportMods, reportCollections)
+		configure(t, a.SetFormat(FormatTarZst), a.AddInclusion("Unused"), a.AddFilter("xx-"))
+		if _, err := a.RunBackup(ctx, RunBackupOptions{}, &recordingObserver{}); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{ReportFile, ReportHTMLFile, ReportMarkdownFile} {
+			if _, err := os.Stat(filepath.Join(a.Config().Output, name)); err != nil {
+				t.Errorf("expected %s: %v", name, err)
+			}
+		}
+
+		report, err := a.BackupReport("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Partial || report.Format != FormatTarZst || len(report.Archives) != 1 || report.Archives[0].Size == 0 {
+			t.Errorf("unexpected report %+v", report)
+		}
+		if len(report.Included) != 2 {
+			t.Fatalf("expected 2 included mods, got %+v", report.Included)
+		}
+		body, unused := report.Included[0], report.Included[1]
+		if body.Name != "Body Mod" || body.Reason() != "collections: Main" || body.Ratio == "" {
+			t.Errorf("expected Body Mod first, kept by its collection, got %+v", body)
+		}
+		if unused.Name != "Unused Mod" || unused.Reason() != `inclusion filter "Unused"` {
+			t.Errorf("expected Unused Mod kept by the inclusion, got %+v", unused)
+		}
+		if len(report.Excluded) != 1 || report.Excluded[0].Reason() != `exclusion filter "xx-"` {
+			t.Errorf("expected xx-Hat excluded by its filter, got %+v", report.Excluded)
+		}
+		if len(report.Warnings) != 1 || report.Warnings[0].Mod != "Empty Mod" {
+			t.Errorf("expected a warning for the empty mod, got %+v", report.Warnings)
+		}
+
+		markdown, _ := os.ReadFile(filepath.Join(a.Config().Output, ReportMarkdownFile))
+		for _, want := range []string{"# Aurora backup report", "| Body Mod |", `exclusion filter "xx-"`, "Empty Mod: skipped, size 0"} {
+			if !strings.Contains(string(markdown), want) {
+				t.Errorf("expected %q in:\n%s", want, markdown)
+			}
+		}
+	})
+
+	t.Run("an encrypted set only gets the encrypted data", func(t *testing.T) {
+		fastScrypt(t)
+		a := newTestAurora(t, reportMods, reportCollections)
+		configure(t, a.SetFormat(FormatTarZst), a.AddInclusion("Unused"), a.AddFilter("xx-"), a.SetEncrypt(true))
+		if _, err := a.RunBackup(ctx, RunBackupOptions{Passphrase: "secret"}, &recordingObserver{}); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{ReportFile, ReportHTMLFile, ReportMarkdownFile} {
+			if _, err := os.Stat(filepath.Join(a.Config().Output, name)); err == nil {
+				t.Errorf("expected no plain %s", name)
+			}
+		}
+		if _, err := a.BackupReport("", ""); !errors.Is(err, ErrPassphraseRequired) {
+			t.Errorf("expected ErrPassphraseRequired, got %v", err)
+		}
+		report, err := a.BackupReport("", "secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !report.Encrypted || len(report.Included) != 2 {
+			t.Errorf("unexpected report %+v", report)
+		}
+	})
+
+	t.Run("older sets are rebuilt from their manifest", func(t *testing.T) {
+		t.Setenv(paths.EnvHome, t.TempDir())
+		dir := t.TempDir()
+		manifest := writeBackupSet(t, dir, "")
+		manifest.Mods = []string{"Mod"}
+		writeManifest(dir, manifest, "")
+
+		report, err := (&Aurora{}).BackupReport(dir, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !report.Partial || len(report.Archives) != 2 || len(report.Included) != 1 {
+			t.Errorf("expected a partial report of 2 archives and 1 mod, got %+v", report)
+		}
+		if _, err := (&Aurora{}).BackupReport(t.TempDir(), ""); !errors.Is(err, ErrNoBackup) {
+			t.Errorf("expected ErrNoBackup, got %v", err)
+		}
+	})
+}
+
+func TestRenderReport(t *testing.T) {
+	report := BackupReport{
+		Included: []ReportMod{{Name: "<b>Bad|Name</b>", SizeHuman: "1 kB", Ratio: "50.0%", Collections: []string{"Main"}}},
+		Warnings: []BackupWarning{{Mod: "Broken", Path: "/mods/Broken/x", Message: "cannot access: denied"}},
+	}
+
+	markdown := RenderReportMarkdown(report)
+	if !strings.Contains(markdown, `| &lt;b>Bad\|Name&lt;/b> | 1 kB | 50.0% | collections: Main |`) {
+		t.Errorf("expected the mod name escaped in:\n%s", markdown)
+	}
+	if !strings.Contains(markdown, "- Broken: /mods/Broken/x cannot access: denied") {
+		t.Errorf("expected the warning in:\n%s", markdown)
+	}
+
+	html, err := RenderReportHTML(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(html, "<b>Bad") || !strings.Contains(html, "&lt;b&gt;Bad|Name&lt;/b&gt;") {
+		t.Errorf("expected the mod name escaped in:\n%s", html)
+	}
+	if !strings.Contains(html, "No filter excluded a mod.") {
+		t.Errorf("expected the empty exclusions noted in:\n%s", html)
+	}
+}
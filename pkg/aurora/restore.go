@@ -0,0 +1,315 @@
+package aurora
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"aurora/internal/logger"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ErrCannotRestore is returned when a backup set holds go-delta native
+// archives, which Aurora can't read
+var ErrCannotRestore = errors.New("go-delta archives can't be restored by Aurora, extract them with go-delta")
+
+// RestoreOptions selects what RestoreBackup restores, and where
+type RestoreOptions struct {
+	Dir        string   // backup set folder ("" = the output directory), not used by RestoreTarget
+	Dest       string   // folder the mods go to ("" = the mods folder)
+	Mods       []string // mod folders to restore (none = every mod of the set)
+	Passphrase string   // for an encrypted set
+}
+
+// RestoreResult lists the mods RestoreBackup put back
+type RestoreResult struct {
+	Dest     string   `json:"dest"`
+	Restored []string `json:"restored"`
+	Skipped  []string `json:"skipped"` // already in Dest, left as they are
+	Files    int      `json:"files"`
+}
+
+// RestoreBackup extracts the mods of a backup set to a folder. Encrypted
+// sets are decrypted on the way, and archives are checked against the
+// manifest first. Mods are extracted next to Dest, then moved in one by
+// one: a mod already in Dest is skipped, never overwritten. Fails with
+// ErrNoBackup, ErrWrongPassphrase or ErrPassphraseRequired,
+// ErrCannotRestore for go-delta native archives, or ErrModNotFound when a
+// mod asked for isn't in the set.
+func (a *Aurora) RestoreBackup(opts RestoreOptions) (RestoreResult, error) {
+	if opts.Dir == "" {
+		opts.Dir = a.Config().Output
+	}
+	return a.restoreSet(opts, opts.Dir, ListBackupArchives(opts.Dir), localArchives(opts.Dir))
+}
+
+// RestoreTarget is RestoreBackup for the backup set on the configured
+// target. Archives are downloaded, checked and extracted one at a time, so
+// the restore needs room for the largest only besides the mods.
+func (a *Aurora) RestoreTarget(ctx context.Context, opts RestoreOptions) (RestoreResult, error) {
+	storage, err := a.openTarget(ctx)
+	if err != nil {
+		return RestoreResult{}, err
+	}
+	defer storage.Close()
+
+	set, err := openStoredSet(ctx, storage, "")
+	if err != nil {
+		return RestoreResult{}, err
+	}
+	defer set.Close()
+	logger.Info("Restoring the backup on %s one archive at a time", storage)
+	return a.restoreSet(opts, set.tmp, set.archives, set.fetch(ctx))
+}
+
+// restoreSet restores a backup set (see RestoreBackup) whose manifest is
+// in manifestDir, fetching each archive in turn. listed are its archives,
+// used when there is no manifest to name them.
+func (a *Aurora) restoreSet(opts RestoreOptions, manifestDir string, listed []string, fetch fetchFunc) (RestoreResult, error) {
+	if opts.Dest == "" {
+		opts.Dest = a.Config().Mods.Path
+	}
+	if opts.Dest == "" {
+		return RestoreResult{}, fmt.Errorf("%w: no mods folder to restore to", ErrInvalidConfig)
+	}
+
+	manifest, err := ReadManifest(manifestDir, opts.Passphrase)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrWrongPassphrase), errors.Is(err, ErrPassphraseRequired):
+		return RestoreResult{}, err
+	case errors.Is(err, os.ErrNotExist):
+		logger.Warn("No backup manifest in %s: restoring the archives without checksums", manifestDir)
+	default:
+		return RestoreResult{}, fmt.Errorf("read backup manifest: %w", err)
+	}
+	archives := manifest.Parts
+	if len(archives) == 0 {
+		archives = listed
+	}
+	if len(archives) == 0 {
+		return RestoreResult{}, ErrNoBackup
+	}
+	if slices.ContainsFunc(archives, func(name string) bool {
+		format, _ := formatOfFile(name)
+		return format.name == FormatDelta
+	}) {
+		return RestoreResult{}, ErrCannotRestore
+	}
+
+	return restoreArchives(opts, archives, func(name string) (string, func(), error) {
+		archive, cleanup, err := fetch(name)
+		if err != nil {
+			return "", nil, err
+		}
+		if checksum := manifest.Checksums[name]; checksum != "" {
+			sum, err := fileSHA256(archive)
+			if err == nil && sum != checksum {
+				err = fmt.Errorf("%s: checksum mismatch: the file changed since the backup", name)
+			}
+			if err != nil {
+				cleanup()
+				return "", nil, err
+			}
+		}
+		return archive, cleanup, nil
+	})
+}
+
+// restoreArchives extracts the archives of a set, each made available as
+// a local file by fetch, to a folder next to opts.Dest, then moves the mods
+// found in Dest (see RestoreBackup)
+func restoreArchives(opts RestoreOptions, archives []string, fetch fetchFunc) (RestoreResult, error) {
+	if err := os.MkdirAll(opts.Dest, 0755); err != nil {
+		return RestoreResult{}, fmt.Errorf("create %s: %w", opts.Dest, err)
+	}
+	extracted, err := os.MkdirTemp(opts.Dest, ".aurora-restore-*")
+	if err != nil {
+		return RestoreResult{}, err
+	}
+	defer os.RemoveAll(extracted)
+
+	// A mod already in Dest is skipped without extracting it
+	skipped := map[string]bool{}
+	keep := func(mod string) bool {
+		if len(opts.Mods) > 0 && !slices.Contains(opts.Mods, mod) {
+			return false
+		}
+		if _, err := os.Lstat(filepath.Join(opts.Dest, mod)); err == nil {
+			skipped[mod] = true
+			return false
+		}
+		return true
+	}
+
+	result := RestoreResult{Dest: opts.Dest}
+	for _, name := range archives {
+		archive, cleanup, err := fetch(name)
+		if err != nil {
+			return RestoreResult{}, err
+		}
+		files, err := extractArchive(archive, opts.Passphrase, keep, extracted)
+		cleanup()
+		if err != nil {
+			if errors.Is(err, ErrWrongPassphrase) || errors.Is(err, ErrPassphraseRequired) {
+				return RestoreResult{}, err
+			}
+			return RestoreResult{}, fmt.Errorf("extract %s: %w", name, err)
+		}
+		result.Files += files
+	}
+
+	entries, err := os.ReadDir(extracted)
+	if err != nil {
+		return RestoreResult{}, err
+	}
+	found := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		found = append(found, entry.Name())
+	}
+	for _, mod := range opts.Mods {
+		if !slices.Contains(found, mod) && !skipped[mod] {
+			return RestoreResult{}, fmt.Errorf("%w in the backup: %s", ErrModNotFound, mod)
+		}
+	}
+	result.Skipped = slices.Sorted(maps.Keys(skipped))
+	for _, mod := range found {
+		if err := os.Rename(filepath.Join(extracted, mod), filepath.Join(opts.Dest, mod)); err != nil {
+			return result, fmt.Errorf("restore %s: %w", mod, err)
+		}
+		result.Restored = append(result.Restored, mod)
+	}
+	logger.Info("Restored %d mods (%d files) to %s, skipped %d already there", len(result.Restored), result.Files, opts.Dest, len(result.Skipped))
+	return result, nil
+}
+
+// extractArchive writes the files of a ZIP or tar.zst archive, encrypted
+// or not, whose mod folder keep accepts, under dest
+func extractArchive(archive, passphrase string, keep func(mod string) bool, dest string) (files int, err error) {
+	if format, _ := formatOfFile(archive); format.name == FormatTarZst {
+		return extractTarZst(archive, passphrase, keep, dest)
+	}
+
+	// ZIP needs random access, as in verifyArchive
+	zipPath := archive
+	if strings.HasSuffix(archive, EncryptedExt) {
+		zipPath, err = decryptToTemp(archive, passphrase)
+		if err != nil {
+			return 0, err
+		}
+		defer os.Remove(zipPath)
+	}
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return 0, fmt.Errorf("open archive: %w", err)
+	}
+	defer reader.Close()
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		target, ok, err := restorePath(dest, file.Name, keep)
+		if err != nil {
+			return files, err
+		}
+		if !ok {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return files, fmt.Errorf("%s: %w", file.Name, err)
+		}
+		err = writeRestoredFile(target, rc, file.Modified)
+		rc.Close()
+		if err != nil {
+			return files, fmt.Errorf("%s: %w", file.Name, err)
+		}
+		files++
+	}
+	return files, nil
+}
+
+// extractTarZst is extractArchive for tar.zst archives, which stream
+func extractTarZst(archive, passphrase string, keep func(mod string) bool, dest string) (files int, err error) {
+	file, err := OpenBackupFile(archive, passphrase)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	decoder, err := zstd.NewReader(file)
+	if err != nil {
+		return 0, err
+	}
+	defer decoder.Close()
+
+	reader := tar.NewReader(decoder)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return files, fmt.Errorf("read archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		target, ok, err := restorePath(dest, header.Name, keep)
+		if err != nil {
+			return files, err
+		}
+		if !ok {
+			continue
+		}
+		if err := writeRestoredFile(target, reader, header.ModTime); err != nil {
+			return files, fmt.Errorf("%s: %w", header.Name, err)
+		}
+		files++
+	}
+}
+
+// restorePath returns where an archive entry goes under dest, and whether
+// keep accepts its mod folder. Names leaving dest are refused.
+func restorePath(dest, name string, keep func(mod string) bool) (string, bool, error) {
+	name = path.Clean(name)
+	if !filepath.IsLocal(filepath.FromSlash(name)) {
+		return "", false, fmt.Errorf("unsafe path in archive: %s", name)
+	}
+	mod, _, found := strings.Cut(name, "/")
+	if !found || !keep(mod) {
+		return "", false, nil
+	}
+	return filepath.Join(dest, filepath.FromSlash(name)), true, nil
+}
+
+// writeRestoredFile writes r to path with its modification time
+func writeRestoredFile(path string, r io.Reader, modTime time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if !modTime.IsZero() {
+		os.Chtimes(path, modTime, modTime)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package aurora
+
+import (
+	"aurora/internal/config"
+	"aurora/internal/paths"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestRestoreBackup(t *testing.T) {
+	fastScrypt(t)
+
+	t.Run("restores an encrypted backup to another folder", func(t *testing.T) {
+		a := newTestAurora(t, reportMods, reportCollections)
+		configure(t, a.SetFormat(FormatTarZst), a.AddInclusion("Unused"), a.SetEncrypt(true))
+		if _, err := a.RunBackup(context.Background(), RunBackupOptions{Passphrase: "secret"}, &recordingObserver{}); err != nil {
+			t.Fatal(err)
+		}
+		dest := t.TempDir()
+		if _, err := a.RestoreBackup(RestoreOptions{Dest: dest, Passphrase: "wrong"}); !errors.Is(err, ErrWrongPassphrase) {
+			t.Errorf("expected ErrWrongPassphrase, got %v", err)
+		}
+
+		result, err := a.RestoreBackup(RestoreOptions{Dest: dest, Passphrase: "secret"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"Body Mod", "Unused Mod", "xx-Hat"}; !slices.Equal(result.Restored, want) || result.Files != 3 {
+			t.Errorf("expected %v restored, got %+v", want, result)
+		}
+		if got, _ := os.ReadFile(filepath.Join(dest, "Body Mod", "a.tex")); string(got) != "texture data" {
+			t.Errorf("expected Body Mod's file back, got %q", got)
+		}
+		if entries, _ := os.ReadDir(dest); len(entries) != 3 {
+			t.Errorf("expected only the mods in the folder, got %d entries", len(entries))
+		}
+	})
+
+	t.Run("skips mods already in the folder", func(t *testing.T) {
+		t.Setenv(paths.EnvHome, t.TempDir())
+		outputDir := t.TempDir()
+		writeBackupSet(t, outputDir, "secret")
+		dest := t.TempDir()
+		os.MkdirAll(filepath.Join(dest, "ModA"), 0755)
+		a := newAurora(&config.Config{Output: outputDir, Mods: config.ModsConfig{Path: dest}})
+
+		result, err := a.RestoreBackup(RestoreOptions{Passphrase: "secret"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(result.Restored, []string{"Mod", "ModB"}) || !slices.Equal(result.Skipped, []string{"ModA"}) {
+			t.Errorf("unexpected result %+v", result)
+		}
+		if entries, _ := os.ReadDir(filepath.Join(dest, "ModA")); len(entries) != 0 {
+			t.Error("expected ModA left as it was")
+		}
+	})
+
+	t.Run("restores the mods asked for", func(t *testing.T) {
+		t.Setenv(paths.EnvHome, t.TempDir())
+		outputDir := t.TempDir()
+		writeBackupSet(t, outputDir, "")
+		a := newAurora(&config.Config{Output: outputDir})
+		dest := t.TempDir()
+
+		result, err := a.RestoreBackup(RestoreOptions{Dest: dest, Mods: []string{"ModB"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(result.Restored, []string{"ModB"}) || result.Files != 1 {
+			t.Errorf("expected only ModB, got %+v", result)
+		}
+		if _, err := a.RestoreBackup(RestoreOptions{Dest: dest, Mods: []string{"Missing"}}); !errors.Is(err, ErrModNotFound) {
+			t.Errorf("expected ErrModNotFound, got %v", err)
+		}
+	})
+
+	t.Run("refuses a changed archive or a path out of the folder", func(t *testing.T) {
+		t.Setenv(paths.EnvHome, t.TempDir())
+		outputDir := t.TempDir()
+		writeBackupSet(t, outputDir, "")
+		a := newAurora(&config.Config{Output: outputDir})
+		os.WriteFile(filepath.Join(outputDir, "backup_part_02.zip"), []byte("tampered"), 0644)
+		dest := t.TempDir()
+		if _, err := a.RestoreBackup(RestoreOptions{Dest: dest}); err == nil {
+			t.Error("expected the changed archive refused")
+		}
+		if entries, _ := os.ReadDir(dest); len(entries) != 0 {
+			t.Errorf("expected nothing restored, got %d entries", len(entries))
+		}
+
+		unsafeDir := t.TempDir()
+		writeZip(t, filepath.Join(unsafeDir, "backup_part.zip"), map[string]string{"../evil/a.tex": "x"})
+		if _, err := a.RestoreBackup(RestoreOptions{Dir: unsafeDir, Dest: dest}); err == nil {
+			t.Error("expected a path out of the folder refused")
+		}
+		if _, err := os.Stat(filepath.Join(filepath.Dir(dest), "evil")); err == nil {
+			t.Error("expected nothing written outside the folder")
+		}
+	})
+
+	t.Run("restores from the target", func(t *testing.T) {
+		t.Setenv(paths.EnvHome, t.TempDir())
+		outputDir := t.TempDir()
+		writeBackupSet(t, outputDir, "secret")
+		target := Target{Type: TargetDir, Path: t.TempDir()}
+		if _, _, err := uploadBackup(context.Background(), localStorage{dir: target.Path}, outputDir, "", &recordingObserver{}); err != nil {
+			t.Fatal(err)
+		}
+		os.RemoveAll(outputDir)
+		a := newAurora(&config.Config{Output: outputDir, Target: config.TargetConfig(target)})
+		dest := t.TempDir()
+
+		if _, err := a.RestoreTarget(context.Background(), RestoreOptions{Dest: dest}); !errors.Is(err, ErrPassphraseRequired) {
+			t.Errorf("expected ErrPassphraseRequired, got %v", err)
+		}
+		result, err := a.RestoreTarget(context.Background(), RestoreOptions{Dest: dest, Passphrase: "secret"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(result.Restored, []string{"Mod", "ModA", "ModB"}) || result.Files != 6 {
+			t.Errorf("expected every mod of the set, got %+v", result)
+		}
+		if got, _ := os.ReadFile(filepath.Join(dest, "ModB", "b.mdl")); string(got) != "model" {
+			t.Errorf("expected ModB's file back, got %q", got)
+		}
+	})
+
+	t.Run("can't read go-delta archives", func(t *testing.T) {
+		outputDir := t.TempDir()
+		os.WriteFile(filepath.Join(outputDir, "backup_part.gdelta"), []byte("delta"), 0644)
+		a := newAurora(&config.Config{Output: outputDir})
+		if _, err := a.RestoreBackup(RestoreOptions{Dest: t.TempDir()}); !errors.Is(err, ErrCannotRestore) {
+			t.Errorf("expected ErrCannotRestore, got %v", err)
+		}
+	})
+}
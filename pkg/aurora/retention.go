@@ -0,0 +1,147 @@
+package aurora
+
+import (
+	"aurora/internal/config"
+	"aurora/internal/logger"
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"time"
+)
+
+// Retention is which incremental backups are kept, in the output directory,
+// on the target and on every mirror. Full backups are never deleted: each
+// one replaces the previous.
+type Retention struct {
+	Incrementals int `json:"incrementals"` // newest incremental backups kept, 0 = all
+	Days         int `json:"days"`         // older incremental backups are deleted, 0 = never
+}
+
+// Validate reports a retention that can't be applied
+func (r Retention) Validate() error {
+	if r.Incrementals < 0 {
+		return fmt.Errorf("retention must keep 0 (all) or more incremental backups, got %d", r.Incrementals)
+	}
+	if r.Days < 0 {
+		return fmt.Errorf("retention days must be 0 (never delete) or more, got %d", r.Days)
+	}
+	return nil
+}
+
+// expired returns the incremental backup directories among names, sorted
+// oldest first, that r deletes at now. Names not made by incrementalPath
+// are left alone.
+func (r Retention) expired(names []string, now time.Time) []string {
+	cutoff := now.AddDate(0, 0, -r.Days)
+	var expired []string
+	kept := 0
+	for _, name := range slices.Backward(names) {
+		started, err := time.ParseInLocation(incrementalName, name, time.Local)
+		if err != nil {
+			continue
+		}
+		if (r.Incrementals > 0 && kept >= r.Incrementals) || (r.Days > 0 && started.Before(cutoff)) {
+			expired = append(expired, name)
+			continue
+		}
+		kept++
+	}
+	slices.Reverse(expired)
+	return expired
+}
+
+// GetRetention returns which incremental backups are kept
+func (a *Aurora) GetRetention() Retention {
+	return Retention(a.Config().Retention)
+}
+
+// SetRetention sets which incremental backups are kept
+func (a *Aurora) SetRetention(retention Retention) error {
+	if err := retention.Validate(); err != nil {
+		return err
+	}
+	return a.updateConfig(func(cfg *config.Config) {
+		cfg.Retention = config.RetentionConfig(retention)
+	})
+}
+
+// applyRetention deletes the incremental backups past the retention from
+// the output directory, the target and every mirror. The backup itself
+// succeeded: a failure is only reported with a notice.
+func (a *Aurora) applyRetention(ctx context.Context, observer BackupObserver) {
+	retention := a.GetRetention()
+	if retention == (Retention{}) {
+		return
+	}
+	cfg := a.Config()
+	destinations := []Target{{Type: TargetLocal}}
+	if target := a.GetTarget(); target.IsRemote() {
+		destinations = append(destinations, target)
+	}
+	destinations = append(destinations, a.GetMirrors()...)
+
+	now := time.Now()
+	for _, target := range destinations {
+		storage, err := OpenStorage(ctx, target, cfg.Output)
+		if err == nil {
+			var deleted int
+			deleted, err = pruneIncrementals(ctx, storage, retention, now)
+			if deleted > 0 {
+				observer.Notice(fmt.Sprintf("Deleted %d incremental backups past the retention from %s", deleted, storage))
+			}
+			storage.Close()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			where := target.String()
+			if !target.IsRemote() {
+				where = cfg.Output
+			}
+			logger.Warn("Retention on %s failed: %v", where, err)
+			observer.Notice(fmt.Sprintf("Warning: retention on %s failed: %v", where, err))
+		}
+	}
+}
+
+// pruneIncrementals deletes the incremental backups of storage past
+// retention at now and returns how many it deleted
+func pruneIncrementals(ctx context.Context, storage Storage, retention Retention, now time.Time) (int, error) {
+	names, err := storage.Dirs(ctx, IncrementalDir)
+	if err != nil {
+		return 0, fmt.Errorf("list %s: %w", storage, err)
+	}
+	expired := retention.expired(names, now)
+	for i, name := range expired {
+		logger.Info("Deleting incremental backup %s from %s, past the retention", name, storage)
+		if err := deleteTree(ctx, storage, path.Join(IncrementalDir, name)); err != nil {
+			return i, fmt.Errorf("delete incremental backup %s: %w", name, err)
+		}
+	}
+	return len(expired), nil
+}
+
+// deleteTree deletes dir of storage with everything under it
+func deleteTree(ctx context.Context, storage Storage, dir string) error {
+	dirs, err := storage.Dirs(ctx, dir)
+	if err != nil {
+		return err
+	}
+	for _, sub := range dirs {
+		if err := deleteTree(ctx, storage, path.Join(dir, sub)); err != nil {
+			return err
+		}
+	}
+	files, err := storage.List(ctx, dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := storage.Delete(ctx, path.Join(dir, file.Name)); err != nil {
+			return err
+		}
+	}
+	return storage.Delete(ctx, dir)
+}
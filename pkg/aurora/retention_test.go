@@ -0,0 +1,120 @@
+package aurora
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetentionExpired(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.Local)
+	names := []string{"20260101-120000", "20260301-120000", "20260305-120000", "20260309-120000", "notes"}
+
+	tests := []struct {
+		name      string
+		retention Retention
+		want      []string
+	}{
+		{"keeps everything by default", Retention{}, nil},
+		{"keeps the newest", Retention{Incrementals: 2}, []string{"20260101-120000", "20260301-120000"}},
+		{"deletes the older", Retention{Days: 7}, []string{"20260101-120000", "20260301-120000"}},
+		{"both limits apply", Retention{Incrementals: 3, Days: 30}, []string{"20260101-120000"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.retention.expired(names, now); !slices.Equal(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	if err := (Retention{Incrementals: -1}).Validate(); err == nil {
+		t.Error("expected a negative count refused")
+	}
+	if err := (Retention{Days: -1}).Validate(); err == nil {
+		t.Error("expected negative days refused")
+	}
+}
+
+func TestPruneIncrementals(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.Local)
+	// An uploaded incremental backup: a set folder and the file naming it
+	files := func(stamp string) []string {
+		return []string{
+			"incremental/" + stamp + "/" + CurrentSetFile,
+			"incremental/" + stamp + "/" + SetA + "/backup_part.zip",
+			"incremental/" + stamp + "/" + SetA + "/" + ManifestFile,
+		}
+	}
+
+	t.Run("deletes whole folders from a directory", func(t *testing.T) {
+		dir := t.TempDir()
+		storage := localStorage{dir: dir}
+		for _, name := range append(append(files("20260101-120000"), files("20260309-120000")...), "incremental/notes/todo.txt") {
+			storage.Put(ctx, name, strings.NewReader("data"), 4)
+		}
+
+		deleted, err := pruneIncrementals(ctx, storage, Retention{Incrementals: 1}, now)
+		if err != nil || deleted != 1 {
+			t.Fatalf("expected 1 backup deleted, got %d, %v", deleted, err)
+		}
+		if dirs, _ := storage.Dirs(ctx, IncrementalDir); !slices.Equal(dirs, []string{"20260309-120000", "notes"}) {
+			t.Errorf("expected the newest backup and other folders kept, got %v", dirs)
+		}
+	})
+
+	t.Run("deletes whole folders from S3", func(t *testing.T) {
+		fake, target := newFakeS3(t)
+		storage, err := OpenStorage(ctx, target, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range append(files("20260101-120000"), files("20260309-120000")...) {
+			storage.Put(ctx, name, strings.NewReader("data"), 4)
+		}
+
+		if deleted, err := pruneIncrementals(ctx, storage, Retention{Days: 7}, now); err != nil || deleted != 1 {
+			t.Fatalf("expected 1 backup deleted, got %d, %v", deleted, err)
+		}
+		for key := range fake.objects {
+			if strings.Contains(key, "20260101-120000") {
+				t.Errorf("expected %s deleted", key)
+			}
+		}
+		if len(fake.objects) != 3 {
+			t.Errorf("expected the newest backup kept, got %v", fake.objects)
+		}
+	})
+}
+
+func TestRunBackupRetention(t *testing.T) {
+	a := newTestAurora(t, reportMods, reportCollections)
+	target := Target{Type: TargetDir, Path: t.TempDir()}
+	configure(t, a.SetFormat(FormatTarZst), a.SetTarget(target), a.SetRetention(Retention{Incrementals: 1}))
+	outputDir := a.Config().Output
+	old := filepath.Join(IncrementalDir, "20200101-120000")
+	for _, dir := range []string{filepath.Join(outputDir, old), filepath.Join(target.Path, old, SetA)} {
+		os.MkdirAll(dir, 0755)
+		os.WriteFile(filepath.Join(dir, "backup_part.tar.zst"), []byte("old"), 0644)
+	}
+
+	observer := &recordingObserver{}
+	result, err := a.RunBackup(context.Background(), RunBackupOptions{Mods: []string{"Body Mod"}}, observer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Base(result.OutputDir)}
+	for _, dir := range []string{outputDir, target.Path} {
+		if dirs, _ := (localStorage{dir: dir}).Dirs(context.Background(), IncrementalDir); !slices.Equal(dirs, want) {
+			t.Errorf("expected only %v left in %s, got %v", want, dir, dirs)
+		}
+	}
+	if !slices.ContainsFunc(observer.notices, func(n string) bool { return strings.Contains(n, "past the retention") }) {
+		t.Errorf("expected a notice of the deleted backups, got %v", observer.notices)
+	}
+}
@@ -0,0 +1,250 @@
+package aurora
+
+import (
+	"aurora/internal/logger"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// Errors RunBackup fails with before compressing anything
+var (
+	ErrInvalidConfig   = errors.New("configuration is not valid")
+	ErrNoSpace         = errors.New("not enough disk space for backup")
+	ErrNothingToBackup = errors.New("no mods to backup")
+)
+
+// RunBackupOptions configures Aurora.RunBackup
+type RunBackupOptions struct {
+	Threads    int    // compression workers; 0 = the configured concurrency
+	Resume     bool   // continue the interrupted backup instead of starting over
+	Passphrase string // encrypts the backup when encryption is on (see ResolvePassphrase)
+
+	// Mods limits a new backup to these mod folder names, an incremental
+	// backup written to its own directory (see IncrementalDir). Mods no
+	// longer selected for backup are left out; ignored when resuming.
+	Mods []string
+}
+
+// IncrementalDir is the directory under the output directory holding
+// incremental backups, one directory each named after its start time
+const IncrementalDir = "incremental"
+
+// incrementalName is the local time layout of incremental backup directory
+// names
+const incrementalName = "20060102-150405"
+
+// incrementalPath returns the directory of an incremental backup started at
+func incrementalPath(outputDir string, at time.Time) string {
+	return filepath.Join(outputDir, IncrementalDir, at.Format(incrementalName))
+}
+
+// BackupObserver follows a backup run. The CLI and the desktop app each
+// implement it to render the run their way; Progress is called from
+// compression goroutines.
+type BackupObserver interface {
+	// Validated reports the preview of a new backup before any check
+	Validated(validation BackupValidation)
+	// Notice reports a step worth telling the user (resuming, discarding)
+	Notice(message string)
+	Progress(progress BackupProgress)
+}
+
+// RunBackup validates the configuration and the backup, checks disk space,
+// compresses the mods and returns the result, running the configured hooks
+// around it. It fails with ErrInvalidConfig, ErrHookFailed (pre-backup
+// hook), ErrNoSpace, ErrNothingToBackup, ErrNoPendingBackup (resume without
+// an interrupted backup), ErrFinalizingBackup (a new backup while one must
+// be resumed), ErrBackupRunning (another process backs up to the output
+// directory), ErrPassphraseRequired or ErrWrongPassphrase
+// (encrypted backups) or ErrBackupCancelled when ctx is done; a failed or
+// cancelled run can be resumed. With a remote target the finished set is
+// uploaded before the post-backup hook; ErrUploadFailed keeps it in the
+// output directory. Mirrors get a copy too, but a failed mirror is only
+// reported in BackupResult.Destinations. A successful backup takes a history
+// snapshot (see TakeSnapshot), then deletes the incremental backups past the
+// retention (see Retention). A failing post-backup hook doesn't fail the
+// backup: it is reported in BackupResult.HookError.
+func (a *Aurora) RunBackup(ctx context.Context, opts RunBackupOptions, observer BackupObserver) (BackupResult, error) {
+	cfg := a.Config()
+	if !cfg.Status().Valid {
+		return BackupResult{}, ErrInvalidConfig
+	}
+	// Only an encrypted backup takes the passphrase
+	if !opts.Resume && cfg.Encrypt && opts.Passphrase == "" {
+		return BackupResult{}, ErrPassphraseRequired
+	}
+	lock, err := lockOutput(cfg.Output)
+	if err != nil {
+		return BackupResult{}, err
+	}
+	defer lock.release()
+
+	timeout := a.hookTimeout()
+	if err := runHook(ctx, cfg.Hooks.PreBackup, a.hookVars(hookPre, opts), timeout); err != nil {
+		return BackupResult{}, err
+	}
+
+	result, mods, err := a.runBackup(ctx, opts, observer)
+	if err == nil {
+		err = a.copyToDestinations(ctx, &result, observer)
+	}
+	if err == nil {
+		// The history is a side record: a failure doesn't fail the backup
+		if _, snapErr := a.TakeSnapshot(SnapshotBackup); snapErr != nil {
+			logger.Warn("Failed to snapshot the mod library: %v", snapErr)
+		}
+		a.applyRetention(ctx, observer)
+	}
+
+	vars := a.hookVars(hookPost, opts)
+	vars.Mods = mods
+	hookCtx := ctx
+	switch {
+	case err == nil:
+		vars.Status = HookStatusSuccess
+		if outputDir, absErr := filepath.Abs(result.OutputDir); absErr == nil {
+			vars.OutputDir = outputDir
+		}
+		for _, archive := range ListBackupArchives(result.OutputDir) {
+			vars.Archives = append(vars.Archives, filepath.Join(vars.OutputDir, archive))
+		}
+		vars.OriginalSize = result.OriginalSize
+		vars.CompressedSize = result.CompressedSize
+		vars.Ratio = result.Ratio
+	case errors.Is(err, ErrBackupCancelled):
+		vars.Status = HookStatusCancelled
+		hookCtx = context.WithoutCancel(ctx) // still report the cancellation
+	default:
+		vars.Status = HookStatusFailed
+		vars.Error = err.Error()
+	}
+	if hookErr := runHook(hookCtx, cfg.Hooks.PostBackup, vars, timeout); hookErr != nil {
+		if errors.Is(hookErr, ErrBackupCancelled) {
+			hookErr = fmt.Errorf("%s hook: %w", hookPost, hookErr)
+		}
+		observer.Notice(fmt.Sprintf("Warning: %v", hookErr))
+		if err == nil {
+			result.HookError = hookErr.Error()
+		}
+	}
+	return result, err
+}
+
+// runBackup is RunBackup between the hooks; it also returns how many mods
+// the backup holds
+func (a *Aurora) runBackup(ctx context.Context, opts RunBackupOptions, observer BackupObserver) (BackupResult, int, error) {
+	cfg := a.Config()
+	threads := cfg.Concurrency
+	if opts.Threads > 0 {
+		threads = opts.Threads
+	}
+	outputDir := cfg.Output
+
+	pending, err := a.PendingBackup()
+	if err != nil {
+		return BackupResult{}, 0, err
+	}
+
+	var result BackupResult
+	var mods int
+	if opts.Resume {
+		if pending == nil {
+			return BackupResult{}, 0, ErrNoPendingBackup
+		}
+		mods = pending.TotalMods
+		observer.Notice(fmt.Sprintf("Resuming backup started %s (%d/%d mods done)",
+			pending.StartedAt.Format(time.DateTime), pending.DoneMods, pending.TotalMods))
+		// The journal fixes the mods, format, compression, layout and encryption
+		compressOpts := NewBackupOptions(nil, threads, a.GetFormat(), a.GetCompression(), outputDir, true)
+		result, err = resumeBackup(ctx, compressOpts, opts.Passphrase, observer.Progress)
+	} else {
+		passphrase := ""
+		if cfg.Encrypt {
+			passphrase = opts.Passphrase
+		}
+
+		var only map[string]bool
+		if len(opts.Mods) > 0 {
+			only = make(map[string]bool, len(opts.Mods))
+			for _, mod := range opts.Mods {
+				only[mod] = true
+			}
+		}
+
+		var validation BackupValidation
+		validation, err = a.validateBackup(only)
+		if err != nil {
+			return BackupResult{}, 0, fmt.Errorf("validate backup: %w", err)
+		}
+		observer.Validated(validation)
+		if !validation.HasEnoughSpace {
+			return BackupResult{}, 0, ErrNoSpace
+		}
+
+		layout := backupLayout{name: a.GetLayout(), maxPartSize: a.GetMaxPartSize()}
+		layout.groups, err = a.backupGroups(layout.name)
+		if err != nil {
+			return BackupResult{}, 0, fmt.Errorf("collect mods: %w", err)
+		}
+		if only != nil {
+			layout.groups = onlyMods(layout.groups, only)
+			layout.incremental = true
+		}
+		var folders []string
+		for _, group := range layout.groups {
+			folders = append(folders, group.Folders...)
+		}
+		mods = len(folders)
+		if len(folders) == 0 {
+			return BackupResult{}, 0, ErrNothingToBackup
+		}
+
+		// Mods are never split across archives
+		if layout.maxPartSize > 0 {
+			for _, item := range validation.Items {
+				if !item.IsFiltered && item.Mod.Size > layout.maxPartSize {
+					observer.Notice(fmt.Sprintf("%s (%s) is bigger than the max part size of %s: its archive may exceed it",
+						item.Mod.Name, item.Mod.SizeHuman, humanize.Bytes(layout.maxPartSize)))
+				}
+			}
+		}
+
+		if pending != nil && only == nil && !pending.Finalizing {
+			observer.Notice(fmt.Sprintf("Discarding the interrupted backup started %s (resume it to keep its archives)",
+				pending.StartedAt.Format(time.DateTime)))
+		}
+		if only != nil {
+			outputDir = incrementalPath(cfg.Output, time.Now())
+			if err := os.MkdirAll(outputDir, 0755); err != nil {
+				return BackupResult{}, 0, fmt.Errorf("create incremental backup dir: %w", err)
+			}
+			observer.Notice(fmt.Sprintf("Incremental backup of %d mods to %s", len(folders), outputDir))
+		}
+		compressOpts := NewBackupOptions(folders, threads, a.GetFormat(), a.GetCompression(), outputDir, true)
+		result, err = startBackup(ctx, compressOpts, layout, validation, passphrase, observer.Progress)
+		if err != nil && only != nil {
+			// Can't be resumed: the next incremental backup takes the mods again
+			if removeErr := os.RemoveAll(outputDir); removeErr != nil {
+				logger.Warn("Failed to remove incremental backup %s: %v", outputDir, removeErr)
+			}
+		}
+	}
+
+	if err != nil {
+		if errors.Is(err, ErrBackupCancelled) {
+			logger.Warn("Backup cancelled")
+		} else {
+			logger.Error("Backup failed: %v", err)
+		}
+		return BackupResult{}, mods, err
+	}
+	logger.Logger().Info("Backup completed", "output", result.OutputPath, "mods", mods,
+		"original", result.OriginalSize, "compressed", result.CompressedSize, "ratio", result.Ratio)
+	return result, mods, nil
+}
@@ -0,0 +1,178 @@
+package aurora
+
+import (
+	"aurora/internal/config"
+	"aurora/internal/paths"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// recordingObserver keeps what a backup run reported
+type recordingObserver struct {
+	validations []BackupValidation
+	notices     []string
+	progress    []BackupProgress
+}
+
+func (o *recordingObserver) Validated(v BackupValidation) { o.validations = append(o.validations, v) }
+func (o *recordingObserver) Notice(message string)        { o.notices = append(o.notices, message) }
+func (o *recordingObserver) Progress(p BackupProgress)    { o.progress = append(o.progress, p) }
+
+// unusedMod is a library of one mod in no collection: nothing is selected
+// for backup
+var unusedMod = map[string]map[string]string{"Unused Mod": {"a.tex": "data"}}
+
+// newTestAurora returns an Aurora with valid paths and a data dir of its
+// own, holding its config file, so tests change settings through the
+// setters. mods maps each mod folder to its files and their content (a mod
+// without files is an empty folder); collections maps each Penumbra
+// collection to the mods it enables. I/O errors fail the test.
+func newTestAurora(t *testing.T, mods map[string]map[string]string, collections map[string][]string) *Aurora {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv(paths.EnvHome, home)
+	previous := config.ConfigFile
+	config.SetConfigFile(filepath.Join(home, "config.json"))
+	t.Cleanup(func() { config.SetConfigFile(previous) })
+	penumbraDir := t.TempDir()
+	modsDir := t.TempDir()
+	collectionsDir := filepath.Join(penumbraDir, "collections")
+	if err := os.MkdirAll(collectionsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for mod, files := range mods {
+		if err := os.MkdirAll(filepath.Join(modsDir, mod), 0755); err != nil {
+			t.Fatal(err)
+		}
+		for name, content := range files {
+			path := filepath.Join(modsDir, mod, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	for name, enabled := range collections {
+		settings := make(map[string]any, len(enabled))
+		for _, mod := range enabled {
+			settings[mod] = map[string]bool{"Enabled": true}
+		}
+		data, err := json.Marshal(map[string]any{"Name": name, "Settings": settings})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(collectionsDir, name+".json"), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := &config.Config{
+		Penumbra: config.PenumbraConfig{Path: penumbraDir},
+		Mods:     config.ModsConfig{Path: modsDir},
+		Output:   t.TempDir(),
+	}
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+	return newAurora(cfg)
+}
+
+// configure fails the test on the first error of the setters it is given,
+// as in configure(t, a.SetFormat(FormatTarZst), a.SetEncrypt(true))
+func configure(t *testing.T, errs ...error) {
+	t.Helper()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRunBackup(t *testing.T) {
+	t.Run("rejects an invalid config", func(t *testing.T) {
+		a := newAurora(&config.Config{Penumbra: config.PenumbraConfig{Path: filepath.Join(t.TempDir(), "missing")}})
+		_, err := a.RunBackup(context.Background(), RunBackupOptions{}, &recordingObserver{})
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("expected ErrInvalidConfig, got %v", err)
+		}
+	})
+
+	t.Run("reports the preview then fails with nothing to back up", func(t *testing.T) {
+		a := newTestAurora(t, unusedMod, nil)
+		observer := &recordingObserver{}
+		_, err := a.RunBackup(context.Background(), RunBackupOptions{}, observer)
+		if !errors.Is(err, ErrNothingToBackup) {
+			t.Errorf("expected ErrNothingToBackup, got %v", err)
+		}
+		if len(observer.validations) != 1 {
+			t.Errorf("expected the preview reported once, got %d", len(observer.validations))
+		}
+	})
+
+	t.Run("encryption needs a passphrase", func(t *testing.T) {
+		a := newTestAurora(t, unusedMod, nil)
+		configure(t, a.SetEncrypt(true))
+		observer := &recordingObserver{}
+		_, err := a.RunBackup(context.Background(), RunBackupOptions{}, observer)
+		if !errors.Is(err, ErrPassphraseRequired) {
+			t.Errorf("expected ErrPassphraseRequired, got %v", err)
+		}
+		if len(observer.validations) != 0 {
+			t.Error("expected no preview without a passphrase")
+		}
+	})
+
+	t.Run("resume needs an interrupted backup", func(t *testing.T) {
+		a := newTestAurora(t, unusedMod, nil)
+		observer := &recordingObserver{}
+		_, err := a.RunBackup(context.Background(), RunBackupOptions{Resume: true}, observer)
+		if !errors.Is(err, ErrNoPendingBackup) {
+			t.Errorf("expected ErrNoPendingBackup, got %v", err)
+		}
+		if len(observer.validations) != 0 {
+			t.Error("expected no preview for a resumed backup")
+		}
+	})
+
+	t.Run("resumes an interrupted backup", func(t *testing.T) {
+		a := newTestAurora(t, unusedMod, nil)
+		outputDir := a.Config().Output
+		j := &journal{Version: journalVersion, Folders: []string{"a"}}
+		j.Batches = []journalBatch{stageBatch(t, outputDir, "batch_000", []string{"a"}, map[string]string{"backup_part.zip": "x"})}
+		j.save(outputDir)
+
+		observer := &recordingObserver{}
+		result, err := a.RunBackup(context.Background(), RunBackupOptions{Resume: true}, observer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.OutputPath != BackupOutputPath || result.Ratio != "40.0%" {
+			t.Errorf("unexpected result %+v", result)
+		}
+		if len(observer.notices) != 1 || len(observer.progress) == 0 {
+			t.Errorf("expected a resume notice and progress, got %v and %d snapshots", observer.notices, len(observer.progress))
+		}
+	})
+
+	t.Run("completes when the manifest history can't be written", func(t *testing.T) {
+		a := newTestAurora(t, unusedMod, nil)
+		outputDir := a.Config().Output
+		j := &journal{Version: journalVersion, Folders: []string{"a"}}
+		j.Batches = []journalBatch{stageBatch(t, outputDir, "batch_000", []string{"a"}, map[string]string{"backup_part.zip": "x"})}
+		j.save(outputDir)
+		os.MkdirAll(paths.DataDir(), 0755)
+		os.WriteFile(paths.ManifestsDir(), []byte("in the way"), 0644)
+
+		if _, err := a.RunBackup(context.Background(), RunBackupOptions{Resume: true}, &recordingObserver{}); err != nil {
+			t.Fatalf("expected the backup kept, got %v", err)
+		}
+		if _, err := ReadManifest(outputDir, ""); err != nil {
+			t.Errorf("expected the manifest written: %v", err)
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package aurora
+
+import (
+	"aurora/internal/config"
+	"aurora/internal/logger"
+	"aurora/internal/paths"
+	"aurora/internal/util"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// Schedule modes of automatic backups
+const (
+	ScheduleOff     = "off"     // backups only when asked (default)
+	ScheduleDaily   = "daily"   // every day at Schedule.Time
+	ScheduleWeekly  = "weekly"  // every Schedule.Weekday at Schedule.Time
+	ScheduleStartup = "startup" // on app start when the last backup is older than Schedule.MaxAgeDays
+)
+
+// Defaults of an incomplete schedule
+const (
+	DefaultScheduleTime   = "20:00"
+	DefaultScheduleMaxAge = 7 // days
+)
+
+// Schedule is when the desktop app backs up on its own. Daily and weekly
+// backups run while the app is open; one missed while it was closed runs
+// on the next start.
+type Schedule struct {
+	Mode       string `json:"mode"`       // see ScheduleOff
+	Time       string `json:"time"`       // local "HH:MM", daily and weekly
+	Weekday    int    `json:"weekday"`    // weekly, 0 = Sunday
+	MaxAgeDays int    `json:"maxAgeDays"` // startup
+}
+
+// normalize fills the defaults of s
+func (s Schedule) normalize() Schedule {
+	if s.Mode == "" {
+		s.Mode = ScheduleOff
+	}
+	if s.Time == "" {
+		s.Time = DefaultScheduleTime
+	}
+	if s.MaxAgeDays <= 0 {
+		s.MaxAgeDays = DefaultScheduleMaxAge
+	}
+	return s
+}
+
+// Validate reports a schedule the scheduler can't follow
+func (s Schedule) Validate() error {
+	s = s.normalize()
+	switch s.Mode {
+	case ScheduleOff, ScheduleDaily, ScheduleWeekly, ScheduleStartup:
+	default:
+		return fmt.Errorf("schedule must be %q, %q, %q or %q, got %q", ScheduleOff, ScheduleDaily, ScheduleWeekly, ScheduleStartup, s.Mode)
+	}
+	if _, err := time.Parse("15:04", s.Time); err != nil {
+		return fmt.Errorf("schedule time must be HH:MM, got %q", s.Time)
+	}
+	if s.Weekday < 0 || s.Weekday > 6 {
+		return fmt.Errorf("schedule weekday must be 0 (Sunday) to 6, got %d", s.Weekday)
+	}
+	return nil
+}
+
+// Next returns the first scheduled backup after now; false unless the
+// schedule is daily or weekly
+func (s Schedule) Next(now time.Time) (time.Time, bool) {
+	s = s.normalize()
+	if s.Mode != ScheduleDaily && s.Mode != ScheduleWeekly {
+		return time.Time{}, false
+	}
+	clock, err := time.Parse("15:04", s.Time)
+	if err != nil {
+		return time.Time{}, false
+	}
+	next := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	if s.Mode == ScheduleDaily {
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 1)
+		}
+		return next, true
+	}
+	next = next.AddDate(0, 0, (s.Weekday-int(next.Weekday())+7)%7)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 7)
+	}
+	return next, true
+}
+
+// Due reports whether a backup should run on app start: the last one (zero
+// when there is none) is older than the max age, or missed the last
+// scheduled time
+func (s Schedule) Due(lastBackup, now time.Time) bool {
+	s = s.normalize()
+	switch s.Mode {
+	case ScheduleStartup:
+		return lastBackup.IsZero() || now.Sub(lastBackup) >= time.Duration(s.MaxAgeDays)*24*time.Hour
+	case ScheduleDaily, ScheduleWeekly:
+		next, _ := s.Next(now)
+		previous := next.AddDate(0, 0, -1)
+		if s.Mode == ScheduleWeekly {
+			previous = next.AddDate(0, 0, -7)
+		}
+		return lastBackup.Before(previous)
+	}
+	return false
+}
+
+// GetSchedule returns the automatic backup schedule, defaults filled
+func (a *Aurora) GetSchedule() Schedule {
+	return Schedule(a.Config().Schedule).normalize()
+}
+
+// SetSchedule sets the automatic backup schedule
+func (a *Aurora) SetSchedule(schedule Schedule) error {
+	if err := schedule.Validate(); err != nil {
+		return err
+	}
+	return a.updateConfig(func(cfg *config.Config) {
+		cfg.Schedule = config.ScheduleConfig(schedule.normalize())
+	})
+}
+
+// LastBackupTime returns when the last full backup finished, from the
+// manifests kept in the data directory; false when there is none
+func LastBackupTime() (time.Time, bool) {
+	names, _ := keptManifests()
+	dir := paths.ManifestsDir()
+	for _, name := range slices.Backward(names) {
+		var manifest BackupManifest
+		if err := util.ReadJSONFile(filepath.Join(dir, name), &manifest); err != nil {
+			logger.Warn("Skipping unreadable backup manifest %s: %v", name, err)
+			continue
+		}
+		if !manifest.Incremental {
+			return manifest.FinishedAt, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,101 @@
+package aurora
+
+import (
+	"aurora/internal/paths"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSchedule(t *testing.T) {
+	// Wednesday
+	now := time.Date(2026, 3, 11, 18, 30, 0, 0, time.Local)
+
+	t.Run("next run", func(t *testing.T) {
+		tests := []struct {
+			schedule Schedule
+			want     time.Time
+		}{
+			{Schedule{Mode: ScheduleDaily, Time: "20:00"}, time.Date(2026, 3, 11, 20, 0, 0, 0, time.Local)},
+			{Schedule{Mode: ScheduleDaily, Time: "03:15"}, time.Date(2026, 3, 12, 3, 15, 0, 0, time.Local)},
+			{Schedule{Mode: ScheduleDaily, Time: "18:30"}, time.Date(2026, 3, 12, 18, 30, 0, 0, time.Local)},
+			{Schedule{Mode: ScheduleWeekly, Time: "09:00", Weekday: 0}, time.Date(2026, 3, 15, 9, 0, 0, 0, time.Local)},
+			{Schedule{Mode: ScheduleWeekly, Time: "20:00", Weekday: 3}, time.Date(2026, 3, 11, 20, 0, 0, 0, time.Local)},
+			{Schedule{Mode: ScheduleWeekly, Time: "08:00", Weekday: 3}, time.Date(2026, 3, 18, 8, 0, 0, 0, time.Local)},
+		}
+		for _, tt := range tests {
+			got, ok := tt.schedule.Next(now)
+			if !ok || !got.Equal(tt.want) {
+				t.Errorf("%+v: got %v, want %v", tt.schedule, got, tt.want)
+			}
+		}
+		for _, mode := range []string{"", ScheduleOff, ScheduleStartup} {
+			if _, ok := (Schedule{Mode: mode}).Next(now); ok {
+				t.Errorf("expected no next run for %q", mode)
+			}
+		}
+	})
+
+	t.Run("due on start", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			schedule   Schedule
+			lastBackup time.Time
+			want       bool
+		}{
+			{"off", Schedule{}, time.Time{}, false},
+			{"startup without a backup", Schedule{Mode: ScheduleStartup}, time.Time{}, true},
+			{"startup recent backup", Schedule{Mode: ScheduleStartup}, now.AddDate(0, 0, -6), false},
+			{"startup old backup", Schedule{Mode: ScheduleStartup}, now.AddDate(0, 0, -7), true},
+			{"startup custom age", Schedule{Mode: ScheduleStartup, MaxAgeDays: 2}, now.AddDate(0, 0, -3), true},
+			{"daily done", Schedule{Mode: ScheduleDaily, Time: "03:00"}, now.Add(-time.Hour), false},
+			{"daily missed", Schedule{Mode: ScheduleDaily, Time: "03:00"}, now.AddDate(0, 0, -1), true},
+			{"weekly done", Schedule{Mode: ScheduleWeekly, Time: "09:00", Weekday: 0}, now.AddDate(0, 0, -2), false},
+			{"weekly missed", Schedule{Mode: ScheduleWeekly, Time: "09:00", Weekday: 0}, now.AddDate(0, 0, -4), true},
+		}
+		for _, tt := range tests {
+			if got := tt.schedule.Due(tt.lastBackup, now); got != tt.want {
+				t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		valid := []Schedule{{}, {Mode: ScheduleDaily}, {Mode: ScheduleWeekly, Time: "23:59", Weekday: 6}, {Mode: ScheduleStartup, MaxAgeDays: 30}}
+		for _, schedule := range valid {
+			if err := schedule.Validate(); err != nil {
+				t.Errorf("%+v: unexpected error %v", schedule, err)
+			}
+		}
+		invalid := []Schedule{{Mode: "hourly"}, {Mode: ScheduleDaily, Time: "8pm"}, {Mode: ScheduleWeekly, Weekday: 7}}
+		for _, schedule := range invalid {
+			if err := schedule.Validate(); err == nil {
+				t.Errorf("%+v: expected an error", schedule)
+			}
+		}
+	})
+}
+
+func TestLastBackupTime(t *testing.T) {
+	t.Setenv(paths.EnvHome, t.TempDir())
+
+	if _, ok := LastBackupTime(); ok {
+		t.Error("expected no last backup")
+	}
+
+	older := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	newer := time.Date(2026, 3, 8, 10, 0, 0, 0, time.UTC)
+	for _, finished := range []time.Time{newer, older} {
+		if err := keepManifest(BackupManifest{Version: manifestVersion, FinishedAt: finished}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A damaged newest manifest is skipped
+	os.WriteFile(filepath.Join(paths.ManifestsDir(), "backup-20260309-100000.json"), []byte("{"), 0644)
+
+	got, ok := LastBackupTime()
+	if !ok || !got.Equal(newer) {
+		t.Errorf("got %v, want %v", got, newer)
+	}
+}
@@ -0,0 +1,618 @@
+package aurora
+
+import (
+	"aurora/internal/config"
+	"aurora/internal/logger"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+)
+
+// ErrUploadFailed is returned when a finished backup couldn't be copied to
+// the target; it stays in the output directory
+var ErrUploadFailed = errors.New("upload to the backup target failed")
+
+// Target types: where backups go besides the output directory
+const (
+	TargetLocal = "local" // the output directory only (default)
+	TargetDir   = "dir"   // another directory: a second disk, a mounted NAS...
+	TargetSFTP  = "sftp"
+	TargetS3    = "s3" // S3 or a compatible service (MinIO, Backblaze B2, ...)
+)
+
+// Credentials of remote targets, read from the environment so they never
+// land in config.json
+const (
+	EnvSFTPPassword = "AURORA_SFTP_PASSWORD"
+	EnvS3AccessKey  = "AURORA_S3_ACCESS_KEY"
+	EnvS3SecretKey  = "AURORA_S3_SECRET_KEY"
+)
+
+// Target is where finished backups are uploaded, written as an absolute
+// directory or a URL:
+//
+//	D:\Backups\ff14
+//	sftp://user@host:22/backups/ff14?key=~/.ssh/id_ed25519&knownHosts=~/.ssh/known_hosts
+//	s3://bucket/backups/ff14?endpoint=https://minio.lan:9000&region=us-east-1
+//
+// The empty URL or "local" keeps backups in the output directory only.
+type Target struct {
+	Type       string `json:"type"`
+	Host       string `json:"host"`
+	User       string `json:"user"`
+	KeyFile    string `json:"keyFile"`
+	KnownHosts string `json:"knownHosts"`
+	Endpoint   string `json:"endpoint"`
+	Region     string `json:"region"`
+	Bucket     string `json:"bucket"`
+	Path       string `json:"path"`
+}
+
+// ParseTarget reads a target URL (see Target)
+func ParseTarget(raw string) (Target, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || raw == TargetLocal {
+		return Target{Type: TargetLocal}, nil
+	}
+	if filepath.IsAbs(raw) {
+		return Target{Type: TargetDir, Path: filepath.Clean(raw)}, nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return Target{}, fmt.Errorf("invalid target %q: %w", raw, err)
+	}
+	query := u.Query()
+	target := Target{Path: strings.Trim(u.Path, "/")}
+	switch u.Scheme {
+	case TargetSFTP:
+		target.Type = TargetSFTP
+		target.Host = u.Host
+		target.User = u.User.Username()
+		target.KeyFile = query.Get("key")
+		target.KnownHosts = query.Get("knownHosts")
+		if _, hasPassword := u.User.Password(); hasPassword {
+			return Target{}, fmt.Errorf("invalid target: put the SFTP password in %s, not in the URL", EnvSFTPPassword)
+		}
+	case TargetS3:
+		target.Type = TargetS3
+		target.Bucket = u.Host
+		target.Endpoint = query.Get("endpoint")
+		target.Region = query.Get("region")
+	default:
+		return Target{}, fmt.Errorf("invalid target %q: must be an absolute directory, start with sftp:// or s3://, or be empty", raw)
+	}
+	return target, target.Validate()
+}
+
+// String writes the target as a directory or a URL, which ParseTarget
+// reads back
+func (t Target) String() string {
+	if t.Type == TargetDir {
+		return t.Path
+	}
+	query := url.Values{}
+	u := url.URL{Scheme: t.Type, Path: "/" + t.Path}
+	switch t.Type {
+	case TargetSFTP:
+		u.Host = t.Host
+		if t.User != "" {
+			u.User = url.User(t.User)
+		}
+		if t.KeyFile != "" {
+			query.Set("key", t.KeyFile)
+		}
+		if t.KnownHosts != "" {
+			query.Set("knownHosts", t.KnownHosts)
+		}
+	case TargetS3:
+		u.Host = t.Bucket
+		if t.Endpoint != "" {
+			query.Set("endpoint", t.Endpoint)
+		}
+		if t.Region != "" {
+			query.Set("region", t.Region)
+		}
+	default:
+		return ""
+	}
+	if t.Path == "" {
+		u.Path = ""
+	}
+	// Paths and endpoints stay readable: these need no escaping in a query
+	u.RawQuery = queryUnescaper.Replace(query.Encode())
+	return u.String()
+}
+
+var queryUnescaper = strings.NewReplacer("%2F", "/", "%3A", ":", "%7E", "~")
+
+// IsRemote reports whether backups are copied there, out of the output
+// directory
+func (t Target) IsRemote() bool {
+	return t.Type == TargetDir || t.Type == TargetSFTP || t.Type == TargetS3
+}
+
+// Validate reports a target that can't be opened
+func (t Target) Validate() error {
+	switch t.Type {
+	case "", TargetLocal:
+	case TargetDir:
+		if !filepath.IsAbs(t.Path) {
+			return fmt.Errorf("invalid target: the directory must be absolute, got %q", t.Path)
+		}
+	case TargetSFTP:
+		if t.Host == "" || t.User == "" {
+			return errors.New("invalid target: sftp needs a user and a host, as sftp://user@host/path")
+		}
+	case TargetS3:
+		if t.Bucket == "" {
+			return errors.New("invalid target: s3 needs a bucket, as s3://bucket/prefix")
+		}
+		if t.Endpoint != "" {
+			if u, err := url.Parse(t.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("invalid target: endpoint must be an http(s) URL, got %q", t.Endpoint)
+			}
+		}
+	default:
+		return fmt.Errorf("invalid target type %q", t.Type)
+	}
+	return nil
+}
+
+// StoredFile is a file of a storage
+type StoredFile struct {
+	Name string
+	Size int64
+}
+
+// Storage keeps backup sets: archives and their manifest. Names are
+// slash-separated paths relative to the storage root, like
+// "backup_part.zip" or "incremental/20260301-120000/backup_part.zip".
+type Storage interface {
+	// Put writes name from r (size bytes), replacing it
+	Put(ctx context.Context, name string, r io.Reader, size int64) error
+	// Get opens name; fails with an os.ErrNotExist error when it is missing
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+	// List returns the files directly in dir ("" = the root), sorted
+	List(ctx context.Context, dir string) ([]StoredFile, error)
+	// Dirs returns the names of the folders directly in dir, sorted
+	Dirs(ctx context.Context, dir string) ([]string, error)
+	// Delete removes name, a file or an empty folder; a missing one is not
+	// an error
+	Delete(ctx context.Context, name string) error
+	// Close releases the connection
+	Close() error
+	// String tells where the storage is, for messages
+	String() string
+}
+
+// OpenStorage connects to target. A local target is outputDir.
+func OpenStorage(ctx context.Context, target Target, outputDir string) (Storage, error) {
+	if err := target.Validate(); err != nil {
+		return nil, err
+	}
+	switch target.Type {
+	case TargetDir:
+		// Not created: a missing directory is likely an unplugged disk
+		if info, err := os.Stat(target.Path); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("directory %s is not available", target.Path)
+		}
+		return localStorage{dir: target.Path}, nil
+	case TargetSFTP:
+		return openSFTPStorage(ctx, target)
+	case TargetS3:
+		return openS3Storage(target)
+	default:
+		return localStorage{dir: outputDir}, nil
+	}
+}
+
+// localStorage is a directory
+type localStorage struct{ dir string }
+
+func (s localStorage) path(name string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(name))
+}
+
+func (s localStorage) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	dest := s.path(name)
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	// Written aside then renamed: a failed copy never replaces a good file
+	tmp := dest + ".part"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, contextReader{ctx, r})
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, dest)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+func (s localStorage) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	return os.Open(s.path(name))
+}
+
+func (s localStorage) List(ctx context.Context, dir string) ([]StoredFile, error) {
+	entries, err := os.ReadDir(s.path(dir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var files []StoredFile
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil && info.Mode().IsRegular() {
+			files = append(files, StoredFile{Name: entry.Name(), Size: info.Size()})
+		}
+	}
+	return files, nil
+}
+
+func (s localStorage) Dirs(ctx context.Context, dir string) ([]string, error) {
+	entries, err := os.ReadDir(s.path(dir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, entry.Name())
+		}
+	}
+	return dirs, nil
+}
+
+func (s localStorage) Delete(ctx context.Context, name string) error {
+	if err := os.Remove(s.path(name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s localStorage) Close() error { return nil }
+
+func (s localStorage) String() string { return s.dir }
+
+// contextReader stops a copy once ctx is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// GetTarget returns where backups are uploaded
+func (a *Aurora) GetTarget() Target {
+	target := Target(a.Config().Target)
+	if target.Type == "" {
+		target.Type = TargetLocal
+	}
+	return target
+}
+
+// SetTarget sets where backups are uploaded
+func (a *Aurora) SetTarget(target Target) error {
+	if err := target.Validate(); err != nil {
+		return err
+	}
+	if !target.IsRemote() {
+		target = Target{}
+	}
+	return a.updateConfig(func(cfg *config.Config) {
+		cfg.Target = config.TargetConfig(target)
+	})
+}
+
+// openTarget connects to the configured target
+func (a *Aurora) openTarget(ctx context.Context) (Storage, error) {
+	storage, err := OpenStorage(ctx, a.GetTarget(), a.Config().Output)
+	if err != nil {
+		return nil, fmt.Errorf("open target %s: %w", a.GetTarget(), err)
+	}
+	return storage, nil
+}
+
+// copyBackup copies the backup set in outputDir to target, in the same
+// folder relative to the output directory, and reports how it went
+func (a *Aurora) copyBackup(ctx context.Context, target Target, mirror bool, outputDir string, observer BackupObserver) DestinationResult {
+	cfg := a.Config()
+	dest := DestinationResult{Target: target.String(), Mirror: mirror}
+	storage, err := OpenStorage(ctx, target, cfg.Output)
+	if err != nil {
+		dest.Error = err.Error()
+		return dest
+	}
+	defer storage.Close()
+
+	remoteDir := ""
+	if rel, err := filepath.Rel(cfg.Output, outputDir); err == nil && rel != "." {
+		remoteDir = filepath.ToSlash(rel)
+	}
+	dest.Files, dest.Bytes, err = uploadBackup(ctx, storage, outputDir, remoteDir, observer)
+	if err != nil {
+		logger.Error("Copy to %s failed: %v", storage, err)
+		dest.Error = err.Error()
+		return dest
+	}
+	dest.Verified = true
+	return dest
+}
+
+// backupFiles returns the files of the backup set in dir: its archives,
+// the report forms, then the manifest (plain or encrypted) when there is one
+func backupFiles(dir string) []string {
+	files := ListBackupArchives(dir)
+	for _, name := range append(reportFiles(), ManifestFile, ManifestFile+EncryptedExt) {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			files = append(files, name)
+		}
+	}
+	return files
+}
+
+// isManifestFile reports whether name is a plain or encrypted manifest
+func isManifestFile(name string) bool {
+	return name == ManifestFile || name == ManifestFile+EncryptedExt
+}
+
+// CurrentSetFile, in a remote directory, names the set folder holding its
+// latest complete backup: SetA or SetB
+const CurrentSetFile = "backup_current"
+
+// Remote set folders: a backup goes to the one not in use, so the previous
+// set stays whole until the new one is complete
+const (
+	SetA = "set_a"
+	SetB = "set_b"
+)
+
+// currentSet reads which set folder of remoteDir is in use. "" means the
+// target predates set folders: the set, if any, is in remoteDir itself.
+func currentSet(ctx context.Context, storage Storage, remoteDir string) (string, error) {
+	r, err := storage.Get(ctx, path.Join(remoteDir, CurrentSetFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", CurrentSetFile, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(io.LimitReader(r, 64))
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", CurrentSetFile, err)
+	}
+	switch set := strings.TrimSpace(string(data)); set {
+	case SetA, SetB:
+		return set, nil
+	default:
+		return "", fmt.Errorf("%s names an unknown set %q", CurrentSetFile, set)
+	}
+}
+
+// uploadBackup copies the backup set in outputDir to storage under
+// remoteDir ("" = the root) and returns how many files and bytes it
+// copied. Each file is read back after its upload and must hash like what
+// was sent. The set goes to the set folder not in use, cleared of what an
+// interrupted upload left first; CurrentSetFile is switched to it once all
+// its files are there, and only then is the previous set deleted. A
+// failed or interrupted upload leaves the previous backup as it was.
+func uploadBackup(ctx context.Context, storage Storage, outputDir, remoteDir string, observer BackupObserver) (int, uint64, error) {
+	previous, err := currentSet(ctx, storage, remoteDir)
+	if err != nil {
+		return 0, 0, err
+	}
+	set := SetA
+	if previous == SetA {
+		set = SetB
+	}
+	setDir := path.Join(remoteDir, set)
+	if err := deleteStored(ctx, storage, setDir, func(string) bool { return true }); err != nil {
+		return 0, 0, err
+	}
+
+	files := backupFiles(outputDir)
+	var copied uint64
+	for i, name := range files {
+		size, err := uploadFile(ctx, storage, filepath.Join(outputDir, name), path.Join(setDir, name), observer)
+		if err != nil {
+			return i, copied, fmt.Errorf("upload %s: %w", name, err)
+		}
+		copied += size
+	}
+	if err := storage.Put(ctx, path.Join(remoteDir, CurrentSetFile), strings.NewReader(set), int64(len(set))); err != nil {
+		return len(files), copied, fmt.Errorf("switch to the new set: %w", err)
+	}
+	logger.Info("Uploaded and verified %d files to %s", len(files), storage)
+
+	if previous != "" {
+		if err := deleteStored(ctx, storage, path.Join(remoteDir, previous), func(string) bool { return true }); err != nil {
+			return len(files), copied, err
+		}
+	}
+	// A set uploaded before set folders existed sits in remoteDir itself
+	legacy := func(name string) bool {
+		return isBackupArchive(name) || isManifestFile(name) || isReportFile(name)
+	}
+	if err := deleteStored(ctx, storage, remoteDir, legacy); err != nil {
+		return len(files), copied, err
+	}
+	return len(files), copied, nil
+}
+
+// deleteStored deletes the files in dir of storage that match
+func deleteStored(ctx context.Context, storage Storage, dir string, match func(name string) bool) error {
+	stored, err := storage.List(ctx, dir)
+	if err != nil {
+		return fmt.Errorf("list %s: %w", storage, err)
+	}
+	for _, file := range stored {
+		if !match(file.Name) {
+			continue
+		}
+		logger.Info("Deleting %s of an older backup from %s", path.Join(dir, file.Name), storage)
+		if err := storage.Delete(ctx, path.Join(dir, file.Name)); err != nil {
+			return fmt.Errorf("delete %s: %w", file.Name, err)
+		}
+	}
+	return nil
+}
+
+// uploadFile puts the local file src to name, then reads it back to check
+// the copy's checksum
+func uploadFile(ctx context.Context, storage Storage, src, name string, observer BackupObserver) (uint64, error) {
+	file, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	observer.Notice(fmt.Sprintf("Uploading %s (%s) to %s", path.Base(name), humanize.Bytes(uint64(info.Size())), storage))
+	sent := sha256.New()
+	if err := storage.Put(ctx, name, io.TeeReader(file, sent), info.Size()); err != nil {
+		return 0, err
+	}
+
+	r, err := storage.Get(ctx, name)
+	if err != nil {
+		return 0, fmt.Errorf("read back: %w", err)
+	}
+	defer r.Close()
+	stored := sha256.New()
+	if _, err := io.Copy(stored, contextReader{ctx, r}); err != nil {
+		return 0, fmt.Errorf("read back: %w", err)
+	}
+	if !bytes.Equal(sent.Sum(nil), stored.Sum(nil)) {
+		return 0, errors.New("checksum mismatch: the copy differs from the original")
+	}
+	logger.Debug("Uploaded %s to %s, checksum verified", name, storage)
+	return uint64(info.Size()), nil
+}
+
+// storedSet is the backup set in use in a folder of a storage. Only its
+// manifest is downloaded up front: archives are fetched one at a time.
+type storedSet struct {
+	storage  Storage
+	dir      string   // set folder on the storage
+	archives []string // archives in the set folder
+	tmp      string   // local folder holding the manifest and fetched archives
+}
+
+// openStoredSet reads the backup set in use in remoteDir of storage;
+// Close deletes what it downloaded
+func openStoredSet(ctx context.Context, storage Storage, remoteDir string) (*storedSet, error) {
+	set, err := currentSet(ctx, storage, remoteDir)
+	if err != nil {
+		return nil, err
+	}
+	s := &storedSet{storage: storage, dir: path.Join(remoteDir, set)}
+	stored, err := storage.List(ctx, s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("list %s: %w", storage, err)
+	}
+	s.tmp, err = os.MkdirTemp("", "aurora-target-")
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range stored {
+		switch {
+		case isBackupArchive(file.Name):
+			s.archives = append(s.archives, file.Name)
+		case isManifestFile(file.Name):
+			if err := downloadFile(ctx, storage, path.Join(s.dir, file.Name), filepath.Join(s.tmp, file.Name)); err != nil {
+				s.Close()
+				return nil, fmt.Errorf("download %s: %w", file.Name, err)
+			}
+		}
+	}
+	return s, nil
+}
+
+// fetch downloads an archive of the set to the local folder; its cleanup
+// deletes the copy
+func (s *storedSet) fetch(ctx context.Context) fetchFunc {
+	return func(name string) (string, func(), error) {
+		// Names come from the manifest: keep them in the set folder
+		if path.Base(name) != name || !filepath.IsLocal(name) {
+			return "", nil, fmt.Errorf("invalid archive name %q", name)
+		}
+		local := filepath.Join(s.tmp, name)
+		if err := downloadFile(ctx, s.storage, path.Join(s.dir, name), local); err != nil {
+			os.Remove(local)
+			return "", nil, fmt.Errorf("download %s: %w", name, err)
+		}
+		return local, func() { os.Remove(local) }, nil
+	}
+}
+
+// Close deletes the downloads of the set
+func (s *storedSet) Close() error {
+	return os.RemoveAll(s.tmp)
+}
+
+func downloadFile(ctx context.Context, storage Storage, name, dest string) error {
+	r, err := storage.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	file, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, contextReader{ctx, r})
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// VerifyTarget checks the backup set on the configured target like
+// VerifyBackup. Archives are downloaded and checked one at a time, so the
+// check needs room for the largest only. Fails with ErrNoBackup when the
+// target holds none.
+func (a *Aurora) VerifyTarget(ctx context.Context, passphrase string) (VerifyResult, error) {
+	storage, err := a.openTarget(ctx)
+	if err != nil {
+		return VerifyResult{}, err
+	}
+	defer storage.Close()
+
+	set, err := openStoredSet(ctx, storage, "")
+	if err != nil {
+		return VerifyResult{}, err
+	}
+	defer set.Close()
+	logger.Info("Verifying the backup on %s one archive at a time", storage)
+	return verifySet(set.tmp, set.archives, set.fetch(ctx), passphrase)
+}
@@ -0,0 +1,483 @@
+package aurora
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultS3Region is used when a target names none; S3-compatible
+// services mostly accept any region
+const defaultS3Region = "us-east-1"
+
+// s3UnsignedPayload skips hashing uploads up front: archives are streamed
+// from disk and TLS protects them on the way
+const s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+// Objects bigger than s3PartSize are uploaded in parts: a single PUT
+// takes at most 5 GB. Parts grow past s3PartSize when an object would need
+// more than s3MaxParts of them.
+var s3PartSize int64 = 64 << 20
+
+const s3MaxParts = 10000
+
+// s3Storage is a key prefix in an S3 bucket, signed with AWS Signature
+// Version 4. AWS is addressed per bucket host (bucket.s3.region.amazonaws.com),
+// other endpoints by path (endpoint/bucket/key), as MinIO expects.
+type s3Storage struct {
+	client    *http.Client
+	base      *url.URL // the bucket's URL
+	region    string
+	accessKey string
+	secretKey string
+	prefix    string
+	name      string
+	now       func() time.Time
+}
+
+// openS3Storage reads the keys from AURORA_S3_ACCESS_KEY and
+// AURORA_S3_SECRET_KEY, else the usual AWS variables
+func openS3Storage(target Target) (Storage, error) {
+	accessKey, secretKey := os.Getenv(EnvS3AccessKey), os.Getenv(EnvS3SecretKey)
+	if accessKey == "" && secretKey == "" {
+		accessKey, secretKey = os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	if accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("s3 needs %s and %s", EnvS3AccessKey, EnvS3SecretKey)
+	}
+	region := target.Region
+	if region == "" {
+		region = defaultS3Region
+	}
+
+	var base *url.URL
+	if target.Endpoint != "" {
+		endpoint, err := url.Parse(target.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		base = endpoint.JoinPath(target.Bucket)
+	} else {
+		base = &url.URL{Scheme: "https", Host: fmt.Sprintf("%s.s3.%s.amazonaws.com", target.Bucket, region)}
+	}
+	return &s3Storage{
+		client:    &http.Client{},
+		base:      base,
+		region:    region,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		prefix:    target.Path,
+		name:      target.String(),
+		now:       time.Now,
+	}, nil
+}
+
+func (s *s3Storage) key(name string) string {
+	if s.prefix == "" {
+		return name
+	}
+	return path.Join(s.prefix, name)
+}
+
+// objectURL returns the URL of key in the bucket
+func (s *s3Storage) objectURL(key string) *url.URL {
+	u := *s.base
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	// Sent exactly as signed
+	u.RawPath = s3EscapePath(u.Path)
+	return &u
+}
+
+func (s *s3Storage) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	if size > s3PartSize {
+		return s.putMultipart(ctx, s.key(name), r, size)
+	}
+	resp, err := s.put(ctx, s.objectURL(s.key(name)), r, size)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// put sends size bytes of r to u
+func (s *s3Storage) put(ctx context.Context, u *url.URL, r io.Reader, size int64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), r)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	return s.do(req, s3UnsignedPayload)
+}
+
+// s3CompletedPart is a part of a multipart upload, as completing it lists
+type s3CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// putMultipart uploads r to key in parts of s3PartSize or more, one after
+// the other. The object only appears once every part is in; a failed upload
+// is aborted so its parts don't linger in the bucket.
+func (s *s3Storage) putMultipart(ctx context.Context, key string, r io.Reader, size int64) error {
+	partSize := max(s3PartSize, (size+s3MaxParts-1)/s3MaxParts)
+	u := s.objectURL(key)
+	u.RawQuery = "uploads="
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := s.postXML(ctx, u, nil, &initiated); err != nil {
+		return fmt.Errorf("start multipart upload: %w", err)
+	}
+
+	var parts []s3CompletedPart
+	err := func() error {
+		for offset := int64(0); offset < size; offset += partSize {
+			number := len(parts) + 1
+			n := min(partSize, size-offset)
+			u := s.objectURL(key)
+			u.RawQuery = url.Values{"partNumber": {fmt.Sprint(number)}, "uploadId": {initiated.UploadID}}.Encode()
+			resp, err := s.put(ctx, u, io.LimitReader(r, n), n)
+			if err != nil {
+				return fmt.Errorf("upload part %d: %w", number, err)
+			}
+			resp.Body.Close()
+			parts = append(parts, s3CompletedPart{PartNumber: number, ETag: resp.Header.Get("ETag")})
+		}
+		body, err := xml.Marshal(struct {
+			XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+			Parts   []s3CompletedPart `xml:"Part"`
+		}{Parts: parts})
+		if err != nil {
+			return err
+		}
+		u := s.objectURL(key)
+		u.RawQuery = url.Values{"uploadId": {initiated.UploadID}}.Encode()
+		if err := s.postXML(ctx, u, body, nil); err != nil {
+			return fmt.Errorf("complete multipart upload: %w", err)
+		}
+		return nil
+	}()
+	if err != nil {
+		// Not ctx: a cancelled upload is aborted too
+		u := s.objectURL(key)
+		u.RawQuery = url.Values{"uploadId": {initiated.UploadID}}.Encode()
+		if req, reqErr := http.NewRequestWithContext(context.WithoutCancel(ctx), http.MethodDelete, u.String(), nil); reqErr == nil {
+			if resp, abortErr := s.do(req, emptySHA256); abortErr == nil {
+				resp.Body.Close()
+			}
+		}
+	}
+	return err
+}
+
+// postXML posts body to u and decodes the XML answer into v (may be nil).
+// S3 can answer 200 with an error in the body: it is returned as *s3Error.
+func (s *s3Storage) postXML(ctx context.Context, u *url.URL, body []byte, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req, sha256Hex(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	var answer struct{ XMLName xml.Name }
+	if xml.Unmarshal(data, &answer) == nil && answer.XMLName.Local == "Error" {
+		s3Err := &s3Error{Status: resp.StatusCode}
+		xml.Unmarshal(data, s3Err)
+		return s3Err
+	}
+	if v == nil {
+		return nil
+	}
+	return xml.Unmarshal(data, v)
+}
+
+func (s *s3Storage) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(s.key(name)).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req, emptySHA256)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// s3ListResult is a ListObjectsV2 response page
+type s3ListResult struct {
+	Contents []struct {
+		Key  string `xml:"Key"`
+		Size int64  `xml:"Size"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (s *s3Storage) List(ctx context.Context, dir string) ([]StoredFile, error) {
+	prefix, pages, err := s.list(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []StoredFile
+	for _, page := range pages {
+		for _, object := range page.Contents {
+			if name := strings.TrimPrefix(object.Key, prefix); name != "" && !strings.Contains(name, "/") {
+				files = append(files, StoredFile{Name: name, Size: object.Size})
+			}
+		}
+	}
+	slices.SortFunc(files, func(a, b StoredFile) int { return strings.Compare(a.Name, b.Name) })
+	return files, nil
+}
+
+// Dirs returns the key prefixes one level under dir: S3 has no folders,
+// only keys holding slashes
+func (s *s3Storage) Dirs(ctx context.Context, dir string) ([]string, error) {
+	prefix, pages, err := s.list(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, page := range pages {
+		for _, common := range page.CommonPrefixes {
+			if name := strings.TrimSuffix(strings.TrimPrefix(common.Prefix, prefix), "/"); name != "" && !strings.Contains(name, "/") {
+				dirs = append(dirs, name)
+			}
+		}
+	}
+	slices.Sort(dirs)
+	return dirs, nil
+}
+
+// list reads every page of the listing of dir, one level deep, and returns
+// them with the key prefix of dir
+func (s *s3Storage) list(ctx context.Context, dir string) (string, []s3ListResult, error) {
+	prefix := s.key(dir)
+	if prefix != "" {
+		prefix += "/"
+	}
+	var pages []s3ListResult
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}, "delimiter": {"/"}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		u := s.objectURL("")
+		u.RawQuery = query.Encode()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return "", nil, err
+		}
+		resp, err := s.do(req, emptySHA256)
+		if err != nil {
+			return "", nil, err
+		}
+		var page s3ListResult
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return "", nil, fmt.Errorf("read bucket listing: %w", err)
+		}
+		pages = append(pages, page)
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return prefix, pages, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// Delete removes name; S3 answers success for missing keys too, and a
+// folder is gone with its last key
+func (s *s3Storage) Delete(ctx context.Context, name string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(s.key(name)).String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req, emptySHA256)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *s3Storage) Close() error { return nil }
+
+func (s *s3Storage) String() string { return s.name }
+
+// s3Error is an error response of S3
+type s3Error struct {
+	Status  int    `xml:"-"`
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func (e *s3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("s3: HTTP %d", e.Status)
+	}
+	return fmt.Sprintf("s3: %s: %s", e.Code, e.Message)
+}
+
+// Is makes a missing key match os.ErrNotExist; a missing bucket doesn't
+func (e *s3Error) Is(target error) bool {
+	return target == os.ErrNotExist && (e.Code == "NoSuchKey" || e.Code == "" && e.Status == http.StatusNotFound)
+}
+
+// do signs and sends req, turning error responses into *s3Error
+func (s *s3Storage) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	signS3Request(req, payloadHash, s.accessKey, s.secretKey, s.region, s.now())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	s3Err := &s3Error{Status: resp.StatusCode}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	xml.Unmarshal(body, s3Err)
+	return nil, s3Err
+}
+
+// emptySHA256 is the SHA-256 of an empty body
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// signS3Request adds the AWS Signature Version 4 headers to req. The host,
+// Range and every x-amz-* header are signed.
+func signS3Request(req *http.Request, payloadHash, accessKey, secretKey, region string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") || lower == "range" {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3EscapePath(req.URL.Path),
+		s3CanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+// s3EscapePath encodes a path as SigV4 wants it: every byte but the
+// unreserved ones and slashes
+func s3EscapePath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	var b strings.Builder
+	for _, c := range []byte(p) {
+		if c == '/' || isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// s3CanonicalQuery sorts and encodes query parameters as SigV4 wants them
+func s3CanonicalQuery(query url.Values) string {
+	var pairs []string
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, s3EscapeQuery(name)+"="+s3EscapeQuery(value))
+		}
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// s3EscapeQuery encodes a query name or value as SigV4 wants it: every
+// byte but the unreserved ones, slashes included. Empty stays empty, as in
+// "uploads=".
+func s3EscapeQuery(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func isUnreserved(c byte) bool {
+	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~'
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,246 @@
+package aurora
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sftpReadBuffer is how much a Get reads at once: pkg/sftp splits a read
+// into concurrent requests, so big reads aren't bound by the round trip
+const sftpReadBuffer = 1 << 20
+
+// posixRename is the OpenSSH extension renaming over an existing file
+const posixRename = "posix-rename@openssh.com"
+
+// sftpStorage is a directory on an SFTP server, relative to the login
+// directory unless the target path starts with "/"
+type sftpStorage struct {
+	client *sftp.Client
+	closer func() error
+	root   string
+	name   string
+}
+
+// openSFTPStorage logs in with the target's key file, or the password in
+// AURORA_SFTP_PASSWORD. The host key must be in known_hosts. ctx bounds
+// the connection and the SSH handshake.
+func openSFTPStorage(ctx context.Context, target Target) (Storage, error) {
+	var auth ssh.AuthMethod
+	switch password := os.Getenv(EnvSFTPPassword); {
+	case target.KeyFile != "":
+		key, err := os.ReadFile(expandHome(target.KeyFile))
+		if err != nil {
+			return nil, fmt.Errorf("read key file: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("parse key file %s: %w", target.KeyFile, err)
+		}
+		auth = ssh.PublicKeys(signer)
+	case password != "":
+		auth = ssh.Password(password)
+	default:
+		return nil, fmt.Errorf("sftp needs a key file (?key=) or %s", EnvSFTPPassword)
+	}
+
+	knownHostsFile := target.KnownHosts
+	if knownHostsFile == "" {
+		knownHostsFile = filepath.Join("~", ".ssh", "known_hosts")
+	}
+	hostKeys, err := knownhosts.New(expandHome(knownHostsFile))
+	if err != nil {
+		return nil, fmt.Errorf("read known hosts: %w", err)
+	}
+
+	addr := target.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "22")
+	}
+	var dialer net.Dialer
+	netConn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	// The handshake and the SFTP init don't take a context: closing the
+	// connection is what stops them
+	stop := context.AfterFunc(ctx, func() { netConn.Close() })
+	sshConn, chans, reqs, err := ssh.NewClientConn(netConn, addr, &ssh.ClientConfig{
+		User:            target.User,
+		Auth:            []ssh.AuthMethod{auth},
+		HostKeyCallback: hostKeys,
+	})
+	if err != nil {
+		stop()
+		netConn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	sshClient := ssh.NewClient(sshConn, chans, reqs)
+	client, err := sftp.NewClient(sshClient, sftp.UseConcurrentWrites(true))
+	if !stop() && err == nil {
+		client.Close()
+		err = ctx.Err()
+	}
+	if err != nil {
+		sshClient.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	return &sftpStorage{
+		client: client,
+		closer: func() error {
+			client.Close()
+			return sshClient.Close()
+		},
+		root: target.Path,
+		name: target.String(),
+	}, nil
+}
+
+func (s *sftpStorage) path(name string) string {
+	if s.root == "" {
+		return name
+	}
+	return path.Join(s.root, name)
+}
+
+// Put writes with concurrent requests to a file aside, then renames it
+// over name: a failed upload never replaces a good file
+func (s *sftpStorage) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	dest := s.path(name)
+	if err := s.client.MkdirAll(path.Dir(dest)); err != nil {
+		return err
+	}
+	tmp := dest + ".part"
+	file, err := s.client.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+	_, err = file.ReadFromWithConcurrency(contextReader{ctx, r}, 0)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = s.replace(tmp, dest)
+	}
+	if err != nil {
+		s.client.Remove(tmp)
+	}
+	return err
+}
+
+// replace renames tmp over dest. Without the OpenSSH extension, renames
+// fail over an existing file: dest is moved aside first and put back if
+// the rename fails.
+func (s *sftpStorage) replace(tmp, dest string) error {
+	if _, ok := s.client.HasExtension(posixRename); ok {
+		return s.client.PosixRename(tmp, dest)
+	}
+	old := dest + ".old"
+	s.client.Remove(old)
+	if err := s.client.Rename(dest, old); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := s.client.Rename(tmp, dest); err != nil {
+		s.client.Rename(old, dest)
+		return err
+	}
+	s.client.Remove(old)
+	return nil
+}
+
+func (s *sftpStorage) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	file, err := s.client.Open(s.path(name))
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{bufio.NewReaderSize(file, sftpReadBuffer), file}, nil
+}
+
+func (s *sftpStorage) List(ctx context.Context, dir string) ([]StoredFile, error) {
+	p := s.path(dir)
+	if p == "" {
+		p = "."
+	}
+	entries, err := s.client.ReadDirContext(ctx, p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var files []StoredFile
+	for _, entry := range entries {
+		if entry.Mode().IsRegular() {
+			files = append(files, StoredFile{Name: entry.Name(), Size: entry.Size()})
+		}
+	}
+	slices.SortFunc(files, func(a, b StoredFile) int { return strings.Compare(a.Name, b.Name) })
+	return files, nil
+}
+
+func (s *sftpStorage) Dirs(ctx context.Context, dir string) ([]string, error) {
+	p := s.path(dir)
+	if p == "" {
+		p = "."
+	}
+	entries, err := s.client.ReadDirContext(ctx, p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, entry.Name())
+		}
+	}
+	slices.Sort(dirs)
+	return dirs, nil
+}
+
+func (s *sftpStorage) Delete(ctx context.Context, name string) error {
+	if err := s.client.Remove(s.path(name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *sftpStorage) Close() error { return s.closer() }
+
+func (s *sftpStorage) String() string { return s.name }
+
+// expandHome replaces a leading ~ with the user's home directory
+func expandHome(p string) string {
+	rest, ok := strings.CutPrefix(p, "~")
+	if !ok || (rest != "" && rest[0] != '/' && rest[0] != filepath.Separator) {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return p
+	}
+	return filepath.Join(home, rest)
+}
@@ -0,0 +1,269 @@
+package aurora
+
+import (
+	"aurora/internal/paths"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// serveSFTP serves root over rw with pkg/sftp's server, until rw is closed
+func serveSFTP(rw io.ReadWriteCloser, root string) {
+	server, err := sftp.NewServer(rw, sftp.WithServerWorkingDirectory(root))
+	if err != nil {
+		rw.Close()
+		return
+	}
+	server.Serve()
+	server.Close()
+}
+
+// newTestSFTPStorage returns an sftpStorage under "backups" of a served
+// root directory, over an in-memory pipe
+func newTestSFTPStorage(t *testing.T) (*sftpStorage, string) {
+	t.Helper()
+	root := t.TempDir()
+	conn, server := net.Pipe()
+	go serveSFTP(server, root)
+	client, err := sftp.NewClientPipe(conn, conn, sftp.UseConcurrentWrites(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage := &sftpStorage{client: client, closer: client.Close, root: "backups", name: "sftp://test/backups"}
+	t.Cleanup(func() { storage.Close() })
+	return storage, root
+}
+
+func TestSFTPStorage(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("puts, gets, lists and deletes files", func(t *testing.T) {
+		storage, root := newTestSFTPStorage(t)
+		content := strings.Repeat("archive data ", 10000) // several chunks
+		if err := storage.Put(ctx, "incremental/1/backup_part.zip", strings.NewReader(content), int64(len(content))); err != nil {
+			t.Fatal(err)
+		}
+		// Replacing a file renames the new one over it
+		if err := storage.Put(ctx, "incremental/1/backup_part.zip", strings.NewReader(content), int64(len(content))); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := os.ReadFile(filepath.Join(root, "backups", "incremental", "1", "backup_part.zip")); string(got) != content {
+			t.Errorf("expected the file on the server, got %d bytes", len(got))
+		}
+
+		r, err := storage.Get(ctx, "incremental/1/backup_part.zip")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(r)
+		r.Close()
+		if err != nil || string(got) != content {
+			t.Errorf("expected the content back, got %d bytes, %v", len(got), err)
+		}
+
+		files, err := storage.List(ctx, "incremental/1")
+		if err != nil || len(files) != 1 || files[0] != (StoredFile{Name: "backup_part.zip", Size: int64(len(content))}) {
+			t.Errorf("unexpected listing %+v, %v", files, err)
+		}
+		// Directories are not files
+		if files, _ := storage.List(ctx, "incremental"); len(files) != 0 {
+			t.Errorf("expected no files, got %+v", files)
+		}
+		if files, err := storage.List(ctx, "missing"); err != nil || len(files) != 0 {
+			t.Errorf("expected an empty listing for a missing directory, got %+v, %v", files, err)
+		}
+		if dirs, err := storage.Dirs(ctx, "incremental"); err != nil || !slices.Equal(dirs, []string{"1"}) {
+			t.Errorf("unexpected folders %v, %v", dirs, err)
+		}
+
+		if err := storage.Delete(ctx, "incremental/1/backup_part.zip"); err != nil {
+			t.Fatal(err)
+		}
+		if err := storage.Delete(ctx, "incremental/1/backup_part.zip"); err != nil {
+			t.Errorf("expected deleting a missing file to succeed, got %v", err)
+		}
+		if _, err := storage.Get(ctx, "incremental/1/backup_part.zip"); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected os.ErrNotExist, got %v", err)
+		}
+		if err := storage.Delete(ctx, "incremental/1"); err != nil {
+			t.Fatal(err)
+		}
+		if dirs, err := storage.Dirs(ctx, "incremental"); err != nil || len(dirs) != 0 {
+			t.Errorf("expected the empty folder deleted, got %v, %v", dirs, err)
+		}
+	})
+
+	t.Run("round trips a backup set", func(t *testing.T) {
+		t.Setenv(paths.EnvHome, t.TempDir())
+		storage, _ := newTestSFTPStorage(t)
+		outputDir := t.TempDir()
+		writeBackupSet(t, outputDir, "")
+		if _, _, err := uploadBackup(ctx, storage, outputDir, "", &recordingObserver{}); err != nil {
+			t.Fatal(err)
+		}
+		set, err := openStoredSet(ctx, storage, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer set.Close()
+		result, err := verifySet(set.tmp, set.archives, set.fetch(ctx), "")
+		if err != nil || !result.Valid || len(result.Archives) != 2 {
+			t.Errorf("expected a valid stored set, got %+v, %v", result, err)
+		}
+	})
+}
+
+// startTestSSHServer serves SFTP over SSH for root on a local port to a
+// client logging in with password, and returns its address and a
+// known_hosts file for it
+func startTestSSHServer(t *testing.T, root, password string) (string, string) {
+	t.Helper()
+	_, hostKey, _ := ed25519.GenerateKey(rand.Reader)
+	signer, err := ssh.NewSignerFromKey(hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(meta ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if meta.User() == "me" && string(pass) == password {
+				return nil, nil
+			}
+			return nil, errors.New("wrong password")
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			netConn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer netConn.Close()
+				_, chans, reqs, err := ssh.NewServerConn(netConn, config)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for newChannel := range chans {
+					if newChannel.ChannelType() != "session" {
+						newChannel.Reject(ssh.UnknownChannelType, "")
+						continue
+					}
+					channel, requests, err := newChannel.Accept()
+					if err != nil {
+						return
+					}
+					go func() {
+						for req := range requests {
+							subsystem := req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp"
+							req.Reply(subsystem, nil)
+							if subsystem {
+								go serveSFTP(channel, root)
+							}
+						}
+					}()
+				}
+			}()
+		}
+	}()
+
+	addr := listener.Addr().String()
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	os.WriteFile(knownHosts, []byte(knownhosts.Line([]string{knownhosts.Normalize(addr)}, signer.PublicKey())+"\n"), 0600)
+	return addr, knownHosts
+}
+
+func TestOpenSFTPStorage(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	addr, knownHosts := startTestSSHServer(t, root, "secret")
+	target := Target{Type: TargetSFTP, Host: addr, User: "me", KnownHosts: knownHosts, Path: "ff14"}
+
+	t.Run("logs in with the password from the environment", func(t *testing.T) {
+		t.Setenv(EnvSFTPPassword, "secret")
+		storage, err := OpenStorage(ctx, target, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer storage.Close()
+		if err := storage.Put(ctx, "backup_part.zip", strings.NewReader("data"), 4); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := os.ReadFile(filepath.Join(root, "ff14", "backup_part.zip")); string(got) != "data" {
+			t.Errorf("expected the file on the server, got %q", got)
+		}
+		if storage.String() != target.String() {
+			t.Errorf("expected %q, got %q", target.String(), storage.String())
+		}
+	})
+
+	t.Run("needs credentials", func(t *testing.T) {
+		t.Setenv(EnvSFTPPassword, "")
+		if _, err := OpenStorage(ctx, target, ""); err == nil || !strings.Contains(err.Error(), EnvSFTPPassword) {
+			t.Errorf("expected a missing credentials error, got %v", err)
+		}
+	})
+
+	t.Run("rejects a wrong password", func(t *testing.T) {
+		t.Setenv(EnvSFTPPassword, "wrong")
+		if _, err := OpenStorage(ctx, target, ""); err == nil {
+			t.Error("expected the login to fail")
+		}
+	})
+
+	t.Run("gives up when ctx ends during the handshake", func(t *testing.T) {
+		t.Setenv(EnvSFTPPassword, "secret")
+		// Accepts connections but never speaks SSH
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+			}
+		}()
+		silent := target
+		silent.Host = listener.Addr().String()
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		if _, err := OpenStorage(ctx, silent, ""); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the deadline error, got %v", err)
+		}
+	})
+
+	t.Run("rejects an unknown host key", func(t *testing.T) {
+		t.Setenv(EnvSFTPPassword, "secret")
+		unknown := target
+		unknown.KnownHosts = filepath.Join(t.TempDir(), "known_hosts")
+		os.WriteFile(unknown.KnownHosts, nil, 0600)
+		if _, err := OpenStorage(ctx, unknown, ""); err == nil {
+			t.Error("expected the host key to be rejected")
+		}
+	})
+}
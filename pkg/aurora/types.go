@@ -1,15 +1,32 @@
 package aurora
 
+import "time"
+
 // ConfigResult represents the current configuration state
 type ConfigResult struct {
-	PenumbraPath string       `json:"penumbraPath"`
-	ModsPath     string       `json:"modsPath"`
-	OutputPath   string       `json:"outputPath"`
-	Filters      []string     `json:"filters"`
-	Inclusions   []string     `json:"inclusions"`
-	Concurrency  int          `json:"concurrency"`
-	Compression  string       `json:"compression"`
-	Status       ConfigStatus `json:"status"`
+	PenumbraPath    string       `json:"penumbraPath"`
+	ModsPath        string       `json:"modsPath"`
+	OutputPath      string       `json:"outputPath"`
+	Filters         []string     `json:"filters"`
+	Inclusions      []string     `json:"inclusions"`
+	Concurrency     int          `json:"concurrency"`
+	Compression     string       `json:"compression"`
+	Format          string       `json:"format"`      // see FormatZip
+	Layout          string       `json:"layout"`      // see LayoutSingle
+	MaxPartSize     uint64       `json:"maxPartSize"` // bytes, 0 = no limit
+	Encrypt         bool         `json:"encrypt"`
+	KeyFile         string       `json:"keyFile"`         // "" = AURORA_PASSPHRASE or a prompt
+	PassphraseInEnv bool         `json:"passphraseInEnv"` // AURORA_PASSPHRASE is set
+	PreBackupHook   string       `json:"preBackupHook"`   // see HookVars
+	PostBackupHook  string       `json:"postBackupHook"`
+	HookTimeout     int          `json:"hookTimeout"` // seconds, 0 = DefaultHookTimeout
+	Schedule        Schedule     `json:"schedule"`
+	Target          string       `json:"target"`     // target URL, "" = the output directory only
+	Mirrors         []string     `json:"mirrors"`    // mirror URLs
+	Retention       Retention    `json:"retention"`  // incremental backups kept
+	Profile         string       `json:"profile"`    // --profile or AURORA_PROFILE, "" = none
+	ConfigFile      string       `json:"configFile"` // Where the config is stored (see --config, AURORA_CONFIG, AURORA_HOME)
+	Status          ConfigStatus `json:"status"`
 }
 
 // ConfigStatus represents validation status of paths
@@ -63,32 +80,93 @@ type BackupItem struct {
 	IsIncluded bool   `json:"isIncluded"`
 }
 
+// BackupWarning is a mod left out of the backup, or a path of a mod that
+// couldn't be read (Path != "")
+type BackupWarning struct {
+	Mod     string `json:"mod"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
 // BackupValidation represents the backup preview
 type BackupValidation struct {
-	Items               []BackupItem `json:"items"`
-	TotalSize           uint64       `json:"totalSize"`
-	TotalSizeHuman      string       `json:"totalSizeHuman"`
-	EstimatedSize       uint64       `json:"estimatedSize"`
-	EstimatedSizeHuman  string       `json:"estimatedSizeHuman"`
-	AvailableSpace      uint64       `json:"availableSpace"`
-	AvailableSpaceHuman string       `json:"availableSpaceHuman"`
-	HasEnoughSpace      bool         `json:"hasEnoughSpace"`
+	Items               []BackupItem    `json:"items"`
+	Warnings            []BackupWarning `json:"warnings"`
+	TotalSize           uint64          `json:"totalSize"`
+	TotalSizeHuman      string          `json:"totalSizeHuman"`
+	EstimatedSize       uint64          `json:"estimatedSize"`
+	EstimatedSizeHuman  string          `json:"estimatedSizeHuman"`
+	EstimatedLow        uint64          `json:"estimatedLow"`  // confidence range of the estimate
+	EstimatedHigh       uint64          `json:"estimatedHigh"` // (disk space is checked against it)
+	EstimatedRangeHuman string          `json:"estimatedRangeHuman"`
+	EstimateSamples     int             `json:"estimateSamples"` // files compressed to measure ratios
+	EstimateBackups     int             `json:"estimateBackups"` // past backups calibrating the estimate
+	AvailableSpace      uint64          `json:"availableSpace"`
+	AvailableSpaceHuman string          `json:"availableSpaceHuman"`
+	HasEnoughSpace      bool            `json:"hasEnoughSpace"`
+
+	rawEstimate uint64 // uncalibrated, recorded in the manifest
 }
 
-// BackupProgress represents backup progress updates
+// BackupProgress represents backup progress updates. Byte counts cover the
+// mods this run compresses (a resumed run excludes finished batches); mod
+// counts cover the whole backup.
 type BackupProgress struct {
-	Percent float64 `json:"percent"`
-	Current string  `json:"current"`
-	Done    bool    `json:"done"`
-	Error   string  `json:"error,omitempty"`
+	Percent         float64        `json:"percent"`
+	Current         string         `json:"current"`
+	Done            bool           `json:"done"`
+	Error           string         `json:"error,omitempty"`
+	BytesDone       uint64         `json:"bytesDone"`
+	BytesTotal      uint64         `json:"bytesTotal"`
+	FilesDone       int64          `json:"filesDone"`
+	FilesTotal      int64          `json:"filesTotal"`
+	ModsDone        int            `json:"modsDone"`
+	ModsTotal       int            `json:"modsTotal"`
+	CompressedBytes uint64         `json:"compressedBytes"` // archive bytes written so far
+	BytesPerSec     float64        `json:"bytesPerSec"`
+	ElapsedSeconds  int64          `json:"elapsedSeconds"`
+	ETASeconds      int64          `json:"etaSeconds"` // 0 until the rate is known
+	Workers         []WorkerStatus `json:"workers"`    // files being compressed, oldest first
+}
+
+// WorkerStatus is the file a compression worker is on
+type WorkerStatus struct {
+	Mod        string `json:"mod"`
+	File       string `json:"file"` // path inside the mod folder
+	BytesDone  uint64 `json:"bytesDone"`
+	BytesTotal uint64 `json:"bytesTotal"`
 }
 
 // BackupResult represents the backup operation result
 type BackupResult struct {
 	OutputPath     string `json:"outputPath"`
+	OutputDir      string `json:"outputDir"` // where the archives are, an incremental backup's own directory
 	OriginalSize   uint64 `json:"originalSize"`
 	CompressedSize uint64 `json:"compressedSize"`
 	Ratio          string `json:"ratio"`
+	HookError      string `json:"hookError,omitempty"` // the post-backup hook failed; the backup itself is fine
+	Target         string `json:"target,omitempty"`    // the remote target the backup was uploaded to
+	// Destinations reports each copy: the target first, then the mirrors
+	Destinations []DestinationResult `json:"destinations,omitempty"`
+}
+
+// DestinationResult is how copying a backup to a target or a mirror went
+type DestinationResult struct {
+	Target   string `json:"target"` // URL or directory
+	Mirror   bool   `json:"mirror"`
+	Files    int    `json:"files"` // files copied
+	Bytes    uint64 `json:"bytes"`
+	Verified bool   `json:"verified"`        // every file was copied and read back with its checksum
+	Error    string `json:"error,omitempty"` // why the copy failed
+}
+
+// PendingBackup describes an interrupted backup that can be resumed
+type PendingBackup struct {
+	StartedAt  time.Time `json:"startedAt"`
+	DoneMods   int       `json:"doneMods"` // mods in finished batches
+	TotalMods  int       `json:"totalMods"`
+	Encrypted  bool      `json:"encrypted"`  // resuming needs the same passphrase
+	Finalizing bool      `json:"finalizing"` // parts were being moved into place: only resuming finishes it
 }
 
 // FilterMatches reports per-pattern mod match counts for the config filters.
@@ -100,3 +178,34 @@ type FilterMatches struct {
 	Inclusions    map[string]int `json:"inclusions"`
 	InclusionsAny map[string]int `json:"inclusionsAny"`
 }
+
+// VerifyResult reports the check of a backup set
+type VerifyResult struct {
+	Valid         bool           `json:"valid"`
+	Manifest      bool           `json:"manifest"`                // archives were checked against the manifest checksums
+	ManifestError string         `json:"manifestError,omitempty"` // why the manifest couldn't be used
+	Archives      []ArchiveCheck `json:"archives"`
+}
+
+// ArchiveCheck is the check of one archive of a backup set
+type ArchiveCheck struct {
+	Name           string `json:"name"`
+	Format         string `json:"format"` // see FormatZip
+	Size           int64  `json:"size"`
+	Encrypted      bool   `json:"encrypted"`
+	ContentChecked bool   `json:"contentChecked"`  // false for go-delta native archives: checksum and decryption only
+	Files          int    `json:"files"`           // files read back intact
+	Error          string `json:"error,omitempty"` // "" = intact
+}
+
+// DecryptResult lists the plain copies DecryptBackup wrote
+type DecryptResult struct {
+	Dir   string          `json:"dir"` // where the copies are
+	Files []DecryptedFile `json:"files"`
+}
+
+// DecryptedFile is one plain copy of an encrypted file of a backup set
+type DecryptedFile struct {
+	Name string `json:"name"` // without EncryptedExt
+	Size int64  `json:"size"`
+}
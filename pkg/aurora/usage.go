@@ -0,0 +1,192 @@
+package aurora
+
+import (
+	"aurora/internal/logger"
+	"aurora/internal/repository"
+	"cmp"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+)
+
+// DiskUsageResult breaks the disk space of the mods folder down by
+// collection, selector folder, file type and author. Every list is
+// heaviest first.
+type DiskUsageResult struct {
+	Mods            int               `json:"mods"`
+	TotalSize       uint64            `json:"totalSize"`
+	TotalSizeHuman  string            `json:"totalSizeHuman"`
+	UnusedSize      uint64            `json:"unusedSize"` // mods no collection uses
+	UnusedSizeHuman string            `json:"unusedSizeHuman"`
+	Collections     []CollectionUsage `json:"collections"`
+	Folders         []UsageGroup      `json:"folders"` // Name "" = the root of the mod selector
+	Types           []UsageGroup      `json:"types"`   // Name is the lower case extension with the dot, "" for none
+	Authors         []UsageGroup      `json:"authors"` // Name "" = unknown
+	LargestMods     []ModUsage        `json:"largestMods"`
+}
+
+// CollectionUsage is the space of a collection's mods. Exclusive mods are
+// used by no other collection: removing the collection could free them.
+// Shared mods are also used by other collections.
+type CollectionUsage struct {
+	Name               string `json:"name"`
+	Mods               int    `json:"mods"`
+	Size               uint64 `json:"size"`
+	SizeHuman          string `json:"sizeHuman"`
+	ExclusiveMods      int    `json:"exclusiveMods"`
+	ExclusiveSize      uint64 `json:"exclusiveSize"`
+	ExclusiveSizeHuman string `json:"exclusiveSizeHuman"`
+	SharedMods         int    `json:"sharedMods"`
+	SharedSize         uint64 `json:"sharedSize"`
+	SharedSizeHuman    string `json:"sharedSizeHuman"`
+}
+
+// UsageGroup is the space of the mods, or the files, sharing a property
+type UsageGroup struct {
+	Name      string `json:"name"`
+	Mods      int    `json:"mods"`
+	Files     int    `json:"files"`
+	Size      uint64 `json:"size"`
+	SizeHuman string `json:"sizeHuman"`
+}
+
+// ModUsage is the space of one mod
+type ModUsage struct {
+	Name        string `json:"name"`
+	Folder      string `json:"folder"`
+	Author      string `json:"author"`
+	Collections int    `json:"collections"` // using it
+	Files       int    `json:"files"`
+	Size        uint64 `json:"size"`
+	SizeHuman   string `json:"sizeHuman"`
+}
+
+// DiskUsage walks every mod of the mods folder and groups its space.
+// Empty mods are left out, like in GetCollections. Fails with
+// ErrInvalidConfig.
+func (a *Aurora) DiskUsage() (DiskUsageResult, error) {
+	cfg := a.Config()
+	if !cfg.Status().Valid {
+		return DiskUsageResult{}, ErrInvalidConfig
+	}
+	repo, err := repository.NewPenumbraRepositoryNoSizes(cfg)
+	if err != nil {
+		return DiskUsageResult{}, err
+	}
+
+	result := DiskUsageResult{}
+	folders := usageGroups{}
+	types := usageGroups{}
+	authors := usageGroups{}
+	mods := map[string]ModUsage{}
+	for _, mod := range repo.Mods {
+		usage := ModUsage{Name: mod.Name, Folder: mod.Folder, Author: mod.Author, Collections: len(mod.Collections)}
+		modTypes := usageGroups{}
+		root := filepath.Join(cfg.Mods.Path, mod.Path)
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				logger.Warn("Cannot access path %s: %v", path, err)
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			size := uint64(info.Size())
+			usage.Files++
+			usage.Size += size
+			modTypes.add(strings.ToLower(filepath.Ext(path)), 0, 1, size)
+			return nil
+		})
+		if usage.Size == 0 {
+			continue
+		}
+		usage.SizeHuman = humanize.Bytes(usage.Size)
+		mods[mod.Name] = usage
+		result.LargestMods = append(result.LargestMods, usage)
+
+		result.Mods++
+		result.TotalSize += usage.Size
+		if usage.Collections == 0 {
+			result.UnusedSize += usage.Size
+		}
+		folders.add(mod.Folder, 1, usage.Files, usage.Size)
+		authors.add(mod.Author, 1, usage.Files, usage.Size)
+		for ext, group := range modTypes {
+			types.add(ext, 1, group.Files, group.Size)
+		}
+	}
+
+	for _, col := range repo.Collections {
+		usage := CollectionUsage{Name: col.Name}
+		for _, mod := range col.Mods {
+			m, ok := mods[mod.Name]
+			if !ok {
+				continue // empty
+			}
+			usage.Mods++
+			usage.Size += m.Size
+			if m.Collections > 1 {
+				usage.SharedMods++
+				usage.SharedSize += m.Size
+			} else {
+				usage.ExclusiveMods++
+				usage.ExclusiveSize += m.Size
+			}
+		}
+		usage.SizeHuman = humanize.Bytes(usage.Size)
+		usage.ExclusiveSizeHuman = humanize.Bytes(usage.ExclusiveSize)
+		usage.SharedSizeHuman = humanize.Bytes(usage.SharedSize)
+		result.Collections = append(result.Collections, usage)
+	}
+
+	result.TotalSizeHuman = humanize.Bytes(result.TotalSize)
+	result.UnusedSizeHuman = humanize.Bytes(result.UnusedSize)
+	slices.SortFunc(result.Collections, func(a, b CollectionUsage) int {
+		return cmp.Or(cmp.Compare(b.Size, a.Size), strings.Compare(a.Name, b.Name))
+	})
+	if result.Collections == nil {
+		result.Collections = []CollectionUsage{}
+	}
+	result.Folders = folders.sorted()
+	result.Types = types.sorted()
+	result.Authors = authors.sorted()
+	slices.SortFunc(result.LargestMods, func(a, b ModUsage) int {
+		return cmp.Or(cmp.Compare(b.Size, a.Size), strings.Compare(a.Name, b.Name))
+	})
+	if result.LargestMods == nil {
+		result.LargestMods = []ModUsage{}
+	}
+	return result, nil
+}
+
+// usageGroups sums UsageGroups by name
+type usageGroups map[string]*UsageGroup
+
+func (g usageGroups) add(name string, mods, files int, size uint64) {
+	if g[name] == nil {
+		g[name] = &UsageGroup{Name: name}
+	}
+	g[name].Mods += mods
+	g[name].Files += files
+	g[name].Size += size
+}
+
+// sorted returns the groups heaviest first
+func (g usageGroups) sorted() []UsageGroup {
+	groups := make([]UsageGroup, 0, len(g))
+	for _, group := range g {
+		group.SizeHuman = humanize.Bytes(group.Size)
+		groups = append(groups, *group)
+	}
+	slices.SortFunc(groups, func(a, b UsageGroup) int {
+		return cmp.Or(cmp.Compare(b.Size, a.Size), strings.Compare(a.Name, b.Name))
+	})
+	return groups
+}
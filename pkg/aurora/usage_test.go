@@ -0,0 +1,106 @@
+package aurora
+
+import (
+	"aurora/internal/config"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestDiskUsage(t *testing.T) {
+	t.Run("rejects an invalid config", func(t *testing.T) {
+		a := newAurora(&config.Config{})
+		if _, err := a.DiskUsage(); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("expected ErrInvalidConfig, got %v", err)
+		}
+	})
+
+	a := newTestAurora(t, unusedMod, nil) // "Unused Mod" holds a.tex, 4 bytes
+	modsDir, penumbraDir := a.Config().Mods.Path, a.Config().Penumbra.Path
+	mods := map[string]map[string]string{
+		"Body":  {"a.tex": "0123456789", "b.mdl": "model", "meta.json": `{"Author":"Someone"}`}, // 35 bytes
+		"Hair":  {"hair.tex": "01234567890123456789", "meta.json": `{"Author":"Someone"}`},      // 40 bytes
+		"Voice": {"voice.scd": "sound", "README": "x"},                                          // 6 bytes
+		"Empty": {},
+	}
+	for mod, files := range mods {
+		os.MkdirAll(filepath.Join(modsDir, mod), 0755)
+		for name, content := range files {
+			os.WriteFile(filepath.Join(modsDir, mod, name), []byte(content), 0644)
+		}
+	}
+	os.WriteFile(filepath.Join(penumbraDir, "collections", "Main.json"),
+		[]byte(`{"Name":"Main","Settings":{"Body":{"Enabled":true},"Hair":{"Enabled":true},"Empty":{"Enabled":true}}}`), 0644)
+	os.WriteFile(filepath.Join(penumbraDir, "collections", "Alt.json"),
+		[]byte(`{"Name":"Alt","Settings":{"Body":{"Enabled":true},"Voice":{"Enabled":true},"Hair":{"Enabled":false}}}`), 0644)
+	os.WriteFile(filepath.Join(penumbraDir, "sort_order.json"),
+		[]byte(`{"Data":{"Body":"Bodies/Body","Hair":"Hair/Hair","Voice":"Voice"}}`), 0644)
+
+	result, err := a.DiskUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("sums every mod", func(t *testing.T) {
+		if result.Mods != 4 || result.TotalSize != 85 || result.UnusedSize != 4 {
+			t.Errorf("unexpected totals %+v", result)
+		}
+		var names []string
+		for _, mod := range result.LargestMods {
+			names = append(names, mod.Name)
+		}
+		if !slices.Equal(names, []string{"Hair", "Body", "Voice", "Unused Mod"}) {
+			t.Errorf("expected the largest mods first, got %v", names)
+		}
+		if body := result.LargestMods[1]; body.Collections != 2 || body.Files != 3 || body.Folder != "Bodies" || body.Author != "Someone" {
+			t.Errorf("unexpected mod usage %+v", body)
+		}
+	})
+
+	t.Run("splits collections into exclusive and shared mods", func(t *testing.T) {
+		want := []CollectionUsage{
+			{Name: "Main", Mods: 2, Size: 75, ExclusiveMods: 1, ExclusiveSize: 40, SharedMods: 1, SharedSize: 35},
+			{Name: "Alt", Mods: 2, Size: 41, ExclusiveMods: 1, ExclusiveSize: 6, SharedMods: 1, SharedSize: 35},
+		}
+		if len(result.Collections) != 2 {
+			t.Fatalf("expected 2 collections, got %+v", result.Collections)
+		}
+		for i, got := range result.Collections {
+			got.SizeHuman, got.ExclusiveSizeHuman, got.SharedSizeHuman = "", "", ""
+			if got != want[i] {
+				t.Errorf("expected %+v, got %+v", want[i], got)
+			}
+		}
+	})
+
+	t.Run("groups by folder, type and author", func(t *testing.T) {
+		group := func(groups []UsageGroup, name string) UsageGroup {
+			t.Helper()
+			i := slices.IndexFunc(groups, func(g UsageGroup) bool { return g.Name == name })
+			if i < 0 {
+				t.Fatalf("expected a %q group in %+v", name, groups)
+			}
+			return groups[i]
+		}
+		if got := group(result.Folders, ""); got.Mods != 2 || got.Size != 10 {
+			t.Errorf("expected Voice and Unused Mod at the root, got %+v", got)
+		}
+		if got := group(result.Types, ".tex"); got.Mods != 3 || got.Files != 3 || got.Size != 34 {
+			t.Errorf("unexpected textures %+v", got)
+		}
+		if got := result.Types[0]; got.Name != ".json" || got.Size != 40 {
+			t.Errorf("expected the heaviest type first, got %+v", got)
+		}
+		if got := group(result.Types, ""); got.Files != 1 || got.Size != 1 {
+			t.Errorf("expected files without extension grouped, got %+v", got)
+		}
+		if got := result.Authors[0]; got.Name != "Someone" || got.Mods != 2 || got.Size != 75 {
+			t.Errorf("expected Someone first, got %+v", got)
+		}
+		if got := group(result.Authors, ""); got.Mods != 2 {
+			t.Errorf("expected 2 mods without author, got %+v", got)
+		}
+	})
+}
@@ -0,0 +1,227 @@
+package aurora
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"aurora/internal/logger"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ErrNoBackup is returned when the output directory holds no backup set
+var ErrNoBackup = errors.New("no backup found in the output directory")
+
+// VerifyBackup checks the backup set in the output directory: archives
+// match the checksums in the manifest and every file in them reads back
+// intact. Encrypted sets are decrypted with passphrase on the way; a wrong
+// passphrase fails the whole check with ErrWrongPassphrase.
+func (a *Aurora) VerifyBackup(passphrase string) (VerifyResult, error) {
+	return verifyDir(a.Config().Output, passphrase)
+}
+
+// fetchFunc makes an archive of a backup set available as a local file,
+// returned with a cleanup deleting any copy it made
+type fetchFunc func(name string) (path string, cleanup func(), err error)
+
+// localArchives fetches the archives of the backup set in dir in place
+func localArchives(dir string) fetchFunc {
+	return func(name string) (string, func(), error) {
+		return filepath.Join(dir, name), func() {}, nil
+	}
+}
+
+// verifyDir checks the backup set in outputDir (see VerifyBackup)
+func verifyDir(outputDir, passphrase string) (VerifyResult, error) {
+	return verifySet(outputDir, ListBackupArchives(outputDir), localArchives(outputDir), passphrase)
+}
+
+// verifySet checks a backup set (see VerifyBackup) whose manifest is in
+// manifestDir, fetching each archive in turn. listed are its archives, used
+// when there is no manifest to name them.
+func verifySet(manifestDir string, listed []string, fetch fetchFunc, passphrase string) (VerifyResult, error) {
+	result := VerifyResult{Valid: true}
+
+	manifest, err := ReadManifest(manifestDir, passphrase)
+	switch {
+	case err == nil:
+		result.Manifest = true
+	case errors.Is(err, ErrWrongPassphrase), errors.Is(err, ErrPassphraseRequired):
+		return VerifyResult{}, err
+	case errors.Is(err, os.ErrNotExist):
+		logger.Warn("No backup manifest in %s: checking the archives without checksums", manifestDir)
+	default:
+		logger.Warn("Backup manifest unusable, checking the archives without checksums: %v", err)
+		result.Valid = false
+		result.ManifestError = err.Error()
+	}
+
+	archives := manifest.Parts
+	if !result.Manifest {
+		archives = listed
+	}
+	if len(archives) == 0 {
+		return VerifyResult{}, ErrNoBackup
+	}
+
+	for _, name := range archives {
+		format, _ := formatOfFile(name)
+		check := ArchiveCheck{
+			Name:           name,
+			Format:         format.name,
+			Encrypted:      strings.HasSuffix(name, EncryptedExt),
+			ContentChecked: format.name != FormatDelta,
+		}
+		path, cleanup, err := fetch(name)
+		if err == nil {
+			if info, statErr := os.Stat(path); statErr == nil {
+				check.Size = info.Size()
+			}
+			check.Files, err = verifyArchive(path, manifest.Checksums[name], passphrase)
+			cleanup()
+		}
+		if errors.Is(err, ErrWrongPassphrase) || errors.Is(err, ErrPassphraseRequired) {
+			return VerifyResult{}, err
+		}
+		if err != nil {
+			logger.Error("Backup archive %s failed verification: %v", name, err)
+			check.Error = err.Error()
+			result.Valid = false
+		}
+		result.Archives = append(result.Archives, check)
+	}
+
+	logger.Info("Backup verification: %d archives, valid=%v", len(result.Archives), result.Valid)
+	return result, nil
+}
+
+// verifyArchive checks one archive against its SHA-256 ("" = unknown) and
+// reads every file in it back, which checks their CRC (ZIP) or the zstd
+// frame checksums (tar.zst). go-delta native archives are only checked
+// against the checksum and decrypted: Aurora can't read their content.
+func verifyArchive(path, checksum, passphrase string) (files int, err error) {
+	if checksum != "" {
+		sum, err := fileSHA256(path)
+		if err != nil {
+			return 0, err
+		}
+		if sum != checksum {
+			return 0, errors.New("checksum mismatch: the file changed since the backup")
+		}
+	}
+
+	switch format, _ := formatOfFile(path); format.name {
+	case FormatTarZst:
+		return verifyTarZst(path, passphrase)
+	case FormatDelta:
+		return 0, verifyDecrypts(path, passphrase)
+	}
+
+	// ZIP needs random access: encrypted archives are decrypted to a
+	// temporary file first
+	zipPath := path
+	if strings.HasSuffix(path, EncryptedExt) {
+		zipPath, err = decryptToTemp(path, passphrase)
+		if err != nil {
+			return 0, err
+		}
+		defer os.Remove(zipPath)
+	}
+
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return 0, fmt.Errorf("open archive: %w", err)
+	}
+	defer reader.Close()
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return files, fmt.Errorf("%s: %w", file.Name, err)
+		}
+		_, err = io.Copy(io.Discard, rc)
+		rc.Close()
+		if err != nil {
+			return files, fmt.Errorf("%s: %w", file.Name, err)
+		}
+		files++
+	}
+	return files, nil
+}
+
+// verifyTarZst reads every file of a tar.zst archive back
+func verifyTarZst(path, passphrase string) (files int, err error) {
+	file, err := OpenBackupFile(path, passphrase)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	decoder, err := zstd.NewReader(file)
+	if err != nil {
+		return 0, err
+	}
+	defer decoder.Close()
+
+	reader := tar.NewReader(decoder)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return files, fmt.Errorf("read archive: %w", err)
+		}
+		if _, err := io.Copy(io.Discard, reader); err != nil {
+			return files, fmt.Errorf("%s: %w", header.Name, err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			files++
+		}
+	}
+}
+
+// verifyDecrypts reads an archive through, which authenticates every chunk
+// of an encrypted one; plain archives have nothing to check
+func verifyDecrypts(path, passphrase string) error {
+	if !strings.HasSuffix(path, EncryptedExt) {
+		return nil
+	}
+	file, err := OpenBackupFile(path, passphrase)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(io.Discard, file)
+	return err
+}
+
+// decryptToTemp writes the decrypted content of path to a temporary file
+// and returns its name; the caller removes it
+func decryptToTemp(path, passphrase string) (string, error) {
+	reader, err := OpenBackupFile(path, passphrase)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	tmp, err := os.CreateTemp("", "aurora-verify-*.zip")
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(tmp, reader)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
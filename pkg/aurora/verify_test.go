@@ -0,0 +1,176 @@
+package aurora
+
+import (
+	"archive/zip"
+	"aurora/internal/config"
+	"aurora/internal/paths"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/creativeyann17/go-delta/pkg/compress"
+)
+
+// writeZip creates a ZIP archive holding files
+func writeZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := zip.NewWriter(file)
+	for name, content := range files {
+		w, _ := writer.Create(name)
+		w.Write([]byte(content))
+	}
+	writer.Close()
+	file.Close()
+}
+
+// writeBackupSet writes a two part backup set with its manifest to
+// outputDir, encrypted when passphrase isn't ""
+func writeBackupSet(t *testing.T, outputDir, passphrase string) BackupManifest {
+	t.Helper()
+	manifest := BackupManifest{Version: manifestVersion, Encrypted: passphrase != "", Checksums: map[string]string{}}
+	for i, name := range []string{"backup_part_01.zip", "backup_part_02.zip"} {
+		path := filepath.Join(outputDir, name)
+		writeZip(t, path, map[string]string{"Mod/a.tex": "texture", "Mod/meta.json": "{}", "Mod" + string(rune('A'+i)) + "/b.mdl": "model"})
+		if passphrase != "" {
+			if err := encryptInPlace(path, passphrase); err != nil {
+				t.Fatal(err)
+			}
+			name += EncryptedExt
+			path += EncryptedExt
+		}
+		sum, _ := fileSHA256(path)
+		manifest.Parts = append(manifest.Parts, name)
+		manifest.Checksums[name] = sum
+	}
+	writeManifest(outputDir, manifest, passphrase)
+	return manifest
+}
+
+func TestVerifyBackup(t *testing.T) {
+	fastScrypt(t)
+	t.Setenv(paths.EnvHome, t.TempDir()) // manifest history
+
+	t.Run("plain backup set", func(t *testing.T) {
+		outputDir := t.TempDir()
+		writeBackupSet(t, outputDir, "")
+		a := newAurora(&config.Config{Output: outputDir})
+
+		result, err := a.VerifyBackup("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Valid || !result.Manifest || len(result.Archives) != 2 || result.Archives[0].Files != 3 {
+			t.Errorf("unexpected result %+v", result)
+		}
+	})
+
+	t.Run("encrypted backup set", func(t *testing.T) {
+		outputDir := t.TempDir()
+		writeBackupSet(t, outputDir, "secret")
+		a := newAurora(&config.Config{Output: outputDir})
+		if _, err := os.Stat(filepath.Join(outputDir, ManifestFile)); err == nil {
+			t.Error("expected no plain manifest next to encrypted archives")
+		}
+
+		result, err := a.VerifyBackup("secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Valid || !result.Manifest || len(result.Archives) != 2 || !result.Archives[1].Encrypted || result.Archives[1].Files != 3 {
+			t.Errorf("unexpected result %+v", result)
+		}
+
+		if _, err := a.VerifyBackup("wrong"); !errors.Is(err, ErrWrongPassphrase) {
+			t.Errorf("expected ErrWrongPassphrase, got %v", err)
+		}
+		if _, err := a.VerifyBackup(""); !errors.Is(err, ErrPassphraseRequired) {
+			t.Errorf("expected ErrPassphraseRequired, got %v", err)
+		}
+	})
+
+	t.Run("reports tampered archives", func(t *testing.T) {
+		outputDir := t.TempDir()
+		manifest := writeBackupSet(t, outputDir, "secret")
+		a := newAurora(&config.Config{Output: outputDir})
+		path := filepath.Join(outputDir, manifest.Parts[1])
+		content, _ := os.ReadFile(path)
+		content[len(content)-20] ^= 1
+		os.WriteFile(path, content, 0644)
+
+		result, err := a.VerifyBackup("secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Valid || result.Archives[0].Error != "" || result.Archives[1].Error == "" {
+			t.Errorf("expected only the second archive to fail, got %+v", result)
+		}
+
+		// Without the checksum, decryption still catches it
+		delete(manifest.Checksums, manifest.Parts[1])
+		writeManifest(outputDir, manifest, "secret")
+		_, err = verifyArchive(path, "", "secret")
+		if !errors.Is(err, ErrTampered) {
+			t.Errorf("expected ErrTampered, got %v", err)
+		}
+	})
+
+	t.Run("reads tar.zst archives", func(t *testing.T) {
+		outputDir := t.TempDir()
+		folders := writeMods(t, map[string]map[string]string{"Mod": {"a.tex": "texture", "b.mdl": "model"}})
+		opts := NewBackupOptions(folders, 1, FormatTarZst, CompressionNormal, outputDir, true)
+		if _, _, err := writeTarZst(context.Background(), opts, "", func(compress.ProgressEvent) {}); err != nil {
+			t.Fatal(err)
+		}
+		encryptInPlace(opts.OutputPath, "secret")
+		a := newAurora(&config.Config{Output: outputDir})
+
+		result, err := a.VerifyBackup("secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Valid || len(result.Archives) != 1 || result.Archives[0].Format != FormatTarZst || result.Archives[0].Files != 2 {
+			t.Errorf("unexpected result %+v", result)
+		}
+	})
+
+	t.Run("checks go-delta native archives by checksum only", func(t *testing.T) {
+		outputDir := t.TempDir()
+		os.WriteFile(filepath.Join(outputDir, "backup_part.gdelta"), []byte("native"), 0644)
+		a := newAurora(&config.Config{Output: outputDir})
+
+		result, err := a.VerifyBackup("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Valid || len(result.Archives) != 1 || result.Archives[0].ContentChecked {
+			t.Errorf("unexpected result %+v", result)
+		}
+	})
+
+	t.Run("checks archives without a manifest", func(t *testing.T) {
+		outputDir := t.TempDir()
+		writeZip(t, filepath.Join(outputDir, "backup_part.zip"), map[string]string{"Mod/a.tex": "texture"})
+		a := newAurora(&config.Config{Output: outputDir})
+
+		result, err := a.VerifyBackup("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Valid || result.Manifest || len(result.Archives) != 1 {
+			t.Errorf("unexpected result %+v", result)
+		}
+	})
+
+	t.Run("fails without a backup", func(t *testing.T) {
+		a := newAurora(&config.Config{Output: t.TempDir()})
+		if _, err := a.VerifyBackup(""); !errors.Is(err, ErrNoBackup) {
+			t.Errorf("expected ErrNoBackup, got %v", err)
+		}
+	})
+}
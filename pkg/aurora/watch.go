@@ -0,0 +1,360 @@
+package aurora
+
+import (
+	"aurora/internal/logger"
+	"aurora/internal/paths"
+	"aurora/internal/repository"
+	"aurora/internal/util"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultWatchQuiet is how long mods must stay unchanged before aurora
+// watch backs them up
+const DefaultWatchQuiet = time.Minute
+
+// watchHeartbeat is how often a watcher rewrites its status file; a status
+// older than a few heartbeats belongs to a watcher that died
+const watchHeartbeat = 30 * time.Second
+
+// States of a watcher, see WatchStatus
+const (
+	WatchIdle      = "watching"   // no change since the last backup
+	WatchWaiting   = "waiting"    // changes wait for the quiet period
+	WatchBackingUp = "backing up" // an incremental backup runs
+	WatchStopped   = "stopped"
+)
+
+// WatchOptions configures Aurora.Watch
+type WatchOptions struct {
+	Quiet      time.Duration // quiet period before a backup; 0 = DefaultWatchQuiet
+	Threads    int           // compression workers; 0 = the configured concurrency
+	Passphrase string        // encrypts the backups when encryption is on
+}
+
+// WatchObserver follows a watcher: its notices and every incremental
+// backup it runs
+type WatchObserver interface {
+	BackupObserver
+	BackupStarted(mods []string)
+	BackupFinished(result BackupResult, err error)
+}
+
+// WatchStatus is the status file of a watcher (see paths.WatchStatusFile)
+type WatchStatus struct {
+	PID         int          `json:"pid"`
+	StartedAt   time.Time    `json:"startedAt"`
+	UpdatedAt   time.Time    `json:"updatedAt"` // heartbeat
+	ModsPath    string       `json:"modsPath"`
+	State       string       `json:"state"`       // see WatchIdle
+	PendingMods []string     `json:"pendingMods"` // changed mods not backed up yet
+	LastBackup  *WatchBackup `json:"lastBackup,omitempty"`
+	Active      bool         `json:"active"` // set by ReadWatchStatus: the watcher runs
+}
+
+// WatchBackup is the last incremental backup of a watcher
+type WatchBackup struct {
+	At        time.Time `json:"at"`
+	Mods      []string  `json:"mods"`
+	OutputDir string    `json:"outputDir,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// ReadWatchStatus reads the watcher status file. Active tells whether the
+// watcher still runs: not stopped, and its heartbeat is recent. Fails with
+// an os.ErrNotExist error when no watcher ever ran.
+func ReadWatchStatus() (WatchStatus, error) {
+	var status WatchStatus
+	if err := util.ReadJSONFile(paths.WatchStatusFile(), &status); err != nil {
+		return WatchStatus{}, err
+	}
+	status.Active = status.State != WatchStopped && time.Since(status.UpdatedAt) < 3*watchHeartbeat
+	return status, nil
+}
+
+// save writes the status file, logging failures: the watcher keeps going
+func (s *WatchStatus) save() {
+	s.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err == nil {
+		path := paths.WatchStatusFile()
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+			tmp := path + ".tmp"
+			if err = os.WriteFile(tmp, data, 0644); err == nil {
+				err = os.Rename(tmp, path)
+			}
+		}
+	}
+	if err != nil {
+		logger.Warn("Failed to write watch status: %v", err)
+	}
+}
+
+// watchRun is the outcome of one incremental backup
+type watchRun struct {
+	mods   []string
+	result BackupResult
+	err    error
+}
+
+// Watch monitors the mods folder and Penumbra's collections until ctx is
+// done. Once changes settle for the quiet period it runs an incremental
+// backup (see RunBackupOptions.Mods) of the changed mods it selects, plus
+// mods an edited collection newly selects. Mods of a failed backup are retried
+// with the next one; while another process backs up to the output directory
+// (ErrBackupRunning), after each quiet period until it is done. It fails with ErrInvalidConfig or when the folders
+// can't be watched.
+func (a *Aurora) Watch(ctx context.Context, opts WatchOptions, observer WatchObserver) error {
+	cfg := a.Config()
+	if !cfg.Status().Valid {
+		return ErrInvalidConfig
+	}
+	quiet := opts.Quiet
+	if quiet <= 0 {
+		quiet = DefaultWatchQuiet
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("start watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	modsPath := filepath.Clean(cfg.Mods.Path)
+	collectionsDir := repository.CollectionsDir(cfg)
+	ignored := []string{stagingPath(cfg.Output), filepath.Join(cfg.Output, IncrementalDir), filepath.Join(cfg.Output, BackupLockFile)}
+	if err := watchTree(watcher, modsPath, ignored); err != nil {
+		return fmt.Errorf("watch %s: %w", modsPath, err)
+	}
+	if err := watcher.Add(collectionsDir); err != nil {
+		return fmt.Errorf("watch %s: %w", collectionsDir, err)
+	}
+
+	selected := a.selectedMods(nil)
+	status := &WatchStatus{PID: os.Getpid(), StartedAt: time.Now(), ModsPath: modsPath, State: WatchIdle}
+	status.save()
+	defer func() {
+		status.State = WatchStopped
+		status.save()
+	}()
+	logger.Info("Watching %s and %s (quiet period %s)", modsPath, collectionsDir, quiet)
+	observer.Notice(fmt.Sprintf("Watching %s for changes (backup after %s without changes)", modsPath, quiet))
+
+	pending := make(map[string]bool) // changed mods
+	collectionsChanged := false
+	quietTimer := time.NewTimer(quiet)
+	quietTimer.Stop()
+	heartbeat := time.NewTicker(watchHeartbeat)
+	defer heartbeat.Stop()
+	var running chan watchRun // set while a backup runs
+	due := false              // the quiet period ended while a backup ran
+
+	setState := func() {
+		status.PendingMods = slices.Sorted(maps.Keys(pending))
+		switch {
+		case running != nil:
+			status.State = WatchBackingUp
+		case len(pending) > 0 || collectionsChanged:
+			status.State = WatchWaiting
+		default:
+			status.State = WatchIdle
+		}
+		status.save()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			if running != nil {
+				<-running // RunBackup returns once cancelled
+			}
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if isIgnored(event.Name, ignored) {
+				continue
+			}
+			if isUnder(event.Name, collectionsDir) {
+				collectionsChanged = true
+			} else if mod := modOf(modsPath, event.Name); mod != "" {
+				pending[mod] = true
+				if event.Has(fsnotify.Create) {
+					if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+						watchTree(watcher, event.Name, ignored)
+					}
+				}
+			} else {
+				continue
+			}
+			quietTimer.Reset(quiet)
+			if status.State != WatchWaiting && running == nil {
+				setState()
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			logger.Warn("Watcher error: %v", err)
+			observer.Notice(fmt.Sprintf("Warning: %v", err))
+
+		case <-quietTimer.C:
+			if running != nil {
+				due = true
+				continue
+			}
+			running = a.startWatchBackup(ctx, opts, observer, pending, &collectionsChanged, &selected)
+			setState()
+
+		case run := <-running:
+			running = nil
+			observer.BackupFinished(run.result, run.err)
+			if errors.Is(run.err, ErrBackupRunning) {
+				// Another process backs up to the output directory: the
+				// mods wait another quiet period, and again until it is done
+				observer.Notice(fmt.Sprintf("Another backup is running, retrying in %s", quiet))
+				for _, mod := range run.mods {
+					pending[mod] = true
+				}
+				due = false
+				quietTimer.Reset(quiet)
+				setState()
+				continue
+			}
+			status.LastBackup = &WatchBackup{At: time.Now(), Mods: run.mods, OutputDir: run.result.OutputDir}
+			switch {
+			case run.err == nil, errors.Is(run.err, ErrNothingToBackup):
+			case errors.Is(run.err, ErrBackupCancelled) && ctx.Err() != nil:
+				return nil
+			default:
+				// Retried with the next backup
+				status.LastBackup.Error = run.err.Error()
+				for _, mod := range run.mods {
+					pending[mod] = true
+				}
+			}
+			if due {
+				due = false
+				quietTimer.Reset(0)
+			}
+			setState()
+
+		case <-heartbeat.C:
+			status.save()
+		}
+	}
+}
+
+// startWatchBackup starts an incremental backup of the pending mods the
+// backup selects and of mods it newly selects, clearing them; the run is
+// reported on the returned channel. Returns nil when there is nothing to
+// back up.
+func (a *Aurora) startWatchBackup(ctx context.Context, opts WatchOptions, observer WatchObserver, pending map[string]bool, collectionsChanged *bool, selected *map[string]bool) chan watchRun {
+	*collectionsChanged = false
+	now := a.selectedMods(*selected)
+	for mod := range now {
+		if !(*selected)[mod] {
+			pending[mod] = true
+		}
+	}
+	*selected = now
+	// Changes to mods outside the backup don't matter
+	maps.DeleteFunc(pending, func(mod string, _ bool) bool { return !now[mod] })
+	if len(pending) == 0 {
+		return nil
+	}
+	mods := slices.Sorted(maps.Keys(pending))
+	clear(pending)
+
+	runs := make(chan watchRun, 1)
+	observer.BackupStarted(mods)
+	go func() {
+		result, err := a.RunBackup(ctx, RunBackupOptions{Threads: opts.Threads, Passphrase: opts.Passphrase, Mods: mods}, observer)
+		runs <- watchRun{mods: mods, result: result, err: err}
+	}()
+	return runs
+}
+
+// selectedMods returns the mod folder names the backup selects, previous
+// when they can't be read
+func (a *Aurora) selectedMods(previous map[string]bool) map[string]bool {
+	folders, err := a.GetBackupFolders()
+	if err != nil {
+		logger.Warn("Failed to read the mods selected for backup: %v", err)
+		return previous
+	}
+	selected := make(map[string]bool, len(folders))
+	for _, folder := range folders {
+		selected[filepath.Base(folder)] = true
+	}
+	return selected
+}
+
+// watchTree watches dir and its subdirectories, skipping ignored ones.
+// fsnotify doesn't watch recursively.
+func watchTree(watcher *fsnotify.Watcher, dir string, ignored []string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			logger.Warn("Cannot watch %s: %v", path, err)
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if isIgnored(path, ignored) {
+			return filepath.SkipDir
+		}
+		if err := watcher.Add(path); err != nil {
+			if path == dir {
+				return err
+			}
+			logger.Warn("Cannot watch %s: %v", path, err)
+		}
+		return nil
+	})
+}
+
+// modOf returns the mod folder name of a path under modsPath, "" for the
+// mods folder itself or a path outside it
+func modOf(modsPath, path string) string {
+	rel, err := filepath.Rel(modsPath, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	mod, _, _ := strings.Cut(rel, string(filepath.Separator))
+	return mod
+}
+
+// isUnder reports whether path is dir or inside it
+func isUnder(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// isIgnored reports whether path is inside one of dirs: Aurora's own
+// output when it lives in the mods folder
+func isIgnored(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if isUnder(path, dir) {
+			return true
+		}
+	}
+	return false
+}
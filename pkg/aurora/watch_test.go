@@ -0,0 +1,165 @@
+package aurora
+
+import (
+	"aurora/internal/paths"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// watchObserver hands the backups of a watcher to the test
+type watchObserver struct {
+	started  chan []string
+	finished chan error
+	results  chan BackupResult
+}
+
+func (o *watchObserver) Validated(BackupValidation)  {}
+func (o *watchObserver) Notice(string)               {}
+func (o *watchObserver) Progress(BackupProgress)     {}
+func (o *watchObserver) BackupStarted(mods []string) { o.started <- mods }
+func (o *watchObserver) BackupFinished(result BackupResult, err error) {
+	o.results <- result
+	o.finished <- err
+}
+
+func TestModOf(t *testing.T) {
+	mods := filepath.Join("game", "mods")
+	tests := []struct {
+		path string
+		want string
+	}{
+		{filepath.Join(mods, "Body Mod"), "Body Mod"},
+		{filepath.Join(mods, "Body Mod", "chara", "a.tex"), "Body Mod"},
+		{mods, ""},
+		{filepath.Join("game", "other", "a.tex"), ""},
+		{filepath.Join("game", "mods2", "a.tex"), ""},
+	}
+	for _, tt := range tests {
+		if got := modOf(mods, tt.path); got != tt.want {
+			t.Errorf("modOf(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	t.Run("rejects an invalid config", func(t *testing.T) {
+		a := newTestAurora(t, unusedMod, nil)
+		configure(t, a.SetModsPath(filepath.Join(t.TempDir(), "missing")))
+		if err := a.Watch(context.Background(), WatchOptions{}, &watchObserver{}); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("expected ErrInvalidConfig, got %v", err)
+		}
+	})
+
+	t.Run("backs up changed mods once they settle", func(t *testing.T) {
+		a := newTestAurora(t, unusedMod, nil)
+		configure(t, a.SetFormat(FormatTarZst))
+		modsDir := a.Config().Mods.Path
+		os.MkdirAll(filepath.Join(modsDir, "Body Mod"), 0755)
+		os.WriteFile(filepath.Join(modsDir, "Body Mod", "a.tex"), []byte("texture"), 0644)
+		collection := filepath.Join(a.Config().Penumbra.Path, "collections", "Main.json")
+		os.WriteFile(collection, []byte(`{"Name":"Main","Settings":{"Body Mod":{"Enabled":true}}}`), 0644)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		observer := &watchObserver{started: make(chan []string, 1), finished: make(chan error, 1), results: make(chan BackupResult, 1)}
+		done := make(chan error, 1)
+		go func() { done <- a.Watch(ctx, WatchOptions{Quiet: 100 * time.Millisecond}, observer) }()
+
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			if status, err := ReadWatchStatus(); err == nil && status.Active {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("watcher never reported itself active")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		expectBackup := func(want []string) {
+			t.Helper()
+			select {
+			case mods := <-observer.started:
+				if !slices.Equal(mods, want) {
+					t.Errorf("backed up %v, want %v", mods, want)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("no backup of %v", want)
+			}
+			result := <-observer.results
+			if err := <-observer.finished; err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(result.OutputDir, filepath.Join(a.Config().Output, IncrementalDir)) {
+				t.Errorf("expected an incremental output dir, got %q", result.OutputDir)
+			}
+		}
+
+		// The unused mod isn't in the backup
+		os.WriteFile(filepath.Join(modsDir, "Body Mod", "b.mdl"), []byte("model"), 0644)
+		os.WriteFile(filepath.Join(modsDir, "Unused Mod", "b.mdl"), []byte("model"), 0644)
+		expectBackup([]string{"Body Mod"})
+
+		// A collection enabling a mod backs it up
+		os.WriteFile(collection, []byte(`{"Name":"Main","Settings":{"Body Mod":{"Enabled":true},"Unused Mod":{"Enabled":true}}}`), 0644)
+		expectBackup([]string{"Unused Mod"})
+
+		// Another process backing up holds the changes back until it is done
+		lock, err := lockOutput(a.Config().Output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.WriteFile(filepath.Join(modsDir, "Body Mod", "c.mtrl"), []byte("material"), 0644)
+		<-observer.started
+		<-observer.results
+		if err := <-observer.finished; !errors.Is(err, ErrBackupRunning) {
+			t.Fatalf("expected ErrBackupRunning, got %v", err)
+		}
+		lock.release()
+		expectBackup([]string{"Body Mod"})
+
+		cancel()
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+		status, err := ReadWatchStatus()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.Active || status.State != WatchStopped || status.LastBackup == nil || !slices.Equal(status.LastBackup.Mods, []string{"Body Mod"}) {
+			t.Errorf("unexpected status %+v", status)
+		}
+	})
+}
+
+func TestReadWatchStatus(t *testing.T) {
+	t.Setenv(paths.EnvHome, t.TempDir())
+
+	if _, err := ReadWatchStatus(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist, got %v", err)
+	}
+
+	status := &WatchStatus{PID: 42, State: WatchWaiting, PendingMods: []string{"Body Mod"}}
+	status.save()
+	got, err := ReadWatchStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Active || got.PID != 42 || !slices.Equal(got.PendingMods, []string{"Body Mod"}) {
+		t.Errorf("unexpected status %+v", got)
+	}
+
+	// A watcher that stopped writing its heartbeat died
+	stale, _ := json.Marshal(WatchStatus{State: WatchIdle, UpdatedAt: time.Now().Add(-time.Hour)})
+	os.WriteFile(paths.WatchStatusFile(), stale, 0644)
+	if got, _ := ReadWatchStatus(); got.Active {
+		t.Error("expected a stale watcher to be inactive")
+	}
+}